@@ -13,10 +13,16 @@ type MetricMaker interface {
 	Log() telegraf.Logger
 }
 
+// errorRecorder is implemented by makers keeping track of their last error.
+type errorRecorder interface {
+	SetLastError(err error)
+}
+
 type accumulator struct {
 	maker     MetricMaker
 	metrics   chan<- telegraf.Metric
 	precision time.Duration
+	now       func() time.Time
 }
 
 func NewAccumulator(
@@ -27,6 +33,24 @@ func NewAccumulator(
 		maker:     maker,
 		metrics:   metrics,
 		precision: time.Nanosecond,
+		now:       time.Now,
+	}
+	return &acc
+}
+
+// newSimulatedAccumulator returns an accumulator using ts as the time of all
+// metrics added without a timestamp.  It is used to simulate gathers at
+// different times in test mode.
+func newSimulatedAccumulator(
+	maker MetricMaker,
+	metrics chan<- telegraf.Metric,
+	ts time.Time,
+) telegraf.Accumulator {
+	acc := accumulator{
+		maker:     maker,
+		metrics:   metrics,
+		precision: time.Nanosecond,
+		now:       func() time.Time { return ts },
 	}
 	return &acc
 }
@@ -103,6 +127,9 @@ func (ac *accumulator) AddError(err error) {
 		return
 	}
 	ac.maker.Log().Errorf("Error in plugin: %v", err)
+	if r, ok := ac.maker.(errorRecorder); ok {
+		r.SetLastError(err)
+	}
 }
 
 func (ac *accumulator) SetPrecision(precision time.Duration) {
@@ -114,7 +141,7 @@ func (ac *accumulator) getTime(t []time.Time) time.Time {
 	if len(t) > 0 {
 		timestamp = t[0]
 	} else {
-		timestamp = time.Now()
+		timestamp = ac.now()
 	}
 	return timestamp.Round(ac.precision)
 }
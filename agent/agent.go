@@ -20,6 +20,12 @@ import (
 // Agent runs a set of plugins.
 type Agent struct {
 	Config *config.Config
+
+	// running holds the units started by Run, it is nil if the agent is not
+	// running.  The mutex guards the running units and the configuration
+	// against concurrent reloads.
+	mu      sync.Mutex
+	running *runningUnits
 }
 
 // NewAgent returns an Agent for the given Config.
@@ -30,7 +36,8 @@ func NewAgent(config *config.Config) (*Agent, error) {
 	return a, nil
 }
 
-// inputUnit is a group of input plugins and the shared channel they write to.
+// inputUnit is a group of input plugins and the shared channels they write
+// to, one channel per pipeline.
 //
 // ┌───────┐
 // │ Input │───┐
@@ -42,8 +49,24 @@ func NewAgent(config *config.Config) (*Agent, error) {
 // │ Input │───┘
 // └───────┘
 type inputUnit struct {
-	dst    chan<- telegraf.Metric
+	dsts   map[string]chan<- telegraf.Metric
 	inputs []*models.RunningInput
+
+	// gather loops of the inputs, used to stop individual inputs
+	wg      sync.WaitGroup
+	running map[*models.RunningInput]*runningLoop
+}
+
+// dst returns the channel of the input's pipeline.
+func (u *inputUnit) dst(input *models.RunningInput) chan<- telegraf.Metric {
+	return u.dsts[input.Config.Pipeline]
+}
+
+// closeAll closes the channels of all pipelines.
+func (u *inputUnit) closeAll() {
+	for _, dst := range u.dsts {
+		close(dst)
+	}
 }
 
 //  ______     ┌───────────┐     ______
@@ -75,10 +98,43 @@ type aggregatorUnit struct {
 	aggC        chan<- telegraf.Metric
 	outputC     chan<- telegraf.Metric
 	aggregators []*models.RunningAggregator
+	agent       *config.AgentConfig
+
+	// window is the length of the simulated aggregation window in test
+	// mode, if set all metrics of the window are aggregated at once.
+	window time.Duration
+}
+
+// chainUnit is the chain of processors and aggregators between the inputs and
+// the outputs.  The chain is started as a whole and writes to the outputs
+// channel until its source channel is closed and all metrics are processed.
+//
+//  ______     ┌────────────┐     ┌─────────────┐     ┌────────────┐     ______
+// ()_____)──▶ │ Processors │──▶ │ Aggregators │──▶ │ Processors │──▶ ()_____)
+//             └────────────┘     └─────────────┘     └────────────┘
+type chainUnit struct {
+	src  chan<- telegraf.Metric
+	done <-chan struct{}
 }
 
-// outputUnit is a group of Outputs and their source channel.  Metrics on the
-// channel are written to all outputs.
+// relayUnit forwards the metrics of the inputs to the current chain.  A new
+// chain can be swapped in while running, the previous chain is closed and
+// drains into the outputs.  The outputs channel is closed once the source
+// channel is closed and all chains are done.
+//
+//  ______     ┌───────┐     ┌───────┐     ______
+// ()_____)──▶ │ Relay │──▶ │ Chain │──▶ ()_____)
+//             └───────┘     └───────┘
+type relayUnit struct {
+	src   <-chan telegraf.Metric
+	dst   chan<- telegraf.Metric
+	chain *chainUnit
+	swap  chan *chainUnit
+}
+
+// outputUnit is a group of Outputs and their source channels, one channel per
+// pipeline.  Metrics on the channel of a pipeline are written to all outputs
+// of the pipeline.
 //
 //                            ┌────────┐
 //                       ┌──▶ │ Output │
@@ -90,8 +146,52 @@ type aggregatorUnit struct {
 //                       └──▶ │ Output │
 //                            └────────┘
 type outputUnit struct {
-	src     <-chan telegraf.Metric
+	srcs    map[string]<-chan telegraf.Metric
 	outputs []*models.RunningOutput
+
+	// The lock is held for reading while passing a metric to the outputs,
+	// locking it pauses the outputs so they can be replaced.
+	sync.RWMutex
+	wg      sync.WaitGroup
+	running map[*models.RunningOutput]*runningLoop
+}
+
+// runningLoop is the gather or flush loop of a single plugin.
+type runningLoop struct {
+	cancel  context.CancelFunc
+	done    chan struct{}
+	trigger chan struct{}
+}
+
+func newRunningLoop(cancel context.CancelFunc) *runningLoop {
+	return &runningLoop{
+		cancel:  cancel,
+		done:    make(chan struct{}),
+		trigger: make(chan struct{}, 1),
+	}
+}
+
+// stop ends the loop and waits for it to finish.
+func (l *runningLoop) stop() {
+	l.cancel()
+	<-l.done
+}
+
+// run requests an additional run of the loop outside of its interval.  A
+// request is dropped if another one is still pending.
+func (l *runningLoop) run() {
+	select {
+	case l.trigger <- struct{}{}:
+	default:
+	}
+}
+
+// runningUnits are the units started by Run, there is a relay for each
+// pipeline.
+type runningUnits struct {
+	inputs  *inputUnit
+	relays  map[string]*relayUnit
+	outputs *outputUnit
 }
 
 // Run starts and runs the Agent until the context is done.
@@ -106,42 +206,50 @@ func (a *Agent) Run(ctx context.Context) error {
 	if err != nil {
 		return err
 	}
+	if err := a.Config.CheckPipelines(); err != nil {
+		return err
+	}
 
 	startTime := time.Now()
+	pipelines := a.Config.Pipelines()
 
 	log.Printf("D! [agent] Connecting outputs")
-	next, ou, err := a.startOutputs(ctx, a.Config.Outputs)
+	outputCs, ou, err := a.startOutputs(ctx, a.Config.Outputs, pipelines)
 	if err != nil {
 		return err
 	}
 
-	var apu []*processorUnit
-	var au *aggregatorUnit
-	if len(a.Config.Aggregators) != 0 {
-		aggC := next
-		if len(a.Config.AggProcessors) != 0 {
-			aggC, apu, err = a.startProcessors(next, a.Config.AggProcessors)
-			if err != nil {
-				return err
-			}
-		}
-
-		next, au = a.startAggregators(aggC, next, a.Config.Aggregators)
-	}
-
-	var pu []*processorUnit
-	if len(a.Config.Processors) != 0 {
-		next, pu, err = a.startProcessors(next, a.Config.Processors)
+	inputCs := make(map[string]chan<- telegraf.Metric, len(pipelines))
+	relays := make(map[string]*relayUnit, len(pipelines))
+	for _, pipeline := range pipelines {
+		chain, err := a.startChain(pipeline, outputCs[pipeline], startTime, 0)
 		if err != nil {
 			return err
 		}
+
+		inputC := make(chan telegraf.Metric, 100)
+		inputCs[pipeline] = inputC
+		relays[pipeline] = &relayUnit{
+			src:   inputC,
+			dst:   outputCs[pipeline],
+			chain: chain,
+			swap:  make(chan *chainUnit),
+		}
 	}
 
-	iu, err := a.startInputs(next, a.Config.Inputs)
+	iu, err := a.startInputs(inputCs, startTime, a.Config.Inputs)
 	if err != nil {
 		return err
 	}
 
+	a.mu.Lock()
+	a.running = &runningUnits{
+		inputs:  iu,
+		relays:  relays,
+		outputs: ou,
+	}
+	a.mu.Unlock()
+
 	var wg sync.WaitGroup
 	wg.Add(1)
 	go func() {
@@ -149,32 +257,18 @@ func (a *Agent) Run(ctx context.Context) error {
 		a.runOutputs(ou)
 	}()
 
-	if au != nil {
-		wg.Add(1)
-		go func() {
-			defer wg.Done()
-			a.runProcessors(apu)
-		}()
-
-		wg.Add(1)
-		go func() {
-			defer wg.Done()
-			a.runAggregators(startTime, au)
-		}()
-	}
-
-	if pu != nil {
+	for _, ru := range relays {
 		wg.Add(1)
-		go func() {
+		go func(ru *relayUnit) {
 			defer wg.Done()
-			a.runProcessors(pu)
-		}()
+			a.runRelay(ru)
+		}(ru)
 	}
 
 	wg.Add(1)
 	go func() {
 		defer wg.Done()
-		a.runInputs(ctx, startTime, iu)
+		a.runInputs(ctx, iu)
 	}()
 
 	wg.Wait()
@@ -230,98 +324,142 @@ func (a *Agent) initPlugins() error {
 	return nil
 }
 
+// startInputs starts all service inputs and the periodic gather for all
+// inputs.  If an error occurs starting a service input all started service
+// inputs are stopped.
 func (a *Agent) startInputs(
-	dst chan<- telegraf.Metric,
+	dsts map[string]chan<- telegraf.Metric,
+	startTime time.Time,
 	inputs []*models.RunningInput,
 ) (*inputUnit, error) {
 	log.Printf("D! [agent] Starting service inputs")
 
 	unit := &inputUnit{
-		dst: dst,
+		dsts:    dsts,
+		running: make(map[*models.RunningInput]*runningLoop),
 	}
 
 	for _, input := range inputs {
-		if si, ok := input.Input.(telegraf.ServiceInput); ok {
-			// Service input plugins are not normally subject to timestamp
-			// rounding except for when precision is set on the input plugin.
-			//
-			// This only applies to the accumulator passed to Start(), the
-			// Gather() accumulator does apply rounding according to the
-			// precision and interval agent/plugin settings.
-			var interval time.Duration
-			var precision time.Duration
-			if input.Config.Precision != 0 {
-				precision = input.Config.Precision
-			}
-
-			acc := NewAccumulator(input, dst)
-			acc.SetPrecision(getPrecision(precision, interval))
-
-			err := si.Start(acc)
-			if err != nil {
-				stopServiceInputs(unit.inputs)
-				return nil, fmt.Errorf("starting input %s: %w", input.LogName(), err)
-			}
+		if err := startServiceInput(unit.dst(input), input); err != nil {
+			stopServiceInputs(unit.inputs)
+			return nil, fmt.Errorf("starting input %s: %w", input.LogName(), err)
 		}
 		unit.inputs = append(unit.inputs, input)
 	}
 
+	for _, input := range unit.inputs {
+		a.gatherInput(startTime, unit, input)
+	}
+
 	return unit, nil
 }
 
-// runInputs starts and triggers the periodic gather for Inputs.
-//
-// When the context is done the timers are stopped and this function returns
-// after all ongoing Gather calls complete.
-func (a *Agent) runInputs(
-	ctx context.Context,
+// startServiceInput calls Start if the input is a service input.
+func startServiceInput(dst chan<- telegraf.Metric, input *models.RunningInput) error {
+	si, ok := input.Input.(telegraf.ServiceInput)
+	if !ok {
+		return nil
+	}
+
+	// Service input plugins are not normally subject to timestamp
+	// rounding except for when precision is set on the input plugin.
+	//
+	// This only applies to the accumulator passed to Start(), the
+	// Gather() accumulator does apply rounding according to the
+	// precision and interval agent/plugin settings.
+	var interval time.Duration
+	var precision time.Duration
+	if input.Config.Precision != 0 {
+		precision = input.Config.Precision
+	}
+
+	acc := NewAccumulator(input, dst)
+	acc.SetPrecision(getPrecision(precision, interval))
+
+	return si.Start(acc)
+}
+
+// gatherInput starts the periodic gather for the input.
+func (a *Agent) gatherInput(
 	startTime time.Time,
 	unit *inputUnit,
+	input *models.RunningInput,
 ) {
-	var wg sync.WaitGroup
-	for _, input := range unit.inputs {
-		// Overwrite agent interval if this plugin has its own.
-		interval := time.Duration(a.Config.Agent.Interval)
-		if input.Config.Interval != 0 {
-			interval = input.Config.Interval
-		}
+	// Overwrite agent interval if this plugin has its own.
+	interval := time.Duration(a.Config.Agent.Interval)
+	if input.Config.Interval != 0 {
+		interval = input.Config.Interval
+	}
 
-		// Overwrite agent precision if this plugin has its own.
-		precision := time.Duration(a.Config.Agent.Precision)
-		if input.Config.Precision != 0 {
-			precision = input.Config.Precision
-		}
+	// Overwrite agent precision if this plugin has its own.
+	precision := time.Duration(a.Config.Agent.Precision)
+	if input.Config.Precision != 0 {
+		precision = input.Config.Precision
+	}
 
-		// Overwrite agent collection_jitter if this plugin has its own.
-		jitter := time.Duration(a.Config.Agent.CollectionJitter)
-		if input.Config.CollectionJitter != 0 {
-			jitter = input.Config.CollectionJitter
-		}
+	// Overwrite agent collection_jitter if this plugin has its own.
+	jitter := time.Duration(a.Config.Agent.CollectionJitter)
+	if input.Config.CollectionJitter != 0 {
+		jitter = input.Config.CollectionJitter
+	}
 
-		var ticker Ticker
-		if a.Config.Agent.RoundInterval {
-			ticker = NewAlignedTicker(startTime, interval, jitter)
-		} else {
-			ticker = NewUnalignedTicker(interval, jitter)
-		}
-		defer ticker.Stop()
+	var ticker Ticker
+	if a.Config.Agent.RoundInterval {
+		ticker = NewAlignedTicker(startTime, interval, jitter)
+	} else {
+		ticker = NewUnalignedTicker(interval, jitter)
+	}
 
-		acc := NewAccumulator(input, unit.dst)
-		acc.SetPrecision(getPrecision(precision, interval))
+	acc := NewAccumulator(input, unit.dst(input))
+	acc.SetPrecision(getPrecision(precision, interval))
 
-		wg.Add(1)
-		go func(input *models.RunningInput) {
-			defer wg.Done()
-			a.gatherLoop(ctx, acc, input, ticker, interval)
-		}(input)
+	ctx, cancel := context.WithCancel(context.Background())
+	loop := newRunningLoop(cancel)
+	unit.running[input] = loop
+
+	unit.wg.Add(1)
+	go func() {
+		defer unit.wg.Done()
+		defer close(loop.done)
+		defer ticker.Stop()
+		a.gatherLoop(ctx, acc, input, ticker, interval, loop.trigger)
+	}()
+}
+
+// stopInput stops the periodic gather of the input and stops it if it is a
+// service input.
+func stopInput(unit *inputUnit, input *models.RunningInput) {
+	if loop, ok := unit.running[input]; ok {
+		loop.stop()
+		delete(unit.running, input)
 	}
+	stopServiceInputs([]*models.RunningInput{input})
+}
 
-	wg.Wait()
+// runInputs runs the inputs until the context is done.
+//
+// When the context is done the timers are stopped and this function returns
+// after all ongoing Gather calls complete.
+func (a *Agent) runInputs(
+	ctx context.Context,
+	unit *inputUnit,
+) {
+	<-ctx.Done()
+
+	// Wait for a reload in progress and prevent further reloads.
+	a.mu.Lock()
+	a.running = nil
+	a.mu.Unlock()
+
+	for _, loop := range unit.running {
+		loop.cancel()
+	}
+	unit.wg.Wait()
 
 	log.Printf("D! [agent] Stopping service inputs")
 	stopServiceInputs(unit.inputs)
 
-	close(unit.dst)
+	unit.closeAll()
 	log.Printf("D! [agent] Input channel closed")
 }
 
@@ -329,13 +467,13 @@ func (a *Agent) runInputs(
 // mode.  It differs by logging Start errors and returning only plugins
 // successfully started.
 func (a *Agent) testStartInputs(
-	dst chan<- telegraf.Metric,
+	dsts map[string]chan<- telegraf.Metric,
 	inputs []*models.RunningInput,
 ) *inputUnit {
 	log.Printf("D! [agent] Starting service inputs")
 
 	unit := &inputUnit{
-		dst: dst,
+		dsts: dsts,
 	}
 
 	for _, input := range inputs {
@@ -344,7 +482,7 @@ func (a *Agent) testStartInputs(
 			// This only applies to the accumulator passed to Start(), the
 			// Gather() accumulator does apply rounding according to the
 			// precision agent setting.
-			acc := NewAccumulator(input, dst)
+			acc := NewAccumulator(input, unit.dst(input))
 			acc.SetPrecision(time.Nanosecond)
 
 			err := si.Start(acc)
@@ -361,9 +499,14 @@ func (a *Agent) testStartInputs(
 
 // testRunInputs is a variation of runInputs for use in --test and --once mode.
 // Instead of using a ticker to run the inputs they are called once immediately.
+// If a window is given, the inputs are called once for every interval of the
+// window, using the simulated gather time starting at startTime as the time
+// of the metrics.
 func (a *Agent) testRunInputs(
 	ctx context.Context,
 	wait time.Duration,
+	window time.Duration,
+	startTime time.Time,
 	unit *inputUnit,
 ) {
 	var wg sync.WaitGroup
@@ -404,11 +547,23 @@ func (a *Agent) testRunInputs(
 				time.Sleep(500 * time.Millisecond)
 			}
 
-			acc := NewAccumulator(input, unit.dst)
-			acc.SetPrecision(getPrecision(precision, interval))
+			if window <= 0 {
+				acc := NewAccumulator(input, unit.dst(input))
+				acc.SetPrecision(getPrecision(precision, interval))
 
-			if err := input.Input.Gather(acc); err != nil {
-				acc.AddError(err)
+				if err := input.Input.Gather(acc); err != nil {
+					acc.AddError(err)
+				}
+				return
+			}
+
+			for ts := startTime; ts.Before(startTime.Add(window)); ts = ts.Add(interval) {
+				acc := newSimulatedAccumulator(input, unit.dst(input), ts)
+				acc.SetPrecision(getPrecision(precision, interval))
+
+				if err := input.Input.Gather(acc); err != nil {
+					acc.AddError(err)
+				}
 			}
 		}(input)
 	}
@@ -419,7 +574,7 @@ func (a *Agent) testRunInputs(
 	log.Printf("D! [agent] Stopping service inputs")
 	stopServiceInputs(unit.inputs)
 
-	close(unit.dst)
+	unit.closeAll()
 	log.Printf("D! [agent] Input channel closed")
 }
 
@@ -440,13 +595,14 @@ func stopRunningOutputs(outputs []*models.RunningOutput) {
 }
 
 // gather runs an input's gather function periodically until the context is
-// done.
+// done.  Additional gathers can be requested using the trigger channel.
 func (a *Agent) gatherLoop(
 	ctx context.Context,
 	acc telegraf.Accumulator,
 	input *models.RunningInput,
 	ticker Ticker,
 	interval time.Duration,
+	trigger <-chan struct{},
 ) {
 	defer panicRecover(input)
 
@@ -457,6 +613,11 @@ func (a *Agent) gatherLoop(
 			if err != nil {
 				acc.AddError(err)
 			}
+		case <-trigger:
+			err := a.gatherOnce(acc, input, ticker, interval)
+			if err != nil {
+				acc.AddError(err)
+			}
 		case <-ctx.Done():
 			return
 		}
@@ -573,6 +734,7 @@ func (a *Agent) startAggregators(
 		aggC:        aggC,
 		outputC:     outputC,
 		aggregators: aggregators,
+		agent:       a.Config.Agent,
 	}
 	return src, unit
 }
@@ -587,8 +749,13 @@ func (a *Agent) runAggregators(
 
 	// Before calling Add, initialize the aggregation window.  This ensures
 	// that any metric created after start time will be aggregated.
-	for _, agg := range a.Config.Aggregators {
-		since, until := updateWindow(startTime, a.Config.Agent.RoundInterval, agg.Period())
+	for _, agg := range unit.aggregators {
+		since, until := updateWindow(startTime, unit.agent.RoundInterval, agg.Period())
+		if unit.window > 0 {
+			// Include the metrics rounded down to the start time.
+			precision := getPrecision(time.Duration(unit.agent.Precision), time.Duration(unit.agent.Interval))
+			since, until = startTime.Add(-precision), startTime.Add(unit.window)
+		}
 		agg.UpdateWindow(since, until)
 	}
 
@@ -598,7 +765,7 @@ func (a *Agent) runAggregators(
 		defer wg.Done()
 		for metric := range unit.src {
 			var dropOriginal bool
-			for _, agg := range a.Config.Aggregators {
+			for _, agg := range unit.aggregators {
 				if ok := agg.Add(metric); ok {
 					dropOriginal = true
 				}
@@ -613,13 +780,13 @@ func (a *Agent) runAggregators(
 		cancel()
 	}()
 
-	for _, agg := range a.Config.Aggregators {
+	for _, agg := range unit.aggregators {
 		wg.Add(1)
 		go func(agg *models.RunningAggregator) {
 			defer wg.Done()
 
-			interval := time.Duration(a.Config.Agent.Interval)
-			precision := time.Duration(a.Config.Agent.Precision)
+			interval := time.Duration(unit.agent.Interval)
+			precision := time.Duration(unit.agent.Precision)
 
 			acc := NewAccumulator(agg, unit.aggC)
 			acc.SetPrecision(getPrecision(precision, interval))
@@ -636,6 +803,112 @@ func (a *Agent) runAggregators(
 	log.Printf("D! [agent] Aggregator channel closed")
 }
 
+// startChain starts the processors and aggregators of the pipeline and
+// returns the chain writing to dst.  If a window is given, the aggregators
+// aggregate all metrics of the simulated window.
+func (a *Agent) startChain(
+	pipeline string,
+	dst chan<- telegraf.Metric,
+	startTime time.Time,
+	window time.Duration,
+) (*chainUnit, error) {
+	out := make(chan telegraf.Metric, 100)
+	next := chan<- telegraf.Metric(out)
+
+	processors := pipelineProcessors(a.Config.Processors, pipeline)
+	aggProcessors := pipelineProcessors(a.Config.AggProcessors, pipeline)
+	aggregators := pipelineAggregators(a.Config.Aggregators, pipeline)
+
+	var err error
+	var apu []*processorUnit
+	var au *aggregatorUnit
+	if len(aggregators) != 0 {
+		aggC := next
+		if len(aggProcessors) != 0 {
+			aggC, apu, err = a.startProcessors(next, aggProcessors)
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		next, au = a.startAggregators(aggC, next, aggregators)
+		au.window = window
+	}
+
+	var pu []*processorUnit
+	if len(processors) != 0 {
+		next, pu, err = a.startProcessors(next, processors)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var wg sync.WaitGroup
+	if au != nil {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			a.runProcessors(apu)
+		}()
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			a.runAggregators(startTime, au)
+		}()
+	}
+
+	if pu != nil {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			a.runProcessors(pu)
+		}()
+	}
+
+	done := make(chan struct{})
+	go func() {
+		for metric := range out {
+			dst <- metric
+		}
+		wg.Wait()
+		close(done)
+	}()
+
+	return &chainUnit{src: next, done: done}, nil
+}
+
+// runRelay forwards metrics to the current chain until the source channel is
+// closed and all chains finished processing.
+func (a *Agent) runRelay(unit *relayUnit) {
+	chain := unit.chain
+	retired := make([]*chainUnit, 0)
+
+loop:
+	for {
+		select {
+		case metric, ok := <-unit.src:
+			if !ok {
+				break loop
+			}
+			chain.src <- metric
+		case next := <-unit.swap:
+			close(chain.src)
+			retired = append(retired, chain)
+			chain = next
+		}
+	}
+
+	close(chain.src)
+	<-chain.done
+	for _, c := range retired {
+		<-c.done
+	}
+
+	close(unit.dst)
+	log.Printf("D! [agent] Relay channel closed")
+}
+
 func updateWindow(start time.Time, roundInterval bool, period time.Duration) (time.Time, time.Time) {
 	var until time.Time
 	if roundInterval {
@@ -676,14 +949,24 @@ func (a *Agent) push(
 	}
 }
 
-// startOutputs calls Connect on all outputs and returns the source channel.
-// If an error occurs calling Connect all stared plugins have Close called.
+// startOutputs calls Connect on all outputs, starts their flush loops and
+// returns the source channels of the pipelines.  If an error occurs calling
+// Connect all stared plugins have Close called.
 func (a *Agent) startOutputs(
 	ctx context.Context,
 	outputs []*models.RunningOutput,
-) (chan<- telegraf.Metric, *outputUnit, error) {
-	src := make(chan telegraf.Metric, 100)
-	unit := &outputUnit{src: src}
+	pipelines []string,
+) (map[string]chan<- telegraf.Metric, *outputUnit, error) {
+	dsts := make(map[string]chan<- telegraf.Metric, len(pipelines))
+	unit := &outputUnit{
+		srcs:    make(map[string]<-chan telegraf.Metric, len(pipelines)),
+		running: make(map[*models.RunningOutput]*runningLoop),
+	}
+	for _, pipeline := range pipelines {
+		src := make(chan telegraf.Metric, 100)
+		dsts[pipeline] = src
+		unit.srcs[pipeline] = src
+	}
 	for _, output := range outputs {
 		err := a.connectOutput(ctx, output)
 		if err != nil {
@@ -696,7 +979,47 @@ func (a *Agent) startOutputs(
 		unit.outputs = append(unit.outputs, output)
 	}
 
-	return src, unit, nil
+	for _, output := range unit.outputs {
+		a.flushOutput(unit, output)
+	}
+
+	return dsts, unit, nil
+}
+
+// flushOutput starts the flush loop of the output.
+func (a *Agent) flushOutput(unit *outputUnit, output *models.RunningOutput) {
+	// Overwrite agent flush_interval if this plugin has its own.
+	interval := time.Duration(a.Config.Agent.FlushInterval)
+	if output.Config.FlushInterval != 0 {
+		interval = output.Config.FlushInterval
+	}
+
+	// Overwrite agent flush_jitter if this plugin has its own.
+	jitter := time.Duration(a.Config.Agent.FlushJitter)
+	if output.Config.FlushJitter != 0 {
+		jitter = output.Config.FlushJitter
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	loop := newRunningLoop(cancel)
+	unit.running[output] = loop
+
+	unit.wg.Add(1)
+	go func() {
+		defer unit.wg.Done()
+		defer close(loop.done)
+
+		ticker := NewRollingTicker(interval, jitter)
+		defer ticker.Stop()
+
+		// Do not delay the shutdown by writes waiting for the rate limits
+		go func() {
+			<-ctx.Done()
+			output.Interrupt()
+		}()
+
+		a.flushLoop(ctx, output, ticker, loop.trigger)
+	}()
 }
 
 // connectOutputs connects to all outputs.
@@ -721,68 +1044,43 @@ func (a *Agent) connectOutput(ctx context.Context, output *models.RunningOutput)
 	return nil
 }
 
-// runOutputs begins processing metrics and returns until the source channel is
-// closed and all metrics have been written.  On shutdown metrics will be
+// runOutputs begins processing metrics and returns until the source channels
+// are closed and all metrics have been written.  On shutdown metrics will be
 // written one last time and dropped if unsuccessful.
 func (a *Agent) runOutputs(
 	unit *outputUnit,
 ) {
 	var wg sync.WaitGroup
-
-	// Start flush loop
-	interval := time.Duration(a.Config.Agent.FlushInterval)
-	jitter := time.Duration(a.Config.Agent.FlushJitter)
-
-	ctx, cancel := context.WithCancel(context.Background())
-
-	for _, output := range unit.outputs {
-		interval := interval
-		// Overwrite agent flush_interval if this plugin has its own.
-		if output.Config.FlushInterval != 0 {
-			interval = output.Config.FlushInterval
-		}
-
-		jitter := jitter
-		// Overwrite agent flush_jitter if this plugin has its own.
-		if output.Config.FlushJitter != 0 {
-			jitter = output.Config.FlushJitter
-		}
-
+	for pipeline, src := range unit.srcs {
 		wg.Add(1)
-		go func(output *models.RunningOutput) {
+		go func(pipeline string, src <-chan telegraf.Metric) {
 			defer wg.Done()
-
-			ticker := NewRollingTicker(interval, jitter)
-			defer ticker.Stop()
-
-			a.flushLoop(ctx, output, ticker)
-		}(output)
-	}
-
-	for metric := range unit.src {
-		for i, output := range unit.outputs {
-			if i == len(a.Config.Outputs)-1 {
-				output.AddMetric(metric)
-			} else {
-				output.AddMetric(metric.Copy())
+			for metric := range src {
+				unit.RLock()
+				addMetric(unit.outputs, pipeline, metric)
+				unit.RUnlock()
 			}
-		}
+		}(pipeline, src)
 	}
+	wg.Wait()
 
 	log.Println("I! [agent] Hang on, flushing any cached metrics before shutdown")
-	cancel()
-	wg.Wait()
+	for _, loop := range unit.running {
+		loop.cancel()
+	}
+	unit.wg.Wait()
 
 	log.Println("I! [agent] Stopping running outputs")
 	stopRunningOutputs(unit.outputs)
 }
 
 // flushLoop runs an output's flush function periodically until the context is
-// done.
+// done.  Additional flushes can be requested using the trigger channel.
 func (a *Agent) flushLoop(
 	ctx context.Context,
 	output *models.RunningOutput,
 	ticker Ticker,
+	trigger <-chan struct{},
 ) {
 	logError := func(err error) {
 		if err != nil {
@@ -799,6 +1097,7 @@ func (a *Agent) flushLoop(
 		// Favor shutdown over other methods.
 		select {
 		case <-ctx.Done():
+			output.ResetBackoff()
 			logError(a.flushOnce(output, ticker, output.Write))
 			return
 		default:
@@ -806,12 +1105,15 @@ func (a *Agent) flushLoop(
 
 		select {
 		case <-ctx.Done():
+			output.ResetBackoff()
 			logError(a.flushOnce(output, ticker, output.Write))
 			return
 		case <-ticker.Elapsed():
 			logError(a.flushOnce(output, ticker, output.Write))
 		case <-flushRequested:
 			logError(a.flushOnce(output, ticker, output.Write))
+		case <-trigger:
+			logError(a.flushOnce(output, ticker, output.Write))
 		case <-output.BatchReady:
 			// Favor the ticker over batch ready
 			select {
@@ -870,7 +1172,13 @@ func (a *Agent) Test(ctx context.Context, wait time.Duration) error {
 		}
 	}()
 
-	err := a.test(ctx, wait, src)
+	// All pipelines are printed alike.
+	outputCs := make(map[string]chan<- telegraf.Metric)
+	for _, pipeline := range a.Config.Pipelines() {
+		outputCs[pipeline] = src
+	}
+
+	err := a.test(ctx, wait, 0, outputCs)
 	if err != nil {
 		return err
 	}
@@ -884,9 +1192,16 @@ func (a *Agent) Test(ctx context.Context, wait time.Duration) error {
 }
 
 // Test runs the agent and performs a single gather sending output to the
-// outputF.  After gathering pauses for the wait duration to allow service
-// inputs to run.
-func (a *Agent) test(ctx context.Context, wait time.Duration, outputC chan<- telegraf.Metric) error {
+// channels of the pipelines in outputCs.  After gathering pauses for the wait
+// duration to allow service inputs to run.  If a window is given, the gathers
+// over the window are simulated and aggregated at once.
+func (a *Agent) test(ctx context.Context, wait, window time.Duration, outputCs map[string]chan<- telegraf.Metric) error {
+	// The disk buffers may be in use by a running agent and must not be
+	// replayed or modified in test mode.
+	for _, output := range a.Config.Outputs {
+		output.Config.BufferStrategy = "memory"
+	}
+
 	log.Printf("D! [agent] Initializing plugins")
 	err := a.initPlugins()
 	if err != nil {
@@ -895,63 +1210,11 @@ func (a *Agent) test(ctx context.Context, wait time.Duration, outputC chan<- tel
 
 	startTime := time.Now()
 
-	next := outputC
-
-	var apu []*processorUnit
-	var au *aggregatorUnit
-	if len(a.Config.Aggregators) != 0 {
-		procC := next
-		if len(a.Config.AggProcessors) != 0 {
-			procC, apu, err = a.startProcessors(next, a.Config.AggProcessors)
-			if err != nil {
-				return err
-			}
-		}
-
-		next, au = a.startAggregators(procC, next, a.Config.Aggregators)
-	}
-
-	var pu []*processorUnit
-	if len(a.Config.Processors) != 0 {
-		next, pu, err = a.startProcessors(next, a.Config.Processors)
-		if err != nil {
-			return err
-		}
-	}
-
-	iu := a.testStartInputs(next, a.Config.Inputs)
-
-	var wg sync.WaitGroup
-	if au != nil {
-		wg.Add(1)
-		go func() {
-			defer wg.Done()
-			a.runProcessors(apu)
-		}()
-
-		wg.Add(1)
-		go func() {
-			defer wg.Done()
-			a.runAggregators(startTime, au)
-		}()
-	}
-
-	if pu != nil {
-		wg.Add(1)
-		go func() {
-			defer wg.Done()
-			a.runProcessors(pu)
-		}()
+	err = a.testRunPipelines(ctx, wait, window, startTime, outputCs)
+	if err != nil {
+		return err
 	}
 
-	wg.Add(1)
-	go func() {
-		defer wg.Done()
-		a.testRunInputs(ctx, wait, iu)
-	}()
-
-	wg.Wait()
-
 	log.Printf("D! [agent] Stopped Successfully")
 
 	return nil
@@ -987,39 +1250,18 @@ func (a *Agent) once(ctx context.Context, wait time.Duration) error {
 	if err != nil {
 		return err
 	}
+	if err := a.Config.CheckPipelines(); err != nil {
+		return err
+	}
 
 	startTime := time.Now()
 
 	log.Printf("D! [agent] Connecting outputs")
-	next, ou, err := a.startOutputs(ctx, a.Config.Outputs)
+	outputCs, ou, err := a.startOutputs(ctx, a.Config.Outputs, a.Config.Pipelines())
 	if err != nil {
 		return err
 	}
 
-	var apu []*processorUnit
-	var au *aggregatorUnit
-	if len(a.Config.Aggregators) != 0 {
-		procC := next
-		if len(a.Config.AggProcessors) != 0 {
-			procC, apu, err = a.startProcessors(next, a.Config.AggProcessors)
-			if err != nil {
-				return err
-			}
-		}
-
-		next, au = a.startAggregators(procC, next, a.Config.Aggregators)
-	}
-
-	var pu []*processorUnit
-	if len(a.Config.Processors) != 0 {
-		next, pu, err = a.startProcessors(next, a.Config.Processors)
-		if err != nil {
-			return err
-		}
-	}
-
-	iu := a.testStartInputs(next, a.Config.Inputs)
-
 	var wg sync.WaitGroup
 	wg.Add(1)
 	go func() {
@@ -1027,36 +1269,14 @@ func (a *Agent) once(ctx context.Context, wait time.Duration) error {
 		a.runOutputs(ou)
 	}()
 
-	if au != nil {
-		wg.Add(1)
-		go func() {
-			defer wg.Done()
-			a.runProcessors(apu)
-		}()
+	err = a.testRunPipelines(ctx, wait, 0, startTime, outputCs)
 
-		wg.Add(1)
-		go func() {
-			defer wg.Done()
-			a.runAggregators(startTime, au)
-		}()
-	}
+	wg.Wait()
 
-	if pu != nil {
-		wg.Add(1)
-		go func() {
-			defer wg.Done()
-			a.runProcessors(pu)
-		}()
+	if err != nil {
+		return err
 	}
 
-	wg.Add(1)
-	go func() {
-		defer wg.Done()
-		a.testRunInputs(ctx, wait, iu)
-	}()
-
-	wg.Wait()
-
 	log.Printf("D! [agent] Stopped Successfully")
 
 	return nil
@@ -0,0 +1,485 @@
+package agent
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"reflect"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/config"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/models"
+	"github.com/influxdata/telegraf/selfstat"
+)
+
+const apiPrefix = "/api/v1/"
+
+// sensitiveOptionRe matches the names of plugin options, and of keys of
+// options holding maps like HTTP headers, redacted in the responses of the
+// API.
+var sensitiveOptionRe = regexp.MustCompile(`(?i)(password|passwd|secret|token|credential|private_key|api_?key|authorization|cookie)`)
+
+// Credentials embedded in option values: the user information of URLs, the
+// password of DSNs like "user:pass@tcp(host)/db" and password parameters of
+// key-value DSNs like "host=db password=pass".
+var (
+	urlUserinfoRe = regexp.MustCompile(`(?i)\b([a-z][a-z0-9+.-]*://)[^/@\s]+@`)
+	dsnPasswordRe = regexp.MustCompile(`^([^:@/\s]+):[^@\s]*@`)
+	kvPasswordRe  = regexp.MustCompile(`(?i)\b(password|pwd)=('[^']*'|[^\s;&]*)`)
+)
+
+// pluginInfo describes a running plugin in the responses of the API.
+type pluginInfo struct {
+	ID       string                 `json:"id"`
+	Type     string                 `json:"type"`
+	Name     string                 `json:"name"`
+	Alias    string                 `json:"alias,omitempty"`
+	Pipeline string                 `json:"pipeline,omitempty"`
+	Config   map[string]interface{} `json:"config"`
+	Stats    map[string]int64       `json:"stats"`
+	Gather   *gatherInfo            `json:"gather,omitempty"`
+	Buffer   *bufferInfo            `json:"buffer,omitempty"`
+
+	input  *models.RunningInput
+	output *models.RunningOutput
+}
+
+type gatherInfo struct {
+	LastGather     *time.Time `json:"last_gather,omitempty"`
+	LastDurationNs int64      `json:"last_duration_ns"`
+	LastError      string     `json:"last_error,omitempty"`
+	LastErrorTime  *time.Time `json:"last_error_time,omitempty"`
+}
+
+type bufferInfo struct {
+	Length int `json:"length"`
+	Limit  int `json:"limit"`
+}
+
+type agentInfo struct {
+	Running bool             `json:"running"`
+	Stats   map[string]int64 `json:"stats"`
+}
+
+// APIHandler returns the handler of the HTTP control API of the agent.  The
+// API lists the running plugins with their configuration and statistics and
+// allows to trigger a gather of an input or a flush of the outputs:
+//
+//	GET  /api/v1/agent               agent statistics
+//	GET  /api/v1/plugins             all running plugins
+//	GET  /api/v1/plugins/<id>        a single plugin
+//	POST /api/v1/plugins/<id>/gather gather the input once
+//	POST /api/v1/plugins/<id>/flush  flush the output
+//	POST /api/v1/flush               flush all outputs
+func (a *Agent) APIHandler() http.Handler {
+	return http.HandlerFunc(a.serveAPI)
+}
+
+func (a *Agent) serveAPI(w http.ResponseWriter, req *http.Request) {
+	if !strings.HasPrefix(req.URL.Path, apiPrefix) {
+		apiError(w, http.StatusNotFound, "not found")
+		return
+	}
+	path := strings.Split(strings.Trim(strings.TrimPrefix(req.URL.Path, apiPrefix), "/"), "/")
+
+	switch {
+	case len(path) == 1 && path[0] == "agent":
+		if !allowMethod(w, req, http.MethodGet) {
+			return
+		}
+		a.mu.Lock()
+		running := a.running != nil
+		a.mu.Unlock()
+		apiResponse(w, http.StatusOK, agentInfo{Running: running, Stats: selfstat.Values("agent", map[string]string{})})
+	case len(path) == 1 && path[0] == "plugins":
+		if !allowMethod(w, req, http.MethodGet) {
+			return
+		}
+		apiResponse(w, http.StatusOK, a.plugins())
+	case len(path) == 1 && path[0] == "flush":
+		if !allowMethod(w, req, http.MethodPost) {
+			return
+		}
+		a.trigger(w, "", "flush")
+	case len(path) == 2 && path[0] == "plugins":
+		if !allowMethod(w, req, http.MethodGet) {
+			return
+		}
+		for _, plugin := range a.plugins() {
+			if plugin.ID == path[1] {
+				apiResponse(w, http.StatusOK, plugin)
+				return
+			}
+		}
+		apiError(w, http.StatusNotFound, fmt.Sprintf("unknown plugin %q", path[1]))
+	case len(path) == 3 && path[0] == "plugins" && (path[2] == "gather" || path[2] == "flush"):
+		if !allowMethod(w, req, http.MethodPost) {
+			return
+		}
+		a.trigger(w, path[1], path[2])
+	default:
+		apiError(w, http.StatusNotFound, "not found")
+	}
+}
+
+// trigger requests a gather or flush of the plugin with the given ID, or a
+// flush of all outputs if the ID is empty.
+func (a *Agent) trigger(w http.ResponseWriter, id, action string) {
+	plugins := a.plugins()
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if a.running == nil {
+		apiError(w, http.StatusServiceUnavailable, ErrNotRunning.Error())
+		return
+	}
+
+	if id == "" {
+		for _, plugin := range plugins {
+			if plugin.output != nil {
+				a.runOutputLoop(plugin.output)
+			}
+		}
+		apiResponse(w, http.StatusAccepted, map[string]string{"status": "flush requested"})
+		return
+	}
+
+	for _, plugin := range plugins {
+		if plugin.ID != id {
+			continue
+		}
+		switch {
+		case action == "gather" && plugin.input != nil:
+			a.runInputLoop(plugin.input)
+		case action == "flush" && plugin.output != nil:
+			a.runOutputLoop(plugin.output)
+		default:
+			apiError(w, http.StatusBadRequest, fmt.Sprintf("cannot %s %s plugin %q", action, plugin.Type, id))
+			return
+		}
+		log.Printf("D! [agent] Requested %s of %s via API", action, id)
+		apiResponse(w, http.StatusAccepted, map[string]string{"status": action + " requested"})
+		return
+	}
+	apiError(w, http.StatusNotFound, fmt.Sprintf("unknown plugin %q", id))
+}
+
+// runInputLoop requests a gather of the input, the agent must be locked.
+func (a *Agent) runInputLoop(input *models.RunningInput) {
+	if loop, ok := a.running.inputs.running[input]; ok {
+		loop.run()
+	}
+}
+
+// runOutputLoop requests a flush of the output, the agent must be locked.
+func (a *Agent) runOutputLoop(output *models.RunningOutput) {
+	ou := a.running.outputs
+	ou.RLock()
+	defer ou.RUnlock()
+	if loop, ok := ou.running[output]; ok {
+		loop.run()
+	}
+}
+
+// plugins returns the plugins of the current configuration.
+func (a *Agent) plugins() []*pluginInfo {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	ids := newPluginIDs()
+	plugins := make([]*pluginInfo, 0)
+	for _, input := range a.Config.Inputs {
+		status := input.Status()
+		info := &pluginInfo{
+			ID:       ids.next("inputs", input.Config.Name, input.Config.Fingerprint),
+			Type:     "inputs",
+			Name:     input.Config.Name,
+			Alias:    input.Config.Alias,
+			Pipeline: input.Config.Pipeline,
+			Config:   pluginOptions(input.Input),
+			Stats:    selfstat.Values("gather", pluginTags("input", input.Config.Name, input.Config.Alias)),
+			Gather: &gatherInfo{
+				LastDurationNs: status.LastDuration.Nanoseconds(),
+				LastError:      status.LastError,
+			},
+			input: input,
+		}
+		if !status.LastGather.IsZero() {
+			info.Gather.LastGather = &status.LastGather
+		}
+		if !status.LastErrorTime.IsZero() {
+			info.Gather.LastErrorTime = &status.LastErrorTime
+		}
+		plugins = append(plugins, info)
+	}
+	for _, processor := range a.Config.Processors {
+		plugins = append(plugins, &pluginInfo{
+			ID:       ids.next("processors", processor.Config.Name, processor.Config.Fingerprint),
+			Type:     "processors",
+			Name:     processor.Config.Name,
+			Alias:    processor.Config.Alias,
+			Pipeline: processor.Config.Pipeline,
+			Config:   pluginOptions(processor.Processor),
+			Stats:    selfstat.Values("process", pluginTags("processor", processor.Config.Name, processor.Config.Alias)),
+		})
+	}
+	for _, aggregator := range a.Config.Aggregators {
+		plugins = append(plugins, &pluginInfo{
+			ID:       ids.next("aggregators", aggregator.Config.Name, aggregator.Config.Fingerprint),
+			Type:     "aggregators",
+			Name:     aggregator.Config.Name,
+			Alias:    aggregator.Config.Alias,
+			Pipeline: aggregator.Config.Pipeline,
+			Config:   pluginOptions(aggregator.Aggregator),
+			Stats:    selfstat.Values("aggregate", pluginTags("aggregator", aggregator.Config.Name, aggregator.Config.Alias)),
+		})
+	}
+	for _, processor := range a.Config.AggProcessors {
+		plugins = append(plugins, &pluginInfo{
+			ID:       ids.next("processors", processor.Config.Name, processor.Config.Fingerprint),
+			Type:     "processors",
+			Name:     processor.Config.Name,
+			Alias:    processor.Config.Alias,
+			Pipeline: processor.Config.Pipeline,
+			Config:   pluginOptions(processor.Processor),
+			Stats:    selfstat.Values("process", pluginTags("processor", processor.Config.Name, processor.Config.Alias)),
+		})
+	}
+	for _, output := range a.Config.Outputs {
+		plugins = append(plugins, &pluginInfo{
+			ID:       ids.next("outputs", output.Config.Name, output.Config.Fingerprint),
+			Type:     "outputs",
+			Name:     output.Config.Name,
+			Alias:    output.Config.Alias,
+			Pipeline: output.Config.Pipeline,
+			Config:   pluginOptions(output.Output),
+			Stats:    selfstat.Values("write", pluginTags("output", output.Config.Name, output.Config.Alias)),
+			Buffer: &bufferInfo{
+				Length: output.BufferLength(),
+				Limit:  output.MetricBufferLimit,
+			},
+			output: output,
+		})
+	}
+	return plugins
+}
+
+func pluginTags(kind, name, alias string) map[string]string {
+	tags := map[string]string{kind: name}
+	if alias != "" {
+		tags["alias"] = alias
+	}
+	return tags
+}
+
+// pluginIDs generates the IDs of the plugins.  The ID is derived from the
+// plugin's configuration, so it is kept as long as the configuration of the
+// plugin does not change.  Plugins with identical configurations are numbered.
+type pluginIDs map[string]int
+
+func newPluginIDs() pluginIDs {
+	return make(pluginIDs)
+}
+
+func (ids pluginIDs) next(kind, name, fingerprint string) string {
+	if len(fingerprint) > 8 {
+		fingerprint = fingerprint[:8]
+	}
+	id := kind + "." + name
+	if fingerprint != "" {
+		id += "-" + fingerprint
+	}
+
+	ids[id]++
+	if n := ids[id]; n > 1 {
+		return fmt.Sprintf("%s-%d", id, n)
+	}
+	return id
+}
+
+var (
+	secretType   = reflect.TypeOf(config.Secret{})
+	durationType = reflect.TypeOf(config.Duration(0))
+)
+
+// pluginOptions returns the options of the plugin settable in the
+// configuration.  Secrets and options looking like credentials are redacted.
+func pluginOptions(plugin interface{}) map[string]interface{} {
+	// Processors are wrapped into streaming processors.
+	if p, ok := plugin.(interface{ Unwrap() telegraf.Processor }); ok {
+		plugin = p.Unwrap()
+	}
+
+	options := make(map[string]interface{})
+	v := reflect.Indirect(reflect.ValueOf(plugin))
+	if v.Kind() == reflect.Struct {
+		addOptions(options, v, 0)
+	}
+	return options
+}
+
+func addOptions(options map[string]interface{}, v reflect.Value, depth int) {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		tag := field.Tag.Get("toml")
+		if tag == "-" {
+			continue
+		}
+		name := strings.Split(tag, ",")[0]
+
+		// Options of embedded structs are part of the plugin's options.
+		if field.Anonymous && name == "" {
+			fv := reflect.Indirect(v.Field(i))
+			if fv.Kind() == reflect.Struct && fv.CanInterface() {
+				addOptions(options, fv, depth)
+			}
+			continue
+		}
+		if field.PkgPath != "" {
+			continue
+		}
+
+		// Fields without tag are only considered if they are plain values,
+		// other fields are usually clients or internal state.
+		tagged := name != ""
+		if !tagged {
+			name = internal.SnakeCase(field.Name)
+		}
+		value, ok := optionValue(v.Field(i), tagged, depth)
+		if !ok {
+			continue
+		}
+		if field.Type != secretType {
+			value = redact(value, sensitiveOptionRe.MatchString(name))
+		}
+		options[name] = value
+	}
+}
+
+// redact replaces all strings in the option value if it is sensitive, and
+// credentials embedded in strings otherwise.  Values of maps are redacted if
+// their key is sensitive.
+func redact(value interface{}, sensitive bool) interface{} {
+	switch v := value.(type) {
+	case string:
+		if v == "" {
+			return v
+		}
+		if sensitive {
+			return "<redacted>"
+		}
+		if urlUserinfoRe.MatchString(v) {
+			v = urlUserinfoRe.ReplaceAllString(v, "${1}<redacted>@")
+		} else {
+			v = dsnPasswordRe.ReplaceAllString(v, "${1}:<redacted>@")
+		}
+		return kvPasswordRe.ReplaceAllString(v, "${1}=<redacted>")
+	case []interface{}:
+		values := make([]interface{}, 0, len(v))
+		for _, e := range v {
+			values = append(values, redact(e, sensitive))
+		}
+		return values
+	case map[string]interface{}:
+		values := make(map[string]interface{}, len(v))
+		for k, e := range v {
+			values[k] = redact(e, sensitive || sensitiveOptionRe.MatchString(k))
+		}
+		return values
+	}
+	return value
+}
+
+func optionValue(v reflect.Value, nested bool, depth int) (interface{}, bool) {
+	if depth > 8 {
+		return nil, false
+	}
+
+	switch v.Type() {
+	case secretType:
+		return v.Interface().(config.Secret).String(), true
+	case durationType:
+		return time.Duration(v.Int()).String(), true
+	case reflect.TypeOf(time.Duration(0)):
+		return time.Duration(v.Int()).String(), true
+	}
+
+	switch v.Kind() {
+	case reflect.Bool, reflect.String,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return v.Interface(), true
+	case reflect.Slice, reflect.Array:
+		if v.Kind() == reflect.Slice && v.IsNil() {
+			return nil, true
+		}
+		values := make([]interface{}, 0, v.Len())
+		for i := 0; i < v.Len(); i++ {
+			value, ok := optionValue(v.Index(i), nested, depth+1)
+			if !ok {
+				return nil, false
+			}
+			values = append(values, value)
+		}
+		return values, true
+	case reflect.Map:
+		if v.Type().Key().Kind() != reflect.String {
+			return nil, false
+		}
+		values := make(map[string]interface{}, v.Len())
+		iter := v.MapRange()
+		for iter.Next() {
+			value, ok := optionValue(iter.Value(), nested, depth+1)
+			if !ok {
+				return nil, false
+			}
+			values[iter.Key().String()] = value
+		}
+		return values, true
+	case reflect.Ptr, reflect.Interface:
+		if !nested {
+			return nil, false
+		}
+		if v.IsNil() {
+			return nil, true
+		}
+		return optionValue(v.Elem(), nested, depth+1)
+	case reflect.Struct:
+		if !nested {
+			return nil, false
+		}
+		values := make(map[string]interface{})
+		addOptions(values, v, depth+1)
+		return values, true
+	}
+	return nil, false
+}
+
+func allowMethod(w http.ResponseWriter, req *http.Request, method string) bool {
+	if req.Method == method {
+		return true
+	}
+	w.Header().Set("Allow", method)
+	apiError(w, http.StatusMethodNotAllowed, "method not allowed")
+	return false
+}
+
+func apiError(w http.ResponseWriter, code int, msg string) {
+	apiResponse(w, code, map[string]string{"error": msg})
+}
+
+func apiResponse(w http.ResponseWriter, code int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	if err := json.NewEncoder(w).Encode(body); err != nil {
+		log.Printf("E! [agent] Writing API response failed: %v", err)
+	}
+}
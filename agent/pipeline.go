@@ -0,0 +1,95 @@
+package agent
+
+import (
+	"context"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/models"
+)
+
+// pipelineProcessors returns the processors of the pipeline.
+func pipelineProcessors(processors models.RunningProcessors, pipeline string) models.RunningProcessors {
+	var result models.RunningProcessors
+	for _, processor := range processors {
+		if processor.Config.Pipeline == pipeline {
+			result = append(result, processor)
+		}
+	}
+	return result
+}
+
+// pipelineAggregators returns the aggregators of the pipeline.
+func pipelineAggregators(aggregators []*models.RunningAggregator, pipeline string) []*models.RunningAggregator {
+	var result []*models.RunningAggregator
+	for _, aggregator := range aggregators {
+		if aggregator.Config.Pipeline == pipeline {
+			result = append(result, aggregator)
+		}
+	}
+	return result
+}
+
+// addMetric adds the metric to all outputs of the pipeline, the metric is
+// dropped if the pipeline has no outputs.
+func addMetric(outputs []*models.RunningOutput, pipeline string, metric telegraf.Metric) {
+	last := -1
+	for i, output := range outputs {
+		if output.Config.Pipeline == pipeline {
+			last = i
+		}
+	}
+	if last < 0 {
+		metric.Drop()
+		return
+	}
+
+	for _, output := range outputs[:last] {
+		if output.Config.Pipeline == pipeline {
+			output.AddMetric(metric.Copy())
+		}
+	}
+	outputs[last].AddMetric(metric)
+}
+
+// testRunPipelines is a variation of running the relays for use in --test and
+// --once mode.  The inputs are run once by testRunInputs and write to the
+// chains of their pipelines directly.  The output channels of the pipelines,
+// which may be shared by several pipelines, are closed once all chains are
+// done.
+func (a *Agent) testRunPipelines(
+	ctx context.Context,
+	wait time.Duration,
+	window time.Duration,
+	startTime time.Time,
+	outputCs map[string]chan<- telegraf.Metric,
+) error {
+	defer func() {
+		closed := make(map[chan<- telegraf.Metric]bool)
+		for _, outputC := range outputCs {
+			if !closed[outputC] {
+				close(outputC)
+				closed[outputC] = true
+			}
+		}
+	}()
+
+	chains := make([]*chainUnit, 0, len(outputCs))
+	inputCs := make(map[string]chan<- telegraf.Metric, len(outputCs))
+	for pipeline, outputC := range outputCs {
+		chain, err := a.startChain(pipeline, outputC, startTime, window)
+		if err != nil {
+			return err
+		}
+		chains = append(chains, chain)
+		inputCs[pipeline] = chain.src
+	}
+
+	iu := a.testStartInputs(inputCs, a.Config.Inputs)
+	a.testRunInputs(ctx, wait, window, startTime, iu)
+
+	for _, chain := range chains {
+		<-chain.done
+	}
+	return nil
+}
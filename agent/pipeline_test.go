@@ -0,0 +1,115 @@
+package agent
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestAgent_PipelinesRouteMetrics(t *testing.T) {
+	events := newReloadEvents()
+	startReloadAgent(t, events, `
+[agent]
+  interval = "50ms"
+  flush_interval = "50ms"
+  omit_hostname = true
+
+[[inputs.reload_test]]
+  id = "a"
+
+[[inputs.reload_test]]
+  id = "b"
+  pipeline = "security"
+
+[[outputs.reload_test]]
+  id = "x"
+
+[[outputs.reload_test]]
+  id = "y"
+  pipeline = "security"
+`)
+
+	require.Eventually(t, func() bool {
+		return events.received("x", "a") && events.received("y", "b")
+	}, 5*time.Second, 10*time.Millisecond)
+	require.False(t, events.received("x", "b"))
+	require.False(t, events.received("y", "a"))
+}
+
+func TestAgent_PipelinesProcessSeparately(t *testing.T) {
+	a, err := NewAgent(loadReloadConfig(t, newReloadEvents(), `
+[agent]
+  omit_hostname = true
+
+[[inputs.reload_test]]
+  id = "a"
+
+[[inputs.reload_test]]
+  id = "b"
+  pipeline = "security"
+
+[[processors.override]]
+  [processors.override.tags]
+    stage = "default"
+
+[[processors.override]]
+  pipeline = "security"
+  [processors.override.tags]
+    stage = "security"
+
+[[outputs.reload_test]]
+  id = "x"
+
+[[outputs.reload_test]]
+  id = "y"
+  pipeline = "security"
+`))
+	require.NoError(t, err)
+
+	var buf bytes.Buffer
+	require.NoError(t, a.TestPipeline(context.Background(), 0, 0, &buf))
+
+	require.Equal(t, `> outputs.reload_test: batch of 1 metrics (no data format, shown as line protocol)
+reload,id=a,stage=default value=1i TS
+> outputs.reload_test: batch of 1 metrics (no data format, shown as line protocol)
+reload,id=b,stage=security value=1i TS
+`, timestampRe.ReplaceAllString(buf.String(), "TS"))
+}
+
+func TestAgent_PipelineWithoutOutputs(t *testing.T) {
+	a, err := NewAgent(loadReloadConfig(t, newReloadEvents(), `
+[[inputs.reload_test]]
+  id = "a"
+  pipeline = "security"
+
+[[outputs.reload_test]]
+  id = "x"
+`))
+	require.NoError(t, err)
+	require.EqualError(t, a.Run(context.Background()), `pipeline "security" of input inputs.reload_test has no outputs`)
+}
+
+func TestAgent_ReloadChangedPipelines(t *testing.T) {
+	events := newReloadEvents()
+	a := startReloadAgent(t, events, `
+[[inputs.reload_test]]
+  id = "a"
+
+[[outputs.reload_test]]
+  id = "x"
+`)
+
+	c := loadReloadConfig(t, events, `
+[[inputs.reload_test]]
+  id = "a"
+  pipeline = "security"
+
+[[outputs.reload_test]]
+  id = "x"
+  pipeline = "security"
+`)
+	require.ErrorIs(t, a.Reload(context.Background(), c), ErrRestartRequired)
+}
@@ -0,0 +1,130 @@
+package agent
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/models"
+	"github.com/influxdata/telegraf/plugins/serializers"
+	"github.com/influxdata/telegraf/plugins/serializers/influx"
+)
+
+// TestPipeline runs the whole pipeline like Test, but instead of printing
+// the metrics leaving the processors and aggregators it writes the data each
+// output would send to w.  The outputs are not connected, the metrics passing
+// the filters of an output are serialized in batches of the output's batch
+// size using the serializer of the output.  Outputs without a data format are
+// shown in InfluxDB line protocol.
+//
+// If a window is given, the inputs are gathered once for every interval of
+// the window using simulated timestamps and the aggregators aggregate all
+// metrics of the window.
+func (a *Agent) TestPipeline(ctx context.Context, wait, window time.Duration, w io.Writer) error {
+	outputs := make([]*models.RunningOutput, 0, len(a.Config.Outputs))
+	for _, output := range a.Config.Outputs {
+		preview := &previewOutput{
+			name:       output.LogName(),
+			serializer: output.Serializer,
+			w:          w,
+		}
+		if preview.serializer == nil {
+			s := influx.NewSerializer()
+			s.SetFieldSortOrder(influx.SortFields)
+			preview.serializer = s
+			preview.lineProtocol = true
+		}
+
+		// The batches are printed one by one and without delay.
+		config := *output.Config
+		config.MetricRateLimit = 0
+		config.ByteRateLimit = 0
+		config.MaxParallelWrites = 0
+		config.BufferStrategy = "memory"
+		outputs = append(outputs, models.NewRunningOutput(preview, &config, output.MetricBatchSize, output.MetricBufferLimit))
+	}
+
+	var wg sync.WaitGroup
+	outputCs := make(map[string]chan<- telegraf.Metric)
+	for _, pipeline := range a.Config.Pipelines() {
+		src := make(chan telegraf.Metric, 100)
+		outputCs[pipeline] = src
+
+		wg.Add(1)
+		go func(pipeline string) {
+			defer wg.Done()
+			for metric := range src {
+				addMetric(outputs, pipeline, metric)
+			}
+		}(pipeline)
+	}
+
+	err := a.test(ctx, wait, window, outputCs)
+	if err != nil {
+		return err
+	}
+
+	wg.Wait()
+
+	for _, output := range outputs {
+		if output.BufferLength() == 0 {
+			fmt.Fprintf(w, "> %s: no metrics\n", output.LogName())
+			continue
+		}
+		if err := output.Write(); err != nil {
+			return fmt.Errorf("serializing metrics for %s failed: %w", output.LogName(), err)
+		}
+	}
+
+	if models.GlobalGatherErrors.Get() != 0 {
+		return fmt.Errorf("input plugins recorded %d errors", models.GlobalGatherErrors.Get())
+	}
+	return nil
+}
+
+// previewOutput replaces an output in TestPipeline and writes the serialized
+// batches instead of sending them.
+type previewOutput struct {
+	name         string
+	serializer   serializers.Serializer
+	lineProtocol bool
+	w            io.Writer
+}
+
+func (p *previewOutput) SampleConfig() string {
+	return ""
+}
+
+func (p *previewOutput) Description() string {
+	return "Print the data an output would send"
+}
+
+func (p *previewOutput) Connect() error {
+	return nil
+}
+
+func (p *previewOutput) Close() error {
+	return nil
+}
+
+func (p *previewOutput) Write(metrics []telegraf.Metric) error {
+	octets, err := p.serializer.SerializeBatch(metrics)
+	if err != nil {
+		return err
+	}
+
+	note := ""
+	if p.lineProtocol {
+		note = " (no data format, shown as line protocol)"
+	}
+	fmt.Fprintf(p.w, "> %s: batch of %d metrics%s\n", p.name, len(metrics), note)
+	if len(octets) > 0 && !bytes.HasSuffix(octets, []byte("\n")) {
+		octets = append(octets, '\n')
+	}
+	_, err = p.w.Write(octets)
+	return err
+}
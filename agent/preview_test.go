@@ -0,0 +1,113 @@
+package agent
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/influxdata/telegraf/models"
+	_ "github.com/influxdata/telegraf/plugins/aggregators/basicstats"
+	_ "github.com/influxdata/telegraf/plugins/processors/override"
+	"github.com/influxdata/telegraf/testutil"
+)
+
+var timestampRe = regexp.MustCompile(`\d{10,}`)
+
+func TestAgent_TestPipeline(t *testing.T) {
+	a, err := NewAgent(loadReloadConfig(t, newReloadEvents(), `
+[agent]
+  interval = "10s"
+  omit_hostname = true
+
+[[inputs.reload_test]]
+  id = "a"
+
+[[processors.override]]
+  [processors.override.tags]
+    stage = "processed"
+
+[[aggregators.basicstats]]
+  period = "30s"
+  drop_original = true
+  stats = ["count"]
+
+[[outputs.file]]
+  files = ["stdout"]
+  data_format = "json"
+
+[[outputs.reload_test]]
+  id = "x"
+
+[[outputs.reload_test]]
+  id = "y"
+  namepass = ["other"]
+`))
+	require.NoError(t, err)
+
+	var buf bytes.Buffer
+	require.NoError(t, a.TestPipeline(context.Background(), 0, time.Minute, &buf))
+
+	require.Equal(t, `> outputs.file: batch of 1 metrics
+{"metrics":[{"fields":{"value_count":6},"name":"reload","tags":{"id":"a","stage":"processed"},"timestamp":TS}]}
+> outputs.reload_test: batch of 1 metrics (no data format, shown as line protocol)
+reload,id=a,stage=processed value_count=6 TS
+> outputs.reload_test: no metrics
+`, timestampRe.ReplaceAllString(buf.String(), "TS"))
+}
+
+func TestAgent_TestPipelineSingleGather(t *testing.T) {
+	a, err := NewAgent(loadReloadConfig(t, newReloadEvents(), `
+[agent]
+  omit_hostname = true
+
+[[inputs.reload_test]]
+  id = "a"
+
+[[outputs.file]]
+  files = ["stdout"]
+  data_format = "influx"
+  metric_batch_size = 1
+`))
+	require.NoError(t, err)
+
+	var buf bytes.Buffer
+	require.NoError(t, a.TestPipeline(context.Background(), 0, 0, &buf))
+
+	require.Equal(t, `> outputs.file: batch of 1 metrics
+reload,id=a value=1i TS
+`, timestampRe.ReplaceAllString(buf.String(), "TS"))
+}
+
+func TestAgent_TestPipelineDiskBufferInUse(t *testing.T) {
+	a, err := NewAgent(loadReloadConfig(t, newReloadEvents(), fmt.Sprintf(`
+[agent]
+  omit_hostname = true
+  buffer_strategy = "disk"
+  buffer_directory = %q
+
+[[inputs.reload_test]]
+  id = "a"
+
+[[outputs.reload_test]]
+  id = "x"
+`, t.TempDir())))
+	require.NoError(t, err)
+
+	// The disk buffer is held by the running agent
+	output := a.Config.Outputs[0]
+	buffer, err := models.NewDiskBuffer(output.Config.Name, output.Config.Alias, 10, output.Config.BufferDirectory, testutil.Logger{})
+	require.NoError(t, err)
+	defer buffer.Close()
+
+	var buf bytes.Buffer
+	require.NoError(t, a.TestPipeline(context.Background(), 0, 0, &buf))
+	require.Equal(t, `> outputs.reload_test: batch of 1 metrics (no data format, shown as line protocol)
+reload,id=a value=1i TS
+`, timestampRe.ReplaceAllString(buf.String(), "TS"))
+	require.Equal(t, 0, buffer.Len())
+}
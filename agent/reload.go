@@ -0,0 +1,352 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/influxdata/telegraf/config"
+	"github.com/influxdata/telegraf/models"
+)
+
+// ErrNotRunning is returned when reloading an agent that is not running.
+var ErrNotRunning = errors.New("agent is not running")
+
+// ErrRestartRequired is returned when the changes of the configuration cannot
+// be applied to the running agent, e.g. when pipelines are added or removed.
+var ErrRestartRequired = errors.New("configuration changes require a restart")
+
+// Reload applies the configuration c to the running agent.
+//
+// Only the plugins whose configuration changed are stopped and started, all
+// other plugins keep running.  Processors and aggregators form a chain that is
+// restarted as a whole if any of them changed.  If the agent settings or the
+// global tags changed all plugins are replaced.  Outputs replaced by an
+// output of the same plugin and alias hand over their buffered metrics to
+// their replacement.
+//
+// The outputs are paused while being replaced, so no metrics are lost.  If a
+// new input, processor or aggregator fails to initialize the running
+// configuration is kept.  Adding or removing pipelines is not supported and
+// results in ErrRestartRequired.
+func (a *Agent) Reload(ctx context.Context, c *config.Config) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	running := a.running
+	if running == nil {
+		return ErrNotRunning
+	}
+	current := a.Config
+
+	if !reflect.DeepEqual(current.Pipelines(), c.Pipelines()) {
+		return ErrRestartRequired
+	}
+	if err := c.CheckPipelines(); err != nil {
+		return err
+	}
+
+	// Changes to the agent settings or global tags affect all plugins.
+	replaceAll := !reflect.DeepEqual(current.Agent, c.Agent) || !reflect.DeepEqual(current.Tags, c.Tags)
+
+	// Determine the inputs to keep, start and stop.
+	inputs := make([]*models.RunningInput, 0, len(c.Inputs))
+	var startInputs, stopInputs []*models.RunningInput
+	keptInputs := make(map[*models.RunningInput]bool)
+	for i, j := range matchFingerprints(inputFingerprints(current.Inputs), inputFingerprints(c.Inputs)) {
+		if j < 0 || replaceAll {
+			inputs = append(inputs, c.Inputs[i])
+			startInputs = append(startInputs, c.Inputs[i])
+			continue
+		}
+		inputs = append(inputs, current.Inputs[j])
+		keptInputs[current.Inputs[j]] = true
+	}
+	for _, input := range current.Inputs {
+		if !keptInputs[input] {
+			stopInputs = append(stopInputs, input)
+		}
+	}
+
+	// Determine the outputs to keep, start and stop, outputs replacing an
+	// output with the same configuration take over its buffer.  Changed
+	// outputs take over the buffer of a stopped output of the same plugin
+	// and alias.
+	outputs := make([]*models.RunningOutput, 0, len(c.Outputs))
+	var startOutputs, stopOutputs []*models.RunningOutput
+	keptOutputs := make(map[*models.RunningOutput]bool)
+	predecessors := make(map[*models.RunningOutput]*models.RunningOutput)
+	for i, j := range matchFingerprints(outputFingerprints(current.Outputs), outputFingerprints(c.Outputs)) {
+		switch {
+		case j < 0:
+			outputs = append(outputs, c.Outputs[i])
+			startOutputs = append(startOutputs, c.Outputs[i])
+		case replaceAll:
+			outputs = append(outputs, c.Outputs[i])
+			startOutputs = append(startOutputs, c.Outputs[i])
+			predecessors[c.Outputs[i]] = current.Outputs[j]
+		default:
+			outputs = append(outputs, current.Outputs[j])
+			keptOutputs[current.Outputs[j]] = true
+		}
+	}
+	replaced := make(map[*models.RunningOutput]bool, len(predecessors))
+	for _, predecessor := range predecessors {
+		replaced[predecessor] = true
+	}
+	for _, output := range current.Outputs {
+		if !keptOutputs[output] {
+			stopOutputs = append(stopOutputs, output)
+		}
+	}
+	for _, output := range startOutputs {
+		if predecessors[output] != nil {
+			continue
+		}
+		for _, old := range stopOutputs {
+			if !replaced[old] && old.Config.Name == output.Config.Name && old.Config.Alias == output.Config.Alias {
+				replaced[old] = true
+				predecessors[output] = old
+				break
+			}
+		}
+	}
+
+	restartChain := replaceAll ||
+		!equalFingerprints(processorFingerprints(current.Processors), processorFingerprints(c.Processors)) ||
+		!equalFingerprints(processorFingerprints(current.AggProcessors), processorFingerprints(c.AggProcessors)) ||
+		!equalFingerprints(aggregatorFingerprints(current.Aggregators), aggregatorFingerprints(c.Aggregators))
+
+	// Initialize the new plugins before touching the running ones.  Outputs
+	// are initialized when they are started, as their disk buffer must be
+	// released by the output they replace first.
+	for _, input := range startInputs {
+		if err := input.Init(); err != nil {
+			return fmt.Errorf("could not initialize input %s: %v", input.LogName(), err)
+		}
+	}
+	for _, parser := range c.Parsers {
+		if err := parser.Init(); err != nil {
+			return fmt.Errorf("could not initialize parser %s::%s: %v",
+				parser.Config.DataFormat, parser.Config.Parent, err)
+		}
+	}
+	if restartChain {
+		for _, processor := range c.Processors {
+			if err := processor.Init(); err != nil {
+				return fmt.Errorf("could not initialize processor %s: %v", processor.LogName(), err)
+			}
+		}
+		for _, aggregator := range c.Aggregators {
+			if err := aggregator.Init(); err != nil {
+				return fmt.Errorf("could not initialize aggregator %s: %v", aggregator.LogName(), err)
+			}
+		}
+		for _, processor := range c.AggProcessors {
+			if err := processor.Init(); err != nil {
+				return fmt.Errorf("could not initialize processor %s: %v", processor.LogName(), err)
+			}
+		}
+	} else {
+		c.Processors = current.Processors
+		c.AggProcessors = current.AggProcessors
+		c.Aggregators = current.Aggregators
+	}
+
+	// From here on the new configuration is the running one.
+	c.Inputs = inputs
+	c.Outputs = outputs
+	a.Config = c
+
+	var errs []string
+
+	// Replace the outputs while they are paused, metrics queue up in the
+	// channels in the meantime.
+	ou := running.outputs
+	ou.Lock()
+	for _, output := range stopOutputs {
+		log.Printf("I! [agent] Stopping output %s", output.LogName())
+		if loop, ok := ou.running[output]; ok {
+			loop.stop()
+			delete(ou.running, output)
+		}
+		output.Close()
+	}
+	for _, output := range startOutputs {
+		log.Printf("I! [agent] Starting output %s", output.LogName())
+		if err := a.startOutput(ctx, output, predecessors[output]); err != nil {
+			errs = append(errs, err.Error())
+			c.Outputs = removeOutput(c.Outputs, output)
+			continue
+		}
+		a.flushOutput(ou, output)
+	}
+	ou.outputs = c.Outputs
+	ou.Unlock()
+
+	if restartChain {
+		log.Printf("I! [agent] Restarting processors and aggregators")
+		if err := a.restartChains(running.relays); err != nil {
+			errs = append(errs, err.Error())
+			c.Processors = current.Processors
+			c.AggProcessors = current.AggProcessors
+			c.Aggregators = current.Aggregators
+		}
+	}
+
+	iu := running.inputs
+	for _, input := range stopInputs {
+		log.Printf("I! [agent] Stopping input %s", input.LogName())
+		stopInput(iu, input)
+	}
+	startTime := time.Now()
+	for _, input := range startInputs {
+		log.Printf("I! [agent] Starting input %s", input.LogName())
+		if err := startServiceInput(iu.dst(input), input); err != nil {
+			errs = append(errs, fmt.Sprintf("starting input %s: %v", input.LogName(), err))
+			c.Inputs = removeInput(c.Inputs, input)
+			continue
+		}
+		a.gatherInput(startTime, iu, input)
+	}
+	iu.inputs = c.Inputs
+
+	log.Printf("I! [agent] Reloaded configuration, started %d and stopped %d inputs, started %d and stopped %d outputs",
+		len(startInputs), len(stopInputs), len(startOutputs), len(stopOutputs))
+
+	if len(errs) > 0 {
+		return fmt.Errorf("reloading configuration: %s", strings.Join(errs, "; "))
+	}
+	return nil
+}
+
+// restartChains starts a new chain for every pipeline and swaps them in once
+// all are started.  If a chain fails to start the running chains are kept.
+func (a *Agent) restartChains(relays map[string]*relayUnit) error {
+	startTime := time.Now()
+	chains := make(map[string]*chainUnit, len(relays))
+	for pipeline, relay := range relays {
+		chain, err := a.startChain(pipeline, relay.dst, startTime, 0)
+		if err != nil {
+			// The chains did not receive any metric yet, closing them stops
+			// their processors.
+			for _, chain := range chains {
+				close(chain.src)
+				<-chain.done
+			}
+			return err
+		}
+		chains[pipeline] = chain
+	}
+
+	for pipeline, relay := range relays {
+		relay.swap <- chains[pipeline]
+	}
+	return nil
+}
+
+// startOutput initializes and connects an output started by a reload.  If
+// the output replaces a previous output, it takes over its buffered metrics.
+func (a *Agent) startOutput(ctx context.Context, output, predecessor *models.RunningOutput) error {
+	if err := output.Init(); err != nil {
+		return fmt.Errorf("could not initialize output %s: %v", output.LogName(), err)
+	}
+	if predecessor != nil {
+		output.TakeOver(predecessor)
+	}
+	if err := a.connectOutput(ctx, output); err != nil {
+		output.Close()
+		return fmt.Errorf("connecting output %s: %w", output.LogName(), err)
+	}
+	return nil
+}
+
+// matchFingerprints pairs plugins of the running and the updated configuration
+// with identical fingerprints.  For each updated plugin the index of the
+// matching running plugin is returned, or -1 if there is none.
+func matchFingerprints(running, updated []string) []int {
+	used := make([]bool, len(running))
+	matches := make([]int, len(updated))
+	for i, fp := range updated {
+		matches[i] = -1
+		for j, other := range running {
+			if !used[j] && fp == other {
+				used[j] = true
+				matches[i] = j
+				break
+			}
+		}
+	}
+	return matches
+}
+
+// equalFingerprints returns true if both sets of fingerprints are equal
+// regardless of their order.
+func equalFingerprints(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	sort.Strings(a)
+	sort.Strings(b)
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func inputFingerprints(inputs []*models.RunningInput) []string {
+	fps := make([]string, 0, len(inputs))
+	for _, input := range inputs {
+		fps = append(fps, input.Config.Fingerprint)
+	}
+	return fps
+}
+
+func outputFingerprints(outputs []*models.RunningOutput) []string {
+	fps := make([]string, 0, len(outputs))
+	for _, output := range outputs {
+		fps = append(fps, output.Config.Fingerprint)
+	}
+	return fps
+}
+
+func processorFingerprints(processors models.RunningProcessors) []string {
+	fps := make([]string, 0, len(processors))
+	for _, processor := range processors {
+		fps = append(fps, processor.Config.Fingerprint)
+	}
+	return fps
+}
+
+func aggregatorFingerprints(aggregators []*models.RunningAggregator) []string {
+	fps := make([]string, 0, len(aggregators))
+	for _, aggregator := range aggregators {
+		fps = append(fps, aggregator.Config.Fingerprint)
+	}
+	return fps
+}
+
+func removeInput(inputs []*models.RunningInput, input *models.RunningInput) []*models.RunningInput {
+	for i, other := range inputs {
+		if other == input {
+			return append(inputs[:i], inputs[i+1:]...)
+		}
+	}
+	return inputs
+}
+
+func removeOutput(outputs []*models.RunningOutput, output *models.RunningOutput) []*models.RunningOutput {
+	for i, other := range outputs {
+		if other == output {
+			return append(outputs[:i], outputs[i+1:]...)
+		}
+	}
+	return outputs
+}
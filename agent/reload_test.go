@@ -0,0 +1,299 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/config"
+	"github.com/influxdata/telegraf/plugins/inputs"
+	"github.com/influxdata/telegraf/plugins/outputs"
+)
+
+func TestAgent_ReloadChangedPlugins(t *testing.T) {
+	events := newReloadEvents()
+	a := startReloadAgent(t, events, `
+[agent]
+  interval = "50ms"
+  flush_interval = "50ms"
+  omit_hostname = true
+
+[[inputs.reload_test]]
+  id = "a"
+[[inputs.reload_test]]
+  id = "b"
+
+[[outputs.reload_test]]
+  id = "x"
+`)
+
+	require.Eventually(t, func() bool {
+		return events.received("x", "a") && events.received("x", "b")
+	}, 5*time.Second, 10*time.Millisecond)
+	output := a.Config.Outputs[0]
+
+	c := loadReloadConfig(t, events, `
+[agent]
+  interval = "50ms"
+  flush_interval = "50ms"
+  omit_hostname = true
+
+[[inputs.reload_test]]
+  id = "a"
+[[inputs.reload_test]]
+  id = "c"
+
+[[outputs.reload_test]]
+  id = "x"
+`)
+	require.NoError(t, a.Reload(context.Background(), c))
+
+	require.Equal(t, []string{"connect x", "start a", "start b", "stop b", "start c"}, events.lifecycle())
+	require.Same(t, output, a.Config.Outputs[0])
+	require.Len(t, a.Config.Inputs, 2)
+
+	require.Eventually(t, func() bool {
+		return events.received("x", "c")
+	}, 5*time.Second, 10*time.Millisecond)
+}
+
+func TestAgent_ReloadAgentSettingsTakeOverBuffer(t *testing.T) {
+	events := newReloadEvents()
+	a := startReloadAgent(t, events, `
+[agent]
+  interval = "50ms"
+  flush_interval = "50ms"
+  omit_hostname = true
+
+[[inputs.reload_test]]
+  id = "a"
+
+[[outputs.reload_test]]
+  id = "x"
+  fail = true
+`)
+
+	output := a.Config.Outputs[0]
+	require.Eventually(t, func() bool {
+		return output.BufferLength() > 0
+	}, 5*time.Second, 10*time.Millisecond)
+
+	c := loadReloadConfig(t, events, `
+[agent]
+  interval = "60ms"
+  flush_interval = "50ms"
+  omit_hostname = true
+
+[[inputs.reload_test]]
+  id = "a"
+
+[[outputs.reload_test]]
+  id = "x"
+  fail = true
+`)
+	require.NoError(t, a.Reload(context.Background(), c))
+
+	require.Equal(t, []string{"connect x", "start a", "close x", "connect x", "stop a", "start a"}, events.lifecycle())
+	require.NotSame(t, output, a.Config.Outputs[0])
+	require.Equal(t, 0, output.BufferLength())
+	require.Greater(t, a.Config.Outputs[0].BufferLength(), 0)
+}
+
+func TestAgent_ReloadChangedOutputTakeOverBuffer(t *testing.T) {
+	events := newReloadEvents()
+	a := startReloadAgent(t, events, `
+[agent]
+  interval = "50ms"
+  flush_interval = "50ms"
+  omit_hostname = true
+
+[[inputs.reload_test]]
+  id = "a"
+
+[[outputs.reload_test]]
+  id = "x"
+  fail = true
+`)
+
+	output := a.Config.Outputs[0]
+	require.Eventually(t, func() bool {
+		return output.BufferLength() > 0
+	}, 5*time.Second, 10*time.Millisecond)
+
+	c := loadReloadConfig(t, events, `
+[agent]
+  interval = "50ms"
+  flush_interval = "50ms"
+  omit_hostname = true
+
+[[inputs.reload_test]]
+  id = "a"
+
+[[outputs.reload_test]]
+  id = "y"
+  fail = true
+`)
+	buffered := output.BufferLength()
+	require.NoError(t, a.Reload(context.Background(), c))
+
+	require.Equal(t, []string{"connect x", "start a", "close x", "connect y"}, events.lifecycle())
+	require.NotSame(t, output, a.Config.Outputs[0])
+	require.Equal(t, 0, output.BufferLength())
+	require.GreaterOrEqual(t, a.Config.Outputs[0].BufferLength(), buffered)
+}
+
+func TestAgent_ReloadNotRunning(t *testing.T) {
+	a, err := NewAgent(config.NewConfig())
+	require.NoError(t, err)
+	require.EqualError(t, a.Reload(context.Background(), config.NewConfig()), "agent is not running")
+}
+
+func startReloadAgent(t *testing.T, events *reloadEvents, cfg string) *Agent {
+	a, err := NewAgent(loadReloadConfig(t, events, cfg))
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		require.NoError(t, a.Run(ctx))
+	}()
+	t.Cleanup(func() {
+		cancel()
+		wg.Wait()
+	})
+
+	require.Eventually(t, func() bool {
+		a.mu.Lock()
+		defer a.mu.Unlock()
+		return a.running != nil
+	}, 5*time.Second, 10*time.Millisecond)
+	return a
+}
+
+func loadReloadConfig(t *testing.T, events *reloadEvents, cfg string) *config.Config {
+	reloadTestEvents = events
+	c := config.NewConfig()
+	require.NoError(t, c.LoadConfigData([]byte(cfg)))
+	return c
+}
+
+// reloadEvents records the lifecycle of the test plugins and the metrics
+// received by the outputs.
+type reloadEvents struct {
+	sync.Mutex
+	events  []string
+	metrics map[string]map[string]bool
+}
+
+func newReloadEvents() *reloadEvents {
+	return &reloadEvents{metrics: make(map[string]map[string]bool)}
+}
+
+func (e *reloadEvents) add(event string) {
+	e.Lock()
+	defer e.Unlock()
+	e.events = append(e.events, event)
+}
+
+func (e *reloadEvents) lifecycle() []string {
+	e.Lock()
+	defer e.Unlock()
+	return append([]string{}, e.events...)
+}
+
+func (e *reloadEvents) receive(output string, metrics []telegraf.Metric) {
+	e.Lock()
+	defer e.Unlock()
+	if e.metrics[output] == nil {
+		e.metrics[output] = make(map[string]bool)
+	}
+	for _, m := range metrics {
+		id, _ := m.GetTag("id")
+		e.metrics[output][id] = true
+	}
+}
+
+func (e *reloadEvents) received(output, input string) bool {
+	e.Lock()
+	defer e.Unlock()
+	return e.metrics[output][input]
+}
+
+var reloadTestEvents *reloadEvents
+
+type reloadInput struct {
+	ID string `toml:"id"`
+
+	events *reloadEvents
+}
+
+func (*reloadInput) SampleConfig() string {
+	return ""
+}
+
+func (*reloadInput) Description() string {
+	return ""
+}
+
+func (i *reloadInput) Start(telegraf.Accumulator) error {
+	i.events.add("start " + i.ID)
+	return nil
+}
+
+func (i *reloadInput) Stop() {
+	i.events.add("stop " + i.ID)
+}
+
+func (i *reloadInput) Gather(acc telegraf.Accumulator) error {
+	acc.AddFields("reload", map[string]interface{}{"value": 1}, map[string]string{"id": i.ID})
+	return nil
+}
+
+type reloadOutput struct {
+	ID   string `toml:"id"`
+	Fail bool   `toml:"fail"`
+
+	events *reloadEvents
+}
+
+func (*reloadOutput) SampleConfig() string {
+	return ""
+}
+
+func (*reloadOutput) Description() string {
+	return ""
+}
+
+func (o *reloadOutput) Connect() error {
+	o.events.add("connect " + o.ID)
+	return nil
+}
+
+func (o *reloadOutput) Close() error {
+	o.events.add("close " + o.ID)
+	return nil
+}
+
+func (o *reloadOutput) Write(metrics []telegraf.Metric) error {
+	if o.Fail {
+		return errors.New("failed write")
+	}
+	o.events.receive(o.ID, metrics)
+	return nil
+}
+
+func init() {
+	inputs.Add("reload_test", func() telegraf.Input {
+		return &reloadInput{events: reloadTestEvents}
+	})
+	outputs.Add("reload_test", func() telegraf.Output {
+		return &reloadOutput{events: reloadTestEvents}
+	})
+}
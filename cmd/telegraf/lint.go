@@ -0,0 +1,59 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+
+	"github.com/influxdata/telegraf/config"
+)
+
+const lintUsage = `Usage:
+
+  telegraf [--config <file>] [--config-directory <directory>] config lint [--format text|json]`
+
+// errLintFailed signals that the configuration contains errors, the
+// diagnostics are already printed.
+var errLintFailed = errors.New("configuration contains errors")
+
+// runLint implements the "config lint" command checking the configuration
+// without starting any plugin.
+func runLint(args []string, w io.Writer) error {
+	flags := flag.NewFlagSet("lint", flag.ContinueOnError)
+	flags.SetOutput(io.Discard)
+	format := flags.String("format", "text", "output format of the diagnostics [text, json]")
+	if err := flags.Parse(args); err != nil || flags.NArg() > 0 {
+		return errors.New(lintUsage)
+	}
+	if *format != "text" && *format != "json" {
+		return fmt.Errorf("unknown format %q\n\n%s", *format, lintUsage)
+	}
+
+	diagnostics := config.Lint(fConfigs, fConfigDirs)
+
+	switch *format {
+	case "json":
+		if diagnostics == nil {
+			diagnostics = []config.Diagnostic{}
+		}
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(diagnostics); err != nil {
+			return err
+		}
+	default:
+		for _, d := range diagnostics {
+			fmt.Fprintln(w, d.String())
+		}
+		if len(diagnostics) == 0 {
+			fmt.Fprintln(w, "Configuration is valid")
+		}
+	}
+
+	if config.HasErrors(diagnostics) {
+		return errLintFailed
+	}
+	return nil
+}
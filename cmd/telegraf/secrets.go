@@ -0,0 +1,112 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+
+	"golang.org/x/term"
+
+	"github.com/influxdata/telegraf/config"
+)
+
+const secretsUsage = `Usage:
+
+  telegraf [--config <file>] secrets list <store-id>
+  telegraf [--config <file>] secrets get <store-id> <key>
+  telegraf [--config <file>] secrets set <store-id> <key>
+
+The value of "set" is read from stdin, or prompted for without echo when
+stdin is a terminal.`
+
+// runSecrets implements the "secrets" command used to manage the secrets of
+// the secret-stores defined in the configuration.
+func runSecrets(args []string) error {
+	if len(args) < 2 {
+		return errors.New(secretsUsage)
+	}
+	command, id := args[0], args[1]
+
+	c := config.NewConfig()
+	if err := loadConfiguration(c); err != nil {
+		return err
+	}
+
+	store, found := c.SecretStores[id]
+	if !found {
+		return fmt.Errorf("unknown secret-store %q", id)
+	}
+
+	switch command {
+	case "list":
+		if len(args) != 2 {
+			return errors.New(secretsUsage)
+		}
+		keys, err := store.List()
+		if err != nil {
+			return fmt.Errorf("listing secrets failed: %w", err)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			fmt.Println(k)
+		}
+	case "get":
+		if len(args) != 3 {
+			return errors.New(secretsUsage)
+		}
+		secret, err := store.Get(args[2])
+		if err != nil {
+			return fmt.Errorf("getting secret failed: %w", err)
+		}
+		defer config.ReleaseSecret(secret)
+		if _, err := os.Stdout.Write(append(secret, '\n')); err != nil {
+			return err
+		}
+	case "set":
+		if len(args) != 3 {
+			return errors.New(secretsUsage)
+		}
+		value, err := readSecret(os.Stdin)
+		if err != nil {
+			return fmt.Errorf("reading secret failed: %w", err)
+		}
+		defer config.ReleaseSecret(value)
+		if err := store.Set(args[2], string(value)); err != nil {
+			return fmt.Errorf("setting secret failed: %w", err)
+		}
+	default:
+		return fmt.Errorf("unknown secrets command %q\n\n%s", command, secretsUsage)
+	}
+	return nil
+}
+
+// readSecret reads the secret from the file, prompting for it without echo
+// if the file is a terminal.  Passing secrets as arguments would expose them
+// in the process list and shell history.
+func readSecret(f *os.File) ([]byte, error) {
+	if term.IsTerminal(int(f.Fd())) {
+		fmt.Fprint(os.Stderr, "Enter secret: ")
+		value, err := term.ReadPassword(int(f.Fd()))
+		fmt.Fprintln(os.Stderr)
+		return value, err
+	}
+	return readSecretFrom(f)
+}
+
+// readSecretFrom reads the secret, stripping a single trailing line break as
+// added by e.g. "echo".
+func readSecretFrom(r io.Reader) ([]byte, error) {
+	value, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	value = bytes.TrimSuffix(value, []byte("\n"))
+	value = bytes.TrimSuffix(value, []byte("\r"))
+	if len(value) == 0 {
+		return nil, errors.New("empty secret")
+	}
+	return value, nil
+}
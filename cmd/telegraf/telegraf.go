@@ -33,6 +33,7 @@ import (
 	_ "github.com/influxdata/telegraf/plugins/outputs/all"
 	_ "github.com/influxdata/telegraf/plugins/parsers/all"
 	_ "github.com/influxdata/telegraf/plugins/processors/all"
+	_ "github.com/influxdata/telegraf/plugins/secretstores/all"
 	"gopkg.in/tomb.v1"
 )
 
@@ -53,10 +54,14 @@ var fDebug = flag.Bool("debug", false,
 	"turn on debug logging")
 var pprofAddr = flag.String("pprof-addr", "",
 	"pprof address to listen on, not activate pprof if empty")
+var fAPIAddr = flag.String("api-addr", "",
+	"control API address to listen on, not activate the API if empty")
 var fQuiet = flag.Bool("quiet", false,
 	"run in quiet mode")
-var fTest = flag.Bool("test", false, "enable test mode: gather metrics, print them out, and exit. Note: Test mode only runs inputs, not processors, aggregators, or outputs")
+var fTest = flag.Bool("test", false, "enable test mode: gather metrics, print them out, and exit. Note: Test mode does not run outputs, use --test-pipeline to print the data sent by the outputs")
 var fTestWait = flag.Int("test-wait", 0, "wait up to this many seconds for service inputs to complete in test mode")
+var fTestPipeline = flag.Bool("test-pipeline", false, "enable pipeline test mode: gather metrics, print the data each output would send without connecting it, and exit")
+var fTestWindow = flag.Int("test-window", 0, "simulate gathers over this many seconds for the aggregators in pipeline test mode")
 
 var fConfigs sliceFlags
 var fConfigDirs sliceFlags
@@ -111,6 +116,10 @@ var (
 
 var stop chan struct{}
 
+// hotReload passes reload requests to the running agent, the result of the
+// reload is sent back on the given channel.
+var hotReload = make(chan chan error)
+
 func reloadLoop(
 	inputFilters []string,
 	outputFilters []string,
@@ -124,26 +133,31 @@ func reloadLoop(
 		signals := make(chan os.Signal, 1)
 		signal.Notify(signals, os.Interrupt, syscall.SIGHUP,
 			syscall.SIGTERM, syscall.SIGINT)
-		if *fWatchConfig != "" {
-			for _, fConfig := range fConfigs {
-				if _, err := os.Stat(fConfig); err == nil {
-					go watchLocalConfig(signals, fConfig)
-				} else {
-					log.Printf("W! Cannot watch config %s: %s", fConfig, err)
-				}
-			}
-		}
+		stopWatch := watchConfig(signals)
 		go func() {
-			select {
-			case sig := <-signals:
-				if sig == syscall.SIGHUP {
-					log.Printf("I! Reloading Telegraf config")
-					<-reload
-					reload <- true
+			defer signal.Stop(signals)
+			for {
+				select {
+				case sig := <-signals:
+					// The signal may not come from a watcher, the others are
+					// still running in any case.
+					stopWatch()
+					if sig == syscall.SIGHUP {
+						log.Printf("I! Reloading Telegraf config")
+						if reloadRunningAgent() {
+							stopWatch = watchConfig(signals)
+							continue
+						}
+						<-reload
+						reload <- true
+					}
+					cancel()
+					return
+				case <-stop:
+					stopWatch()
+					cancel()
+					return
 				}
-				cancel()
-			case <-stop:
-				cancel()
 			}
 		}()
 
@@ -154,15 +168,38 @@ func reloadLoop(
 	}
 }
 
-func watchLocalConfig(signals chan os.Signal, fConfig string) {
-	var mytomb tomb.Tomb
+// watchConfig starts watching the config files if requested and returns a
+// function stopping the watchers.  The watchers also stop after reporting
+// the first change.
+func watchConfig(signals chan os.Signal) func() {
+	if *fWatchConfig == "" {
+		return func() {}
+	}
+	tombs := make([]*tomb.Tomb, 0, len(fConfigs))
+	for _, fConfig := range fConfigs {
+		if _, err := os.Stat(fConfig); err == nil {
+			mytomb := &tomb.Tomb{}
+			tombs = append(tombs, mytomb)
+			go watchLocalConfig(mytomb, signals, fConfig)
+		} else {
+			log.Printf("W! Cannot watch config %s: %s", fConfig, err)
+		}
+	}
+	return func() {
+		for _, mytomb := range tombs {
+			mytomb.Kill(nil)
+		}
+	}
+}
+
+func watchLocalConfig(mytomb *tomb.Tomb, signals chan os.Signal, fConfig string) {
 	var watcher watch.FileWatcher
 	if *fWatchConfig == "poll" {
 		watcher = watch.NewPollingFileWatcher(fConfig)
 	} else {
 		watcher = watch.NewInotifyFileWatcher(fConfig)
 	}
-	changes, err := watcher.ChangeEvents(&mytomb, 0)
+	changes, err := watcher.ChangeEvents(mytomb, 0)
 	if err != nil {
 		log.Printf("E! Error watching config: %s\n", err)
 		return
@@ -178,7 +215,7 @@ func watchLocalConfig(signals chan os.Signal, fConfig string) {
 			log.Println("I! Config file overwritten")
 		} else {
 			log.Println("W! Config file deleted")
-			if err := watcher.BlockUntilExists(&mytomb); err != nil {
+			if err := watcher.BlockUntilExists(mytomb); err != nil {
 				log.Printf("E! Cannot watch for config: %s\n", err.Error())
 				return
 			}
@@ -191,41 +228,40 @@ func watchLocalConfig(signals chan os.Signal, fConfig string) {
 		return
 	}
 	mytomb.Done()
-	signals <- syscall.SIGHUP
-}
 
-func runAgent(ctx context.Context,
-	inputFilters []string,
-	outputFilters []string,
-) error {
-	log.Printf("I! Starting Telegraf %s", version)
+	// A pending signal already triggers a reload
+	select {
+	case signals <- syscall.SIGHUP:
+	default:
+	}
+}
 
-	// If no other options are specified, load the config file and run.
-	c := config.NewConfig()
-	c.OutputFilters = outputFilters
-	c.InputFilters = inputFilters
-	var err error
+// loadConfiguration loads the configuration files and directories given on
+// the command-line into c.
+func loadConfiguration(c *config.Config) error {
 	// providing no "config" flag should load default config
 	if len(fConfigs) == 0 {
-		err = c.LoadConfig("")
-		if err != nil {
+		if err := c.LoadConfig(""); err != nil {
 			return err
 		}
 	}
 	for _, fConfig := range fConfigs {
-		err = c.LoadConfig(fConfig)
-		if err != nil {
+		if err := c.LoadConfig(fConfig); err != nil {
 			return err
 		}
 	}
 
 	for _, fConfigDirectory := range fConfigDirs {
-		err = c.LoadDirectory(fConfigDirectory)
-		if err != nil {
+		if err := c.LoadDirectory(fConfigDirectory); err != nil {
 			return err
 		}
 	}
+	return nil
+}
 
+// checkConfiguration checks if the configuration can be used to run the
+// agent.
+func checkConfiguration(c *config.Config) error {
 	if !*fTest && len(c.Outputs) == 0 {
 		return errors.New("Error: no outputs found, did you provide a valid config file?")
 	}
@@ -240,31 +276,105 @@ func runAgent(ctx context.Context,
 	if int64(c.Agent.FlushInterval) <= 0 {
 		return fmt.Errorf("Agent flush_interval must be positive; found %v", c.Agent.Interval)
 	}
+	return nil
+}
+
+// setupLogging configures the logger according to the agent settings.
+func setupLogging(c *config.Config) {
+	telegraf.Debug = c.Agent.Debug || *fDebug
+	logConfig := logger.LogConfig{
+		Debug:               telegraf.Debug,
+		Quiet:               c.Agent.Quiet || *fQuiet,
+		LogTarget:           c.Agent.LogTarget,
+		Logfile:             c.Agent.Logfile,
+		RotationInterval:    c.Agent.LogfileRotationInterval,
+		RotationMaxSize:     c.Agent.LogfileRotationMaxSize,
+		RotationMaxArchives: c.Agent.LogfileRotationMaxArchives,
+		LogWithTimezone:     c.Agent.LogWithTimezone,
+	}
 
-	ag, err := agent.NewAgent(c)
+	logger.SetupLogging(logConfig)
+}
+
+// reloadAgent loads the configuration and applies the changes to the running
+// agent.
+func reloadAgent(
+	ctx context.Context,
+	ag *agent.Agent,
+	inputFilters []string,
+	outputFilters []string,
+) error {
+	c := config.NewConfig()
+	c.OutputFilters = outputFilters
+	c.InputFilters = inputFilters
+	if err := loadConfiguration(c); err != nil {
+		return err
+	}
+	if err := checkConfiguration(c); err != nil {
+		return err
+	}
+
+	if err := ag.Reload(ctx, c); err != nil {
+		return err
+	}
+	setupLogging(c)
+	return nil
+}
+
+// reloadRunningAgent asks the running agent to apply the changed
+// configuration.  It returns false if the agent cannot be reloaded and must be
+// restarted instead.
+func reloadRunningAgent() bool {
+	result := make(chan error, 1)
+	select {
+	case hotReload <- result:
+	default:
+		return false
+	}
+
+	err := <-result
+	if errors.Is(err, agent.ErrNotRunning) {
+		return false
+	}
+	if errors.Is(err, agent.ErrRestartRequired) {
+		log.Printf("I! [telegraf] Restarting the agent to apply the configuration changes")
+		return false
+	}
 	if err != nil {
+		log.Printf("E! [telegraf] Error reloading config: %v", err)
+	}
+	return true
+}
+
+func runAgent(ctx context.Context,
+	inputFilters []string,
+	outputFilters []string,
+) error {
+	log.Printf("I! Starting Telegraf %s", version)
+
+	// If no other options are specified, load the config file and run.
+	c := config.NewConfig()
+	c.OutputFilters = outputFilters
+	c.InputFilters = inputFilters
+	if err := loadConfiguration(c); err != nil {
+		return err
+	}
+	if err := checkConfiguration(c); err != nil {
 		return err
 	}
 
-	// Setup logging as configured.
-	telegraf.Debug = ag.Config.Agent.Debug || *fDebug
-	logConfig := logger.LogConfig{
-		Debug:               telegraf.Debug,
-		Quiet:               ag.Config.Agent.Quiet || *fQuiet,
-		LogTarget:           ag.Config.Agent.LogTarget,
-		Logfile:             ag.Config.Agent.Logfile,
-		RotationInterval:    ag.Config.Agent.LogfileRotationInterval,
-		RotationMaxSize:     ag.Config.Agent.LogfileRotationMaxSize,
-		RotationMaxArchives: ag.Config.Agent.LogfileRotationMaxArchives,
-		LogWithTimezone:     ag.Config.Agent.LogWithTimezone,
+	ag, err := agent.NewAgent(c)
+	if err != nil {
+		return err
 	}
 
-	logger.SetupLogging(logConfig)
+	// Setup logging as configured.
+	setupLogging(ag.Config)
 
 	log.Printf("I! Loaded inputs: %s", strings.Join(c.InputNames(), " "))
 	log.Printf("I! Loaded aggregators: %s", strings.Join(c.AggregatorNames(), " "))
 	log.Printf("I! Loaded processors: %s", strings.Join(c.ProcessorNames(), " "))
-	if !*fRunOnce && (*fTest || *fTestWait != 0) {
+	if !*fRunOnce && !*fTestPipeline && (*fTest || *fTestWait != 0) {
 		log.Print("W! " + color.RedString("Outputs are not used in testing mode!"))
 	} else {
 		log.Printf("I! Loaded outputs: %s", strings.Join(c.OutputNames(), " "))
@@ -295,6 +405,12 @@ func runAgent(ctx context.Context,
 		return ag.Once(ctx, wait)
 	}
 
+	if *fTestPipeline {
+		wait := time.Duration(*fTestWait) * time.Second
+		window := time.Duration(*fTestWindow) * time.Second
+		return ag.TestPipeline(ctx, wait, window, os.Stdout)
+	}
+
 	if *fTest || *fTestWait != 0 {
 		wait := time.Duration(*fTestWait) * time.Second
 		return ag.Test(ctx, wait)
@@ -318,6 +434,29 @@ func runAgent(ctx context.Context,
 		}
 	}
 
+	if *fAPIAddr != "" {
+		server := &http.Server{Addr: *fAPIAddr, Handler: ag.APIHandler()}
+		go func() {
+			log.Printf("I! Starting control API server at: http://%s/api/v1/", *fAPIAddr)
+			if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+				log.Printf("E! Control API server failed: %v", err)
+			}
+		}()
+		defer server.Close()
+	}
+
+	// Apply configuration changes to the running agent on request.
+	go func() {
+		for {
+			select {
+			case result := <-hotReload:
+				result <- reloadAgent(ctx, ag, inputFilters, outputFilters)
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
 	return ag.Run(ctx)
 }
 
@@ -414,6 +553,16 @@ func main() {
 			fmt.Println(formatFullVersion())
 			return
 		case "config":
+			if len(args) > 1 && args[1] == "lint" {
+				err := runLint(args[2:], os.Stdout)
+				if errors.Is(err, errLintFailed) {
+					os.Exit(1)
+				}
+				if err != nil {
+					log.Fatal("E! " + err.Error())
+				}
+				return
+			}
 			config.PrintSampleConfig(
 				sectionFilters,
 				inputFilters,
@@ -422,6 +571,11 @@ func main() {
 				processorFilters,
 			)
 			return
+		case "secrets":
+			if err := runSecrets(args[1:]); err != nil {
+				log.Fatal("E! " + err.Error())
+			}
+			return
 		}
 	}
 
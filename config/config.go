@@ -2,7 +2,9 @@ package config
 
 import (
 	"bytes"
+	"crypto/sha256"
 	"crypto/tls"
+	"encoding/hex"
 	"fmt"
 	"io"
 	"log"
@@ -30,6 +32,7 @@ import (
 	"github.com/influxdata/telegraf/plugins/parsers"
 	"github.com/influxdata/telegraf/plugins/parsers/json_v2"
 	"github.com/influxdata/telegraf/plugins/processors"
+	"github.com/influxdata/telegraf/plugins/secretstores"
 	"github.com/influxdata/telegraf/plugins/serializers"
 	"github.com/influxdata/toml"
 	"github.com/influxdata/toml/ast"
@@ -81,6 +84,8 @@ type Config struct {
 	// Processors have a slice wrapper type because they need to be sorted
 	Processors    models.RunningProcessors
 	AggProcessors models.RunningProcessors
+	// SecretStores are indexed by the ID used in secret references
+	SecretStores map[string]telegraf.SecretStore
 
 	Deprecations map[string][]int64
 	version      *semver.Version
@@ -98,6 +103,7 @@ func NewConfig() *Config {
 			Interval:                   Duration(10 * time.Second),
 			RoundInterval:              true,
 			FlushInterval:              Duration(10 * time.Second),
+			BufferStrategy:             "memory",
 			LogTarget:                  "file",
 			LogfileRotationMaxArchives: 5,
 		},
@@ -108,6 +114,7 @@ func NewConfig() *Config {
 		Parsers:       make([]*models.RunningParser, 0),
 		Processors:    make([]*models.RunningProcessor, 0),
 		AggProcessors: make([]*models.RunningProcessor, 0),
+		SecretStores:  make(map[string]telegraf.SecretStore),
 		InputFilters:  make([]string, 0),
 		OutputFilters: make([]string, 0),
 		Deprecations:  make(map[string][]int64),
@@ -173,6 +180,12 @@ type AgentConfig struct {
 	// not be less than 2 times MetricBatchSize.
 	MetricBufferLimit int
 
+	// BufferStrategy is the default type of buffer used by outputs, either
+	// "memory" or "disk".  The disk buffer keeps unsent metrics in files
+	// below BufferDirectory so they survive a restart of the agent.
+	BufferStrategy  string `toml:"buffer_strategy"`
+	BufferDirectory string `toml:"buffer_directory"`
+
 	// FlushBufferWhenFull tells Telegraf to flush the metric buffer whenever
 	// it fills up, regardless of FlushInterval. Setting this option to true
 	// does _not_ deactivate FlushInterval.
@@ -263,6 +276,42 @@ func (c *Config) OutputNames() []string {
 	return PluginNameCounts(name)
 }
 
+// Pipelines returns the sorted names of the pipelines used by the plugins.
+// The default pipeline, named "", is always included.
+func (c *Config) Pipelines() []string {
+	names := map[string]bool{"": true}
+	for _, input := range c.Inputs {
+		names[input.Config.Pipeline] = true
+	}
+	for _, processor := range c.Processors {
+		names[processor.Config.Pipeline] = true
+	}
+	for _, aggregator := range c.Aggregators {
+		names[aggregator.Config.Pipeline] = true
+	}
+	for _, output := range c.Outputs {
+		names[output.Config.Pipeline] = true
+	}
+	pipelines := keys(names)
+	sort.Strings(pipelines)
+	return pipelines
+}
+
+// CheckPipelines returns an error if the metrics of a named pipeline are not
+// written to any output.
+func (c *Config) CheckPipelines() error {
+	outputs := make(map[string]bool)
+	for _, output := range c.Outputs {
+		outputs[output.Config.Pipeline] = true
+	}
+	for _, input := range c.Inputs {
+		if input.Config.Pipeline != "" && !outputs[input.Config.Pipeline] {
+			return fmt.Errorf("pipeline %q of input %s has no outputs", input.Config.Pipeline, input.LogName())
+		}
+	}
+	return nil
+}
+
 // PluginNameCounts returns a list of sorted plugin names and their count
 func PluginNameCounts(plugins []string) []string {
 	names := make(map[string]int)
@@ -341,6 +390,12 @@ var agentConfig = `
   ## cost of higher maximum memory usage.
   metric_buffer_limit = 10000
 
+  ## Type of buffer used for unwritten metrics, either "memory" or "disk".
+  ## The disk buffer persists metrics below buffer_directory, one
+  ## subdirectory per output, so they are sent after a restart of Telegraf.
+  # buffer_strategy = "memory"
+  # buffer_directory = ""
+
   ## Collection jitter is used to jitter the collection by a random amount.
   ## Each plugin will sleep for a random time within jitter before collecting.
   ## This can be used to avoid many plugins querying things like sysfs at the
@@ -919,6 +974,23 @@ func (c *Config) LoadConfigData(data []byte) error {
 					return fmt.Errorf("plugin %s.%s: line %d: configuration specified the fields %q, but they weren't used", name, pluginName, subTable.Line, keys(c.UnusedFields))
 				}
 			}
+		case "secretstores":
+			for pluginName, pluginVal := range subTable.Fields {
+				switch pluginSubTable := pluginVal.(type) {
+				case []*ast.Table:
+					for _, t := range pluginSubTable {
+						if err = c.addSecretStore(pluginName, t); err != nil {
+							return fmt.Errorf("error parsing %s, %w", pluginName, err)
+						}
+					}
+				default:
+					return fmt.Errorf("Unsupported config format: %s",
+						pluginName)
+				}
+				if len(c.UnusedFields) > 0 {
+					return fmt.Errorf("plugin %s.%s: line %d: configuration specified the fields %q, but they weren't used", name, pluginName, subTable.Line, keys(c.UnusedFields))
+				}
+			}
 		// Assume it's an input input for legacy config file support if no other
 		// identifiers are present
 		default:
@@ -1031,6 +1103,60 @@ func parseConfig(contents []byte) (*ast.Table, error) {
 	return toml.Parse(contents)
 }
 
+// fingerprint returns a hash identifying the configuration of the plugin
+// given by name and table.  The hash only depends on the keys and values of
+// the table, so reformatting or commenting the configuration file does not
+// change it.
+func fingerprint(name string, tbl *ast.Table) string {
+	h := sha256.New()
+	_, _ = io.WriteString(h, name)
+	writeTableFingerprint(h, tbl)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+func writeTableFingerprint(w io.Writer, tbl *ast.Table) {
+	keys := make([]string, 0, len(tbl.Fields))
+	for k := range tbl.Fields {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	_, _ = io.WriteString(w, "{")
+	for _, k := range keys {
+		fmt.Fprintf(w, "%q=", k)
+		switch v := tbl.Fields[k].(type) {
+		case *ast.KeyValue:
+			writeValueFingerprint(w, v.Value)
+		case *ast.Table:
+			writeTableFingerprint(w, v)
+		case []*ast.Table:
+			_, _ = io.WriteString(w, "[")
+			for _, t := range v {
+				writeTableFingerprint(w, t)
+			}
+			_, _ = io.WriteString(w, "]")
+		}
+		_, _ = io.WriteString(w, ";")
+	}
+	_, _ = io.WriteString(w, "}")
+}
+
+func writeValueFingerprint(w io.Writer, value ast.Value) {
+	switch v := value.(type) {
+	case *ast.Array:
+		_, _ = io.WriteString(w, "[")
+		for _, elem := range v.Value {
+			writeValueFingerprint(w, elem)
+			_, _ = io.WriteString(w, ",")
+		}
+		_, _ = io.WriteString(w, "]")
+	case *ast.Table:
+		writeTableFingerprint(w, v)
+	default:
+		fmt.Fprintf(w, "%q", v.Source())
+	}
+}
+
 func (c *Config) addAggregator(name string, table *ast.Table) error {
 	creator, ok := aggregators.Aggregators[name]
 	if !ok {
@@ -1060,6 +1186,44 @@ func (c *Config) addAggregator(name string, table *ast.Table) error {
 	return nil
 }
 
+func (c *Config) addSecretStore(name string, table *ast.Table) error {
+	creator, ok := secretstores.SecretStores[name]
+	if !ok {
+		return fmt.Errorf("Undefined but requested secretstore: %s", name)
+	}
+
+	var id string
+	c.getFieldString(table, "id", &id)
+	if !secretStoreIDRe.MatchString(id) {
+		return fmt.Errorf("invalid secret-store ID %q, must only contain letters, numbers or underscore", id)
+	}
+	if _, found := c.SecretStores[id]; found {
+		return fmt.Errorf("duplicate secret-store ID %q", id)
+	}
+	// The ID is handled here and not part of the plugin's options
+	delete(table.Fields, "id")
+
+	store := creator()
+	if err := c.toml.UnmarshalTable(table, store); err != nil {
+		return err
+	}
+
+	logger := models.NewLogger("secretstores", name, id)
+	models.SetLoggerOnPlugin(store, logger)
+
+	// Stores are initialized right away as secrets might be requested while
+	// initializing the other plugins.
+	if p, ok := store.(telegraf.Initializer); ok {
+		if err := p.Init(); err != nil {
+			return fmt.Errorf("could not initialize secret-store %q: %w", id, err)
+		}
+	}
+
+	c.SecretStores[id] = store
+	registerSecretStore(id, store)
+	return nil
+}
+
 func (c *Config) probeParser(table *ast.Table) bool {
 	var dataformat string
 	c.getFieldString(table, "data_format", &dataformat)
@@ -1167,9 +1331,11 @@ func (c *Config) addOutput(name string, table *ast.Table) error {
 
 	// If the output has a SetSerializer function, then this means it can write
 	// arbitrary types of output, so build the serializer and set it.
+	var serializer serializers.Serializer
 	switch t := output.(type) {
 	case serializers.SerializerOutput:
-		serializer, err := c.buildSerializer(table)
+		var err error
+		serializer, err = c.buildSerializer(table)
 		if err != nil {
 			return err
 		}
@@ -1195,7 +1361,17 @@ func (c *Config) addOutput(name string, table *ast.Table) error {
 		}
 	}
 
+	if outputConfig.BufferStrategy == "disk" {
+		for _, other := range c.Outputs {
+			if other.Config.BufferStrategy == "disk" && other.Config.BufferDirectory == outputConfig.BufferDirectory {
+				return fmt.Errorf("buffer directory %q already used by %s, set a distinct alias",
+					outputConfig.BufferDirectory, other.LogName())
+			}
+		}
+	}
+
 	ro := models.NewRunningOutput(output, outputConfig, c.Agent.MetricBatchSize, c.Agent.MetricBufferLimit)
+	ro.Serializer = serializer
 	c.Outputs = append(c.Outputs, ro)
 	return nil
 }
@@ -1368,10 +1544,11 @@ func (c *Config) addInput(name string, table *ast.Table) error {
 // models.AggregatorConfig to be inserted into models.RunningAggregator
 func (c *Config) buildAggregator(name string, tbl *ast.Table) (*models.AggregatorConfig, error) {
 	conf := &models.AggregatorConfig{
-		Name:   name,
-		Delay:  time.Millisecond * 100,
-		Period: time.Second * 30,
-		Grace:  time.Second * 0,
+		Name:        name,
+		Delay:       time.Millisecond * 100,
+		Period:      time.Second * 30,
+		Grace:       time.Second * 0,
+		Fingerprint: fingerprint(name, tbl),
 	}
 
 	c.getFieldDuration(tbl, "period", &conf.Period)
@@ -1382,6 +1559,7 @@ func (c *Config) buildAggregator(name string, tbl *ast.Table) (*models.Aggregato
 	c.getFieldString(tbl, "name_suffix", &conf.MeasurementSuffix)
 	c.getFieldString(tbl, "name_override", &conf.NameOverride)
 	c.getFieldString(tbl, "alias", &conf.Alias)
+	c.getFieldString(tbl, "pipeline", &conf.Pipeline)
 
 	conf.Tags = make(map[string]string)
 	if node, ok := tbl.Fields["tags"]; ok {
@@ -1423,10 +1601,11 @@ func (c *Config) buildParser(name string, tbl *ast.Table) (*models.ParserConfig,
 // builds the filter and returns a
 // models.ProcessorConfig to be inserted into models.RunningProcessor
 func (c *Config) buildProcessor(name string, tbl *ast.Table) (*models.ProcessorConfig, error) {
-	conf := &models.ProcessorConfig{Name: name}
+	conf := &models.ProcessorConfig{Name: name, Fingerprint: fingerprint(name, tbl)}
 
 	c.getFieldInt64(tbl, "order", &conf.Order)
 	c.getFieldString(tbl, "alias", &conf.Alias)
+	c.getFieldString(tbl, "pipeline", &conf.Pipeline)
 
 	if c.hasErrs() {
 		return nil, c.firstErr()
@@ -1477,7 +1656,7 @@ func (c *Config) buildFilter(tbl *ast.Table) (models.Filter, error) {
 // builds the filter and returns a
 // models.InputConfig to be inserted into models.RunningInput
 func (c *Config) buildInput(name string, tbl *ast.Table) (*models.InputConfig, error) {
-	cp := &models.InputConfig{Name: name}
+	cp := &models.InputConfig{Name: name, Fingerprint: fingerprint(name, tbl)}
 	c.getFieldDuration(tbl, "interval", &cp.Interval)
 	c.getFieldDuration(tbl, "precision", &cp.Precision)
 	c.getFieldDuration(tbl, "collection_jitter", &cp.CollectionJitter)
@@ -1485,6 +1664,9 @@ func (c *Config) buildInput(name string, tbl *ast.Table) (*models.InputConfig, e
 	c.getFieldString(tbl, "name_suffix", &cp.MeasurementSuffix)
 	c.getFieldString(tbl, "name_override", &cp.NameOverride)
 	c.getFieldString(tbl, "alias", &cp.Alias)
+	c.getFieldString(tbl, "pipeline", &cp.Pipeline)
+	c.getFieldInt(tbl, "metric_rate_limit", &cp.MetricRateLimit)
+	c.getFieldInt(tbl, "byte_rate_limit", &cp.ByteRateLimit)
 
 	cp.Tags = make(map[string]string)
 	if node, ok := tbl.Fields["tags"]; ok {
@@ -1499,6 +1681,10 @@ func (c *Config) buildInput(name string, tbl *ast.Table) (*models.InputConfig, e
 		return nil, c.firstErr()
 	}
 
+	if cp.MetricRateLimit < 0 || cp.ByteRateLimit < 0 {
+		return nil, fmt.Errorf("rate limits of input %s must not be negative", name)
+	}
+
 	var err error
 	cp.Filter, err = c.buildFilter(tbl)
 	if err != nil {
@@ -1741,6 +1927,28 @@ func (c *Config) buildSerializer(tbl *ast.Table) (serializers.Serializer, error)
 	c.getFieldBool(tbl, "prometheus_sort_metrics", &sc.PrometheusSortMetrics)
 	c.getFieldBool(tbl, "prometheus_string_as_label", &sc.PrometheusStringAsLabel)
 
+	c.getFieldString(tbl, "avro_schema", &sc.AvroSchema)
+	c.getFieldString(tbl, "avro_schema_registry", &sc.AvroSchemaRegistry)
+	c.getFieldInt(tbl, "avro_schema_id", &sc.AvroSchemaID)
+	c.getFieldString(tbl, "avro_measurement_field", &sc.AvroMeasurementField)
+	c.getFieldString(tbl, "avro_timestamp", &sc.AvroTimestamp)
+	c.getFieldString(tbl, "avro_timestamp_format", &sc.AvroTimestampFormat)
+
+	c.getFieldStringSlice(tbl, "protobuf_files", &sc.ProtobufFiles)
+	c.getFieldStringSlice(tbl, "protobuf_import_paths", &sc.ProtobufImportPaths)
+	c.getFieldString(tbl, "protobuf_descriptor_set", &sc.ProtobufDescriptorSet)
+	c.getFieldString(tbl, "protobuf_message_type", &sc.ProtobufMessageType)
+	c.getFieldString(tbl, "protobuf_measurement_field", &sc.ProtobufMeasurementField)
+	c.getFieldString(tbl, "protobuf_timestamp", &sc.ProtobufTimestamp)
+	c.getFieldString(tbl, "protobuf_timestamp_format", &sc.ProtobufTimestampFormat)
+	c.getFieldBool(tbl, "protobuf_length_delimited", &sc.ProtobufLengthDelimited)
+
+	c.getFieldStringSlice(tbl, "csv_columns", &sc.CSVColumns)
+	c.getFieldString(tbl, "csv_header", &sc.CSVHeader)
+	c.getFieldString(tbl, "csv_separator", &sc.CSVSeparator)
+	c.getFieldString(tbl, "csv_timestamp_format", &sc.CSVTimestampFormat)
+	c.getFieldBool(tbl, "csv_wide", &sc.CSVWide)
+
 	if c.hasErrs() {
 		return nil, c.firstErr()
 	}
@@ -1758,8 +1966,9 @@ func (c *Config) buildOutput(name string, tbl *ast.Table) (*models.OutputConfig,
 		return nil, err
 	}
 	oc := &models.OutputConfig{
-		Name:   name,
-		Filter: filter,
+		Name:        name,
+		Filter:      filter,
+		Fingerprint: fingerprint(name, tbl),
 	}
 
 	// TODO: support FieldPass/FieldDrop on outputs
@@ -1773,19 +1982,67 @@ func (c *Config) buildOutput(name string, tbl *ast.Table) (*models.OutputConfig,
 	c.getFieldString(tbl, "name_override", &oc.NameOverride)
 	c.getFieldString(tbl, "name_suffix", &oc.NameSuffix)
 	c.getFieldString(tbl, "name_prefix", &oc.NamePrefix)
+	c.getFieldString(tbl, "pipeline", &oc.Pipeline)
+
+	oc.BufferStrategy = c.Agent.BufferStrategy
+	oc.BufferDirectory = c.Agent.BufferDirectory
+	c.getFieldString(tbl, "buffer_strategy", &oc.BufferStrategy)
+	c.getFieldString(tbl, "buffer_directory", &oc.BufferDirectory)
+
+	c.getFieldInt(tbl, "retry_max_attempts", &oc.RetryMaxAttempts)
+	c.getFieldDuration(tbl, "retry_initial_interval", &oc.RetryInitialInterval)
+	c.getFieldDuration(tbl, "retry_max_interval", &oc.RetryMaxInterval)
+	c.getFieldString(tbl, "dead_letter_file", &oc.DeadLetterFile)
+
+	c.getFieldInt(tbl, "metric_rate_limit", &oc.MetricRateLimit)
+	c.getFieldInt(tbl, "byte_rate_limit", &oc.ByteRateLimit)
+	c.getFieldInt(tbl, "max_parallel_writes", &oc.MaxParallelWrites)
 
 	if c.hasErrs() {
 		return nil, c.firstErr()
 	}
 
+	if oc.MetricRateLimit < 0 || oc.ByteRateLimit < 0 {
+		return nil, fmt.Errorf("rate limits of output %s must not be negative", name)
+	}
+	if oc.MaxParallelWrites < 0 {
+		return nil, fmt.Errorf("max_parallel_writes must not be negative")
+	}
+	if oc.RetryMaxAttempts < 0 {
+		return nil, fmt.Errorf("retry_max_attempts must not be negative")
+	}
+	if oc.RetryMaxInterval > 0 && oc.RetryMaxInterval < oc.RetryInitialInterval {
+		return nil, fmt.Errorf("retry_max_interval must not be less than retry_initial_interval")
+	}
+
+	switch oc.BufferStrategy {
+	case "", "memory":
+	case "disk":
+		if oc.BufferDirectory == "" {
+			return nil, fmt.Errorf("buffer_directory is required for the disk buffer strategy")
+		}
+		// Each output needs a directory of its own that is stable across
+		// restarts, so the files are kept below a subdirectory named after
+		// the plugin and its alias.
+		dir := name
+		if oc.Alias != "" {
+			dir += "-" + oc.Alias
+		}
+		oc.BufferDirectory = filepath.Join(oc.BufferDirectory, dir)
+	default:
+		return nil, fmt.Errorf("invalid buffer_strategy %q", oc.BufferStrategy)
+	}
+
 	return oc, nil
 }
 
 func (c *Config) missingTomlField(_ reflect.Type, key string) error {
 	switch key {
-	case "alias", "carbon2_format", "carbon2_sanitize_replace_char", "collectd_auth_file",
+	case "alias", "avro_measurement_field", "avro_schema", "avro_schema_id", "avro_schema_registry", "avro_timestamp",
+		"avro_timestamp_format", "buffer_directory", "buffer_strategy", "byte_rate_limit", "carbon2_format", "carbon2_sanitize_replace_char", "collectd_auth_file",
 		"collectd_parse_multivalue", "collectd_security_level", "collectd_typesdb", "collection_jitter",
-		"data_format", "data_type", "delay", "drop", "drop_original", "dropwizard_metric_registry_path",
+		"csv_columns", "csv_header", "csv_separator", "csv_timestamp_format", "csv_wide",
+		"data_format", "data_type", "dead_letter_file", "delay", "drop", "drop_original", "dropwizard_metric_registry_path",
 		"dropwizard_tag_paths", "dropwizard_tags_path", "dropwizard_time_format", "dropwizard_time_path",
 		"fielddrop", "fieldpass", "flush_interval", "flush_jitter", "form_urlencoded_tag_keys",
 		"grace", "graphite_separator", "graphite_tag_sanitize_mode", "graphite_tag_support",
@@ -1793,10 +2050,12 @@ func (c *Config) missingTomlField(_ reflect.Type, key string) error {
 		"grok_timezone", "grok_unique_timestamp", "influx_max_line_bytes", "influx_sort_fields",
 		"influx_uint_support", "interval", "json_name_key", "json_query", "json_strict",
 		"json_string_fields", "json_time_format", "json_time_key", "json_timestamp_format", "json_timestamp_units", "json_timezone", "json_v2",
-		"lvm", "metric_batch_size", "metric_buffer_limit", "name_override", "name_prefix",
-		"name_suffix", "namedrop", "namepass", "order", "pass", "period", "precision",
+		"lvm", "max_parallel_writes", "metric_batch_size", "metric_buffer_limit", "metric_rate_limit", "name_override", "name_prefix",
+		"name_suffix", "namedrop", "namepass", "order", "pass", "period", "pipeline", "precision",
 		"prefix", "prometheus_export_timestamp", "prometheus_ignore_timestamp", "prometheus_sort_metrics", "prometheus_string_as_label",
-		"separator", "splunkmetric_hec_routing", "splunkmetric_multimetric", "tag_keys",
+		"protobuf_descriptor_set", "protobuf_files", "protobuf_import_paths", "protobuf_length_delimited",
+		"protobuf_measurement_field", "protobuf_message_type", "protobuf_timestamp", "protobuf_timestamp_format",
+		"retry_initial_interval", "retry_max_attempts", "retry_max_interval", "separator", "splunkmetric_hec_routing", "splunkmetric_multimetric", "tag_keys",
 		"tagdrop", "tagexclude", "taginclude", "tagpass", "tags", "template", "templates",
 		"value_field_name", "wavefront_source_override", "wavefront_use_strict", "wavefront_disable_prefix_conversion",
 		"xml", "xpath", "xpath_json", "xpath_msgpack", "xpath_protobuf", "xpath_print_document",
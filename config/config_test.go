@@ -5,6 +5,7 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"os"
+	"path/filepath"
 	"reflect"
 	"runtime"
 	"strings"
@@ -57,9 +58,10 @@ func TestConfig_LoadSingleInputWithEnvVars(t *testing.T) {
 	}
 	inputConfig.Tags = make(map[string]string)
 
-	// Ignore Log and Parser
+	// Ignore Log, Parser and the fingerprint
 	c.Inputs[0].Input.(*MockupInputPlugin).Log = nil
 	c.Inputs[0].Input.(*MockupInputPlugin).parser = nil
+	c.Inputs[0].Config.Fingerprint = ""
 	require.Equal(t, input, c.Inputs[0].Input, "Testdata did not produce a correct mockup struct.")
 	require.Equal(t, inputConfig, c.Inputs[0].Config, "Testdata did not produce correct input metadata.")
 }
@@ -97,9 +99,10 @@ func TestConfig_LoadSingleInput(t *testing.T) {
 	}
 	inputConfig.Tags = make(map[string]string)
 
-	// Ignore Log and Parser
+	// Ignore Log, Parser and the fingerprint
 	c.Inputs[0].Input.(*MockupInputPlugin).Log = nil
 	c.Inputs[0].Input.(*MockupInputPlugin).parser = nil
+	c.Inputs[0].Config.Fingerprint = ""
 	require.Equal(t, input, c.Inputs[0].Input, "Testdata did not produce a correct memcached struct.")
 	require.Equal(t, inputConfig, c.Inputs[0].Config, "Testdata did not produce correct memcached metadata.")
 }
@@ -210,6 +213,10 @@ func TestConfig_LoadDirectory(t *testing.T) {
 			input.parser = nil
 		}
 
+		// Check the fingerprint and ignore it for comparison
+		require.NotEmpty(t, plugin.Config.Fingerprint)
+		plugin.Config.Fingerprint = ""
+
 		require.Equalf(t, expectedPlugins[i], plugin.Input, "Plugin %d: incorrect struct produced", i)
 		require.Equalf(t, expectedConfigs[i], plugin.Config, "Plugin %d: incorrect config produced", i)
 	}
@@ -291,6 +298,123 @@ func TestConfig_BadOrdering(t *testing.T) {
 	require.Equal(t, "Error loading config file ./testdata/non_slice_slice.toml: error parsing http array, line 4: cannot unmarshal TOML array into string (need slice)", err.Error())
 }
 
+func TestConfig_Fingerprint(t *testing.T) {
+	load := func(data string) *Config {
+		c := NewConfig()
+		require.NoError(t, c.LoadConfigData([]byte(data)))
+		return c
+	}
+
+	c := load(`
+[[inputs.memcached]]
+  servers = ["localhost"]
+  [inputs.memcached.tags]
+    foo = "bar"
+
+[[outputs.http]]
+  url = "http://localhost:8080"
+`)
+	reformatted := load(`
+# A comment
+[[inputs.memcached]]
+  [inputs.memcached.tags]
+    foo="bar"
+[[inputs.memcached]]
+  servers = [
+    "localhost",
+  ]
+  [inputs.memcached.tags]
+    foo = "bar"
+
+[[outputs.http]]
+  url = "http://localhost:8080"  # trailing comment
+`)
+	changed := load(`
+[[inputs.memcached]]
+  servers = ["localhost"]
+  [inputs.memcached.tags]
+    foo = "baz"
+
+[[outputs.http]]
+  url = "http://localhost:8081"
+`)
+
+	require.Len(t, c.Inputs, 1)
+	require.NotEmpty(t, c.Inputs[0].Config.Fingerprint)
+	require.Len(t, reformatted.Inputs, 2)
+	require.NotEqual(t, c.Inputs[0].Config.Fingerprint, reformatted.Inputs[0].Config.Fingerprint)
+	require.Equal(t, c.Inputs[0].Config.Fingerprint, reformatted.Inputs[1].Config.Fingerprint)
+	require.Equal(t, c.Outputs[0].Config.Fingerprint, reformatted.Outputs[0].Config.Fingerprint)
+	require.NotEqual(t, c.Inputs[0].Config.Fingerprint, changed.Inputs[0].Config.Fingerprint)
+	require.NotEqual(t, c.Outputs[0].Config.Fingerprint, changed.Outputs[0].Config.Fingerprint)
+}
+
+func TestConfig_DiskBuffer(t *testing.T) {
+	c := NewConfig()
+	require.NoError(t, c.LoadConfig("./testdata/disk_buffer.toml"))
+	require.Len(t, c.Outputs, 3)
+
+	require.Equal(t, "disk", c.Outputs[0].Config.BufferStrategy)
+	require.Equal(t, filepath.Join("/var/lib/telegraf/buffer", "http"), c.Outputs[0].Config.BufferDirectory)
+	require.Equal(t, "memory", c.Outputs[1].Config.BufferStrategy)
+	require.Equal(t, "disk", c.Outputs[2].Config.BufferStrategy)
+	require.Equal(t, filepath.Join("/tmp/buffer", "http-other"), c.Outputs[2].Config.BufferDirectory)
+}
+
+func TestConfig_DiskBufferDuplicateDirectory(t *testing.T) {
+	c := NewConfig()
+	err := c.LoadConfig("./testdata/disk_buffer_duplicate.toml")
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "already used by outputs.http")
+}
+
+func TestConfig_OutputRetry(t *testing.T) {
+	c := NewConfig()
+	require.NoError(t, c.LoadConfig("./testdata/output_retry.toml"))
+	require.Len(t, c.Outputs, 2)
+
+	require.Equal(t, 5, c.Outputs[0].Config.RetryMaxAttempts)
+	require.Equal(t, time.Second, c.Outputs[0].Config.RetryInitialInterval)
+	require.Equal(t, 30*time.Second, c.Outputs[0].Config.RetryMaxInterval)
+	require.Equal(t, "/var/lib/telegraf/dead_letter.influx", c.Outputs[0].Config.DeadLetterFile)
+
+	require.Equal(t, 0, c.Outputs[1].Config.RetryMaxAttempts)
+	require.Equal(t, time.Duration(0), c.Outputs[1].Config.RetryInitialInterval)
+	require.Empty(t, c.Outputs[1].Config.DeadLetterFile)
+}
+
+func TestConfig_OutputRetryInvalidInterval(t *testing.T) {
+	c := NewConfig()
+	err := c.LoadConfigData([]byte(`
+[[outputs.http]]
+  retry_initial_interval = "1m"
+  retry_max_interval = "10s"
+`))
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "retry_max_interval must not be less than retry_initial_interval")
+}
+
+func TestConfig_RateLimits(t *testing.T) {
+	c := NewConfig()
+	require.NoError(t, c.LoadConfigData([]byte(`
+[[inputs.memcached]]
+  metric_rate_limit = 100
+  byte_rate_limit = 10000
+
+[[outputs.http]]
+  metric_rate_limit = 1000
+  byte_rate_limit = 100000
+  max_parallel_writes = 4
+`)))
+	require.Len(t, c.Inputs, 1)
+	require.Equal(t, 100, c.Inputs[0].Config.MetricRateLimit)
+	require.Equal(t, 10000, c.Inputs[0].Config.ByteRateLimit)
+	require.Len(t, c.Outputs, 1)
+	require.Equal(t, 1000, c.Outputs[0].Config.MetricRateLimit)
+	require.Equal(t, 100000, c.Outputs[0].Config.ByteRateLimit)
+	require.Equal(t, 4, c.Outputs[0].Config.MaxParallelWrites)
+}
+
 func TestConfig_AzureMonitorNamespacePrefix(t *testing.T) {
 	// #8256 Cannot use empty string as the namespace prefix
 	c := NewConfig()
@@ -0,0 +1,380 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/influxdata/toml/ast"
+
+	"github.com/influxdata/telegraf"
+)
+
+// Severities of the lint diagnostics
+const (
+	SeverityError   = "error"
+	SeverityWarning = "warning"
+)
+
+var (
+	// lineRe extracts the line from the errors of the TOML parser.
+	lineRe = regexp.MustCompile(`^line (\d+): ?`)
+
+	// filterOptionRe extracts the option from the errors compiling filters.
+	filterOptionRe = regexp.MustCompile(`^error compiling '(\w+)'`)
+)
+
+// Diagnostic is a problem found when linting a configuration.
+type Diagnostic struct {
+	File     string `json:"file,omitempty"`
+	Line     int    `json:"line,omitempty"`
+	Severity string `json:"severity"`
+	Plugin   string `json:"plugin,omitempty"`
+	Message  string `json:"message"`
+}
+
+func (d Diagnostic) String() string {
+	var location string
+	switch {
+	case d.File != "" && d.Line > 0:
+		location = fmt.Sprintf("%s:%d: ", d.File, d.Line)
+	case d.File != "":
+		location = d.File + ": "
+	}
+	var plugin string
+	if d.Plugin != "" {
+		plugin = "[" + d.Plugin + "] "
+	}
+	return fmt.Sprintf("%s%s: %s%s", location, d.Severity, plugin, d.Message)
+}
+
+// HasErrors returns true if any of the diagnostics is an error.
+func HasErrors(diagnostics []Diagnostic) bool {
+	for _, d := range diagnostics {
+		if d.Severity == SeverityError {
+			return true
+		}
+	}
+	return false
+}
+
+// linter collects the diagnostics of the configuration files.
+type linter struct {
+	c           *Config
+	file        string
+	diagnostics []Diagnostic
+}
+
+// Lint checks the given configuration files and the *.conf files found in
+// the directories without starting any plugin.  In contrast to loading the
+// configuration, all problems are reported: syntax errors, unknown options,
+// invalid option values, deprecated plugins and options, and plugins failing
+// to initialize.  If neither files nor directories are given the default
+// configuration file is checked.
+func Lint(files, directories []string) []Diagnostic {
+	l := &linter{c: NewConfig()}
+
+	if len(files) == 0 && len(directories) == 0 {
+		path, err := getDefaultConfigPath()
+		if err != nil {
+			l.add(SeverityError, 0, "", err.Error())
+			return l.diagnostics
+		}
+		files = []string{path}
+	}
+
+	for _, dir := range directories {
+		err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+			if err != nil {
+				return err
+			}
+			if info.IsDir() {
+				if strings.HasPrefix(info.Name(), "..") {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			if strings.HasSuffix(info.Name(), ".conf") {
+				files = append(files, path)
+			}
+			return nil
+		})
+		if err != nil {
+			l.file = dir
+			l.add(SeverityError, 0, "", fmt.Sprintf("reading directory failed: %v", err))
+		}
+	}
+
+	for _, file := range files {
+		l.lintFile(file)
+	}
+
+	l.file = ""
+	if len(l.c.Inputs) == 0 {
+		l.add(SeverityError, 0, "", "no inputs found")
+	}
+	if len(l.c.Outputs) == 0 {
+		l.add(SeverityError, 0, "", "no outputs found")
+	}
+	if err := l.c.CheckPipelines(); err != nil {
+		l.add(SeverityError, 0, "", err.Error())
+	}
+	if l.c.Agent.Interval <= 0 {
+		l.add(SeverityError, 0, "", fmt.Sprintf("agent interval must be positive, found %v", l.c.Agent.Interval))
+	}
+	if l.c.Agent.FlushInterval <= 0 {
+		l.add(SeverityError, 0, "", fmt.Sprintf("agent flush_interval must be positive, found %v", l.c.Agent.FlushInterval))
+	}
+
+	return l.diagnostics
+}
+
+func (l *linter) lintFile(path string) {
+	l.file = path
+
+	// Report the problems of the file in order of their occurrence.
+	first := len(l.diagnostics)
+	defer func() {
+		diagnostics := l.diagnostics[first:]
+		sort.SliceStable(diagnostics, func(i, j int) bool {
+			return diagnostics[i].Line < diagnostics[j].Line
+		})
+	}()
+
+	data, err := loadConfig(path)
+	if err != nil {
+		l.add(SeverityError, 0, "", fmt.Sprintf("loading file failed: %v", err))
+		return
+	}
+
+	tbl, err := parseConfig(data)
+	if err != nil {
+		l.addError(0, "", err)
+		return
+	}
+
+	// Check the global sections first as the plugins depend on them.
+	for _, name := range []string{"global_tags", "tags", "agent"} {
+		val, ok := tbl.Fields[name]
+		if !ok {
+			continue
+		}
+		subTable, ok := val.(*ast.Table)
+		if !ok {
+			l.add(SeverityError, lineOf(val), "", fmt.Sprintf("invalid section %q, expected a table", name))
+			continue
+		}
+		var target interface{} = l.c.Agent
+		if name != "agent" {
+			target = l.c.Tags
+		}
+		if err := l.c.toml.UnmarshalTable(subTable, target); err != nil {
+			l.addError(subTable.Line, name, err)
+		}
+		l.addUnusedFields(subTable, name)
+	}
+
+	names := make([]string, 0, len(tbl.Fields))
+	for name := range tbl.Fields {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		val := tbl.Fields[name]
+		switch name {
+		case "agent", "global_tags", "tags":
+			continue
+		}
+
+		subTable, ok := val.(*ast.Table)
+		if !ok {
+			l.add(SeverityError, lineOf(val), "", fmt.Sprintf("invalid section %q, expected a table", name))
+			continue
+		}
+
+		switch name {
+		case "inputs", "plugins", "outputs", "processors", "aggregators", "secretstores":
+		default:
+			// Legacy configurations without section are inputs
+			l.lintPlugin("inputs", name, subTable)
+			continue
+		}
+
+		category := name
+		if category == "plugins" {
+			category = "inputs"
+		}
+		pluginNames := make([]string, 0, len(subTable.Fields))
+		for pluginName := range subTable.Fields {
+			pluginNames = append(pluginNames, pluginName)
+		}
+		sort.Strings(pluginNames)
+
+		for _, pluginName := range pluginNames {
+			switch pluginTables := subTable.Fields[pluginName].(type) {
+			case *ast.Table:
+				// Only the legacy [inputs.cpu] and [outputs.influxdb] forms
+				// are supported for single tables.
+				if category != "inputs" && category != "outputs" {
+					l.add(SeverityError, pluginTables.Line, category+"."+pluginName,
+						fmt.Sprintf("unsupported config format, use [[%s.%s]]", category, pluginName))
+					continue
+				}
+				l.lintPlugin(category, pluginName, pluginTables)
+			case []*ast.Table:
+				for _, t := range pluginTables {
+					l.lintPlugin(category, pluginName, t)
+				}
+			default:
+				l.add(SeverityError, lineOf(pluginTables), category+"."+pluginName, "unsupported config format")
+			}
+		}
+	}
+}
+
+// lintPlugin adds and initializes a single plugin.
+func (l *linter) lintPlugin(category, name string, tbl *ast.Table) {
+	c := l.c
+	plugin := category + "." + name
+
+	// Errors of previous plugins must not be reported again.
+	c.errs = nil
+	c.UnusedFields = make(map[string]bool)
+
+	var err error
+	var added interface{}
+	parsers := len(c.Parsers)
+	switch category {
+	case "inputs":
+		n := len(c.Inputs)
+		if err = c.addInput(name, tbl); err == nil && len(c.Inputs) > n {
+			added = c.Inputs[n].Input
+		}
+	case "outputs":
+		n := len(c.Outputs)
+		if err = c.addOutput(name, tbl); err == nil && len(c.Outputs) > n {
+			added = c.Outputs[n].Output
+		}
+	case "processors":
+		n := len(c.Processors)
+		if err = c.addProcessor(name, tbl); err == nil && len(c.Processors) > n {
+			added = c.Processors[n].Processor
+		}
+	case "aggregators":
+		n := len(c.Aggregators)
+		if err = c.addAggregator(name, tbl); err == nil && len(c.Aggregators) > n {
+			added = c.Aggregators[n].Aggregator
+		}
+	case "secretstores":
+		// Secret-stores are initialized when added.
+		err = c.addSecretStore(name, tbl)
+	}
+	if err != nil {
+		line := tbl.Line
+		if m := filterOptionRe.FindStringSubmatch(err.Error()); m != nil {
+			line = fieldLine(tbl, m[1])
+		}
+		l.addError(line, plugin, err)
+	}
+	l.addUnusedFields(tbl, plugin)
+
+	if added == nil {
+		return
+	}
+
+	// Report the deprecations not causing an error as warnings.
+	options := added
+	if p, ok := added.(unwrappable); ok {
+		options = p.Unwrap()
+	}
+	info := c.collectDeprecationInfo(category, name, options, false)
+	if info.LogLevel == Warn {
+		l.add(SeverityWarning, tbl.Line, plugin, deprecationMessage("plugin", info.info))
+	}
+	for _, option := range info.Options {
+		if option.LogLevel == Warn {
+			l.add(SeverityWarning, fieldLine(tbl, option.Name), plugin,
+				deprecationMessage(fmt.Sprintf("option %q", option.Name), option.info))
+		}
+	}
+
+	for _, parser := range c.Parsers[parsers:] {
+		if err := parser.Init(); err != nil {
+			l.add(SeverityError, tbl.Line, plugin, fmt.Sprintf("initializing parser failed: %v", err))
+		}
+	}
+	if p, ok := added.(telegraf.Initializer); ok {
+		if err := p.Init(); err != nil {
+			l.add(SeverityError, tbl.Line, plugin, fmt.Sprintf("initializing plugin failed: %v", err))
+		}
+	}
+}
+
+func deprecationMessage(what string, info telegraf.DeprecationInfo) string {
+	msg := fmt.Sprintf("%s deprecated since version %s and will be removed in %s", what, info.Since, info.RemovalIn)
+	if info.Notice != "" {
+		msg += ": " + info.Notice
+	}
+	return msg
+}
+
+// addUnusedFields reports the unknown options of the table.
+func (l *linter) addUnusedFields(tbl *ast.Table, plugin string) {
+	for _, key := range keys(l.c.UnusedFields) {
+		l.add(SeverityError, fieldLine(tbl, key), plugin, fmt.Sprintf("unknown option %q", key))
+	}
+	l.c.UnusedFields = make(map[string]bool)
+}
+
+// addError reports an error, errors of the TOML parser are reported at the
+// line given in the error.
+func (l *linter) addError(line int, plugin string, err error) {
+	msg := err.Error()
+	if m := lineRe.FindStringSubmatch(msg); m != nil {
+		if n, err := strconv.Atoi(m[1]); err == nil {
+			line = n
+			msg = msg[len(m[0]):]
+		}
+	}
+	l.add(SeverityError, line, plugin, msg)
+}
+
+func (l *linter) add(severity string, line int, plugin, msg string) {
+	l.diagnostics = append(l.diagnostics, Diagnostic{
+		File:     l.file,
+		Line:     line,
+		Severity: severity,
+		Plugin:   plugin,
+		Message:  msg,
+	})
+}
+
+// fieldLine returns the line of the field in the table, or of the table if
+// the field is not found.
+func fieldLine(tbl *ast.Table, name string) int {
+	if val, ok := tbl.Fields[name]; ok {
+		if line := lineOf(val); line > 0 {
+			return line
+		}
+	}
+	return tbl.Line
+}
+
+func lineOf(val interface{}) int {
+	switch v := val.(type) {
+	case *ast.KeyValue:
+		return v.Line
+	case *ast.Table:
+		return v.Line
+	case []*ast.Table:
+		if len(v) > 0 {
+			return v[0].Line
+		}
+	}
+	return 0
+}
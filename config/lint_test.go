@@ -0,0 +1,114 @@
+package config
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/plugins/inputs"
+)
+
+func TestLintValid(t *testing.T) {
+	diagnostics := Lint([]string{"./testdata/lint/valid.conf"}, nil)
+	require.Empty(t, diagnostics)
+	require.False(t, HasErrors(diagnostics))
+}
+
+func TestLintReportsAllProblems(t *testing.T) {
+	file := "./testdata/lint/invalid.conf"
+	diagnostics := Lint([]string{file}, nil)
+
+	// The messages of errors from other packages are only checked partially.
+	for i := range diagnostics {
+		switch {
+		case diagnostics[i].Line == 16:
+			require.Contains(t, diagnostics[i].Message, "error compiling 'namepass'")
+			diagnostics[i].Message = ""
+		case diagnostics[i].Line == 10:
+			require.Contains(t, diagnostics[i].Message, "cannot unmarshal TOML string into []string")
+			diagnostics[i].Message = ""
+		}
+	}
+
+	expected := []Diagnostic{
+		{File: file, Line: 3, Severity: SeverityError, Plugin: "agent", Message: `unknown option "unknown_agent_option"`},
+		{File: file, Line: 7, Severity: SeverityError, Plugin: "inputs.lint_test", Message: `unknown option "unknown_option"`},
+		{File: file, Line: 10, Severity: SeverityError, Plugin: "inputs.lint_test"},
+		{File: file, Line: 12, Severity: SeverityError, Plugin: "inputs.lint_test", Message: "initializing plugin failed: failed on purpose"},
+		{File: file, Line: 16, Severity: SeverityError, Plugin: "inputs.lint_test"},
+		{
+			File:     file,
+			Line:     19,
+			Severity: SeverityWarning,
+			Plugin:   "inputs.lint_test",
+			Message:  `option "old_option" deprecated since version 0.0.0 and will be removed in 1.0.0: use 'servers' instead`,
+		},
+		{File: file, Line: 21, Severity: SeverityError, Plugin: "inputs.does_not_exist", Message: "Undefined but requested input: does_not_exist"},
+	}
+	require.Equal(t, expected, diagnostics)
+	require.True(t, HasErrors(diagnostics))
+}
+
+func TestLintSyntaxError(t *testing.T) {
+	file := "./testdata/lint/syntax.conf"
+	diagnostics := Lint([]string{file}, nil)
+
+	require.Equal(t, []Diagnostic{
+		{File: file, Line: 5, Severity: SeverityError, Message: "invalid TOML syntax"},
+		{Severity: SeverityError, Message: "no inputs found"},
+		{Severity: SeverityError, Message: "no outputs found"},
+	}, diagnostics)
+}
+
+func TestLintDirectory(t *testing.T) {
+	diagnostics := Lint(nil, []string{"./testdata/lint"})
+
+	files := make(map[string]bool)
+	for _, d := range diagnostics {
+		files[d.File] = true
+	}
+	require.Equal(t, map[string]bool{
+		"testdata/lint/invalid.conf": true,
+		"testdata/lint/syntax.conf":  true,
+	}, files)
+}
+
+func TestDiagnosticString(t *testing.T) {
+	d := Diagnostic{File: "telegraf.conf", Line: 3, Severity: SeverityError, Plugin: "inputs.cpu", Message: "failed"}
+	require.Equal(t, "telegraf.conf:3: error: [inputs.cpu] failed", d.String())
+
+	d = Diagnostic{Severity: SeverityWarning, Message: "failed"}
+	require.Equal(t, "warning: failed", d.String())
+}
+
+/*** Mockup input plugin for linting ***/
+type MockupLintPlugin struct {
+	Servers   []string `toml:"servers"`
+	Fail      bool     `toml:"fail"`
+	OldOption string   `toml:"old_option" deprecated:"0.0.0;use 'servers' instead"`
+}
+
+func (m *MockupLintPlugin) Init() error {
+	if m.Fail {
+		return errors.New("failed on purpose")
+	}
+	return nil
+}
+
+func (m *MockupLintPlugin) SampleConfig() string {
+	return "Mockup lint plugin"
+}
+
+func (m *MockupLintPlugin) Description() string {
+	return "Mockup lint plugin"
+}
+
+func (m *MockupLintPlugin) Gather(_ telegraf.Accumulator) error {
+	return nil
+}
+
+func init() {
+	inputs.Add("lint_test", func() telegraf.Input { return &MockupLintPlugin{} })
+}
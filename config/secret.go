@@ -0,0 +1,124 @@
+package config
+
+import (
+	"fmt"
+	"regexp"
+	"sync"
+
+	"github.com/influxdata/telegraf"
+)
+
+var (
+	// secretRefRe matches references of the form @{store:key} to a secret in
+	// a secret-store.
+	secretRefRe = regexp.MustCompile(`@\{(\w+):([^}]+)\}`)
+
+	// secretStoreIDRe restricts the IDs usable in references.
+	secretStoreIDRe = regexp.MustCompile(`^\w+$`)
+
+	secretStoresMu sync.RWMutex
+	secretStores   = make(map[string]telegraf.SecretStore)
+)
+
+// Secret is a configuration value that may contain references of the form
+// @{store:key} to secrets held in a secret-store.  The references are
+// resolved each time the secret is requested, so the actual secret is only
+// kept in memory while it is in use.
+type Secret struct {
+	value string
+}
+
+// NewSecret returns a secret with the given value, the value may contain
+// references to secret-stores.
+func NewSecret(value string) Secret {
+	return Secret{value: value}
+}
+
+// UnmarshalText stores the value from the TOML config file.
+func (s *Secret) UnmarshalText(b []byte) error {
+	s.value = string(b)
+	return nil
+}
+
+// String hides the secret when printing the configuration.
+func (s Secret) String() string {
+	if s.value == "" {
+		return ""
+	}
+	return "<secret>"
+}
+
+// Empty returns true if no secret was configured.
+func (s Secret) Empty() bool {
+	return s.value == ""
+}
+
+// Get returns the secret with all references resolved.  The returned buffer
+// should be released using ReleaseSecret as soon as it is no longer needed.
+func (s Secret) Get() ([]byte, error) {
+	matches := secretRefRe.FindAllStringSubmatchIndex(s.value, -1)
+	if len(matches) == 0 {
+		return []byte(s.value), nil
+	}
+
+	// Resolve all references first to allocate the result only once and
+	// avoid leaving copies of the secrets behind when growing the buffer.
+	resolved := make([][]byte, 0, len(matches))
+	defer func() {
+		for _, r := range resolved {
+			ReleaseSecret(r)
+		}
+	}()
+
+	size := len(s.value)
+	for _, match := range matches {
+		id, key := s.value[match[2]:match[3]], s.value[match[4]:match[5]]
+		store, err := lookupSecretStore(id)
+		if err != nil {
+			return nil, err
+		}
+
+		secret, err := store.Get(key)
+		if err != nil {
+			return nil, fmt.Errorf("getting secret %q from store %q: %w", key, id, err)
+		}
+		resolved = append(resolved, secret)
+		size += len(secret) - (match[1] - match[0])
+	}
+
+	out := make([]byte, 0, size)
+	last := 0
+	for i, match := range matches {
+		out = append(out, s.value[last:match[0]]...)
+		out = append(out, resolved[i]...)
+		last = match[1]
+	}
+	out = append(out, s.value[last:]...)
+
+	return out, nil
+}
+
+// ReleaseSecret overwrites the secret returned by Secret.Get.
+func ReleaseSecret(secret []byte) {
+	for i := range secret {
+		secret[i] = 0
+	}
+}
+
+func registerSecretStore(id string, store telegraf.SecretStore) {
+	secretStoresMu.Lock()
+	defer secretStoresMu.Unlock()
+
+	secretStores[id] = store
+}
+
+func lookupSecretStore(id string) (telegraf.SecretStore, error) {
+	secretStoresMu.RLock()
+	defer secretStoresMu.RUnlock()
+
+	store, found := secretStores[id]
+	if !found {
+		return nil, fmt.Errorf("unknown secret-store %q", id)
+	}
+	return store, nil
+}
@@ -0,0 +1,167 @@
+package config
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/plugins/inputs"
+	"github.com/influxdata/telegraf/plugins/secretstores"
+)
+
+func TestSecretPlain(t *testing.T) {
+	s := NewSecret("no references here")
+	require.False(t, s.Empty())
+	require.Equal(t, "<secret>", s.String())
+
+	secret, err := s.Get()
+	require.NoError(t, err)
+	require.Equal(t, "no references here", string(secret))
+
+	ReleaseSecret(secret)
+	require.Equal(t, make([]byte, len(secret)), secret)
+	require.True(t, NewSecret("").Empty())
+	require.Empty(t, NewSecret("").String())
+}
+
+func TestSecretStoreReferences(t *testing.T) {
+	cfg := []byte(`
+[[secretstores.mockup]]
+  id = "mock"
+  secrets = {user = "admin", password = "p@ss{}"}
+
+[[inputs.secret_test]]
+  secret = "@{mock:user}:@{mock:password}@tcp(localhost)/"
+`)
+	c := NewConfig()
+	require.NoError(t, c.LoadConfigData(cfg))
+	require.Len(t, c.SecretStores, 1)
+	require.Contains(t, c.SecretStores, "mock")
+	require.True(t, c.SecretStores["mock"].(*MockupSecretStore).initialized)
+
+	require.Len(t, c.Inputs, 1)
+	plugin := c.Inputs[0].Input.(*MockupSecretPlugin)
+	secret, err := plugin.Secret.Get()
+	require.NoError(t, err)
+	require.Equal(t, "admin:p@ss{}@tcp(localhost)/", string(secret))
+	ReleaseSecret(secret)
+
+	// The secret must not be exposed when printing the plugin
+	require.NotContains(t, fmt.Sprintf("%v", plugin.Secret), "admin")
+}
+
+func TestSecretStoreUnknownReferences(t *testing.T) {
+	cfg := []byte(`
+[[secretstores.mockup]]
+  id = "known"
+  secrets = {user = "admin"}
+
+[[inputs.secret_test]]
+  secret = "@{known:password}"
+`)
+	c := NewConfig()
+	require.NoError(t, c.LoadConfigData(cfg))
+
+	plugin := c.Inputs[0].Input.(*MockupSecretPlugin)
+	_, err := plugin.Secret.Get()
+	require.EqualError(t, err, `getting secret "password" from store "known": not found`)
+
+	_, err = NewSecret("@{unknown:user}").Get()
+	require.EqualError(t, err, `unknown secret-store "unknown"`)
+}
+
+func TestSecretStoreInvalidID(t *testing.T) {
+	tests := []struct {
+		name     string
+		cfg      string
+		expected string
+	}{
+		{
+			name:     "missing",
+			cfg:      "[[secretstores.mockup]]\n",
+			expected: `invalid secret-store ID ""`,
+		},
+		{
+			name:     "invalid characters",
+			cfg:      "[[secretstores.mockup]]\n  id = \"my-store\"\n",
+			expected: `invalid secret-store ID "my-store"`,
+		},
+		{
+			name:     "duplicate",
+			cfg:      "[[secretstores.mockup]]\n  id = \"dup\"\n[[secretstores.mockup]]\n  id = \"dup\"\n",
+			expected: `duplicate secret-store ID "dup"`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := NewConfig()
+			err := c.LoadConfigData([]byte(tt.cfg))
+			require.Error(t, err)
+			require.Contains(t, err.Error(), tt.expected)
+		})
+	}
+}
+
+/*** Mockup secret-store and plugin for testing ***/
+type MockupSecretStore struct {
+	Secrets map[string]string `toml:"secrets"`
+
+	initialized bool
+}
+
+func (s *MockupSecretStore) Init() error {
+	s.initialized = true
+	return nil
+}
+
+func (s *MockupSecretStore) SampleConfig() string {
+	return "Mockup test secret-store"
+}
+
+func (s *MockupSecretStore) Description() string {
+	return "Mockup test secret-store"
+}
+
+func (s *MockupSecretStore) Get(key string) ([]byte, error) {
+	v, found := s.Secrets[key]
+	if !found {
+		return nil, fmt.Errorf("not found")
+	}
+	return []byte(v), nil
+}
+
+func (s *MockupSecretStore) Set(key, value string) error {
+	s.Secrets[key] = value
+	return nil
+}
+
+func (s *MockupSecretStore) List() ([]string, error) {
+	keys := make([]string, 0, len(s.Secrets))
+	for k := range s.Secrets {
+		keys = append(keys, k)
+	}
+	return keys, nil
+}
+
+type MockupSecretPlugin struct {
+	Secret Secret `toml:"secret"`
+}
+
+func (m *MockupSecretPlugin) SampleConfig() string {
+	return "Mockup test secret plugin"
+}
+
+func (m *MockupSecretPlugin) Description() string {
+	return "Mockup test secret plugin"
+}
+
+func (m *MockupSecretPlugin) Gather(_ telegraf.Accumulator) error {
+	return nil
+}
+
+func init() {
+	secretstores.Add("mockup", func() telegraf.SecretStore { return &MockupSecretStore{} })
+	inputs.Add("secret_test", func() telegraf.Input { return &MockupSecretPlugin{} })
+}
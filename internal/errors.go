@@ -0,0 +1,20 @@
+package internal
+
+// NonRetryableError wraps an error returned by an output's Write function to
+// mark the metrics as rejected permanently, writing them again cannot succeed.
+type NonRetryableError struct {
+	Err error
+}
+
+func (e *NonRetryableError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *NonRetryableError) Unwrap() error {
+	return e.Err
+}
+
+// Retryable implements telegraf.RetryableError.
+func (e *NonRetryableError) Retryable() bool {
+	return false
+}
@@ -14,6 +14,25 @@ type BasicAuthErrorFunc func(rw http.ResponseWriter)
 func AuthHandler(username, password, realm string, onError BasicAuthErrorFunc) func(h http.Handler) http.Handler {
 	return func(h http.Handler) http.Handler {
 		return &basicAuthHandler{
+			enabled:  username != "" || password != "",
+			username: username,
+			password: func() ([]byte, error) { return []byte(password), nil },
+			realm:    realm,
+			onError:  onError,
+			next:     h,
+		}
+	}
+}
+
+// SecretAuthHandler returns a http handler that requires HTTP basic auth
+// credentials to match the given username and the password returned by the
+// password function.  The password is requested for every request and the
+// returned buffer is overwritten afterwards.  Authentication is disabled if
+// both username and password are empty.
+func SecretAuthHandler(username string, password func() ([]byte, error), enabled bool, realm string, onError BasicAuthErrorFunc) func(h http.Handler) http.Handler {
+	return func(h http.Handler) http.Handler {
+		return &basicAuthHandler{
+			enabled:  enabled,
 			username: username,
 			password: password,
 			realm:    realm,
@@ -24,19 +43,31 @@ func AuthHandler(username, password, realm string, onError BasicAuthErrorFunc) f
 }
 
 type basicAuthHandler struct {
+	enabled  bool
 	username string
-	password string
+	password func() ([]byte, error)
 	realm    string
 	onError  BasicAuthErrorFunc
 	next     http.Handler
 }
 
 func (h *basicAuthHandler) ServeHTTP(rw http.ResponseWriter, req *http.Request) {
-	if h.username != "" || h.password != "" {
+	if h.enabled {
+		password, err := h.password()
+		if err != nil {
+			http.Error(rw, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+			return
+		}
+		defer func() {
+			for i := range password {
+				password[i] = 0
+			}
+		}()
+
 		reqUsername, reqPassword, ok := req.BasicAuth()
 		if !ok ||
 			subtle.ConstantTimeCompare([]byte(reqUsername), []byte(h.username)) != 1 ||
-			subtle.ConstantTimeCompare([]byte(reqPassword), []byte(h.password)) != 1 {
+			subtle.ConstantTimeCompare([]byte(reqPassword), password) != 1 {
 			rw.Header().Set("WWW-Authenticate", "Basic realm=\""+h.realm+"\"")
 			h.onError(rw)
 			http.Error(rw, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
@@ -0,0 +1,79 @@
+package limiter
+
+import (
+	"sync"
+	"time"
+)
+
+// TokenBucket limits the rate of events, e.g. metrics or bytes, to rate
+// events per second.  Unused tokens accumulate up to the burst size.
+type TokenBucket struct {
+	sync.Mutex
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+
+	now func() time.Time
+}
+
+// NewTokenBucket returns a full bucket allowing rate events per second and
+// bursts of up to burst events.
+func NewTokenBucket(rate, burst float64) *TokenBucket {
+	return &TokenBucket{
+		rate:   rate,
+		burst:  burst,
+		tokens: burst,
+		last:   time.Now(),
+		now:    time.Now,
+	}
+}
+
+// refill adds the tokens accumulated since the last call.
+func (b *TokenBucket) refill() {
+	now := b.now()
+	b.tokens += now.Sub(b.last).Seconds() * b.rate
+	if b.tokens > b.burst {
+		b.tokens = b.burst
+	}
+	b.last = now
+}
+
+// Allow takes n tokens and returns true if they are available, otherwise no
+// tokens are taken and false is returned.
+func (b *TokenBucket) Allow(n float64) bool {
+	b.Lock()
+	defer b.Unlock()
+
+	b.refill()
+	if b.tokens < n {
+		return false
+	}
+	b.tokens -= n
+	return true
+}
+
+// Reserve takes n tokens, even if they are not available yet, and returns
+// the time to wait until the tokens are available.  Reserving more tokens
+// than the burst size is allowed.
+func (b *TokenBucket) Reserve(n float64) time.Duration {
+	b.Lock()
+	defer b.Unlock()
+
+	b.refill()
+	b.tokens -= n
+	if b.tokens >= 0 {
+		return 0
+	}
+	return time.Duration(-b.tokens / b.rate * float64(time.Second))
+}
+
+// Tokens returns the number of tokens currently available, a negative number
+// if more tokens are reserved than available.
+func (b *TokenBucket) Tokens() float64 {
+	b.Lock()
+	defer b.Unlock()
+
+	b.refill()
+	return b.tokens
+}
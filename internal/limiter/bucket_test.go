@@ -0,0 +1,56 @@
+package limiter
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func newTestBucket(rate, burst float64) (*TokenBucket, *time.Time) {
+	now := time.Unix(0, 0)
+	b := NewTokenBucket(rate, burst)
+	b.last = now
+	b.now = func() time.Time { return now }
+	return b, &now
+}
+
+func TestTokenBucket_Allow(t *testing.T) {
+	b, now := newTestBucket(10, 10)
+
+	for i := 0; i < 10; i++ {
+		require.True(t, b.Allow(1))
+	}
+	require.False(t, b.Allow(1))
+
+	*now = now.Add(100 * time.Millisecond)
+	require.True(t, b.Allow(1))
+	require.False(t, b.Allow(1))
+
+	// Tokens do not accumulate beyond the burst size.
+	*now = now.Add(time.Hour)
+	require.False(t, b.Allow(11))
+	require.True(t, b.Allow(10))
+}
+
+func TestTokenBucket_Reserve(t *testing.T) {
+	b, now := newTestBucket(100, 100)
+
+	require.Equal(t, time.Duration(0), b.Reserve(100))
+	require.Equal(t, 500*time.Millisecond, b.Reserve(50))
+	require.Equal(t, 2500*time.Millisecond, b.Reserve(200))
+
+	*now = now.Add(2500 * time.Millisecond)
+	require.Equal(t, float64(0), b.Tokens())
+}
+
+func TestRateLimiter(t *testing.T) {
+	r := NewRateLimiter(5, time.Second)
+	defer r.Stop()
+
+	start := time.Now()
+	for i := 0; i < 6; i++ {
+		<-r.C
+	}
+	require.GreaterOrEqual(t, time.Since(start), 150*time.Millisecond)
+}
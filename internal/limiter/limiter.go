@@ -10,8 +10,7 @@ import (
 func NewRateLimiter(n int, rate time.Duration) *rateLimiter {
 	r := &rateLimiter{
 		C:        make(chan bool),
-		rate:     rate,
-		n:        n,
+		bucket:   NewTokenBucket(float64(n)/rate.Seconds(), float64(n)),
 		shutdown: make(chan bool),
 	}
 	r.wg.Add(1)
@@ -20,9 +19,8 @@ func NewRateLimiter(n int, rate time.Duration) *rateLimiter {
 }
 
 type rateLimiter struct {
-	C    chan bool
-	rate time.Duration
-	n    int
+	C      chan bool
+	bucket *TokenBucket
 
 	shutdown chan bool
 	wg       sync.WaitGroup
@@ -36,24 +34,21 @@ func (r *rateLimiter) Stop() {
 
 func (r *rateLimiter) limiter() {
 	defer r.wg.Done()
-	ticker := time.NewTicker(r.rate)
-	defer ticker.Stop()
-	counter := 0
 	for {
+		if wait := r.bucket.Reserve(1); wait > 0 {
+			timer := time.NewTimer(wait)
+			select {
+			case <-r.shutdown:
+				timer.Stop()
+				return
+			case <-timer.C:
+			}
+		}
+
 		select {
+		case r.C <- true:
 		case <-r.shutdown:
 			return
-		case <-ticker.C:
-			counter = 0
-		default:
-			if counter < r.n {
-				select {
-				case r.C <- true:
-					counter++
-				case <-r.shutdown:
-					return
-				}
-			}
 		}
 	}
 }
@@ -108,6 +108,8 @@ func LoadMibsFromPath(paths []string, log telegraf.Logger) error {
 type MibEntry struct {
 	MibName string
 	OidText string
+	// OidNum is the numeric OID of the node with a leading dot
+	OidNum string
 }
 
 func TrapLookup(oid string) (e MibEntry, err error) {
@@ -127,9 +129,30 @@ func TrapLookup(oid string) (e MibEntry, err error) {
 	}
 	e.MibName = e.OidText[:i]
 	e.OidText = e.OidText[i+2:]
+	e.OidNum = "." + node.RenderNumeric()
 	return e, nil
 }
 
+// TranslateOid returns the numeric OID with a leading dot of a numeric or
+// textual OID
+func TranslateOid(oid string) (string, error) {
+	if strings.Trim(oid, ".0123456789") != "" {
+		// Names without lowercase letters would be parsed as numeric OIDs
+		if !strings.Contains(oid, "::") && !strings.ContainsAny(oid, "abcdefghijklmnopqrstuvwxyz") {
+			return "", fmt.Errorf("invalid OID %q", oid)
+		}
+		_, oidNum, _, _, _, err := SnmpTranslateCall(oid)
+		if err != nil {
+			return "", err
+		}
+		oid = oidNum
+	}
+	if !strings.HasPrefix(oid, ".") {
+		oid = "." + oid
+	}
+	return oid, nil
+}
+
 // The following is for snmp
 
 func GetIndex(oidNum string, mibPrefix string, node gosmi.SmiNode) (col []string, tagOids map[string]struct{}, err error) {
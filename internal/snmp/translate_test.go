@@ -0,0 +1,20 @@
+package snmp
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestTranslateOid(t *testing.T) {
+	oid, err := TranslateOid("1.3.6.1.6.3.1.1.5.3")
+	require.NoError(t, err)
+	require.Equal(t, ".1.3.6.1.6.3.1.1.5.3", oid)
+
+	oid, err = TranslateOid(".1.3.6.1.6.3.1.1.5.3")
+	require.NoError(t, err)
+	require.Equal(t, ".1.3.6.1.6.3.1.1.5.3", oid)
+
+	_, err = TranslateOid("MESSAGE")
+	require.EqualError(t, err, `invalid OID "MESSAGE"`)
+}
@@ -1,6 +1,7 @@
 package snmp
 
 import (
+	"errors"
 	"fmt"
 	"net/url"
 	"strconv"
@@ -10,6 +11,10 @@ import (
 	"github.com/gosnmp/gosnmp"
 )
 
+// defaultMaxRepetitions is the GETBULK max-repetitions used by gosnmp if
+// not configured
+const defaultMaxRepetitions = 50
+
 // GosnmpWrapper wraps a *gosnmp.GoSNMP object so we can use it as a snmpConnection.
 type GosnmpWrapper struct {
 	*gosnmp.GoSNMP
@@ -20,13 +25,133 @@ func (gs GosnmpWrapper) Host() string {
 	return gs.Target
 }
 
-// Walk wraps GoSNMP.Walk() or GoSNMP.BulkWalk(), depending on whether the
-// connection is using SNMPv1 or newer.
+// TimeoutError is returned if the agent did not respond to a request within
+// the timeout and retries. It implements net.Error.
+type TimeoutError struct {
+	Err error
+}
+
+func (e *TimeoutError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *TimeoutError) Unwrap() error {
+	return e.Err
+}
+
+func (*TimeoutError) Timeout() bool {
+	return true
+}
+
+func (*TimeoutError) Temporary() bool {
+	return true
+}
+
+// wrapError turns the timeouts reported by gosnmp into a TimeoutError.
+// gosnmp replaces the error of timed out requests by an error without type,
+// so the message is the only indication.
+func wrapError(err error) error {
+	if err == nil {
+		return nil
+	}
+	var timeoutErr *TimeoutError
+	if errors.As(err, &timeoutErr) {
+		return err
+	}
+	if strings.HasPrefix(err.Error(), "request timeout") {
+		return &TimeoutError{Err: err}
+	}
+	return err
+}
+
+// Get wraps GoSNMP.Get() returning a TimeoutError if the agent did not
+// respond.
+func (gs GosnmpWrapper) Get(oids []string) (*gosnmp.SnmpPacket, error) {
+	packet, err := gs.GoSNMP.Get(oids)
+	return packet, wrapError(err)
+}
+
+// Walk wraps GoSNMP.Walk() or bulkWalk(), depending on whether the
+// connection is using SNMPv1 or newer. A TimeoutError is returned if the
+// agent did not respond.
 func (gs GosnmpWrapper) Walk(oid string, fn gosnmp.WalkFunc) error {
 	if gs.Version == gosnmp.Version1 {
-		return gs.GoSNMP.Walk(oid, fn)
+		return wrapError(gs.GoSNMP.Walk(oid, fn))
 	}
-	return gs.GoSNMP.BulkWalk(oid, fn)
+	return wrapError(gs.bulkWalk(oid, fn))
+}
+
+// bulkWalk walks the subtree of the OID using GETBULK requests like
+// GoSNMP.BulkWalk(). In contrast to the latter, which silently stops the walk,
+// the max-repetitions are halved and the request is repeated if the agent
+// responds with tooBig. The reduced max-repetitions are kept for all later
+// requests of the connection.
+func (gs GosnmpWrapper) bulkWalk(rootOid string, fn gosnmp.WalkFunc) error {
+	if !strings.HasPrefix(rootOid, ".") {
+		rootOid = "." + rootOid
+	}
+	if gs.MaxRepetitions == 0 {
+		gs.MaxRepetitions = defaultMaxRepetitions
+	}
+
+	oid := rootOid
+	first := true
+	for {
+		response, err := gs.GetBulk([]string{oid}, uint8(gs.NonRepeaters), gs.MaxRepetitions)
+		if err != nil {
+			return err
+		}
+		if response.Error == gosnmp.TooBig && gs.MaxRepetitions > 1 {
+			gs.MaxRepetitions /= 2
+			continue
+		}
+		if response.Error != gosnmp.NoError || len(response.Variables) == 0 {
+			return nil
+		}
+
+		for i, pdu := range response.Variables {
+			switch pdu.Type {
+			case gosnmp.EndOfMibView, gosnmp.NoSuchObject, gosnmp.NoSuchInstance:
+				return nil
+			}
+			if !strings.HasPrefix(pdu.Name, rootOid+".") {
+				// The first variable being out of range means the OID is
+				// a leaf which has to be requested directly
+				if first && i == 0 {
+					return gs.getLeaf(rootOid, fn)
+				}
+				return nil
+			}
+			if pdu.Name == oid {
+				return fmt.Errorf("OID not increasing: %s", pdu.Name)
+			}
+			if err := fn(pdu); err != nil {
+				return err
+			}
+		}
+		oid = response.Variables[len(response.Variables)-1].Name
+		first = false
+	}
+}
+
+// getLeaf calls the walk function with the value of the OID if it exists
+func (gs GosnmpWrapper) getLeaf(oid string, fn gosnmp.WalkFunc) error {
+	response, err := gs.Get([]string{oid})
+	if err != nil {
+		return err
+	}
+	for _, pdu := range response.Variables {
+		switch pdu.Type {
+		case gosnmp.EndOfMibView, gosnmp.NoSuchObject, gosnmp.NoSuchInstance:
+			return nil
+		}
+		if pdu.Name == oid {
+			if err := fn(pdu); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
 }
 
 func NewWrapper(s ClientConfig) (GosnmpWrapper, error) {
@@ -0,0 +1,210 @@
+package snmp
+
+import (
+	"encoding/asn1"
+	"errors"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gosnmp/gosnmp"
+	"github.com/stretchr/testify/require"
+
+	"github.com/influxdata/telegraf/config"
+)
+
+// bulkAgent is an SNMP agent answering GETBULK and GET requests for a sorted
+// list of OIDs. Requests for more than maxRepetitions values are answered
+// with tooBig.
+type bulkAgent struct {
+	conn           net.PacketConn
+	oids           []string
+	maxRepetitions uint32
+
+	sync.Mutex
+	requested []uint32
+}
+
+func startBulkAgent(t *testing.T, oids []string, maxRepetitions uint32) *bulkAgent {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+
+	a := &bulkAgent{conn: conn, oids: oids, maxRepetitions: maxRepetitions}
+	go a.serve()
+	return a
+}
+
+func (a *bulkAgent) serve() {
+	decoder := &gosnmp.GoSNMP{Version: gosnmp.Version2c, Community: "public"}
+	buf := make([]byte, 65535)
+	for {
+		n, addr, err := a.conn.ReadFrom(buf)
+		if err != nil {
+			return
+		}
+		request, err := decoder.SnmpDecodePacket(buf[:n])
+		if err != nil {
+			continue
+		}
+
+		response := &gosnmp.SnmpPacket{
+			Version:   gosnmp.Version2c,
+			Community: "public",
+			PDUType:   gosnmp.GetResponse,
+			RequestID: request.RequestID,
+		}
+		oid := request.Variables[0].Name
+		switch request.PDUType {
+		case gosnmp.GetBulkRequest:
+			maxRepetitions, err := decodeMaxRepetitions(buf[:n])
+			if err != nil {
+				continue
+			}
+			a.Lock()
+			a.requested = append(a.requested, maxRepetitions)
+			a.Unlock()
+			if maxRepetitions > a.maxRepetitions {
+				response.Error = gosnmp.TooBig
+				break
+			}
+			response.Variables = a.next(oid, int(maxRepetitions))
+		case gosnmp.GetRequest:
+			response.Variables = []gosnmp.SnmpPDU{{Name: oid, Type: gosnmp.NoSuchObject}}
+			for _, o := range a.oids {
+				if o == oid {
+					response.Variables = []gosnmp.SnmpPDU{{Name: oid, Type: gosnmp.Integer, Value: 1}}
+				}
+			}
+		}
+
+		out, err := response.MarshalMsg()
+		if err != nil {
+			continue
+		}
+		if _, err := a.conn.WriteTo(out, addr); err != nil {
+			return
+		}
+	}
+}
+
+// decodeMaxRepetitions returns the max-repetitions of a GETBULK request, as
+// gosnmp fails to decode them
+func decodeMaxRepetitions(buf []byte) (uint32, error) {
+	var message struct {
+		Version   int
+		Community []byte
+		PDU       asn1.RawValue
+	}
+	if _, err := asn1.Unmarshal(buf, &message); err != nil {
+		return 0, err
+	}
+	var pdu struct {
+		RequestID      int
+		NonRepeaters   int
+		MaxRepetitions int
+		Variables      asn1.RawValue
+	}
+	if _, err := asn1.UnmarshalWithParams(message.PDU.FullBytes, &pdu, "tag:5"); err != nil {
+		return 0, err
+	}
+	return uint32(pdu.MaxRepetitions), nil
+}
+
+// next returns up to n variables following the OID
+func (a *bulkAgent) next(oid string, n int) []gosnmp.SnmpPDU {
+	var variables []gosnmp.SnmpPDU
+	for i, o := range a.oids {
+		if o <= oid {
+			continue
+		}
+		variables = append(variables, gosnmp.SnmpPDU{Name: o, Type: gosnmp.Integer, Value: i})
+		if len(variables) == n {
+			return variables
+		}
+	}
+	return append(variables, gosnmp.SnmpPDU{Name: oid, Type: gosnmp.EndOfMibView})
+}
+
+func (a *bulkAgent) requests() []uint32 {
+	a.Lock()
+	defer a.Unlock()
+	return append([]uint32(nil), a.requested...)
+}
+
+func newTestWrapper(t *testing.T, a *bulkAgent, maxRepetitions uint32) GosnmpWrapper {
+	gs, err := NewWrapper(ClientConfig{
+		Timeout:        config.Duration(time.Second),
+		Version:        2,
+		MaxRepetitions: maxRepetitions,
+	})
+	require.NoError(t, err)
+	require.NoError(t, gs.SetAgent("udp://"+a.conn.LocalAddr().String()))
+	require.NoError(t, gs.Connect())
+	t.Cleanup(func() { gs.Conn.Close() })
+	return gs
+}
+
+func walkNames(t *testing.T, gs GosnmpWrapper, oid string) []string {
+	var names []string
+	err := gs.Walk(oid, func(pdu gosnmp.SnmpPDU) error {
+		names = append(names, pdu.Name)
+		return nil
+	})
+	require.NoError(t, err)
+	return names
+}
+
+func TestBulkWalkTooBig(t *testing.T) {
+	var oids []string
+	for _, suffix := range []string{"1", "2", "3", "4", "5", "6", "7"} {
+		oids = append(oids, ".1.3.6.1.2.1.2.2.1.1."+suffix)
+	}
+	oids = append(oids, ".1.3.6.1.2.1.2.2.1.2.1")
+	a := startBulkAgent(t, oids, 3)
+
+	gs := newTestWrapper(t, a, 10)
+	require.Equal(t, oids[:7], walkNames(t, gs, ".1.3.6.1.2.1.2.2.1.1"))
+
+	// The max-repetitions are halved until the agent accepts the request
+	// and kept for later requests
+	require.Equal(t, []uint32{10, 5, 2, 2, 2, 2}, a.requests())
+	require.Equal(t, uint32(2), gs.MaxRepetitions)
+}
+
+func TestBulkWalkLeaf(t *testing.T) {
+	oids := []string{".1.3.6.1.2.1.1.3.0", ".1.3.6.1.2.1.1.5.0"}
+	a := startBulkAgent(t, oids, 10)
+
+	gs := newTestWrapper(t, a, 10)
+	require.Equal(t, []string{".1.3.6.1.2.1.1.3.0"}, walkNames(t, gs, "1.3.6.1.2.1.1.3.0"))
+	require.Empty(t, walkNames(t, gs, ".1.3.6.1.2.1.1.4.0"))
+	require.True(t, strings.HasPrefix(gs.Target, "127.0.0.1"))
+}
+
+func TestTimeoutError(t *testing.T) {
+	// The agent never responds
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer conn.Close()
+
+	gs, err := NewWrapper(ClientConfig{
+		Timeout: config.Duration(50 * time.Millisecond),
+		Version: 2,
+	})
+	require.NoError(t, err)
+	require.NoError(t, gs.SetAgent("udp://"+conn.LocalAddr().String()))
+	require.NoError(t, gs.Connect())
+	defer gs.Conn.Close()
+
+	for _, err := range []error{
+		func() error { _, err := gs.Get([]string{".1.3.6.1.2.1.1.1.0"}); return err }(),
+		gs.Walk(".1.3.6.1.2.1.1", func(gosnmp.SnmpPDU) error { return nil }),
+	} {
+		var netErr net.Error
+		require.True(t, errors.As(err, &netErr))
+		require.True(t, netErr.Timeout())
+	}
+}
@@ -12,12 +12,15 @@ Usage:
 The commands & flags are:
 
   config              print out full sample configuration to stdout
+  config lint         check the configuration and report all problems, see examples
+  secrets             list, get or set secrets of a secret-store, see examples
   version             print the version to stdout
 
   --aggregator-filter <filter>   filter the aggregators to enable, separator is :
+  --api-addr <address>           control API address to listen on, don't activate the API if empty
   --config <file>                configuration file to load
   --config-directory <directory> directory containing additional *.conf files
-  --watch-config                 Telegraf will restart on local config changes. Monitor changes
+  --watch-config                 Telegraf will reload on local config changes. Monitor changes
                                  using either fs notifications or polling.  Valid values: 'inotify' or 'poll'.
                                  Monitoring is off by default.
   --plugin-directory             directory containing *.so files, this directory will be
@@ -42,6 +45,10 @@ The commands & flags are:
                                  No outputs are executed!
   --test-wait                    wait up to this many seconds for service inputs to complete
                                  in test or once mode. Implies --test if not used with --once.
+  --test-pipeline                enable pipeline test mode: gather metrics once and print the
+                                 data each output would send. No outputs are connected!
+  --test-window                  simulate gathers over this many seconds for the aggregators
+                                 in pipeline test mode.
   --usage <plugin>               print usage for a plugin, ie, 'telegraf --usage mysql'
   --version                      display the version and exit
 
@@ -56,12 +63,24 @@ Examples:
   # run a single telegraf collection, outputting metrics to stdout
   telegraf --config telegraf.conf --test
 
+  # print the data each output would send for a minute of metrics
+  telegraf --config telegraf.conf --test-pipeline --test-window 60
+
   # run telegraf with all plugins defined in config file
   telegraf --config telegraf.conf
 
   # run telegraf, enabling the cpu & memory input, and influxdb output plugins
   telegraf --config telegraf.conf --input-filter cpu:mem --output-filter influxdb
 
+  # store a secret in the secret-store with id "vault", prompting for the value
+  telegraf --config telegraf.conf secrets set vault mysql_dsn
+
+  # check the configuration and report the problems as JSON
+  telegraf --config telegraf.conf config lint --format json
+
   # run telegraf with pprof
   telegraf --config telegraf.conf --pprof-addr localhost:6060
+
+  # run telegraf with the control API
+  telegraf --config telegraf.conf --api-addr localhost:9274
 `
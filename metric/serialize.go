@@ -0,0 +1,72 @@
+package metric
+
+import (
+	"bytes"
+	"encoding/gob"
+	"time"
+
+	"github.com/influxdata/telegraf"
+)
+
+// serializedMetric is the on-the-wire representation of a metric used by
+// ToBytes and FromBytes.
+type serializedMetric struct {
+	Name   string
+	Tags   []telegraf.Tag
+	Fields []serializedField
+	Time   time.Time
+	Type   telegraf.ValueType
+}
+
+type serializedField struct {
+	Key   string
+	Value interface{}
+}
+
+// ToBytes encodes the metric into a binary representation that preserves
+// the metric type and the exact field value types.  Tracking information is
+// not included.
+func ToBytes(m telegraf.Metric) ([]byte, error) {
+	sm := serializedMetric{
+		Name:   m.Name(),
+		Tags:   make([]telegraf.Tag, 0, len(m.TagList())),
+		Fields: make([]serializedField, 0, len(m.FieldList())),
+		Time:   m.Time(),
+		Type:   m.Type(),
+	}
+	for _, tag := range m.TagList() {
+		sm.Tags = append(sm.Tags, *tag)
+	}
+	for _, field := range m.FieldList() {
+		sm.Fields = append(sm.Fields, serializedField{Key: field.Key, Value: field.Value})
+	}
+
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(&sm); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// FromBytes decodes a metric previously encoded with ToBytes.
+func FromBytes(b []byte) (telegraf.Metric, error) {
+	var sm serializedMetric
+	if err := gob.NewDecoder(bytes.NewReader(b)).Decode(&sm); err != nil {
+		return nil, err
+	}
+
+	m := &metric{
+		name:   sm.Name,
+		tags:   make([]*telegraf.Tag, 0, len(sm.Tags)),
+		fields: make([]*telegraf.Field, 0, len(sm.Fields)),
+		tm:     sm.Time,
+		tp:     sm.Type,
+	}
+	for i := range sm.Tags {
+		m.tags = append(m.tags, &sm.Tags[i])
+	}
+	for _, field := range sm.Fields {
+		m.fields = append(m.fields, &telegraf.Field{Key: field.Key, Value: field.Value})
+	}
+	return m, nil
+}
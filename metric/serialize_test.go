@@ -0,0 +1,37 @@
+package metric
+
+import (
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSerializeRoundTrip(t *testing.T) {
+	m := New(
+		"cpu",
+		map[string]string{"host": "localhost", "cpu": "cpu0"},
+		map[string]interface{}{
+			"float":  42.0,
+			"int":    int64(-42),
+			"uint":   uint64(42),
+			"string": "value",
+			"bool":   true,
+		},
+		time.Unix(0, 1234567890),
+		telegraf.Counter,
+	)
+
+	b, err := ToBytes(m)
+	require.NoError(t, err)
+
+	actual, err := FromBytes(b)
+	require.NoError(t, err)
+
+	require.Equal(t, m.Name(), actual.Name())
+	require.Equal(t, m.Tags(), actual.Tags())
+	require.Equal(t, m.Fields(), actual.Fields())
+	require.Equal(t, telegraf.Counter, actual.Type())
+	require.True(t, m.Time().Equal(actual.Time()))
+}
@@ -0,0 +1,627 @@
+package models
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/metric"
+)
+
+const (
+	// Size at which the active segment file is closed and a new one started.
+	defaultSegmentSize = 8 * 1024 * 1024
+
+	segmentExtension = ".seg"
+	checkpointFile   = "checkpoint"
+	lockFileName     = "lock"
+
+	// Each entry is prefixed with the payload length and a CRC32 checksum.
+	entryHeaderSize = 8
+)
+
+var errChecksum = errors.New("checksum mismatch")
+
+// segment is a single append-only file of encoded metrics.
+type segment struct {
+	first   uint64 // sequence number of the first entry in the file
+	file    *os.File
+	size    int64 // current size of the file in bytes
+	pending int   // number of unacknowledged entries in this segment
+}
+
+// diskEntry locates a single unacknowledged metric on disk.
+type diskEntry struct {
+	seq     uint64
+	segment *segment
+	offset  int64
+	length  uint32
+}
+
+// DiskBuffer stores metrics in segment files on disk so they survive restarts
+// of the agent.  Metrics are appended to the active segment as they are added
+// and removed once the batch containing them is accepted.  The position of
+// the oldest unacknowledged metric is stored in a checkpoint file, on startup
+// all metrics after the checkpoint are replayed.
+//
+// The buffer directory is locked while the buffer is open, so a second
+// process, e.g. Telegraf run with --test or --once, cannot use the buffer of a
+// running agent.  Segments and the checkpoint are synced to disk before
+// metrics are reported as added or accepted.
+//
+// Tracking metrics are accepted as soon as they are persisted.
+type DiskBuffer struct {
+	sync.Mutex
+	BufferStats
+
+	path        string
+	cap         int
+	segmentSize int64
+	log         telegraf.Logger
+	lock        *os.File
+
+	segments []*segment  // segment files, oldest first; the last is active
+	entries  []diskEntry // unacknowledged entries, oldest first
+	nextSeq  uint64      // sequence number of the next entry to write
+
+	// batch maps the metrics of the current batch to their sequence number
+	batch map[telegraf.Metric]uint64
+}
+
+// NewDiskBuffer opens, or creates, the disk buffer in the given directory.
+// Any metrics left from a previous run are loaded and will be returned by
+// the next calls to Batch.
+func NewDiskBuffer(name string, alias string, capacity int, path string, log telegraf.Logger) (*DiskBuffer, error) {
+	if err := os.MkdirAll(path, 0750); err != nil {
+		return nil, fmt.Errorf("creating buffer directory: %w", err)
+	}
+
+	lock, err := os.OpenFile(filepath.Join(path, lockFileName), os.O_RDWR|os.O_CREATE, 0640)
+	if err != nil {
+		return nil, fmt.Errorf("opening buffer lock: %w", err)
+	}
+	if err := lockFile(lock); err != nil {
+		lock.Close()
+		return nil, fmt.Errorf("buffer directory %q is in use by another process: %w", path, err)
+	}
+
+	b := &DiskBuffer{
+		path:        path,
+		cap:         capacity,
+		segmentSize: defaultSegmentSize,
+		log:         log,
+		lock:        lock,
+
+		BufferStats: newBufferStats(name, alias, capacity),
+	}
+
+	if err := b.load(); err != nil {
+		b.closeSegments()
+		lock.Close()
+		return nil, err
+	}
+
+	if n := len(b.entries); n > 0 {
+		b.log.Infof("Replaying %d buffered metrics from %q", n, path)
+	}
+	b.BufferSize.Set(int64(len(b.entries)))
+	return b, nil
+}
+
+// load reads the checkpoint and all segment files in the buffer directory.
+func (b *DiskBuffer) load() error {
+	acked, err := b.readCheckpoint()
+	if err != nil {
+		return err
+	}
+	b.nextSeq = acked
+
+	files, err := filepath.Glob(filepath.Join(b.path, "*"+segmentExtension))
+	if err != nil {
+		return err
+	}
+
+	firsts := make([]uint64, 0, len(files))
+	for _, fn := range files {
+		base := strings.TrimSuffix(filepath.Base(fn), segmentExtension)
+		first, err := strconv.ParseUint(base, 10, 64)
+		if err != nil {
+			b.log.Warnf("Ignoring unexpected file %q in buffer directory", fn)
+			continue
+		}
+		firsts = append(firsts, first)
+	}
+	sort.Slice(firsts, func(i, j int) bool { return firsts[i] < firsts[j] })
+
+	for i, first := range firsts {
+		seg, err := b.openSegment(first)
+		if err != nil {
+			return err
+		}
+
+		last := i == len(firsts)-1
+		if err := b.scanSegment(seg, acked); err != nil {
+			return err
+		}
+
+		if seg.pending == 0 && !last {
+			b.removeSegment(seg)
+			continue
+		}
+		b.segments = append(b.segments, seg)
+	}
+
+	if len(b.segments) == 0 {
+		if err := b.rotate(); err != nil {
+			return err
+		}
+	}
+
+	// The capacity may have been reduced since the metrics were written.
+	if excess := len(b.entries) - b.cap; excess > 0 {
+		b.log.Warnf("Buffer holds more metrics than the limit; dropping %d oldest", excess)
+		b.dropOldest(excess)
+	}
+
+	return b.writeCheckpoint()
+}
+
+// scanSegment indexes all entries in the segment with a sequence number of
+// at least acked.  Entries failing the checksum are skipped.  A truncated
+// tail, usually caused by a crash during a write, is cut off.
+func (b *DiskBuffer) scanSegment(seg *segment, acked uint64) error {
+	info, err := seg.file.Stat()
+	if err != nil {
+		return err
+	}
+	end := info.Size()
+
+	seq := seg.first
+	var offset int64
+	for offset < end {
+		length, err := readEntryHeader(seg.file, offset, end)
+		if err == nil {
+			_, err = b.readPayload(seg.file, offset, length)
+		}
+		if errors.Is(err, errChecksum) {
+			b.log.Warnf("Skipping corrupted entry in segment %q at offset %d", seg.file.Name(), offset)
+			if seq >= acked {
+				b.MetricsDropped.Incr(1)
+				AgentMetricsDropped.Incr(1)
+			}
+			seq++
+			offset += entryHeaderSize + int64(length)
+			continue
+		}
+		if err != nil {
+			b.log.Warnf("Truncating segment %q at offset %d: %v", seg.file.Name(), offset, err)
+			if err := seg.file.Truncate(offset); err != nil {
+				return fmt.Errorf("truncating segment: %w", err)
+			}
+			break
+		}
+
+		if seq >= acked {
+			b.entries = append(b.entries, diskEntry{
+				seq:     seq,
+				segment: seg,
+				offset:  offset,
+				length:  length,
+			})
+			seg.pending++
+		}
+
+		seq++
+		offset += entryHeaderSize + int64(length)
+	}
+	seg.size = offset
+
+	if seq > b.nextSeq {
+		b.nextSeq = seq
+	}
+	return nil
+}
+
+func readEntryHeader(r io.ReaderAt, offset, end int64) (uint32, error) {
+	if offset+entryHeaderSize > end {
+		return 0, io.ErrUnexpectedEOF
+	}
+
+	var header [entryHeaderSize]byte
+	if _, err := r.ReadAt(header[:], offset); err != nil {
+		return 0, err
+	}
+
+	length := binary.BigEndian.Uint32(header[0:4])
+	if offset+entryHeaderSize+int64(length) > end {
+		return 0, io.ErrUnexpectedEOF
+	}
+	return length, nil
+}
+
+func (b *DiskBuffer) readPayload(r io.ReaderAt, offset int64, length uint32) ([]byte, error) {
+	buf := make([]byte, entryHeaderSize+int(length))
+	if _, err := r.ReadAt(buf, offset); err != nil {
+		return nil, err
+	}
+
+	payload := buf[entryHeaderSize:]
+	if crc32.ChecksumIEEE(payload) != binary.BigEndian.Uint32(buf[4:8]) {
+		return nil, errChecksum
+	}
+	return payload, nil
+}
+
+// Len returns the number of metrics currently in the buffer.
+func (b *DiskBuffer) Len() int {
+	b.Lock()
+	defer b.Unlock()
+
+	return len(b.entries)
+}
+
+// Add persists the metrics to disk and returns number of dropped metrics.
+func (b *DiskBuffer) Add(metrics ...telegraf.Metric) int {
+	b.Lock()
+	defer b.Unlock()
+
+	dropped := 0
+	added := make([]telegraf.Metric, 0, len(metrics))
+	for _, m := range metrics {
+		n, ok := b.add(m)
+		dropped += n
+		if ok {
+			added = append(added, m)
+		}
+	}
+
+	// Sync once for all metrics before accepting them
+	active := b.segments[len(b.segments)-1]
+	if err := active.file.Sync(); err != nil {
+		b.log.Errorf("Syncing segment failed: %v", err)
+	}
+	for _, m := range added {
+		b.metricAdded()
+		m.Accept()
+	}
+
+	b.BufferSize.Set(int64(len(b.entries)))
+	return dropped
+}
+
+// add writes the metric to the active segment and returns the number of
+// dropped metrics and whether the metric was written.
+func (b *DiskBuffer) add(m telegraf.Metric) (int, bool) {
+	payload, err := metric.ToBytes(m)
+	if err != nil {
+		b.log.Errorf("Encoding metric failed: %v", err)
+		b.metricDropped(m)
+		return 1, false
+	}
+
+	dropped := 0
+	if len(b.entries) == b.cap {
+		b.dropOldest(1)
+		dropped++
+	}
+
+	if err := b.write(payload); err != nil {
+		b.log.Errorf("Writing metric to buffer failed: %v", err)
+		b.metricDropped(m)
+		return dropped + 1, false
+	}
+	return dropped, true
+}
+
+// write appends an entry to the active segment.
+func (b *DiskBuffer) write(payload []byte) error {
+	seg := b.segments[len(b.segments)-1]
+
+	buf := make([]byte, entryHeaderSize+len(payload))
+	binary.BigEndian.PutUint32(buf[0:4], uint32(len(payload)))
+	binary.BigEndian.PutUint32(buf[4:8], crc32.ChecksumIEEE(payload))
+	copy(buf[entryHeaderSize:], payload)
+
+	if _, err := seg.file.WriteAt(buf, seg.size); err != nil {
+		// Discard partially written data so the next entry starts at a
+		// well-defined offset.
+		_ = seg.file.Truncate(seg.size)
+		return err
+	}
+
+	b.entries = append(b.entries, diskEntry{
+		seq:     b.nextSeq,
+		segment: seg,
+		offset:  seg.size,
+		length:  uint32(len(payload)),
+	})
+	seg.pending++
+	seg.size += int64(len(buf))
+	b.nextSeq++
+
+	if seg.size >= b.segmentSize {
+		if err := b.rotate(); err != nil {
+			b.log.Errorf("Starting new segment failed: %v", err)
+		}
+	}
+	return nil
+}
+
+// Batch returns a slice containing up to batchSize of the oldest metrics not
+// yet dropped.  Metrics are ordered from oldest to newest in the batch.  The
+// metrics are read from disk and stay in the buffer until the batch is
+// accepted.
+func (b *DiskBuffer) Batch(batchSize int) []telegraf.Metric {
+	b.Lock()
+	defer b.Unlock()
+
+	out := make([]telegraf.Metric, 0, min(len(b.entries), batchSize))
+	b.batch = make(map[telegraf.Metric]uint64, cap(out))
+	for i := 0; i < len(b.entries) && len(out) < batchSize; {
+		entry := b.entries[i]
+		m, err := b.read(entry)
+		if err != nil {
+			b.log.Errorf("Reading metric %d from buffer failed, dropping it: %v", entry.seq, err)
+			b.remove(i)
+			b.MetricsDropped.Incr(1)
+			AgentMetricsDropped.Incr(1)
+			continue
+		}
+		out = append(out, m)
+		b.batch[m] = entry.seq
+		i++
+	}
+	b.BufferSize.Set(int64(len(b.entries)))
+
+	return out
+}
+
+func (b *DiskBuffer) read(entry diskEntry) (telegraf.Metric, error) {
+	payload, err := b.readPayload(entry.segment.file, entry.offset, entry.length)
+	if err != nil {
+		return nil, err
+	}
+	return metric.FromBytes(payload)
+}
+
+// Accept marks the batch, acquired from Batch(), as successfully written and
+// removes it from disk.  The batch may be a part of the batch returned by
+// Batch(), the other metrics stay in the buffer.
+func (b *DiskBuffer) Accept(batch []telegraf.Metric) {
+	b.Lock()
+	defer b.Unlock()
+
+	for _, m := range batch {
+		b.metricWritten(m)
+	}
+
+	b.removeBatch(batch)
+	if err := b.writeCheckpoint(); err != nil {
+		b.log.Errorf("Writing buffer checkpoint failed: %v", err)
+	}
+	b.BufferSize.Set(int64(len(b.entries)))
+}
+
+// Drop removes the batch, acquired from Batch(), from the buffer and marks it
+// as dropped.  The batch may be a part of the batch returned by Batch(), the
+// other metrics stay in the buffer.
+func (b *DiskBuffer) Drop(batch []telegraf.Metric) {
+	b.Lock()
+	defer b.Unlock()
+
+	for _, m := range batch {
+		b.metricDropped(m)
+	}
+
+	b.removeBatch(batch)
+	if err := b.writeCheckpoint(); err != nil {
+		b.log.Errorf("Writing buffer checkpoint failed: %v", err)
+	}
+	b.BufferSize.Set(int64(len(b.entries)))
+}
+
+// Reject marks the batch, acquired from Batch(), as unsent.  The metrics are
+// still on disk and will be part of the next batch.
+func (b *DiskBuffer) Reject(_ []telegraf.Metric) {
+	b.Lock()
+	defer b.Unlock()
+
+	b.batch = nil
+}
+
+// Close stores the current checkpoint, closes all segment files and releases
+// the lock of the buffer directory.
+func (b *DiskBuffer) Close() error {
+	b.Lock()
+	defer b.Unlock()
+
+	err := b.writeCheckpoint()
+	b.closeSegments()
+	if b.lock != nil {
+		b.lock.Close()
+		b.lock = nil
+	}
+	return err
+}
+
+// dropOldest discards count of the oldest metrics, including metrics that
+// are part of the current batch.
+func (b *DiskBuffer) dropOldest(count int) {
+	for i := 0; i < count; i++ {
+		AgentMetricsDropped.Incr(1)
+		b.MetricsDropped.Incr(1)
+	}
+	b.removeFront(count)
+}
+
+func (b *DiskBuffer) removeFront(count int) {
+	count = min(count, len(b.entries))
+	for _, entry := range b.entries[:count] {
+		b.release(entry.segment)
+	}
+	b.entries = b.entries[count:]
+}
+
+// removeBatch removes the entries of the metrics of the current batch.  The
+// entries dropped since the batch was taken are skipped.
+func (b *DiskBuffer) removeBatch(batch []telegraf.Metric) {
+	seqs := make(map[uint64]bool, len(batch))
+	for _, m := range batch {
+		if seq, ok := b.batch[m]; ok {
+			seqs[seq] = true
+			delete(b.batch, m)
+		}
+	}
+
+	kept := b.entries[:0]
+	for i, entry := range b.entries {
+		if len(seqs) == 0 {
+			kept = append(kept, b.entries[i:]...)
+			break
+		}
+		if seqs[entry.seq] {
+			delete(seqs, entry.seq)
+			b.release(entry.segment)
+			continue
+		}
+		kept = append(kept, entry)
+	}
+	b.entries = kept
+}
+
+func (b *DiskBuffer) remove(i int) {
+	b.release(b.entries[i].segment)
+	b.entries = append(b.entries[:i], b.entries[i+1:]...)
+}
+
+// release decrements the pending count of the segment and deletes it once
+// all its entries are gone, unless it is the active segment.
+func (b *DiskBuffer) release(seg *segment) {
+	seg.pending--
+	if seg.pending > 0 || seg == b.segments[len(b.segments)-1] {
+		return
+	}
+
+	for i, s := range b.segments {
+		if s == seg {
+			b.segments = append(b.segments[:i], b.segments[i+1:]...)
+			break
+		}
+	}
+	b.removeSegment(seg)
+}
+
+// rotate syncs the active segment and starts a new one.
+func (b *DiskBuffer) rotate() error {
+	if n := len(b.segments); n > 0 {
+		if err := b.segments[n-1].file.Sync(); err != nil {
+			return fmt.Errorf("syncing segment: %w", err)
+		}
+	}
+
+	seg, err := b.openSegment(b.nextSeq)
+	if err != nil {
+		return err
+	}
+
+	if n := len(b.segments); n > 0 && b.segments[n-1].pending == 0 {
+		b.removeSegment(b.segments[n-1])
+		b.segments = b.segments[:n-1]
+	}
+	b.segments = append(b.segments, seg)
+	return nil
+}
+
+func (b *DiskBuffer) segmentPath(first uint64) string {
+	return filepath.Join(b.path, fmt.Sprintf("%020d%s", first, segmentExtension))
+}
+
+func (b *DiskBuffer) openSegment(first uint64) (*segment, error) {
+	f, err := os.OpenFile(b.segmentPath(first), os.O_RDWR|os.O_CREATE, 0640)
+	if err != nil {
+		return nil, fmt.Errorf("opening segment: %w", err)
+	}
+	return &segment{first: first, file: f}, nil
+}
+
+func (b *DiskBuffer) removeSegment(seg *segment) {
+	if err := seg.file.Close(); err != nil {
+		b.log.Errorf("Closing segment failed: %v", err)
+	}
+	if err := os.Remove(seg.file.Name()); err != nil {
+		b.log.Errorf("Removing segment failed: %v", err)
+	}
+}
+
+func (b *DiskBuffer) closeSegments() {
+	for _, seg := range b.segments {
+		if err := seg.file.Close(); err != nil {
+			b.log.Errorf("Closing segment failed: %v", err)
+		}
+	}
+	b.segments = nil
+}
+
+// readCheckpoint returns the sequence number of the oldest unacknowledged
+// metric stored by a previous run.
+func (b *DiskBuffer) readCheckpoint() (uint64, error) {
+	buf, err := os.ReadFile(filepath.Join(b.path, checkpointFile))
+	if errors.Is(err, os.ErrNotExist) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("reading checkpoint: %w", err)
+	}
+	if len(buf) != 8 {
+		return 0, fmt.Errorf("invalid checkpoint of %d bytes", len(buf))
+	}
+	return binary.BigEndian.Uint64(buf), nil
+}
+
+// writeCheckpoint atomically replaces the checkpoint file and syncs it to
+// disk.
+func (b *DiskBuffer) writeCheckpoint() error {
+	acked := b.nextSeq
+	if len(b.entries) > 0 {
+		acked = b.entries[0].seq
+	}
+
+	var buf [8]byte
+	binary.BigEndian.PutUint64(buf[:], acked)
+
+	fn := filepath.Join(b.path, checkpointFile)
+	f, err := os.OpenFile(fn+".tmp", os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0640)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(buf[:]); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(fn+".tmp", fn); err != nil {
+		return err
+	}
+
+	// Persist the rename; directories cannot be synced on all platforms
+	if dir, err := os.Open(b.path); err == nil {
+		_ = dir.Sync()
+		dir.Close()
+	}
+	return nil
+}
@@ -0,0 +1,245 @@
+package models
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/metric"
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestDiskBuffer(t *testing.T, path string, capacity int) *DiskBuffer {
+	b, err := NewDiskBuffer("test", "", capacity, path, testutil.Logger{})
+	require.NoError(t, err)
+	b.MetricsAdded.Set(0)
+	b.MetricsWritten.Set(0)
+	b.MetricsDropped.Set(0)
+	return b
+}
+
+func diskMetrics(n int) []telegraf.Metric {
+	metrics := make([]telegraf.Metric, 0, n)
+	for i := 0; i < n; i++ {
+		metrics = append(metrics, metric.New(
+			"cpu",
+			map[string]string{"host": "localhost"},
+			map[string]interface{}{"value": int64(i), "usage": 42.0},
+			time.Unix(int64(i), 0),
+		))
+	}
+	return metrics
+}
+
+func TestDiskBuffer_BatchAccept(t *testing.T) {
+	b := newTestDiskBuffer(t, t.TempDir(), 5)
+	defer b.Close()
+
+	metrics := diskMetrics(3)
+	require.Equal(t, 0, b.Add(metrics...))
+	require.Equal(t, 3, b.Len())
+
+	batch := b.Batch(2)
+	testutil.RequireMetricsEqual(t, metrics[:2], batch)
+	require.Equal(t, 3, b.Len())
+
+	b.Accept(batch)
+	require.Equal(t, 1, b.Len())
+	require.Equal(t, int64(2), b.MetricsWritten.Get())
+
+	testutil.RequireMetricsEqual(t, metrics[2:], b.Batch(2))
+}
+
+func TestDiskBuffer_RejectKeepsBatch(t *testing.T) {
+	b := newTestDiskBuffer(t, t.TempDir(), 5)
+	defer b.Close()
+
+	metrics := diskMetrics(3)
+	b.Add(metrics...)
+
+	batch := b.Batch(2)
+	b.Reject(batch)
+	require.Equal(t, 3, b.Len())
+
+	testutil.RequireMetricsEqual(t, metrics, b.Batch(5))
+}
+
+func TestDiskBuffer_DropRemovesBatch(t *testing.T) {
+	dir := t.TempDir()
+	b := newTestDiskBuffer(t, dir, 5)
+
+	metrics := diskMetrics(3)
+	b.Add(metrics...)
+
+	batch := b.Batch(2)
+	b.Drop(batch)
+	require.Equal(t, 1, b.Len())
+	require.Equal(t, int64(2), b.MetricsDropped.Get())
+	require.Equal(t, int64(0), b.MetricsWritten.Get())
+	require.NoError(t, b.Close())
+
+	b = newTestDiskBuffer(t, dir, 5)
+	defer b.Close()
+	testutil.RequireMetricsEqual(t, metrics[2:], b.Batch(5))
+}
+
+func TestDiskBuffer_AddDropsOldest(t *testing.T) {
+	b := newTestDiskBuffer(t, t.TempDir(), 3)
+	defer b.Close()
+
+	metrics := diskMetrics(5)
+	require.Equal(t, 2, b.Add(metrics...))
+	require.Equal(t, 3, b.Len())
+	require.Equal(t, int64(2), b.MetricsDropped.Get())
+
+	testutil.RequireMetricsEqual(t, metrics[2:], b.Batch(5))
+}
+
+func TestDiskBuffer_AddDropsBatch(t *testing.T) {
+	b := newTestDiskBuffer(t, t.TempDir(), 3)
+	defer b.Close()
+
+	metrics := diskMetrics(4)
+	b.Add(metrics[:3]...)
+	batch := b.Batch(2)
+
+	b.Add(metrics[3])
+	b.Accept(batch)
+	require.Equal(t, 2, b.Len())
+
+	testutil.RequireMetricsEqual(t, metrics[2:], b.Batch(5))
+}
+
+func TestDiskBuffer_ReplayAfterReopen(t *testing.T) {
+	path := t.TempDir()
+	metrics := diskMetrics(4)
+
+	b := newTestDiskBuffer(t, path, 10)
+	b.Add(metrics...)
+	b.Accept(b.Batch(1))
+	b.Batch(2) // unacknowledged batch must be replayed
+	require.NoError(t, b.Close())
+
+	b = newTestDiskBuffer(t, path, 10)
+	defer b.Close()
+	require.Equal(t, 3, b.Len())
+	testutil.RequireMetricsEqual(t, metrics[1:], b.Batch(10))
+}
+
+func TestDiskBuffer_ReplayTruncatedSegment(t *testing.T) {
+	path := t.TempDir()
+	metrics := diskMetrics(2)
+
+	b := newTestDiskBuffer(t, path, 10)
+	b.Add(metrics...)
+	seg := b.segments[0].file.Name()
+	size := b.segments[0].size
+	require.NoError(t, b.Close())
+
+	// Simulate a crash in the middle of writing the last entry
+	require.NoError(t, os.Truncate(seg, size-3))
+
+	b = newTestDiskBuffer(t, path, 10)
+	defer b.Close()
+	require.Equal(t, 1, b.Len())
+	testutil.RequireMetricsEqual(t, metrics[:1], b.Batch(10))
+
+	// New metrics continue after the truncated entry
+	b.Add(metrics[1])
+	testutil.RequireMetricsEqual(t, metrics, b.Batch(10))
+}
+
+func TestDiskBuffer_ReplayRespectsLimit(t *testing.T) {
+	path := t.TempDir()
+	metrics := diskMetrics(5)
+
+	b := newTestDiskBuffer(t, path, 10)
+	b.Add(metrics...)
+	require.NoError(t, b.Close())
+
+	b = newTestDiskBuffer(t, path, 2)
+	defer b.Close()
+	testutil.RequireMetricsEqual(t, metrics[3:], b.Batch(10))
+}
+
+func TestDiskBuffer_SegmentsRemovedWhenAccepted(t *testing.T) {
+	path := t.TempDir()
+
+	b := newTestDiskBuffer(t, path, 100)
+	defer b.Close()
+	b.segmentSize = 1
+
+	metrics := diskMetrics(5)
+	b.Add(metrics...)
+
+	files, err := filepath.Glob(filepath.Join(path, "*"+segmentExtension))
+	require.NoError(t, err)
+	require.Len(t, files, 6)
+
+	b.Accept(b.Batch(4))
+	files, err = filepath.Glob(filepath.Join(path, "*"+segmentExtension))
+	require.NoError(t, err)
+	require.Len(t, files, 2)
+
+	testutil.RequireMetricsEqual(t, metrics[4:], b.Batch(10))
+}
+
+func TestDiskBuffer_AcceptsTrackingMetricOnAdd(t *testing.T) {
+	b := newTestDiskBuffer(t, t.TempDir(), 5)
+	defer b.Close()
+
+	var accept int
+	mm := &MockMetric{
+		Metric: diskMetrics(1)[0],
+		AcceptF: func() {
+			accept++
+		},
+	}
+
+	b.Add(mm)
+	require.Equal(t, 1, accept)
+}
+
+func TestDiskBuffer_LockedDirectory(t *testing.T) {
+	path := t.TempDir()
+
+	b := newTestDiskBuffer(t, path, 5)
+	_, err := NewDiskBuffer("test", "", 5, path, testutil.Logger{})
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "is in use by another process")
+
+	// The lock is released on close
+	require.NoError(t, b.Close())
+	b = newTestDiskBuffer(t, path, 5)
+	require.NoError(t, b.Close())
+}
+
+func TestDiskBuffer_ReplaySkipsCorruptedEntry(t *testing.T) {
+	path := t.TempDir()
+	metrics := diskMetrics(3)
+
+	b := newTestDiskBuffer(t, path, 10)
+	b.Add(metrics...)
+	seg := b.segments[0].file.Name()
+	offset := b.entries[1].offset
+	require.NoError(t, b.Close())
+
+	// Flip a byte in the payload of the second entry
+	f, err := os.OpenFile(seg, os.O_RDWR, 0)
+	require.NoError(t, err)
+	buf := make([]byte, 1)
+	_, err = f.ReadAt(buf, offset+entryHeaderSize)
+	require.NoError(t, err)
+	buf[0] ^= 0xff
+	_, err = f.WriteAt(buf, offset+entryHeaderSize)
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+
+	b = newTestDiskBuffer(t, path, 10)
+	defer b.Close()
+	require.Equal(t, 2, b.Len())
+	testutil.RequireMetricsEqual(t, []telegraf.Metric{metrics[0], metrics[2]}, b.Batch(10))
+}
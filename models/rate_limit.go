@@ -0,0 +1,85 @@
+package models
+
+import (
+	"sync"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal/limiter"
+	"github.com/influxdata/telegraf/plugins/serializers/influx"
+)
+
+// rateLimit limits the number of metrics and bytes per second passing a
+// plugin.  The size of a metric is the size of its InfluxDB line protocol
+// representation.  Both limits allow bursts of one second worth of data.
+type rateLimit struct {
+	metrics *limiter.TokenBucket
+	bytes   *limiter.TokenBucket
+
+	sync.Mutex
+	serializer *influx.Serializer
+}
+
+// newRateLimit returns nil if neither limit is set.
+func newRateLimit(metricRate, byteRate int) *rateLimit {
+	if metricRate <= 0 && byteRate <= 0 {
+		return nil
+	}
+
+	l := &rateLimit{}
+	if metricRate > 0 {
+		l.metrics = limiter.NewTokenBucket(float64(metricRate), float64(metricRate))
+	}
+	if byteRate > 0 {
+		l.bytes = limiter.NewTokenBucket(float64(byteRate), float64(byteRate))
+		l.serializer = influx.NewSerializer()
+	}
+	return l
+}
+
+// size returns the total size of the metrics in bytes.
+func (l *rateLimit) size(metrics ...telegraf.Metric) float64 {
+	l.Lock()
+	defer l.Unlock()
+
+	var size int
+	for _, m := range metrics {
+		octets, err := l.serializer.Serialize(m)
+		if err != nil {
+			continue
+		}
+		size += len(octets)
+	}
+	return float64(size)
+}
+
+// allow returns true if the metric is within the limits.  A metric exceeding
+// the limits is not counted against them.
+func (l *rateLimit) allow(metric telegraf.Metric) bool {
+	if l.bytes == nil {
+		return l.metrics.Allow(1)
+	}
+
+	if l.metrics != nil && l.metrics.Tokens() < 1 {
+		return false
+	}
+	if !l.bytes.Allow(l.size(metric)) {
+		return false
+	}
+	return l.metrics == nil || l.metrics.Allow(1)
+}
+
+// reserve counts the metrics against the limits and returns the time to wait
+// before sending them to keep within the limits.
+func (l *rateLimit) reserve(metrics []telegraf.Metric) time.Duration {
+	var wait time.Duration
+	if l.metrics != nil {
+		wait = l.metrics.Reserve(float64(len(metrics)))
+	}
+	if l.bytes != nil {
+		if w := l.bytes.Reserve(l.size(metrics...)); w > wait {
+			wait = w
+		}
+	}
+	return wait
+}
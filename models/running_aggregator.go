@@ -78,6 +78,14 @@ type AggregatorConfig struct {
 	MeasurementSuffix string
 	Tags              map[string]string
 	Filter            Filter
+
+	// Pipeline is the name of the pipeline the plugin belongs to, plugins
+	// without a pipeline belong to the default pipeline.
+	Pipeline string
+
+	// Fingerprint identifies the plugin's configuration and changes whenever
+	// the plugin's TOML table changes.
+	Fingerprint string
 }
 
 func (r *RunningAggregator) LogName() string {
@@ -1,6 +1,7 @@
 package models
 
 import (
+	"sync"
 	"time"
 
 	"github.com/influxdata/telegraf"
@@ -21,6 +22,22 @@ type RunningInput struct {
 
 	MetricsGathered selfstat.Stat
 	GatherTime      selfstat.Stat
+
+	// MetricsRateLimited counts the metrics dropped for exceeding the rate
+	// limits, it is nil if the input has no limits.
+	MetricsRateLimited selfstat.Stat
+	limit              *rateLimit
+
+	statusMu sync.Mutex
+	status   GatherStatus
+}
+
+// GatherStatus describes the last gather and the last error of an input.
+type GatherStatus struct {
+	LastGather    time.Time
+	LastDuration  time.Duration
+	LastError     string
+	LastErrorTime time.Time
 }
 
 func NewRunningInput(input telegraf.Input, config *InputConfig) *RunningInput {
@@ -37,7 +54,7 @@ func NewRunningInput(input telegraf.Input, config *InputConfig) *RunningInput {
 	})
 	SetLoggerOnPlugin(input, logger)
 
-	return &RunningInput{
+	ri := &RunningInput{
 		Input:  input,
 		Config: config,
 		MetricsGathered: selfstat.Register(
@@ -50,8 +67,17 @@ func NewRunningInput(input telegraf.Input, config *InputConfig) *RunningInput {
 			"gather_time_ns",
 			tags,
 		),
-		log: logger,
+		limit: newRateLimit(config.MetricRateLimit, config.ByteRateLimit),
+		log:   logger,
 	}
+	if ri.limit != nil {
+		ri.MetricsRateLimited = selfstat.Register(
+			"gather",
+			"metrics_rate_limited",
+			tags,
+		)
+	}
+	return ri
 }
 
 // InputConfig is the common config for all inputs.
@@ -67,6 +93,20 @@ type InputConfig struct {
 	MeasurementSuffix string
 	Tags              map[string]string
 	Filter            Filter
+
+	// MetricRateLimit and ByteRateLimit are the maximum number of metrics
+	// and bytes per second accepted from the input, metrics exceeding the
+	// limits are dropped.  Zero disables the limit.
+	MetricRateLimit int
+	ByteRateLimit   int
+
+	// Pipeline is the name of the pipeline the plugin belongs to, plugins
+	// without a pipeline belong to the default pipeline.
+	Pipeline string
+
+	// Fingerprint identifies the plugin's configuration and changes whenever
+	// the plugin's TOML table changes.
+	Fingerprint string
 }
 
 func (r *RunningInput) metricFiltered(metric telegraf.Metric) {
@@ -107,6 +147,12 @@ func (r *RunningInput) MakeMetric(metric telegraf.Metric) telegraf.Metric {
 		return nil
 	}
 
+	if r.limit != nil && !r.limit.allow(m) {
+		r.MetricsRateLimited.Incr(1)
+		m.Drop()
+		return nil
+	}
+
 	r.MetricsGathered.Incr(1)
 	GlobalMetricsGathered.Incr(1)
 	return m
@@ -117,9 +163,29 @@ func (r *RunningInput) Gather(acc telegraf.Accumulator) error {
 	err := r.Input.Gather(acc)
 	elapsed := time.Since(start)
 	r.GatherTime.Incr(elapsed.Nanoseconds())
+
+	r.statusMu.Lock()
+	r.status.LastGather = start
+	r.status.LastDuration = elapsed
+	r.statusMu.Unlock()
 	return err
 }
 
+// SetLastError records an error reported by the input.
+func (r *RunningInput) SetLastError(err error) {
+	r.statusMu.Lock()
+	defer r.statusMu.Unlock()
+	r.status.LastError = err.Error()
+	r.status.LastErrorTime = time.Now()
+}
+
+// Status returns the status of the last gather.
+func (r *RunningInput) Status() GatherStatus {
+	r.statusMu.Lock()
+	defer r.statusMu.Unlock()
+	return r.status
+}
+
 func (r *RunningInput) SetDefaultTags(tags map[string]string) {
 	r.defaultTags = tags
 }
@@ -244,6 +244,45 @@ func TestMakeMetricNameSuffix(t *testing.T) {
 	require.Equal(t, expected, m)
 }
 
+func TestMakeMetricRateLimit(t *testing.T) {
+	now := time.Now()
+	ri := NewRunningInput(&testInput{}, &InputConfig{
+		Name:            "TestMetricRateLimit",
+		MetricRateLimit: 3,
+	})
+	ri.MetricsRateLimited.Set(0)
+
+	var passed int
+	for i := 0; i < 5; i++ {
+		m := metric.New("RITest",
+			map[string]string{},
+			map[string]interface{}{"value": i},
+			now)
+		if ri.MakeMetric(m) != nil {
+			passed++
+		}
+	}
+	require.Equal(t, 3, passed)
+	require.Equal(t, int64(2), ri.MetricsRateLimited.Get())
+}
+
+func TestMakeMetricByteRateLimit(t *testing.T) {
+	now := time.Unix(0, 0)
+	ri := NewRunningInput(&testInput{}, &InputConfig{
+		Name:          "TestByteRateLimit",
+		ByteRateLimit: 20,
+	})
+	ri.MetricsRateLimited.Set(0)
+
+	// "RITest value=1i 0\n" is 18 bytes, so the second metric exceeds the
+	// limit.
+	m := metric.New("RITest", map[string]string{}, map[string]interface{}{"value": 1}, now)
+	require.NotNil(t, ri.MakeMetric(m))
+	m = metric.New("RITest", map[string]string{}, map[string]interface{}{"value": 2}, now)
+	require.Nil(t, ri.MakeMetric(m))
+	require.Equal(t, int64(1), ri.MetricsRateLimited.Get())
+}
+
 func TestMetricErrorCounters(t *testing.T) {
 	ri := NewRunningInput(&testInput{}, &InputConfig{
 		Name: "TestMetricErrorCounters",
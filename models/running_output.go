@@ -1,11 +1,19 @@
 package models
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"os"
 	"sync"
 	"sync/atomic"
 	"time"
 
 	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/plugins/serializers"
+	"github.com/influxdata/telegraf/plugins/serializers/influx"
 	"github.com/influxdata/telegraf/selfstat"
 )
 
@@ -15,6 +23,9 @@ const (
 
 	// Default number of metrics kept. It should be a multiple of batch size.
 	DefaultMetricBufferLimit = 10000
+
+	// Default upper bound of the backoff between retries of a write.
+	DefaultRetryMaxInterval = time.Minute
 )
 
 // OutputConfig containing name and filter
@@ -28,9 +39,46 @@ type OutputConfig struct {
 	MetricBufferLimit int
 	MetricBatchSize   int
 
+	// BufferStrategy selects where unsent metrics are kept, either "memory"
+	// or "disk".  For the disk strategy BufferDirectory is the directory
+	// holding the buffer files of this output.
+	BufferStrategy  string
+	BufferDirectory string
+
+	// RetryMaxAttempts is the number of attempts to write a batch before the
+	// batch is dropped, zero retries forever.  Failed writes are retried with
+	// an exponential backoff between RetryInitialInterval and
+	// RetryMaxInterval, a zero initial interval retries on the next flush.
+	RetryMaxAttempts     int
+	RetryInitialInterval time.Duration
+	RetryMaxInterval     time.Duration
+
+	// MetricRateLimit and ByteRateLimit are the maximum number of metrics
+	// and bytes per second written to the output, writes exceeding the
+	// limits are delayed.  Zero disables the limit.
+	MetricRateLimit int
+	ByteRateLimit   int
+
+	// MaxParallelWrites is the maximum number of batches written to the
+	// output concurrently, values below two write the batches one by one.
+	MaxParallelWrites int
+
+	// DeadLetterFile is the file receiving the metrics, in InfluxDB line
+	// protocol, that were dropped after exhausting their retries or being
+	// rejected permanently by the output.
+	DeadLetterFile string
+
 	NameOverride string
 	NamePrefix   string
 	NameSuffix   string
+
+	// Pipeline is the name of the pipeline the plugin belongs to, plugins
+	// without a pipeline belong to the default pipeline.
+	Pipeline string
+
+	// Fingerprint identifies the plugin's configuration and changes whenever
+	// the plugin's TOML table changes.
+	Fingerprint string
 }
 
 // RunningOutput contains the output configuration
@@ -47,12 +95,34 @@ type RunningOutput struct {
 	MetricsFiltered selfstat.Stat
 	WriteTime       selfstat.Stat
 
+	// RateLimitWait is the total time writes were delayed by the rate
+	// limits, it is nil if the output has no limits.  ParallelWrites is the
+	// number of writes in progress, it is nil unless parallel writes are
+	// enabled.
+	RateLimitWait  selfstat.Stat
+	ParallelWrites selfstat.Stat
+	limit          *rateLimit
+
+	// ctx is cancelled by Interrupt to abort the waits for the rate limits.
+	ctx    context.Context
+	cancel context.CancelFunc
+
 	BatchReady chan time.Time
 
-	buffer *Buffer
+	// Serializer is the serializer of outputs supporting data formats, nil
+	// for other outputs.
+	Serializer serializers.Serializer
+
+	buffer MetricBuffer
 	log    telegraf.Logger
 
 	aggMutex sync.Mutex
+
+	// attempts is the number of failed writes of the current batch and
+	// retryAfter the time before which no write is attempted.
+	retryMutex sync.Mutex
+	attempts   int
+	retryAfter time.Time
 }
 
 func NewRunningOutput(
@@ -103,7 +173,23 @@ func NewRunningOutput(
 			"write_time_ns",
 			tags,
 		),
-		log: logger,
+		limit: newRateLimit(config.MetricRateLimit, config.ByteRateLimit),
+		log:   logger,
+	}
+	ro.ctx, ro.cancel = context.WithCancel(context.Background())
+	if ro.limit != nil {
+		ro.RateLimitWait = selfstat.Register(
+			"write",
+			"rate_limit_wait_ns",
+			tags,
+		)
+	}
+	if config.MaxParallelWrites > 1 {
+		ro.ParallelWrites = selfstat.Register(
+			"write",
+			"parallel_writes",
+			tags,
+		)
 	}
 
 	return ro
@@ -125,9 +211,40 @@ func (r *RunningOutput) Init() error {
 			return err
 		}
 	}
+
+	switch r.Config.BufferStrategy {
+	case "", "memory":
+	case "disk":
+		buffer, err := NewDiskBuffer(r.Config.Name, r.Config.Alias, r.MetricBufferLimit, r.Config.BufferDirectory, r.log)
+		if err != nil {
+			return fmt.Errorf("opening disk buffer: %w", err)
+		}
+		r.buffer = buffer
+	default:
+		return fmt.Errorf("invalid buffer strategy %q", r.Config.BufferStrategy)
+	}
 	return nil
 }
 
+// TakeOver moves the metrics still buffered by old into the buffer of r.  It
+// is used when r replaces old on a configuration reload, old must be closed
+// already.  Disk buffers need no hand over as r replays the metrics from the
+// buffer directory when initialized.
+func (r *RunningOutput) TakeOver(old *RunningOutput) {
+	buffer, ok := old.buffer.(*Buffer)
+	if !ok {
+		return
+	}
+
+	metrics := buffer.drain()
+	if len(metrics) == 0 {
+		return
+	}
+	dropped := r.buffer.Add(metrics...)
+	atomic.AddInt64(&r.droppedMetrics, int64(dropped))
+	r.log.Debugf("Took over %d buffered metrics", len(metrics))
+}
+
 // AddMetric adds a metric to the output.
 //
 // Takes ownership of metric
@@ -190,39 +307,201 @@ func (r *RunningOutput) Write() error {
 
 	// Only process the metrics in the buffer now.  Metrics added while we are
 	// writing will be sent on the next call.
+	if r.backingOff() {
+		return nil
+	}
+
+	batchSize := r.writeSize()
 	nBuffer := r.buffer.Len()
-	nBatches := nBuffer/r.MetricBatchSize + 1
+	nBatches := nBuffer/batchSize + 1
 	for i := 0; i < nBatches; i++ {
-		batch := r.buffer.Batch(r.MetricBatchSize)
+		batch := r.buffer.Batch(batchSize)
 		if len(batch) == 0 {
 			break
 		}
 
-		err := r.write(batch)
-		if err != nil {
-			r.buffer.Reject(batch)
+		if err := r.writeBatch(batch); err != nil {
 			return err
 		}
-		r.buffer.Accept(batch)
 	}
 	return nil
 }
 
 // WriteBatch writes a single batch of metrics to the output.
 func (r *RunningOutput) WriteBatch() error {
-	batch := r.buffer.Batch(r.MetricBatchSize)
+	if r.backingOff() {
+		return nil
+	}
+
+	batch := r.buffer.Batch(r.writeSize())
 	if len(batch) == 0 {
 		return nil
 	}
 
-	err := r.write(batch)
+	return r.writeBatch(batch)
+}
+
+// writeSize returns the number of metrics taken from the buffer for a single
+// write, with parallel writes enabled this covers a batch for each write.
+func (r *RunningOutput) writeSize() int {
+	if r.Config.MaxParallelWrites > 1 {
+		return r.MetricBatchSize * r.Config.MaxParallelWrites
+	}
+	return r.MetricBatchSize
+}
+
+// ResetBackoff allows the next write to happen immediately even if the
+// output is backing off after a failed write, e.g. for the final write when
+// the agent shuts down.
+func (r *RunningOutput) ResetBackoff() {
+	r.retryMutex.Lock()
+	r.retryAfter = time.Time{}
+	r.retryMutex.Unlock()
+}
+
+// backingOff returns true if the retry of a failed write is not due yet.
+func (r *RunningOutput) backingOff() bool {
+	r.retryMutex.Lock()
+	defer r.retryMutex.Unlock()
+
+	if time.Now().Before(r.retryAfter) {
+		r.log.Debugf("Backing off after failed write, retrying in %s", time.Until(r.retryAfter).Round(time.Millisecond))
+		return true
+	}
+	return false
+}
+
+// writeBatch writes the batch, acquired from the buffer, and applies the
+// retry policy if the write fails.  With parallel writes the result of each
+// write is handled separately, only the metrics of failed writes are retried
+// or dropped.  Metrics that can not be retried are dropped, the returned
+// error is nil if no metrics are retried.
+func (r *RunningOutput) writeBatch(batch []telegraf.Metric) error {
+	errs := r.write(batch)
+
+	r.retryMutex.Lock()
+	defer r.retryMutex.Unlock()
+
+	var written, retry, dropped []telegraf.Metric
+	var retryErr error
+	for i, err := range errs {
+		part := batch[i*r.MetricBatchSize : min((i+1)*r.MetricBatchSize, len(batch))]
+		switch {
+		case err == nil:
+			written = append(written, part...)
+		case isRetryable(err):
+			retry = append(retry, part...)
+			if retryErr == nil {
+				retryErr = err
+			}
+		default:
+			r.log.Errorf("Batch of %d metrics was rejected permanently: %v", len(part), err)
+			dropped = append(dropped, part...)
+		}
+	}
+
+	if len(written) > 0 {
+		r.buffer.Accept(written)
+	}
+
+	if retryErr == nil {
+		r.attempts = 0
+		r.retryAfter = time.Time{}
+		if len(dropped) > 0 {
+			r.deadLetter(dropped)
+			r.buffer.Drop(dropped)
+		}
+		return nil
+	}
+
+	r.attempts++
+	if r.Config.RetryMaxAttempts > 0 && r.attempts >= r.Config.RetryMaxAttempts {
+		r.log.Errorf("Giving up writing batch of %d metrics after %d attempts: %v", len(retry), r.attempts, retryErr)
+		dropped = append(dropped, retry...)
+		r.deadLetter(dropped)
+		r.attempts = 0
+		r.retryAfter = time.Time{}
+		r.buffer.Drop(dropped)
+		return nil
+	}
+
+	if len(dropped) > 0 {
+		r.deadLetter(dropped)
+		r.buffer.Drop(dropped)
+	}
+	r.buffer.Reject(retry)
+	if wait := r.backoff(); wait > 0 {
+		r.retryAfter = time.Now().Add(wait)
+		time.AfterFunc(wait, func() {
+			select {
+			case r.BatchReady <- time.Now():
+			default:
+			}
+		})
+	}
+	return retryErr
+}
+
+// backoff returns the time to wait before the next attempt.  The wait grows
+// exponentially with the number of failed attempts up to the maximum
+// interval, half of it is randomized to spread the retries.
+func (r *RunningOutput) backoff() time.Duration {
+	initial := r.Config.RetryInitialInterval
+	if initial <= 0 {
+		return 0
+	}
+	max := r.Config.RetryMaxInterval
+	if max <= 0 {
+		max = DefaultRetryMaxInterval
+	}
+
+	wait := initial
+	for i := 1; i < r.attempts && wait < max; i++ {
+		wait *= 2
+	}
+	if wait > max {
+		wait = max
+	}
+	return wait/2 + time.Duration(rand.Int63n(int64(wait/2)+1))
+}
+
+// deadLetter appends the dropped metrics to the dead-letter file, if one is
+// configured.
+func (r *RunningOutput) deadLetter(batch []telegraf.Metric) {
+	if r.Config.DeadLetterFile == "" {
+		r.log.Errorf("Dropped %d metrics", len(batch))
+		return
+	}
+
+	serializer := influx.NewSerializer()
+	octets, err := serializer.SerializeBatch(batch)
 	if err != nil {
-		r.buffer.Reject(batch)
-		return err
+		r.log.Errorf("Serializing %d metrics for the dead-letter file failed: %v", len(batch), err)
+		return
 	}
-	r.buffer.Accept(batch)
 
-	return nil
+	f, err := os.OpenFile(r.Config.DeadLetterFile, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0640)
+	if err != nil {
+		r.log.Errorf("Opening dead-letter file failed, dropped %d metrics: %v", len(batch), err)
+		return
+	}
+	defer f.Close()
+
+	if _, err := f.Write(octets); err != nil {
+		r.log.Errorf("Writing dead-letter file failed, dropped %d metrics: %v", len(batch), err)
+		return
+	}
+	r.log.Warnf("Wrote %d dropped metrics to dead-letter file %q", len(batch), r.Config.DeadLetterFile)
+}
+
+// isRetryable returns false if the error, or an error it wraps, marks the
+// metrics as rejected permanently.
+func isRetryable(err error) bool {
+	var rerr telegraf.RetryableError
+	if errors.As(err, &rerr) {
+		return rerr.Retryable()
+	}
+	return true
 }
 
 // Close closes the output
@@ -231,15 +510,56 @@ func (r *RunningOutput) Close() {
 	if err != nil {
 		r.log.Errorf("Error closing output: %v", err)
 	}
+
+	if err := r.buffer.Close(); err != nil {
+		r.log.Errorf("Error closing buffer: %v", err)
+	}
 }
 
-func (r *RunningOutput) write(metrics []telegraf.Metric) error {
+// write writes the metrics to the output and returns the result of each
+// write.  With parallel writes the metrics are split into batches of the
+// batch size, written concurrently.
+func (r *RunningOutput) write(metrics []telegraf.Metric) []error {
 	dropped := atomic.LoadInt64(&r.droppedMetrics)
 	if dropped > 0 {
 		r.log.Warnf("Metric buffer overflow; %d metrics have been dropped", dropped)
 		atomic.StoreInt64(&r.droppedMetrics, 0)
 	}
 
+	if len(metrics) <= r.MetricBatchSize {
+		return []error{r.writeOne(metrics)}
+	}
+
+	errs := make([]error, (len(metrics)+r.MetricBatchSize-1)/r.MetricBatchSize)
+	var wg sync.WaitGroup
+	for i := range errs {
+		batch := metrics[i*r.MetricBatchSize : min((i+1)*r.MetricBatchSize, len(metrics))]
+		wg.Add(1)
+		go func(i int, batch []telegraf.Metric) {
+			defer wg.Done()
+			r.ParallelWrites.Incr(1)
+			defer r.ParallelWrites.Incr(-1)
+
+			errs[i] = r.writeOne(batch)
+		}(i, batch)
+	}
+	wg.Wait()
+	return errs
+}
+
+// writeOne writes a single batch of metrics to the output once the rate
+// limits permit it.  The wait is aborted by Interrupt.
+func (r *RunningOutput) writeOne(metrics []telegraf.Metric) error {
+	if r.limit != nil {
+		if wait := r.limit.reserve(metrics); wait > 0 {
+			r.log.Debugf("Rate limit exceeded, delaying write of %d metrics by %s", len(metrics), wait.Round(time.Millisecond))
+			r.RateLimitWait.Incr(wait.Nanoseconds())
+			if err := internal.SleepContext(r.ctx, wait); err != nil {
+				return fmt.Errorf("waiting for rate limit: %w", err)
+			}
+		}
+	}
+
 	start := time.Now()
 	err := r.Output.Write(metrics)
 	elapsed := time.Since(start)
@@ -251,6 +571,13 @@ func (r *RunningOutput) write(metrics []telegraf.Metric) error {
 	return err
 }
 
+// Interrupt aborts the writes waiting for the rate limits, e.g. when the
+// agent shuts down.  The aborted writes fail and later writes fail instead of
+// waiting.
+func (r *RunningOutput) Interrupt() {
+	r.cancel()
+}
+
 func (r *RunningOutput) LogBufferStatus() {
 	nBuffer := r.buffer.Len()
 	r.log.Debugf("Buffer fullness: %d / %d metrics", nBuffer, r.MetricBufferLimit)
@@ -2,11 +2,14 @@ package models
 
 import (
 	"fmt"
+	"os"
+	"path/filepath"
 	"sync"
 	"testing"
 	"time"
 
 	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
 	"github.com/influxdata/telegraf/selfstat"
 	"github.com/influxdata/telegraf/testutil"
 	"github.com/stretchr/testify/assert"
@@ -460,6 +463,280 @@ func TestRunningOutputWriteFailOrder3(t *testing.T) {
 	assert.Equal(t, expected, m.Metrics())
 }
 
+func TestRunningOutputTakeOver(t *testing.T) {
+	conf := &OutputConfig{
+		Filter: Filter{},
+	}
+
+	m := &mockOutput{}
+	m.failWrite = true
+	old := NewRunningOutput(m, conf, 4, 12)
+	for _, metric := range first5 {
+		old.AddMetric(metric)
+	}
+	require.Error(t, old.Write())
+	old.Close()
+
+	replacement := &mockOutput{}
+	ro := NewRunningOutput(replacement, conf, 4, 12)
+	ro.TakeOver(old)
+	require.Equal(t, 0, old.BufferLength())
+	require.Equal(t, 5, ro.BufferLength())
+
+	require.NoError(t, ro.Write())
+	testutil.RequireMetricsEqual(t, first5, replacement.Metrics())
+}
+
+func TestRunningOutputRetryBackoff(t *testing.T) {
+	conf := &OutputConfig{
+		Filter:               Filter{},
+		RetryInitialInterval: time.Hour,
+	}
+
+	m := &mockOutput{}
+	m.failWrite = true
+	ro := NewRunningOutput(m, conf, 4, 12)
+	for _, metric := range first5 {
+		ro.AddMetric(metric)
+	}
+	require.Error(t, ro.Write())
+
+	// The retry is not due yet, so the output is not written to.
+	m.failWrite = false
+	require.NoError(t, ro.Write())
+	require.Len(t, m.Metrics(), 0)
+	require.Equal(t, 5, ro.BufferLength())
+
+	ro.ResetBackoff()
+	require.NoError(t, ro.Write())
+	testutil.RequireMetricsEqual(t, first5, m.Metrics())
+}
+
+func TestRunningOutputRetryBackoffInterval(t *testing.T) {
+	conf := &OutputConfig{
+		Filter:               Filter{},
+		RetryInitialInterval: time.Second,
+		RetryMaxInterval:     4 * time.Second,
+	}
+	ro := NewRunningOutput(&mockOutput{}, conf, 4, 12)
+
+	expected := []time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 4 * time.Second}
+	for i, max := range expected {
+		ro.attempts = i + 1
+		wait := ro.backoff()
+		require.GreaterOrEqual(t, wait, max/2)
+		require.LessOrEqual(t, wait, max)
+	}
+}
+
+func TestRunningOutputRetryMaxAttempts(t *testing.T) {
+	deadLetter := filepath.Join(t.TempDir(), "dead_letter.influx")
+	conf := &OutputConfig{
+		Filter:           Filter{},
+		RetryMaxAttempts: 2,
+		DeadLetterFile:   deadLetter,
+	}
+
+	m := &mockOutput{}
+	m.failWrite = true
+	ro := NewRunningOutput(m, conf, 4, 12)
+	ro.AddMetric(testutil.TestMetric(101, "metric1"))
+	ro.AddMetric(testutil.TestMetric(102, "metric2"))
+
+	require.Error(t, ro.Write())
+	require.Equal(t, 2, ro.BufferLength())
+
+	// The second attempt exhausts the retries and drops the batch.
+	require.NoError(t, ro.Write())
+	require.Equal(t, 0, ro.BufferLength())
+
+	octets, err := os.ReadFile(deadLetter)
+	require.NoError(t, err)
+	require.Equal(t, "metric1,tag1=value1 value=101i 1257894000000000000\n"+
+		"metric2,tag1=value1 value=102i 1257894000000000000\n", string(octets))
+
+	// Metrics added later are written normally.
+	m.failWrite = false
+	ro.AddMetric(testutil.TestMetric(103, "metric3"))
+	require.NoError(t, ro.Write())
+	require.Len(t, m.Metrics(), 1)
+}
+
+func TestRunningOutputNonRetryableError(t *testing.T) {
+	conf := &OutputConfig{
+		Filter: Filter{},
+	}
+
+	m := &mockOutput{}
+	m.failWrite = true
+	m.permanent = true
+	ro := NewRunningOutput(m, conf, 4, 12)
+	for _, metric := range first5 {
+		ro.AddMetric(metric)
+	}
+
+	// Each batch is dropped on the first attempt.
+	require.NoError(t, ro.Write())
+	require.Equal(t, 0, ro.BufferLength())
+	require.Len(t, m.Metrics(), 0)
+}
+
+func TestRunningOutputParallelWrites(t *testing.T) {
+	conf := &OutputConfig{
+		Filter:            Filter{},
+		MaxParallelWrites: 3,
+	}
+
+	m := &blockingOutput{
+		started: make(chan int, 3),
+		release: make(chan struct{}),
+	}
+	ro := NewRunningOutput(m, conf, 2, 12)
+	for _, metric := range first5 {
+		ro.AddMetric(metric)
+	}
+
+	done := make(chan error)
+	go func() {
+		done <- ro.Write()
+	}()
+
+	// All three batches are written at the same time.
+	var sizes []int
+	for i := 0; i < 3; i++ {
+		sizes = append(sizes, <-m.started)
+	}
+	require.ElementsMatch(t, []int{2, 2, 1}, sizes)
+	require.Equal(t, int64(3), ro.ParallelWrites.Get())
+
+	close(m.release)
+	require.NoError(t, <-done)
+	require.Equal(t, 0, ro.BufferLength())
+	require.Equal(t, int64(0), ro.ParallelWrites.Get())
+}
+
+func TestRunningOutputParallelWritesFail(t *testing.T) {
+	conf := &OutputConfig{
+		Filter:            Filter{},
+		MaxParallelWrites: 2,
+	}
+
+	m := &mockOutput{}
+	m.failWrite = true
+	ro := NewRunningOutput(m, conf, 2, 12)
+	for _, metric := range first5 {
+		ro.AddMetric(metric)
+	}
+
+	require.Error(t, ro.Write())
+	require.Equal(t, 5, ro.BufferLength())
+
+	m.failWrite = false
+	require.NoError(t, ro.Write())
+	testutil.RequireMetricsEqual(t, first5, m.Metrics(), testutil.SortMetrics())
+}
+
+func TestRunningOutputParallelWritesPartialFail(t *testing.T) {
+	for _, strategy := range []string{"memory", "disk"} {
+		t.Run(strategy, func(t *testing.T) {
+			conf := &OutputConfig{
+				Filter:            Filter{},
+				MaxParallelWrites: 3,
+				BufferStrategy:    strategy,
+				BufferDirectory:   t.TempDir(),
+			}
+
+			m := &mockOutput{failWrite: true, failName: "metric3"}
+			ro := NewRunningOutput(m, conf, 2, 12)
+			require.NoError(t, ro.Init())
+			defer ro.Close()
+			for _, metric := range first5 {
+				ro.AddMetric(metric)
+			}
+
+			// Only the failed batch is retried
+			require.Error(t, ro.Write())
+			require.Equal(t, 2, ro.BufferLength())
+			testutil.RequireMetricsEqual(t, []telegraf.Metric{first5[0], first5[1], first5[4]}, m.Metrics(), testutil.SortMetrics())
+
+			m.failWrite = false
+			require.NoError(t, ro.Write())
+			require.Equal(t, 0, ro.BufferLength())
+			testutil.RequireMetricsEqual(t, first5, m.Metrics(), testutil.SortMetrics())
+		})
+	}
+}
+
+func TestRunningOutputParallelWritesPartialReject(t *testing.T) {
+	conf := &OutputConfig{
+		Name:              "partial_reject",
+		Filter:            Filter{},
+		MaxParallelWrites: 3,
+	}
+
+	m := &mockOutput{failWrite: true, permanent: true, failName: "metric3"}
+	ro := NewRunningOutput(m, conf, 2, 12)
+	for _, metric := range first5 {
+		ro.AddMetric(metric)
+	}
+	buffer := ro.buffer.(*Buffer)
+	written := buffer.MetricsWritten.Get()
+	dropped := buffer.MetricsDropped.Get()
+
+	// Only the rejected batch is dropped
+	require.NoError(t, ro.Write())
+	require.Equal(t, 0, ro.BufferLength())
+	testutil.RequireMetricsEqual(t, []telegraf.Metric{first5[0], first5[1], first5[4]}, m.Metrics(), testutil.SortMetrics())
+	require.Equal(t, written+3, buffer.MetricsWritten.Get())
+	require.Equal(t, dropped+2, buffer.MetricsDropped.Get())
+}
+
+func TestRunningOutputRateLimitInterrupt(t *testing.T) {
+	conf := &OutputConfig{
+		Filter:          Filter{},
+		MetricRateLimit: 1,
+	}
+
+	m := &mockOutput{}
+	ro := NewRunningOutput(m, conf, 4, 12)
+	for _, metric := range first5 {
+		ro.AddMetric(metric)
+	}
+
+	// The write of the first batch would wait for several seconds
+	ro.Interrupt()
+	start := time.Now()
+	require.Error(t, ro.Write())
+	require.Less(t, time.Since(start), time.Second)
+	require.Equal(t, 5, ro.BufferLength())
+	require.Len(t, m.Metrics(), 0)
+}
+
+func TestRunningOutputRateLimit(t *testing.T) {
+	conf := &OutputConfig{
+		Filter:          Filter{},
+		MetricRateLimit: 40,
+	}
+
+	m := &mockOutput{}
+	ro := NewRunningOutput(m, conf, 4, 12)
+	for _, metric := range first5 {
+		ro.AddMetric(metric)
+	}
+	for _, metric := range next5 {
+		ro.AddMetric(metric)
+	}
+
+	// The burst of 40 metrics is used up, the next 10 metrics take 250ms.
+	require.Equal(t, time.Duration(0), ro.limit.reserve(make([]telegraf.Metric, 40)))
+
+	start := time.Now()
+	require.NoError(t, ro.Write())
+	require.GreaterOrEqual(t, time.Since(start), 200*time.Millisecond)
+	require.Len(t, m.Metrics(), 10)
+	require.Greater(t, ro.RateLimitWait.Get(), int64(0))
+}
+
 func TestInternalMetrics(t *testing.T) {
 	_ = NewRunningOutput(
 		&mockOutput{},
@@ -510,6 +787,10 @@ type mockOutput struct {
 
 	// if true, mock a write failure
 	failWrite bool
+	// if true, the write failure is not retryable
+	permanent bool
+	// if set, only writes containing a metric of this name fail
+	failName string
 }
 
 func (m *mockOutput) Connect() error {
@@ -531,6 +812,19 @@ func (m *mockOutput) SampleConfig() string {
 func (m *mockOutput) Write(metrics []telegraf.Metric) error {
 	m.Lock()
 	defer m.Unlock()
+	if m.failWrite && m.failName != "" {
+		failed := false
+		for _, metric := range metrics {
+			failed = failed || metric.Name() == m.failName
+		}
+		if !failed {
+			m.metrics = append(m.metrics, metrics...)
+			return nil
+		}
+	}
+	if m.failWrite && m.permanent {
+		return &internal.NonRetryableError{Err: fmt.Errorf("failed write")}
+	}
 	if m.failWrite {
 		return fmt.Errorf("failed write")
 	}
@@ -549,6 +843,35 @@ func (m *mockOutput) Metrics() []telegraf.Metric {
 	return m.metrics
 }
 
+// blockingOutput reports the size of each batch written and blocks the
+// write until released.
+type blockingOutput struct {
+	started chan int
+	release chan struct{}
+}
+
+func (m *blockingOutput) Connect() error {
+	return nil
+}
+
+func (m *blockingOutput) Close() error {
+	return nil
+}
+
+func (m *blockingOutput) Description() string {
+	return ""
+}
+
+func (m *blockingOutput) SampleConfig() string {
+	return ""
+}
+
+func (m *blockingOutput) Write(metrics []telegraf.Metric) error {
+	m.started <- len(metrics)
+	<-m.release
+	return nil
+}
+
 type perfOutput struct {
 	// if true, mock a write failure
 	failWrite bool
@@ -26,6 +26,14 @@ type ProcessorConfig struct {
 	Alias  string
 	Order  int64
 	Filter Filter
+
+	// Pipeline is the name of the pipeline the plugin belongs to, plugins
+	// without a pipeline belong to the default pipeline.
+	Pipeline string
+
+	// Fingerprint identifies the plugin's configuration and changes whenever
+	// the plugin's TOML table changes.
+	Fingerprint string
 }
 
 func NewRunningProcessor(processor telegraf.StreamingProcessor, config *ProcessorConfig) *RunningProcessor {
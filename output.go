@@ -14,6 +14,16 @@ type Output interface {
 	Write(metrics []Metric) error
 }
 
+// RetryableError can be implemented by the errors returned by an output's
+// Write function to tell whether writing the same metrics again can succeed.
+// Errors not implementing the interface are considered retryable.
+type RetryableError interface {
+	error
+
+	// Retryable returns false if the metrics are rejected permanently.
+	Retryable() bool
+}
+
 // AggregatingOutput adds aggregating functionality to an Output.  May be used
 // if the Output only accepts a fixed set of aggregations over a time period.
 // These functions may be called concurrently to the Write function.
@@ -1,11 +1,13 @@
 package histogram
 
 import (
+	"fmt"
 	"sort"
 	"strconv"
 
 	"github.com/influxdata/telegraf"
 	"github.com/influxdata/telegraf/plugins/aggregators"
+	"github.com/influxdata/telegraf/plugins/common/exphistogram"
 )
 
 // bucketRightTag is the tag, which contains right bucket border
@@ -20,14 +22,22 @@ const bucketLeftTag = "gt"
 // bucketNegInf is the left bucket border for infinite values
 const bucketNegInf = "-Inf"
 
+// typeExponential is the config type of exponential histograms
+const typeExponential = "exponential"
+
+// defaultMaxBuckets is the default maximum number of exponential buckets as
+// in OpenTelemetry
+const defaultMaxBuckets = 160
+
 // HistogramAggregator is aggregator with histogram configs and particular histograms for defined metrics
 type HistogramAggregator struct {
 	Configs      []config `toml:"config"`
 	ResetBuckets bool     `toml:"reset"`
 	Cumulative   bool     `toml:"cumulative"`
 
-	buckets bucketsByMetrics
-	cache   map[uint64]metricHistogramCollection
+	buckets          bucketsByMetrics
+	cache            map[uint64]metricHistogramCollection
+	exponentialCache map[uint64]metricExponentialCollection
 }
 
 // config is the config, which contains name, field of metric and histogram buckets.
@@ -35,6 +45,12 @@ type config struct {
 	Metric  string   `toml:"measurement_name"`
 	Fields  []string `toml:"fields"`
 	Buckets buckets  `toml:"buckets"`
+
+	// Exponential histogram settings
+	Type          string  `toml:"type"`
+	MaxScale      *int32  `toml:"max_scale"`
+	MaxBuckets    int     `toml:"max_buckets"`
+	ZeroThreshold float64 `toml:"zero_threshold"`
 }
 
 // bucketsByMetrics contains the buckets grouped by metric and field name
@@ -53,6 +69,13 @@ type metricHistogramCollection struct {
 	tags                map[string]string
 }
 
+// metricExponentialCollection aggregates the exponential histograms of a metric
+type metricExponentialCollection struct {
+	histograms map[string]*exphistogram.Histogram
+	name       string
+	tags       map[string]string
+}
+
 // counts is the number of hits in the bucket
 type counts []int64
 
@@ -105,6 +128,22 @@ var sampleConfig = `
   #   measurement_name = "diskio"
   #   ## The concrete fields of metric
   #   fields = ["io_time", "read_time", "write_time"]
+
+  ## Example config that aggregates fields into exponential histograms with
+  ## automatically chosen buckets as used by OpenTelemetry and Prometheus
+  ## native histograms. The "cumulative" setting does not apply.
+  # [[aggregators.histogram.config]]
+  #   type = "exponential"
+  #   ## The name of metric.
+  #   measurement_name = "http"
+  #   ## The concrete fields of metric
+  #   fields = ["response_time"]
+  #   ## Initial resolution of the buckets between -10 and 20, reduced when
+  #   ## the values exceed max_buckets buckets.
+  #   # max_scale = 20
+  #   # max_buckets = 160
+  #   ## Absolute values up to the threshold are counted in the zero bucket.
+  #   # zero_threshold = 0.0
 `
 
 // SampleConfig returns sample of config
@@ -117,8 +156,39 @@ func (h *HistogramAggregator) Description() string {
 	return "Create aggregate histograms."
 }
 
+func (h *HistogramAggregator) Init() error {
+	for i := range h.Configs {
+		cfg := &h.Configs[i]
+		switch cfg.Type {
+		case "", "fixed":
+		case typeExponential:
+			if cfg.MaxScale == nil {
+				scale := int32(exphistogram.MaxScale)
+				cfg.MaxScale = &scale
+			}
+			if *cfg.MaxScale < exphistogram.MinScale || *cfg.MaxScale > exphistogram.MaxScale {
+				return fmt.Errorf("max_scale must be between %d and %d", exphistogram.MinScale, exphistogram.MaxScale)
+			}
+			if cfg.MaxBuckets == 0 {
+				cfg.MaxBuckets = defaultMaxBuckets
+			}
+			if cfg.MaxBuckets < 2 {
+				return fmt.Errorf("max_buckets must be at least 2")
+			}
+			if cfg.ZeroThreshold < 0 {
+				return fmt.Errorf("zero_threshold must not be negative")
+			}
+		default:
+			return fmt.Errorf("invalid histogram type %q", cfg.Type)
+		}
+	}
+	return nil
+}
+
 // Add adds new hit to the buckets
 func (h *HistogramAggregator) Add(in telegraf.Metric) {
+	h.addExponential(in)
+
 	bucketsByField := make(map[string][]float64)
 	for field := range in.Fields() {
 		buckets := h.getBuckets(in.Name(), field)
@@ -170,6 +240,49 @@ func (h *HistogramAggregator) Push(acc telegraf.Accumulator) {
 	for _, metric := range metricsWithGroupedFields {
 		acc.AddFields(metric.name, makeFieldsWithCount(metric.fieldsWithCount), metric.tags)
 	}
+
+	for _, aggregate := range h.exponentialCache {
+		fields := make(map[string]interface{})
+		for field, histogram := range aggregate.histograms {
+			histogram.AddFields(fields, field)
+		}
+		acc.AddHistogram(aggregate.name, fields, copyTags(aggregate.tags))
+	}
+}
+
+// addExponential records the fields with an exponential config
+func (h *HistogramAggregator) addExponential(in telegraf.Metric) {
+	var agr metricExponentialCollection
+	for _, field := range in.FieldList() {
+		cfg := h.getExponentialConfig(in.Name(), field.Key)
+		if cfg == nil {
+			continue
+		}
+		value, ok := convert(field.Value)
+		if !ok {
+			continue
+		}
+
+		if agr.histograms == nil {
+			var found bool
+			id := in.HashID()
+			if agr, found = h.exponentialCache[id]; !found {
+				agr = metricExponentialCollection{
+					name:       in.Name(),
+					tags:       in.Tags(),
+					histograms: make(map[string]*exphistogram.Histogram),
+				}
+				h.exponentialCache[id] = agr
+			}
+		}
+
+		histogram, ok := agr.histograms[field.Key]
+		if !ok {
+			histogram = exphistogram.New(*cfg.MaxScale, cfg.ZeroThreshold)
+			agr.histograms[field.Key] = histogram
+		}
+		histogram.Record(value, cfg.MaxBuckets)
+	}
 }
 
 // groupFieldsByBuckets groups fields by metric buckets which are represented as tags
@@ -241,6 +354,7 @@ func (h *HistogramAggregator) Reset() {
 // resetCache resets cached counts(hits) in the buckets
 func (h *HistogramAggregator) resetCache() {
 	h.cache = make(map[uint64]metricHistogramCollection)
+	h.exponentialCache = make(map[uint64]metricExponentialCollection)
 }
 
 // getBuckets finds buckets and returns them
@@ -250,7 +364,7 @@ func (h *HistogramAggregator) getBuckets(metric string, field string) []float64
 	}
 
 	for _, config := range h.Configs {
-		if config.Metric == metric {
+		if config.Metric == metric && config.Type != typeExponential {
 			if !isBucketExists(field, config) {
 				continue
 			}
@@ -266,6 +380,16 @@ func (h *HistogramAggregator) getBuckets(metric string, field string) []float64
 	return h.buckets[metric][field]
 }
 
+// getExponentialConfig finds the exponential config of the field
+func (h *HistogramAggregator) getExponentialConfig(metric string, field string) *config {
+	for i, config := range h.Configs {
+		if config.Metric == metric && config.Type == typeExponential && isBucketExists(field, config) {
+			return &h.Configs[i]
+		}
+	}
+	return nil
+}
+
 // isBucketExists checks if buckets exists for the passed field
 func isBucketExists(field string, cfg config) bool {
 	if len(cfg.Fields) == 0 {
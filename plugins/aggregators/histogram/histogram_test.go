@@ -244,6 +244,77 @@ func TestWrongBucketsOrder(t *testing.T) {
 	histogram.Add(firstMetric2)
 }
 
+// TestHistogramExponential tests exponential histograms next to fixed buckets
+func TestHistogramExponential(t *testing.T) {
+	scale := int32(0)
+	cfg := []config{
+		{Metric: "http", Fields: []string{"response_time"}, Type: "exponential", MaxScale: &scale},
+		{Metric: "http", Fields: []string{"size"}, Buckets: []float64{100}},
+	}
+	histogram := NewTestHistogram(cfg, false, true).(*HistogramAggregator)
+	require.NoError(t, histogram.Init())
+
+	for _, v := range []float64{1.5, 3, 3.5, 0} {
+		histogram.Add(metric.New("http", tags{"host": "a"}, fields{"response_time": v, "size": int64(10)}, time.Now()))
+	}
+	histogram.Add(metric.New("http", tags{"host": "a"}, fields{"response_time": "n/a"}, time.Now()))
+
+	acc := &testutil.Accumulator{}
+	histogram.Push(acc)
+
+	expected := []telegraf.Metric{
+		metric.New(
+			"http",
+			tags{"host": "a"},
+			fields{
+				"response_time_count":          uint64(4),
+				"response_time_sum":            8.0,
+				"response_time_scale":          int64(0),
+				"response_time_zero_threshold": 0.0,
+				"response_time_zero_count":     uint64(1),
+				"response_time_positive_0":     uint64(1),
+				"response_time_positive_1":     uint64(2),
+			},
+			time.Unix(0, 0),
+			telegraf.Histogram,
+		),
+	}
+	var actual []telegraf.Metric
+	for _, m := range acc.GetTelegrafMetrics() {
+		if m.Type() == telegraf.Histogram {
+			actual = append(actual, m)
+		}
+	}
+	testutil.RequireMetricsEqual(t, expected, actual, testutil.IgnoreTime())
+	assertContainsTaggedField(t, acc, "http", fields{"size_bucket": int64(4)}, tags{bucketRightTag: "100"})
+
+	histogram.ResetBuckets = true
+	histogram.Reset()
+	acc.ClearMetrics()
+	histogram.Push(acc)
+	require.Empty(t, acc.GetTelegrafMetrics())
+}
+
+func TestHistogramExponentialInitErrors(t *testing.T) {
+	scale := int32(21)
+	tests := []struct {
+		name string
+		cfg  config
+	}{
+		{"invalid type", config{Metric: "http", Type: "linear"}},
+		{"invalid scale", config{Metric: "http", Type: "exponential", MaxScale: &scale}},
+		{"invalid max buckets", config{Metric: "http", Type: "exponential", MaxBuckets: 1}},
+		{"invalid zero threshold", config{Metric: "http", Type: "exponential", ZeroThreshold: -1}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			histogram := NewHistogramAggregator()
+			histogram.Configs = []config{tt.cfg}
+			require.Error(t, histogram.Init())
+		})
+	}
+}
+
 // assertContainsTaggedField is help functions to test histogram data
 func assertContainsTaggedField(t *testing.T, acc *testutil.Accumulator, metricName string, fields map[string]interface{}, tags map[string]string) {
 	acc.Lock()
@@ -1,9 +1,13 @@
 package quantile
 
 import (
+	"bytes"
+	"errors"
 	"math"
 	"sort"
 
+	"github.com/DataDog/sketches-go/ddsketch"
+	"github.com/DataDog/sketches-go/ddsketch/store"
 	"github.com/caio/go-tdigest"
 )
 
@@ -12,8 +16,75 @@ type algorithm interface {
 	Quantile(q float64) float64
 }
 
+// sketch is an algorithm which can be serialized and merged with the
+// serialized sketches of other instances, e.g. of other agents
+type sketch interface {
+	algorithm
+	Encode() ([]byte, error)
+	Merge(data []byte) error
+}
+
+type tDigestSketch struct {
+	*tdigest.TDigest
+}
+
 func newTDigest(compression float64) (algorithm, error) {
-	return tdigest.New(tdigest.Compression(compression))
+	t, err := tdigest.New(tdigest.Compression(compression))
+	if err != nil {
+		return nil, err
+	}
+	return &tDigestSketch{t}, nil
+}
+
+func (t *tDigestSketch) Encode() ([]byte, error) {
+	return t.AsBytes()
+}
+
+func (t *tDigestSketch) Merge(data []byte) error {
+	other, err := tdigest.FromBytes(bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	return t.TDigest.Merge(other)
+}
+
+type ddSketch struct {
+	*ddsketch.DDSketch
+}
+
+func newDDSketch(relativeAccuracy float64) (algorithm, error) {
+	s, err := ddsketch.NewDefaultDDSketch(relativeAccuracy)
+	if err != nil {
+		return nil, err
+	}
+	return &ddSketch{s}, nil
+}
+
+func (d *ddSketch) Quantile(q float64) float64 {
+	v, err := d.GetValueAtQuantile(q)
+	if err != nil {
+		return math.NaN()
+	}
+	return v
+}
+
+func (d *ddSketch) Encode() ([]byte, error) {
+	var b []byte
+	d.DDSketch.Encode(&b, false)
+	return b, nil
+}
+
+func (d *ddSketch) Merge(data []byte) error {
+	// Decode into a separate sketch first to not leave the sketch
+	// partially merged in case of invalid data
+	other, err := ddsketch.DecodeDDSketch(data, store.DefaultProvider, nil)
+	if err != nil {
+		return err
+	}
+	if other.IndexMapping == nil {
+		return errors.New("missing index mapping")
+	}
+	return d.MergeWith(other)
 }
 
 type exactAlgorithmR7 struct {
@@ -1,16 +1,28 @@
 package quantile
 
 import (
+	"encoding/base64"
 	"fmt"
+	"strings"
 
 	"github.com/influxdata/telegraf"
 	"github.com/influxdata/telegraf/plugins/aggregators"
 )
 
+// sketchSuffix is appended to the field name for serialized sketches
+const sketchSuffix = "_sketch"
+
 type Quantile struct {
-	Quantiles     []float64 `toml:"quantiles"`
-	Compression   float64   `toml:"compression"`
-	AlgorithmType string    `toml:"algorithm"`
+	Quantiles        []float64       `toml:"quantiles"`
+	Compression      float64         `toml:"compression"`
+	RelativeAccuracy float64         `toml:"relative_accuracy"`
+	AlgorithmType    string          `toml:"algorithm"`
+	Emit             []string        `toml:"emit"`
+	MergeSketches    bool            `toml:"merge_sketches"`
+	Log              telegraf.Logger `toml:"-"`
+
+	emitQuantiles bool
+	emitSketch    bool
 
 	newAlgorithm newAlgorithmFunc
 
@@ -41,6 +53,7 @@ var sampleConfig = `
   ## Type of aggregation algorithm
   ## Supported are:
   ##  "t-digest" -- approximation using centroids, can cope with large number of samples
+  ##  "ddsketch" -- approximation with relative error guarantees using logarithmic buckets
   ##  "exact R7" -- exact computation also used by Excel or NumPy (Hyndman & Fan 1996 R7)
   ##  "exact R8" -- exact computation (Hyndman & Fan 1996 R8)
   ## NOTE: Do not use "exact" algorithms with large number of samples
@@ -51,6 +64,22 @@ var sampleConfig = `
   ## greater or equal to 1.0. Smaller values will result in more
   ## performance but less accuracy.
   # compression = 100.0
+
+  ## Relative accuracy for approximation (ddsketch) in the range (0,1).
+  ## Quantiles are within this fraction of the true value.
+  # relative_accuracy = 0.01
+
+  ## What to output for each field. Available are
+  ##  "quantiles" -- the configured quantiles as <field>_<quantile*100>
+  ##  "sketch"    -- the base64 encoded t-digest or ddsketch as <field>_sketch
+  ## Sketches can be merged by another aggregator with "merge_sketches"
+  ## enabled, e.g. to compute quantiles over multiple agents.
+  # emit = ["quantiles"]
+
+  ## Merge the sketches of incoming <field>_sketch string fields produced
+  ## by the "sketch" output above into the aggregate of <field>. The
+  ## algorithm settings must match the ones of the emitting aggregator.
+  # merge_sketches = false
 `
 
 func (q *Quantile) SampleConfig() string {
@@ -72,6 +101,7 @@ func (q *Quantile) Add(in telegraf.Metric) {
 				}
 			}
 		}
+		q.mergeSketches(cached, in)
 		return
 	}
 
@@ -89,15 +119,58 @@ func (q *Quantile) Add(in telegraf.Metric) {
 			a.fields[k] = algo
 		}
 	}
+	q.mergeSketches(a, in)
 	q.cache[id] = a
 }
 
+// mergeSketches merges the serialized sketches of the metric into the
+// algorithms of the fields the sketches were created from
+func (q *Quantile) mergeSketches(a aggregate, in telegraf.Metric) {
+	if !q.MergeSketches {
+		return
+	}
+
+	for _, field := range in.FieldList() {
+		encoded, ok := field.Value.(string)
+		if !ok || !strings.HasSuffix(field.Key, sketchSuffix) {
+			continue
+		}
+		data, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil {
+			q.Log.Errorf("Decoding sketch of field %q failed: %v", field.Key, err)
+			continue
+		}
+
+		k := strings.TrimSuffix(field.Key, sketchSuffix)
+		algo, found := a.fields[k]
+		if !found {
+			// This should never error out as we tested it in Init()
+			algo, _ = q.newAlgorithm(q.Compression)
+		}
+		if err := algo.(sketch).Merge(data); err != nil {
+			q.Log.Errorf("Merging sketch of field %q failed: %v", field.Key, err)
+			continue
+		}
+		a.fields[k] = algo
+	}
+}
+
 func (q *Quantile) Push(acc telegraf.Accumulator) {
 	for _, aggregate := range q.cache {
 		fields := map[string]interface{}{}
 		for k, algo := range aggregate.fields {
-			for i, qtl := range q.Quantiles {
-				fields[k+q.suffixes[i]] = algo.Quantile(qtl)
+			if q.emitQuantiles {
+				for i, qtl := range q.Quantiles {
+					fields[k+q.suffixes[i]] = algo.Quantile(qtl)
+				}
+			}
+			if q.emitSketch {
+				data, err := algo.(sketch).Encode()
+				if err != nil {
+					q.Log.Errorf("Encoding sketch of field %q failed: %v", k, err)
+					continue
+				}
+				fields[k+sketchSuffix] = base64.StdEncoding.EncodeToString(data)
 			}
 		}
 		acc.AddFields(aggregate.name, fields, aggregate.tags)
@@ -125,6 +198,10 @@ func (q *Quantile) Init() error {
 	switch q.AlgorithmType {
 	case "t-digest", "":
 		q.newAlgorithm = newTDigest
+	case "ddsketch":
+		q.newAlgorithm = func(float64) (algorithm, error) {
+			return newDDSketch(q.RelativeAccuracy)
+		}
 	case "exact R7":
 		q.newAlgorithm = newExactR7
 	case "exact R8":
@@ -132,10 +209,29 @@ func (q *Quantile) Init() error {
 	default:
 		return fmt.Errorf("unknown algorithm type %q", q.AlgorithmType)
 	}
-	if _, err := q.newAlgorithm(q.Compression); err != nil {
+	algo, err := q.newAlgorithm(q.Compression)
+	if err != nil {
 		return fmt.Errorf("cannot create %q algorithm: %v", q.AlgorithmType, err)
 	}
 
+	if len(q.Emit) == 0 {
+		q.Emit = []string{"quantiles"}
+	}
+	q.emitQuantiles, q.emitSketch = false, false
+	for _, e := range q.Emit {
+		switch e {
+		case "quantiles":
+			q.emitQuantiles = true
+		case "sketch":
+			q.emitSketch = true
+		default:
+			return fmt.Errorf("unknown emit option %q", e)
+		}
+	}
+	if _, ok := algo.(sketch); !ok && (q.emitSketch || q.MergeSketches) {
+		return fmt.Errorf("algorithm %q does not support sketches", q.AlgorithmType)
+	}
+
 	if len(q.Quantiles) == 0 {
 		q.Quantiles = []float64{0.25, 0.5, 0.75}
 	}
@@ -160,6 +256,6 @@ func (q *Quantile) Init() error {
 
 func init() {
 	aggregators.Add("quantile", func() telegraf.Aggregator {
-		return &Quantile{Compression: 100}
+		return &Quantile{Compression: 100, RelativeAccuracy: 0.01}
 	})
 }
@@ -424,6 +424,184 @@ func TestMultipleMetricsExactR8(t *testing.T) {
 	testutil.RequireMetricsEqual(t, expected, acc.GetTelegrafMetrics(), testutil.IgnoreTime(), epsilon, sort)
 }
 
+func TestConfigInvalidRelativeAccuracy(t *testing.T) {
+	q := Quantile{RelativeAccuracy: 1.5, AlgorithmType: "ddsketch"}
+	err := q.Init()
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "cannot create \"ddsketch\" algorithm")
+}
+
+func TestConfigInvalidEmit(t *testing.T) {
+	q := Quantile{Compression: 100, Emit: []string{"sketch", "histogram"}}
+	err := q.Init()
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "unknown emit option \"histogram\"")
+}
+
+func TestConfigSketchesUnsupported(t *testing.T) {
+	q := Quantile{AlgorithmType: "exact R7", Emit: []string{"sketch"}}
+	err := q.Init()
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "does not support sketches")
+
+	q = Quantile{AlgorithmType: "exact R8", MergeSketches: true}
+	err = q.Init()
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "does not support sketches")
+}
+
+func TestSingleMetricDDSketch(t *testing.T) {
+	acc := testutil.Accumulator{}
+
+	q := Quantile{RelativeAccuracy: 0.01, AlgorithmType: "ddsketch"}
+	err := q.Init()
+	require.NoError(t, err)
+
+	for i := 0; i < 100; i++ {
+		q.Add(testutil.MustMetric(
+			"test",
+			map[string]string{"foo": "bar"},
+			map[string]interface{}{
+				"a":  int64(i + 1),
+				"x1": "string",
+			},
+			time.Now(),
+		))
+	}
+	q.Push(&acc)
+
+	expected := []telegraf.Metric{
+		testutil.MustMetric(
+			"test",
+			map[string]string{"foo": "bar"},
+			map[string]interface{}{
+				"a_025": 25.0,
+				"a_050": 50.0,
+				"a_075": 75.0,
+			},
+			time.Now(),
+		),
+	}
+
+	// Values are guaranteed to be within the relative accuracy
+	epsilon := cmpopts.EquateApprox(0.01, 0)
+	testutil.RequireMetricsEqual(t, expected, acc.GetTelegrafMetrics(), testutil.IgnoreTime(), epsilon)
+}
+
+func TestMergeSketches(t *testing.T) {
+	tests := []struct {
+		name      string
+		algorithm string
+	}{
+		{name: "t-digest", algorithm: "t-digest"},
+		{name: "ddsketch", algorithm: "ddsketch"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Each agent sees half of the values and only emits the sketch
+			var sketches []telegraf.Metric
+			for agent := 0; agent < 2; agent++ {
+				q := Quantile{
+					Compression:      100,
+					RelativeAccuracy: 0.01,
+					AlgorithmType:    tt.algorithm,
+					Emit:             []string{"sketch"},
+				}
+				require.NoError(t, q.Init())
+
+				for i := agent; i < 200; i += 2 {
+					q.Add(testutil.MustMetric(
+						"test",
+						map[string]string{"foo": "bar"},
+						map[string]interface{}{"a": float64(i + 1)},
+						time.Now(),
+					))
+				}
+
+				acc := testutil.Accumulator{}
+				q.Push(&acc)
+				metrics := acc.GetTelegrafMetrics()
+				require.Len(t, metrics, 1)
+				require.Len(t, metrics[0].FieldList(), 1)
+				require.IsType(t, "", metrics[0].Fields()["a_sketch"])
+				sketches = append(sketches, metrics...)
+			}
+
+			// The central aggregator computes the quantiles over all values
+			q := Quantile{
+				Compression:      100,
+				RelativeAccuracy: 0.01,
+				AlgorithmType:    tt.algorithm,
+				MergeSketches:    true,
+				Log:              testutil.Logger{},
+			}
+			require.NoError(t, q.Init())
+			for _, m := range sketches {
+				q.Add(m)
+			}
+
+			acc := testutil.Accumulator{}
+			q.Push(&acc)
+
+			expected := []telegraf.Metric{
+				testutil.MustMetric(
+					"test",
+					map[string]string{"foo": "bar"},
+					map[string]interface{}{
+						"a_025": 50.0,
+						"a_050": 100.0,
+						"a_075": 150.0,
+					},
+					time.Now(),
+				),
+			}
+			epsilon := cmpopts.EquateApprox(0.02, 0)
+			testutil.RequireMetricsEqual(t, expected, acc.GetTelegrafMetrics(), testutil.IgnoreTime(), epsilon)
+		})
+	}
+}
+
+func TestMergeSketchesInvalid(t *testing.T) {
+	q := Quantile{
+		RelativeAccuracy: 0.01,
+		AlgorithmType:    "ddsketch",
+		MergeSketches:    true,
+		Log:              testutil.Logger{},
+	}
+	require.NoError(t, q.Init())
+
+	q.Add(testutil.MustMetric(
+		"test",
+		map[string]string{},
+		map[string]interface{}{
+			"a":        1.0,
+			"a_sketch": "not base64!",
+			"b_sketch": "AAAA",
+		},
+		time.Now(),
+	))
+
+	acc := testutil.Accumulator{}
+	q.Push(&acc)
+
+	// Invalid sketches are ignored
+	expected := []telegraf.Metric{
+		testutil.MustMetric(
+			"test",
+			map[string]string{},
+			map[string]interface{}{
+				"a_025": 1.0,
+				"a_050": 1.0,
+				"a_075": 1.0,
+			},
+			time.Now(),
+		),
+	}
+	epsilon := cmpopts.EquateApprox(0.02, 0)
+	testutil.RequireMetricsEqual(t, expected, acc.GetTelegrafMetrics(), testutil.IgnoreTime(), epsilon)
+}
+
 func BenchmarkDefaultTDigest(b *testing.B) {
 	metrics := make([]telegraf.Metric, 100)
 	for i := range metrics {
@@ -0,0 +1,120 @@
+package avro
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/linkedin/goavro/v2"
+)
+
+// magicByte prefixes every message in the Confluent wire format
+const magicByte byte = 0x00
+
+// DefaultTimeout is the default timeout for requests to the schema registry
+const DefaultTimeout = 5 * time.Second
+
+// headerSize is the length of the magic byte and the big-endian schema ID
+const headerSize = 5
+
+// SchemaRegistry is a client for a Confluent compatible schema registry
+// caching the codecs of all schemas retrieved so far.
+type SchemaRegistry struct {
+	url    string
+	client *http.Client
+
+	codecs map[int]*goavro.Codec
+	sync.Mutex
+}
+
+// NewSchemaRegistry creates a client for the schema registry at the given
+// address. Credentials can be passed as user-info part of the address.
+func NewSchemaRegistry(address string, timeout time.Duration) (*SchemaRegistry, error) {
+	u, err := url.Parse(address)
+	if err != nil {
+		return nil, fmt.Errorf("invalid schema registry address: %w", err)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return nil, fmt.Errorf("invalid schema registry scheme %q", u.Scheme)
+	}
+
+	return &SchemaRegistry{
+		url:    strings.TrimSuffix(address, "/"),
+		client: &http.Client{Timeout: timeout},
+		codecs: make(map[int]*goavro.Codec),
+	}, nil
+}
+
+// Codec returns the codec for the schema with the given ID, querying the
+// registry if the schema is not cached yet.
+func (r *SchemaRegistry) Codec(id int) (*goavro.Codec, error) {
+	r.Lock()
+	defer r.Unlock()
+
+	if codec, found := r.codecs[id]; found {
+		return codec, nil
+	}
+
+	schema, err := r.fetch(id)
+	if err != nil {
+		return nil, err
+	}
+
+	codec, err := goavro.NewCodec(schema)
+	if err != nil {
+		return nil, fmt.Errorf("invalid schema %d: %w", id, err)
+	}
+	r.codecs[id] = codec
+
+	return codec, nil
+}
+
+func (r *SchemaRegistry) fetch(id int) (string, error) {
+	resp, err := r.client.Get(r.url + "/schemas/ids/" + strconv.Itoa(id))
+	if err != nil {
+		return "", fmt.Errorf("requesting schema %d failed: %w", id, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("requesting schema %d failed: %s", id, resp.Status)
+	}
+
+	var result struct {
+		Schema string `json:"schema"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return "", fmt.Errorf("decoding schema %d failed: %w", id, err)
+	}
+
+	return result.Schema, nil
+}
+
+// SplitWireFormat extracts the schema ID and the Avro payload from a message
+// in the Confluent wire format.
+func SplitWireFormat(buf []byte) (int, []byte, error) {
+	if len(buf) < headerSize {
+		return 0, nil, fmt.Errorf("message too short (%d bytes) for wire format", len(buf))
+	}
+	if buf[0] != magicByte {
+		return 0, nil, fmt.Errorf("unknown magic byte %#x", buf[0])
+	}
+
+	return int(binary.BigEndian.Uint32(buf[1:headerSize])), buf[headerSize:], nil
+}
+
+// AppendWireHeader appends the Confluent wire format header referencing the
+// given schema ID to the buffer.
+func AppendWireHeader(buf []byte, id int) []byte {
+	var header [headerSize]byte
+	header[0] = magicByte
+	binary.BigEndian.PutUint32(header[1:], uint32(id))
+
+	return append(buf, header[:]...)
+}
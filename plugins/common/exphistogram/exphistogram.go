@@ -0,0 +1,363 @@
+// Package exphistogram implements base-2 exponential histograms as defined
+// by OpenTelemetry and used by Prometheus native histograms, together with
+// their representation as fields of telegraf metrics.
+//
+// A histogram of a field "latency" is represented by the fields
+//
+//	latency_count           number of observations (uint64)
+//	latency_sum             sum of observations (float64)
+//	latency_scale           resolution of the buckets (int64)
+//	latency_zero_threshold  width of the zero bucket (float64)
+//	latency_zero_count      observations within the zero bucket (uint64)
+//	latency_positive_<i>    observations in positive bucket i (uint64)
+//	latency_negative_<i>    observations in negative bucket i (uint64)
+//
+// Bucket i covers the values (base^i, base^(i+1)] with base = 2^(2^-scale),
+// the bucket index convention of OpenTelemetry. Only populated buckets are
+// represented. Metrics with such fields have the histogram value type.
+package exphistogram
+
+import (
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/influxdata/telegraf"
+)
+
+const (
+	// MinScale and MaxScale limit the scale as defined by OpenTelemetry
+	MinScale = -10
+	MaxScale = 20
+
+	// PrometheusMinScale and PrometheusMaxScale limit the schema of
+	// Prometheus native histograms
+	PrometheusMinScale = -4
+	PrometheusMaxScale = 8
+)
+
+// Histogram is an exponential histogram
+type Histogram struct {
+	Scale         int32
+	ZeroThreshold float64
+	ZeroCount     uint64
+	Count         uint64
+	Sum           float64
+	Positive      Buckets
+	Negative      Buckets
+}
+
+// Buckets are consecutive buckets starting at bucket index Offset
+type Buckets struct {
+	Offset int32
+	Counts []uint64
+}
+
+// Span is a run of consecutive buckets as used by Prometheus
+type Span struct {
+	Offset int32
+	Length uint32
+}
+
+// New returns an empty histogram
+func New(scale int32, zeroThreshold float64) *Histogram {
+	return &Histogram{Scale: scale, ZeroThreshold: zeroThreshold}
+}
+
+// Record adds the value to the histogram. The histogram is downscaled if
+// the positive or negative buckets would exceed maxBuckets buckets.
+func (h *Histogram) Record(value float64, maxBuckets int) {
+	if math.IsNaN(value) || math.IsInf(value, 0) {
+		return
+	}
+
+	h.Count++
+	h.Sum += value
+
+	abs := math.Abs(value)
+	if abs <= h.ZeroThreshold {
+		h.ZeroCount++
+		return
+	}
+
+	b := &h.Positive
+	if value < 0 {
+		b = &h.Negative
+	}
+
+	idx := Index(abs, h.Scale)
+	if by := b.downscaleNeeded(idx, maxBuckets); by > 0 {
+		h.Downscale(h.Scale - by)
+		idx = Index(abs, h.Scale)
+	}
+	b.increment(idx)
+}
+
+// Downscale reduces the resolution of the histogram to the given scale by
+// merging neighbouring buckets. Histograms with a lower scale are unchanged.
+func (h *Histogram) Downscale(scale int32) {
+	if scale >= h.Scale {
+		return
+	}
+	by := h.Scale - scale
+	h.Positive.downscale(by)
+	h.Negative.downscale(by)
+	h.Scale = scale
+}
+
+// Index returns the index of the bucket of the positive value at the scale
+func Index(value float64, scale int32) int32 {
+	frac, exp := math.Frexp(value)
+	// Exact powers of two are the inclusive upper bound of their bucket
+	if frac == 0.5 {
+		if scale <= 0 {
+			return int32((exp - 2) >> uint(-scale))
+		}
+		return int32((exp-1)<<uint(scale)) - 1
+	}
+	if scale <= 0 {
+		return int32((exp - 1) >> uint(-scale))
+	}
+	return int32(math.Ceil(math.Log2(value)*math.Exp2(float64(scale)))) - 1
+}
+
+// Spans returns the populated buckets as Prometheus spans and the deltas
+// between the bucket counts. Prometheus bucket indices are shifted by one
+// relative to the OpenTelemetry convention.
+func (b *Buckets) Spans() ([]Span, []int64) {
+	var spans []Span
+	var deltas []int64
+	var previousCount int64
+	next := b.Offset + 1
+	for i, c := range b.Counts {
+		if c == 0 {
+			continue
+		}
+		idx := b.Offset + int32(i) + 1
+		if len(spans) > 0 && idx == next {
+			spans[len(spans)-1].Length++
+		} else {
+			offset := idx
+			if len(spans) > 0 {
+				offset = idx - next
+			}
+			spans = append(spans, Span{Offset: offset, Length: 1})
+		}
+		next = idx + 1
+		deltas = append(deltas, int64(c)-previousCount)
+		previousCount = int64(c)
+	}
+	return spans, deltas
+}
+
+func (b *Buckets) increment(idx int32) {
+	if len(b.Counts) == 0 {
+		b.Offset = idx
+		b.Counts = []uint64{1}
+		return
+	}
+	if idx < b.Offset {
+		counts := make([]uint64, int(b.Offset-idx)+len(b.Counts))
+		copy(counts[b.Offset-idx:], b.Counts)
+		b.Counts = counts
+		b.Offset = idx
+	}
+	for int(idx-b.Offset) >= len(b.Counts) {
+		b.Counts = append(b.Counts, 0)
+	}
+	b.Counts[idx-b.Offset]++
+}
+
+// downscaleNeeded returns the scale reduction required to fit the index
+func (b *Buckets) downscaleNeeded(idx int32, maxBuckets int) int32 {
+	if len(b.Counts) == 0 || maxBuckets <= 0 {
+		return 0
+	}
+	low, high := b.Offset, b.Offset+int32(len(b.Counts))-1
+	if idx < low {
+		low = idx
+	}
+	if idx > high {
+		high = idx
+	}
+	var by int32
+	for int(high-low)+1 > maxBuckets {
+		low >>= 1
+		high >>= 1
+		by++
+	}
+	return by
+}
+
+func (b *Buckets) downscale(by int32) {
+	if by <= 0 || len(b.Counts) == 0 {
+		return
+	}
+	start := b.Offset >> uint(by)
+	end := (b.Offset + int32(len(b.Counts)) - 1) >> uint(by)
+	counts := make([]uint64, end-start+1)
+	for i, c := range b.Counts {
+		counts[((b.Offset+int32(i))>>uint(by))-start] += c
+	}
+	b.Offset = start
+	b.Counts = counts
+}
+
+// AddFields adds the fields representing the histogram of the field
+func (h *Histogram) AddFields(fields map[string]interface{}, field string) {
+	fields[field+"_count"] = h.Count
+	fields[field+"_sum"] = h.Sum
+	fields[field+"_scale"] = int64(h.Scale)
+	fields[field+"_zero_threshold"] = h.ZeroThreshold
+	fields[field+"_zero_count"] = h.ZeroCount
+	for i, c := range h.Positive.Counts {
+		if c > 0 {
+			fields[field+"_positive_"+strconv.Itoa(int(h.Positive.Offset)+i)] = c
+		}
+	}
+	for i, c := range h.Negative.Counts {
+		if c > 0 {
+			fields[field+"_negative_"+strconv.Itoa(int(h.Negative.Offset)+i)] = c
+		}
+	}
+}
+
+// FromMetric returns the exponential histograms of the metric by field
+// together with the keys of the fields representing them. Metrics not of
+// the histogram type never contain exponential histograms.
+func FromMetric(m telegraf.Metric) (map[string]*Histogram, map[string]bool) {
+	if m.Type() != telegraf.Histogram {
+		return nil, nil
+	}
+
+	var histograms map[string]*Histogram
+	for _, f := range m.FieldList() {
+		if !strings.HasSuffix(f.Key, "_scale") {
+			continue
+		}
+		scale, ok := f.Value.(int64)
+		if !ok || scale < MinScale || scale > MaxScale {
+			continue
+		}
+		if histograms == nil {
+			histograms = make(map[string]*Histogram)
+		}
+		histograms[strings.TrimSuffix(f.Key, "_scale")] = &Histogram{Scale: int32(scale)}
+	}
+	if len(histograms) == 0 {
+		return nil, nil
+	}
+
+	positive := make(map[string]map[int32]uint64)
+	negative := make(map[string]map[int32]uint64)
+	used := make(map[string]bool)
+	for _, f := range m.FieldList() {
+		name, suffix, h := split(f.Key, histograms)
+		if h == nil {
+			continue
+		}
+		used[f.Key] = true
+
+		switch suffix {
+		case "scale":
+		case "sum":
+			h.Sum, _ = toFloat(f.Value)
+		case "zero_threshold":
+			h.ZeroThreshold, _ = toFloat(f.Value)
+		case "count":
+			h.Count, _ = toUint(f.Value)
+		case "zero_count":
+			h.ZeroCount, _ = toUint(f.Value)
+		default:
+			buckets := positive
+			idx := strings.TrimPrefix(suffix, "positive_")
+			if strings.HasPrefix(suffix, "negative_") {
+				buckets = negative
+				idx = strings.TrimPrefix(suffix, "negative_")
+			}
+			i, err := strconv.ParseInt(idx, 10, 32)
+			c, ok := toUint(f.Value)
+			if err != nil || !ok {
+				continue
+			}
+			if buckets[name] == nil {
+				buckets[name] = make(map[int32]uint64)
+			}
+			buckets[name][int32(i)] = c
+		}
+	}
+
+	for name, h := range histograms {
+		h.Positive = fromSparse(positive[name])
+		h.Negative = fromSparse(negative[name])
+	}
+	return histograms, used
+}
+
+// split returns the histogram field name and the suffix of a field key
+// belonging to one of the histograms
+func split(key string, histograms map[string]*Histogram) (string, string, *Histogram) {
+	for _, suffix := range []string{"_zero_threshold", "_zero_count", "_scale", "_count", "_sum"} {
+		if name := strings.TrimSuffix(key, suffix); name != key {
+			if h, ok := histograms[name]; ok {
+				return name, suffix[1:], h
+			}
+		}
+	}
+	for _, sep := range []string{"_positive_", "_negative_"} {
+		if i := strings.LastIndex(key, sep); i > 0 {
+			if h, ok := histograms[key[:i]]; ok {
+				return key[:i], key[i+1:], h
+			}
+		}
+	}
+	return "", "", nil
+}
+
+func fromSparse(counts map[int32]uint64) Buckets {
+	if len(counts) == 0 {
+		return Buckets{}
+	}
+	low, high := int32(math.MaxInt32), int32(math.MinInt32)
+	for i := range counts {
+		if i < low {
+			low = i
+		}
+		if i > high {
+			high = i
+		}
+	}
+	b := Buckets{Offset: low, Counts: make([]uint64, high-low+1)}
+	for i, c := range counts {
+		b.Counts[i-low] = c
+	}
+	return b
+}
+
+func toFloat(value interface{}) (float64, bool) {
+	switch v := value.(type) {
+	case float64:
+		return v, true
+	case int64:
+		return float64(v), true
+	case uint64:
+		return float64(v), true
+	}
+	return 0, false
+}
+
+func toUint(value interface{}) (uint64, bool) {
+	switch v := value.(type) {
+	case uint64:
+		return v, true
+	case int64:
+		if v >= 0 {
+			return uint64(v), true
+		}
+	case float64:
+		if v >= 0 {
+			return uint64(v), true
+		}
+	}
+	return 0, false
+}
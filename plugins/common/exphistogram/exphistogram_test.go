@@ -0,0 +1,121 @@
+package exphistogram
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/metric"
+)
+
+func TestIndex(t *testing.T) {
+	tests := []struct {
+		value    float64
+		scale    int32
+		expected int32
+	}{
+		{1, 0, -1},
+		{1.5, 0, 0},
+		{2, 0, 0},
+		{3, 0, 1},
+		{4, 0, 1},
+		{0.3, 0, -2},
+		{4, 1, 3},
+		{5, 1, 4},
+		{3, -1, 0},
+		{4, -1, 0},
+		{5, -1, 1},
+		{0.3, -1, -1},
+		{1.3, 1, 0},
+		{1.5, 1, 1},
+	}
+	for _, tt := range tests {
+		require.Equal(t, tt.expected, Index(tt.value, tt.scale), "value %v at scale %d", tt.value, tt.scale)
+	}
+}
+
+func TestRecord(t *testing.T) {
+	h := New(0, 0.5)
+	for _, v := range []float64{0, 0.25, 1.5, 3, 4, 100, -3, math.NaN()} {
+		h.Record(v, 160)
+	}
+
+	require.Equal(t, uint64(7), h.Count)
+	require.Equal(t, 105.75, h.Sum)
+	require.Equal(t, uint64(2), h.ZeroCount)
+	require.Equal(t, Buckets{Offset: 0, Counts: []uint64{1, 2, 0, 0, 0, 0, 1}}, h.Positive)
+	require.Equal(t, Buckets{Offset: 1, Counts: []uint64{1}}, h.Negative)
+}
+
+func TestRecordDownscale(t *testing.T) {
+	h := New(2, 0)
+	for _, v := range []float64{1.1, 1.5, 2, 3, 6, 7, 8, 100} {
+		h.Record(v, 4)
+	}
+
+	require.Equal(t, int32(-1), h.Scale)
+	require.Equal(t, uint64(8), h.Count)
+	require.Equal(t, Buckets{Offset: 0, Counts: []uint64{4, 3, 0, 1}}, h.Positive)
+}
+
+func TestDownscale(t *testing.T) {
+	h := &Histogram{
+		Scale:    1,
+		Positive: Buckets{Offset: -1, Counts: []uint64{1, 2, 3, 4}},
+		Negative: Buckets{Offset: 4, Counts: []uint64{5}},
+	}
+	h.Downscale(0)
+
+	require.Equal(t, int32(0), h.Scale)
+	require.Equal(t, Buckets{Offset: -1, Counts: []uint64{1, 5, 4}}, h.Positive)
+	require.Equal(t, Buckets{Offset: 2, Counts: []uint64{5}}, h.Negative)
+}
+
+func TestSpans(t *testing.T) {
+	b := Buckets{Offset: -2, Counts: []uint64{2, 3, 0, 0, 1, 1}}
+	spans, deltas := b.Spans()
+
+	require.Equal(t, []Span{{Offset: -1, Length: 2}, {Offset: 2, Length: 2}}, spans)
+	require.Equal(t, []int64{2, 1, -2, 0}, deltas)
+}
+
+func TestFields(t *testing.T) {
+	h := &Histogram{
+		Scale:         3,
+		ZeroThreshold: 0.001,
+		ZeroCount:     1,
+		Count:         6,
+		Sum:           12.5,
+		Positive:      Buckets{Offset: -1, Counts: []uint64{2, 0, 2}},
+		Negative:      Buckets{Offset: 5, Counts: []uint64{1}},
+	}
+
+	fields := map[string]interface{}{"other": 1.0}
+	h.AddFields(fields, "latency")
+	require.Equal(t, map[string]interface{}{
+		"other":                  1.0,
+		"latency_count":          uint64(6),
+		"latency_sum":            12.5,
+		"latency_scale":          int64(3),
+		"latency_zero_threshold": 0.001,
+		"latency_zero_count":     uint64(1),
+		"latency_positive_-1":    uint64(2),
+		"latency_positive_1":     uint64(2),
+		"latency_negative_5":     uint64(1),
+	}, fields)
+
+	m := metric.New("http", map[string]string{}, fields, time.Unix(0, 0), telegraf.Histogram)
+	histograms, used := FromMetric(m)
+	require.Equal(t, map[string]*Histogram{"latency": h}, histograms)
+	require.Len(t, used, len(fields)-1)
+	require.False(t, used["other"])
+}
+
+func TestFromMetricIgnoresOtherTypes(t *testing.T) {
+	m := metric.New("http", map[string]string{}, map[string]interface{}{"latency_scale": int64(0)}, time.Unix(0, 0))
+	histograms, _ := FromMetric(m)
+	require.Nil(t, histograms)
+}
@@ -6,27 +6,59 @@ import (
 
 	"golang.org/x/oauth2"
 	"golang.org/x/oauth2/clientcredentials"
+
+	"github.com/influxdata/telegraf/config"
 )
 
 type OAuth2Config struct {
 	// OAuth2 Credentials
-	ClientID     string   `toml:"client_id"`
-	ClientSecret string   `toml:"client_secret"`
-	TokenURL     string   `toml:"token_url"`
-	Scopes       []string `toml:"scopes"`
+	ClientID     string        `toml:"client_id"`
+	ClientSecret config.Secret `toml:"client_secret"`
+	TokenURL     string        `toml:"token_url"`
+	Scopes       []string      `toml:"scopes"`
 }
 
 func (o *OAuth2Config) CreateOauth2Client(ctx context.Context, client *http.Client) *http.Client {
-	if o.ClientID != "" && o.ClientSecret != "" && o.TokenURL != "" {
+	if o.ClientID != "" && !o.ClientSecret.Empty() && o.TokenURL != "" {
 		oauthConfig := clientcredentials.Config{
-			ClientID:     o.ClientID,
-			ClientSecret: o.ClientSecret,
-			TokenURL:     o.TokenURL,
-			Scopes:       o.Scopes,
+			ClientID: o.ClientID,
+			TokenURL: o.TokenURL,
+			Scopes:   o.Scopes,
 		}
 		ctx = context.WithValue(ctx, oauth2.HTTPClient, client)
-		client = oauthConfig.Client(ctx)
+		client = oauth2.NewClient(ctx, NewSecretTokenSource(ctx, oauthConfig, o.ClientSecret))
 	}
 
 	return client
 }
+
+// secretTokenSource requests tokens using the client-credentials flow and
+// resolves the client secret only when a new token is needed.
+type secretTokenSource struct {
+	ctx    context.Context
+	config clientcredentials.Config
+	secret config.Secret
+}
+
+// NewSecretTokenSource returns a token source for the client-credentials flow
+// resolving the client secret each time a token is fetched.  Tokens are
+// reused until they expire.
+func NewSecretTokenSource(ctx context.Context, cfg clientcredentials.Config, secret config.Secret) oauth2.TokenSource {
+	return oauth2.ReuseTokenSource(nil, &secretTokenSource{
+		ctx:    ctx,
+		config: cfg,
+		secret: secret,
+	})
+}
+
+func (s *secretTokenSource) Token() (*oauth2.Token, error) {
+	secret, err := s.secret.Get()
+	if err != nil {
+		return nil, err
+	}
+	defer config.ReleaseSecret(secret)
+
+	cfg := s.config
+	cfg.ClientSecret = string(secret)
+	return cfg.Token(s.ctx)
+}
@@ -0,0 +1,109 @@
+package protobuf
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/jhump/protoreflect/desc"
+	"github.com/jhump/protoreflect/desc/protoparse"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+// Descriptor configures where to load message definitions from
+type Descriptor struct {
+	Files         []string `toml:"protobuf_files"`
+	ImportPaths   []string `toml:"protobuf_import_paths"`
+	DescriptorSet string   `toml:"protobuf_descriptor_set"`
+	MessageType   string   `toml:"protobuf_message_type"`
+}
+
+// Load returns the descriptor of the configured message type. Definitions
+// are kept in a private registry to not interfere with other plugins loading
+// the same files.
+func (d *Descriptor) Load() (protoreflect.MessageDescriptor, error) {
+	if d.MessageType == "" {
+		return nil, errors.New("`protobuf_message_type` must be specified")
+	}
+
+	var set *descriptorpb.FileDescriptorSet
+	var err error
+	switch {
+	case len(d.Files) > 0 && d.DescriptorSet != "":
+		return nil, errors.New("`protobuf_files` and `protobuf_descriptor_set` are mutually exclusive")
+	case len(d.Files) > 0:
+		set, err = d.parseFiles()
+	case d.DescriptorSet != "":
+		set, err = d.readDescriptorSet()
+	default:
+		return nil, errors.New("either `protobuf_files` or `protobuf_descriptor_set` must be specified")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	files, err := protodesc.NewFiles(set)
+	if err != nil {
+		return nil, fmt.Errorf("creating file descriptors failed: %w", err)
+	}
+
+	found, err := files.FindDescriptorByName(protoreflect.FullName(d.MessageType))
+	if err != nil {
+		if errors.Is(err, protoregistry.NotFound) {
+			return nil, fmt.Errorf("message type %q not found", d.MessageType)
+		}
+		return nil, fmt.Errorf("looking up message type %q failed: %w", d.MessageType, err)
+	}
+	msg, ok := found.(protoreflect.MessageDescriptor)
+	if !ok {
+		return nil, fmt.Errorf("%q is not a message descriptor (%T)", d.MessageType, found)
+	}
+
+	return msg, nil
+}
+
+func (d *Descriptor) parseFiles() (*descriptorpb.FileDescriptorSet, error) {
+	parser := protoparse.Parser{ImportPaths: d.ImportPaths}
+	fds, err := parser.ParseFiles(d.Files...)
+	if err != nil {
+		return nil, fmt.Errorf("parsing protocol-buffer definitions failed: %w", err)
+	}
+
+	// Collect all files including their dependencies in dependency order
+	set := &descriptorpb.FileDescriptorSet{}
+	seen := make(map[string]bool)
+	var add func(fd *desc.FileDescriptor)
+	add = func(fd *desc.FileDescriptor) {
+		if fd == nil || seen[fd.GetName()] {
+			return
+		}
+		seen[fd.GetName()] = true
+		for _, dep := range fd.GetDependencies() {
+			add(dep)
+		}
+		set.File = append(set.File, fd.AsFileDescriptorProto())
+	}
+	for _, fd := range fds {
+		add(fd)
+	}
+
+	return set, nil
+}
+
+func (d *Descriptor) readDescriptorSet() (*descriptorpb.FileDescriptorSet, error) {
+	buf, err := os.ReadFile(d.DescriptorSet)
+	if err != nil {
+		return nil, fmt.Errorf("reading descriptor set failed: %w", err)
+	}
+
+	set := &descriptorpb.FileDescriptorSet{}
+	if err := proto.Unmarshal(buf, set); err != nil {
+		return nil, fmt.Errorf("decoding descriptor set %q failed: %w", d.DescriptorSet, err)
+	}
+
+	return set, nil
+}
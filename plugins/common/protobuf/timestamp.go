@@ -0,0 +1,34 @@
+package protobuf
+
+import (
+	"time"
+
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+const timestampName protoreflect.FullName = "google.protobuf.Timestamp"
+
+// IsTimestamp checks if the message descriptor describes a well-known
+// google.protobuf.Timestamp
+func IsTimestamp(md protoreflect.MessageDescriptor) bool {
+	return md != nil && md.FullName() == timestampName
+}
+
+// TimeFromMessage converts a google.protobuf.Timestamp message to time
+func TimeFromMessage(msg protoreflect.Message) (time.Time, bool) {
+	md := msg.Descriptor()
+	if !IsTimestamp(md) {
+		return time.Time{}, false
+	}
+
+	seconds := msg.Get(md.Fields().ByName("seconds")).Int()
+	nanos := msg.Get(md.Fields().ByName("nanos")).Int()
+	return time.Unix(seconds, nanos).UTC(), true
+}
+
+// SetTime fills a google.protobuf.Timestamp message with the given time
+func SetTime(msg protoreflect.Message, t time.Time) {
+	md := msg.Descriptor()
+	msg.Set(md.Fields().ByName("seconds"), protoreflect.ValueOfInt64(t.Unix()))
+	msg.Set(md.Fields().ByName("nanos"), protoreflect.ValueOfInt32(int32(t.Nanosecond())))
+}
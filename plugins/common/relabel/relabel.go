@@ -0,0 +1,233 @@
+package relabel
+
+import (
+	"crypto/md5"
+	"encoding/binary"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/influxdata/telegraf"
+)
+
+// NameLabel is the pseudo-label holding the metric name
+const NameLabel = "__name__"
+
+const (
+	defaultSeparator   = ";"
+	defaultRegex       = "(.*)"
+	defaultReplacement = "$1"
+)
+
+var (
+	labelName   = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*$`)
+	labelTarget = regexp.MustCompile(`^(?:(?:[a-zA-Z_]|\$(?:\{\w+\}|\w+))+\w*)+$`)
+)
+
+// Config is a relabelling rule with the options and semantics of the
+// relabel_configs of Prometheus.
+type Config struct {
+	// SourceLabels are joined by the Separator to the value matched by the
+	// Regex. Missing labels are treated as empty.
+	SourceLabels []string `toml:"source_labels"`
+	Separator    *string  `toml:"separator"`
+	// Regex is anchored at both ends.
+	Regex *string `toml:"regex"`
+	// TargetLabel is set by the replace and hashmod actions.
+	TargetLabel string `toml:"target_label"`
+	// Replacement may refer to the capture groups of the Regex.
+	Replacement *string `toml:"replacement"`
+	// Modulus of the hash used by the hashmod action.
+	Modulus uint64 `toml:"modulus"`
+	// Action is one of replace, keep, drop, labelmap, labeldrop, labelkeep
+	// or hashmod; defaults to replace.
+	Action string `toml:"action"`
+
+	separator   string
+	regex       *regexp.Regexp
+	replacement string
+}
+
+// Init validates the rule and sets the defaults.
+func (c *Config) Init() error {
+	c.Action = strings.ToLower(c.Action)
+	if c.Action == "" {
+		c.Action = "replace"
+	}
+
+	c.separator = defaultSeparator
+	if c.Separator != nil {
+		c.separator = *c.Separator
+	}
+	c.replacement = defaultReplacement
+	if c.Replacement != nil {
+		c.replacement = *c.Replacement
+	}
+	expr := defaultRegex
+	if c.Regex != nil {
+		expr = *c.Regex
+	}
+	regex, err := regexp.Compile("^(?:" + expr + ")$")
+	if err != nil {
+		return fmt.Errorf("invalid regex %q: %w", expr, err)
+	}
+	c.regex = regex
+
+	switch c.Action {
+	case "replace":
+		if c.TargetLabel == "" {
+			return fmt.Errorf("action %q requires target_label", c.Action)
+		}
+		if !labelTarget.MatchString(c.TargetLabel) {
+			return fmt.Errorf("invalid target_label %q for action %q", c.TargetLabel, c.Action)
+		}
+	case "hashmod":
+		if c.TargetLabel == "" {
+			return fmt.Errorf("action %q requires target_label", c.Action)
+		}
+		if !labelName.MatchString(c.TargetLabel) {
+			return fmt.Errorf("invalid target_label %q for action %q", c.TargetLabel, c.Action)
+		}
+		if c.Modulus == 0 {
+			return fmt.Errorf("action %q requires a non-zero modulus", c.Action)
+		}
+	case "labelmap":
+		if !labelTarget.MatchString(c.replacement) {
+			return fmt.Errorf("invalid replacement %q for action %q", c.replacement, c.Action)
+		}
+	case "labeldrop", "labelkeep":
+		if len(c.SourceLabels) > 0 || c.TargetLabel != "" || c.Modulus != 0 || c.separator != defaultSeparator || c.replacement != defaultReplacement {
+			return fmt.Errorf("action %q only supports regex", c.Action)
+		}
+	case "keep", "drop":
+	default:
+		return fmt.Errorf("invalid action %q", c.Action)
+	}
+	return nil
+}
+
+// Apply relabels the labels in place by the rules in order. It returns false
+// if the labels are dropped by a keep or drop rule.
+func Apply(rules []*Config, labels map[string]string) bool {
+	for _, c := range rules {
+		if !c.apply(labels) {
+			return false
+		}
+	}
+	return true
+}
+
+func (c *Config) apply(labels map[string]string) bool {
+	values := make([]string, 0, len(c.SourceLabels))
+	for _, name := range c.SourceLabels {
+		values = append(values, labels[name])
+	}
+	value := strings.Join(values, c.separator)
+
+	switch c.Action {
+	case "replace":
+		indexes := c.regex.FindStringSubmatchIndex(value)
+		if indexes == nil {
+			break
+		}
+		target := string(c.regex.ExpandString(nil, c.TargetLabel, value, indexes))
+		if !labelName.MatchString(target) {
+			break
+		}
+		set(labels, target, string(c.regex.ExpandString(nil, c.replacement, value, indexes)))
+	case "keep":
+		if !c.regex.MatchString(value) {
+			return false
+		}
+	case "drop":
+		if c.regex.MatchString(value) {
+			return false
+		}
+	case "hashmod":
+		// Same hash as Prometheus to shard targets identically
+		sum := md5.Sum([]byte(value))
+		mod := binary.BigEndian.Uint64(sum[8:]) % c.Modulus
+		set(labels, c.TargetLabel, strconv.FormatUint(mod, 10))
+	case "labelmap":
+		// Map the labels as present before the rule
+		original := make(map[string]string, len(labels))
+		for name, value := range labels {
+			original[name] = value
+		}
+		for _, name := range sortedNames(original) {
+			if c.regex.MatchString(name) {
+				set(labels, c.regex.ReplaceAllString(name, c.replacement), original[name])
+			}
+		}
+	case "labeldrop":
+		for name := range labels {
+			if c.regex.MatchString(name) {
+				delete(labels, name)
+			}
+		}
+	case "labelkeep":
+		for name := range labels {
+			if !c.regex.MatchString(name) {
+				delete(labels, name)
+			}
+		}
+	}
+	return true
+}
+
+// set sets the label, removing it if the value is empty
+func set(labels map[string]string, name, value string) {
+	if value == "" {
+		delete(labels, name)
+		return
+	}
+	labels[name] = value
+}
+
+func sortedNames(labels map[string]string) []string {
+	names := make([]string, 0, len(labels))
+	for name := range labels {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// ApplyMetric relabels the tags and the name of the metric, the name being
+// available as the __name__ label. It returns false if the metric is dropped
+// by a rule or its name is removed.
+func ApplyMetric(rules []*Config, m telegraf.Metric) bool {
+	labels := make(map[string]string, len(m.TagList())+1)
+	for _, tag := range m.TagList() {
+		labels[tag.Key] = tag.Value
+	}
+	labels[NameLabel] = m.Name()
+
+	if !Apply(rules, labels) {
+		return false
+	}
+	name, ok := labels[NameLabel]
+	if !ok {
+		return false
+	}
+	delete(labels, NameLabel)
+
+	m.SetName(name)
+	var removed []string
+	for _, tag := range m.TagList() {
+		if _, ok := labels[tag.Key]; !ok {
+			removed = append(removed, tag.Key)
+		}
+	}
+	for _, key := range removed {
+		m.RemoveTag(key)
+	}
+	for k, v := range labels {
+		if current, ok := m.GetTag(k); !ok || current != v {
+			m.AddTag(k, v)
+		}
+	}
+	return true
+}
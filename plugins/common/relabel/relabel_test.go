@@ -0,0 +1,262 @@
+package relabel
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/influxdata/telegraf/testutil"
+)
+
+func str(s string) *string {
+	return &s
+}
+
+func TestApply(t *testing.T) {
+	tests := []struct {
+		name     string
+		rules    []*Config
+		input    map[string]string
+		expected map[string]string
+	}{
+		{
+			name: "replace",
+			rules: []*Config{
+				{SourceLabels: []string{"a"}, Regex: str("f(.*)"), TargetLabel: "d", Replacement: str("ch${1}-ch${1}")},
+			},
+			input:    map[string]string{"a": "foo", "b": "bar", "c": "baz"},
+			expected: map[string]string{"a": "foo", "b": "bar", "c": "baz", "d": "choo-choo"},
+		},
+		{
+			name: "replace multiple source labels",
+			rules: []*Config{
+				{SourceLabels: []string{"a", "b"}, Regex: str("f(.*);(.*)r"), TargetLabel: "a", Replacement: str("b${1}${2}m")},
+				{SourceLabels: []string{"c", "a"}, Regex: str("(b).*b(.*)ba(.*)"), TargetLabel: "d", Replacement: str("$1$2$2$3")},
+			},
+			input:    map[string]string{"a": "foo", "b": "bar", "c": "baz"},
+			expected: map[string]string{"a": "boobam", "b": "bar", "c": "baz", "d": "boooom"},
+		},
+		{
+			name: "replace without match",
+			rules: []*Config{
+				{SourceLabels: []string{"a"}, Regex: str("o(.*)"), TargetLabel: "d"},
+			},
+			input:    map[string]string{"a": "foo"},
+			expected: map[string]string{"a": "foo"},
+		},
+		{
+			name: "replace with empty result removes label",
+			rules: []*Config{
+				{SourceLabels: []string{"a"}, Regex: str("foo"), TargetLabel: "b", Replacement: str("")},
+			},
+			input:    map[string]string{"a": "foo", "b": "bar"},
+			expected: map[string]string{"a": "foo"},
+		},
+		{
+			name: "replace target from capture group",
+			rules: []*Config{
+				{SourceLabels: []string{"a"}, Regex: str("some-([^-]+)-([^,]+)"), TargetLabel: "${1}", Replacement: str("${2}")},
+			},
+			input:    map[string]string{"a": "some-name-value"},
+			expected: map[string]string{"a": "some-name-value", "name": "value"},
+		},
+		{
+			name: "replace invalid target",
+			rules: []*Config{
+				{SourceLabels: []string{"a"}, Regex: str("some-([^-]+)-([^,]+)"), TargetLabel: "${3}", Replacement: str("${1}")},
+			},
+			input:    map[string]string{"a": "some-name-value"},
+			expected: map[string]string{"a": "some-name-value"},
+		},
+		{
+			name: "replace missing source label",
+			rules: []*Config{
+				{SourceLabels: []string{"z"}, Regex: str(""), TargetLabel: "a", Replacement: str("empty")},
+			},
+			input:    map[string]string{"a": "foo"},
+			expected: map[string]string{"a": "empty"},
+		},
+		{
+			name: "keep",
+			rules: []*Config{
+				{SourceLabels: []string{"a"}, Regex: str("f.*"), Action: "keep"},
+			},
+			input:    map[string]string{"a": "foo"},
+			expected: map[string]string{"a": "foo"},
+		},
+		{
+			name: "keep without match",
+			rules: []*Config{
+				{SourceLabels: []string{"a"}, Regex: str("f"), Action: "keep"},
+			},
+			input: map[string]string{"a": "foo"},
+		},
+		{
+			name: "drop",
+			rules: []*Config{
+				{SourceLabels: []string{"a"}, Regex: str(".*o.*"), Action: "drop"},
+			},
+			input: map[string]string{"a": "foo"},
+		},
+		{
+			name: "drop without match",
+			rules: []*Config{
+				{SourceLabels: []string{"a"}, Regex: str("f"), Action: "drop"},
+			},
+			input:    map[string]string{"a": "foo"},
+			expected: map[string]string{"a": "foo"},
+		},
+		{
+			name: "hashmod",
+			rules: []*Config{
+				{SourceLabels: []string{"c"}, TargetLabel: "d", Modulus: 1000, Action: "hashmod"},
+			},
+			input:    map[string]string{"a": "foo", "b": "bar", "c": "baz"},
+			expected: map[string]string{"a": "foo", "b": "bar", "c": "baz", "d": "976"},
+		},
+		{
+			name: "labelmap",
+			rules: []*Config{
+				{Regex: str("__meta_(.+)"), Action: "labelmap"},
+			},
+			input:    map[string]string{"a": "foo", "__meta_b": "bar", "__meta_c": "baz"},
+			expected: map[string]string{"a": "foo", "b": "bar", "c": "baz", "__meta_b": "bar", "__meta_c": "baz"},
+		},
+		{
+			name: "labelmap overwrites labels",
+			rules: []*Config{
+				{Regex: str("(a|b)"), Replacement: str("${1}_copy"), Action: "labelmap"},
+				{Regex: str("a"), Replacement: str("b"), Action: "labelmap"},
+			},
+			input:    map[string]string{"a": "foo", "b": "bar"},
+			expected: map[string]string{"a": "foo", "b": "foo", "a_copy": "foo", "b_copy": "bar"},
+		},
+		{
+			name: "labeldrop",
+			rules: []*Config{
+				{Regex: str("a|b"), Action: "labeldrop"},
+			},
+			input:    map[string]string{"a": "foo", "b": "bar", "c": "baz"},
+			expected: map[string]string{"c": "baz"},
+		},
+		{
+			name: "labelkeep",
+			rules: []*Config{
+				{Regex: str("a|b"), Action: "labelkeep"},
+			},
+			input:    map[string]string{"a": "foo", "b": "bar", "c": "baz"},
+			expected: map[string]string{"a": "foo", "b": "bar"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, rule := range tt.rules {
+				require.NoError(t, rule.Init())
+			}
+			kept := Apply(tt.rules, tt.input)
+			if tt.expected == nil {
+				require.False(t, kept)
+				return
+			}
+			require.True(t, kept)
+			require.Equal(t, tt.expected, tt.input)
+		})
+	}
+}
+
+func TestApplyMetric(t *testing.T) {
+	rules := []*Config{
+		{SourceLabels: []string{NameLabel}, Regex: str("go_.*"), Action: "drop"},
+		{SourceLabels: []string{NameLabel, "code"}, Regex: str("(http_requests)_total;5.."), TargetLabel: NameLabel, Replacement: str("${1}_errors")},
+		{Regex: str("instance"), Action: "labeldrop"},
+	}
+	for _, rule := range rules {
+		require.NoError(t, rule.Init())
+	}
+
+	m := testutil.MustMetric(
+		"http_requests_total",
+		map[string]string{"code": "503", "instance": "a:9100"},
+		map[string]interface{}{"counter": 1.0},
+		time.Unix(0, 0),
+	)
+	require.True(t, ApplyMetric(rules, m))
+	expected := testutil.MustMetric(
+		"http_requests_errors",
+		map[string]string{"code": "503"},
+		map[string]interface{}{"counter": 1.0},
+		time.Unix(0, 0),
+	)
+	testutil.RequireMetricEqual(t, expected, m)
+
+	m = testutil.MustMetric("go_goroutines", map[string]string{}, map[string]interface{}{"gauge": 1.0}, time.Unix(0, 0))
+	require.False(t, ApplyMetric(rules, m))
+
+	// Metrics without name are dropped
+	rules = []*Config{{Regex: str(NameLabel), Action: "labeldrop"}}
+	require.NoError(t, rules[0].Init())
+	m = testutil.MustMetric("cpu", map[string]string{}, map[string]interface{}{"value": 1.0}, time.Unix(0, 0))
+	require.False(t, ApplyMetric(rules, m))
+}
+
+func TestInitErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		rule     *Config
+		expected string
+	}{
+		{
+			name:     "invalid action",
+			rule:     &Config{Action: "rename"},
+			expected: `invalid action "rename"`,
+		},
+		{
+			name:     "invalid regex",
+			rule:     &Config{Regex: str("("), TargetLabel: "a"},
+			expected: "invalid regex \"(\": error parsing regexp: missing closing ): `^(?:()$`",
+		},
+		{
+			name:     "replace without target",
+			rule:     &Config{},
+			expected: `action "replace" requires target_label`,
+		},
+		{
+			name:     "replace with invalid target",
+			rule:     &Config{TargetLabel: "0a"},
+			expected: `invalid target_label "0a" for action "replace"`,
+		},
+		{
+			name:     "hashmod without modulus",
+			rule:     &Config{TargetLabel: "shard", Action: "hashmod"},
+			expected: `action "hashmod" requires a non-zero modulus`,
+		},
+		{
+			name:     "hashmod with template target",
+			rule:     &Config{TargetLabel: "${1}", Modulus: 2, Action: "hashmod"},
+			expected: `invalid target_label "${1}" for action "hashmod"`,
+		},
+		{
+			name:     "labelmap with invalid replacement",
+			rule:     &Config{Replacement: str("a-b"), Action: "labelmap"},
+			expected: `invalid replacement "a-b" for action "labelmap"`,
+		},
+		{
+			name:     "labeldrop with source labels",
+			rule:     &Config{SourceLabels: []string{"a"}, Action: "labeldrop"},
+			expected: `action "labeldrop" only supports regex`,
+		},
+		{
+			name:     "labelkeep with replacement",
+			rule:     &Config{Replacement: str("$2"), Action: "LabelKeep"},
+			expected: `action "labelkeep" only supports regex`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.EqualError(t, tt.rule.Init(), tt.expected)
+		})
+	}
+}
@@ -11,6 +11,7 @@ import (
 
 	"github.com/stretchr/testify/require"
 
+	"github.com/influxdata/telegraf/config"
 	httpconfig "github.com/influxdata/telegraf/plugins/common/http"
 	"github.com/influxdata/telegraf/plugins/common/oauth"
 	httpplugin "github.com/influxdata/telegraf/plugins/inputs/http"
@@ -301,7 +302,7 @@ func TestOAuthClientCredentialsGrant(t *testing.T) {
 				HTTPClientConfig: httpconfig.HTTPClientConfig{
 					OAuth2Config: oauth.OAuth2Config{
 						ClientID:     "howdy",
-						ClientSecret: "secret",
+						ClientSecret: config.NewSecret("secret"),
 						TokenURL:     u.String() + "/token",
 						Scopes:       []string{"urn:opc:idm:__myscopes__"},
 					},
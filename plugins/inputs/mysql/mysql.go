@@ -11,6 +11,7 @@ import (
 	"github.com/go-sql-driver/mysql"
 
 	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/config"
 	"github.com/influxdata/telegraf/plugins/common/tls"
 	"github.com/influxdata/telegraf/plugins/inputs"
 	v1 "github.com/influxdata/telegraf/plugins/inputs/mysql/v1"
@@ -18,31 +19,31 @@ import (
 )
 
 type Mysql struct {
-	Servers                             []string `toml:"servers"`
-	PerfEventsStatementsDigestTextLimit int64    `toml:"perf_events_statements_digest_text_limit"`
-	PerfEventsStatementsLimit           int64    `toml:"perf_events_statements_limit"`
-	PerfEventsStatementsTimeLimit       int64    `toml:"perf_events_statements_time_limit"`
-	TableSchemaDatabases                []string `toml:"table_schema_databases"`
-	GatherProcessList                   bool     `toml:"gather_process_list"`
-	GatherUserStatistics                bool     `toml:"gather_user_statistics"`
-	GatherInfoSchemaAutoInc             bool     `toml:"gather_info_schema_auto_inc"`
-	GatherInnoDBMetrics                 bool     `toml:"gather_innodb_metrics"`
-	GatherSlaveStatus                   bool     `toml:"gather_slave_status"`
-	GatherAllSlaveChannels              bool     `toml:"gather_all_slave_channels"`
-	MariadbDialect                      bool     `toml:"mariadb_dialect"`
-	GatherBinaryLogs                    bool     `toml:"gather_binary_logs"`
-	GatherTableIOWaits                  bool     `toml:"gather_table_io_waits"`
-	GatherTableLockWaits                bool     `toml:"gather_table_lock_waits"`
-	GatherIndexIOWaits                  bool     `toml:"gather_index_io_waits"`
-	GatherEventWaits                    bool     `toml:"gather_event_waits"`
-	GatherTableSchema                   bool     `toml:"gather_table_schema"`
-	GatherFileEventsStats               bool     `toml:"gather_file_events_stats"`
-	GatherPerfEventsStatements          bool     `toml:"gather_perf_events_statements"`
-	GatherGlobalVars                    bool     `toml:"gather_global_variables"`
-	GatherPerfSummaryPerAccountPerEvent bool     `toml:"gather_perf_sum_per_acc_per_event"`
-	PerfSummaryEvents                   []string `toml:"perf_summary_events"`
-	IntervalSlow                        string   `toml:"interval_slow"`
-	MetricVersion                       int      `toml:"metric_version"`
+	Servers                             []config.Secret `toml:"servers"`
+	PerfEventsStatementsDigestTextLimit int64           `toml:"perf_events_statements_digest_text_limit"`
+	PerfEventsStatementsLimit           int64           `toml:"perf_events_statements_limit"`
+	PerfEventsStatementsTimeLimit       int64           `toml:"perf_events_statements_time_limit"`
+	TableSchemaDatabases                []string        `toml:"table_schema_databases"`
+	GatherProcessList                   bool            `toml:"gather_process_list"`
+	GatherUserStatistics                bool            `toml:"gather_user_statistics"`
+	GatherInfoSchemaAutoInc             bool            `toml:"gather_info_schema_auto_inc"`
+	GatherInnoDBMetrics                 bool            `toml:"gather_innodb_metrics"`
+	GatherSlaveStatus                   bool            `toml:"gather_slave_status"`
+	GatherAllSlaveChannels              bool            `toml:"gather_all_slave_channels"`
+	MariadbDialect                      bool            `toml:"mariadb_dialect"`
+	GatherBinaryLogs                    bool            `toml:"gather_binary_logs"`
+	GatherTableIOWaits                  bool            `toml:"gather_table_io_waits"`
+	GatherTableLockWaits                bool            `toml:"gather_table_lock_waits"`
+	GatherIndexIOWaits                  bool            `toml:"gather_index_io_waits"`
+	GatherEventWaits                    bool            `toml:"gather_event_waits"`
+	GatherTableSchema                   bool            `toml:"gather_table_schema"`
+	GatherFileEventsStats               bool            `toml:"gather_file_events_stats"`
+	GatherPerfEventsStatements          bool            `toml:"gather_perf_events_statements"`
+	GatherGlobalVars                    bool            `toml:"gather_global_variables"`
+	GatherPerfSummaryPerAccountPerEvent bool            `toml:"gather_perf_sum_per_acc_per_event"`
+	PerfSummaryEvents                   []string        `toml:"perf_summary_events"`
+	IntervalSlow                        string          `toml:"interval_slow"`
+	MetricVersion                       int             `toml:"metric_version"`
 
 	Log telegraf.Logger `toml:"-"`
 	tls.ClientConfig
@@ -59,6 +60,8 @@ const sampleConfig = `
   ##  e.g.
   ##    servers = ["user:passwd@tcp(127.0.0.1:3306)/?tls=false"]
   ##    servers = ["user@tcp(127.0.0.1:3306)/?tls=false"]
+  ##  servers can reference secrets in a secret-store, e.g.
+  ##    servers = ["@{vault:mysql_dsn}"]
   #
   ## If no servers are specified, then localhost is used as the host.
   servers = ["tcp(127.0.0.1:3306)/"]
@@ -208,11 +211,17 @@ func (m *Mysql) Gather(acc telegraf.Accumulator) error {
 
 	// Loop through each server and collect metrics
 	for _, server := range m.Servers {
+		dsn, err := server.Get()
+		if err != nil {
+			acc.AddError(fmt.Errorf("getting server DSN: %w", err))
+			continue
+		}
 		wg.Add(1)
 		go func(s string) {
 			defer wg.Done()
 			acc.AddError(m.gatherServer(s, acc))
-		}(server)
+		}(string(dsn))
+		config.ReleaseSecret(dsn)
 	}
 
 	wg.Wait()
@@ -6,6 +6,7 @@ import (
 
 	"github.com/stretchr/testify/require"
 
+	"github.com/influxdata/telegraf/config"
 	"github.com/influxdata/telegraf/testutil"
 )
 
@@ -15,7 +16,7 @@ func TestMysqlDefaultsToLocalIntegration(t *testing.T) {
 	}
 
 	m := &Mysql{
-		Servers: []string{fmt.Sprintf("root@tcp(%s:3306)/", testutil.GetLocalHost())},
+		Servers: []config.Secret{config.NewSecret(fmt.Sprintf("root@tcp(%s:3306)/", testutil.GetLocalHost()))},
 	}
 
 	var acc testutil.Accumulator
@@ -33,7 +34,7 @@ func TestMysqlMultipleInstancesIntegration(t *testing.T) {
 	}
 	testServer := "root@tcp(127.0.0.1:3306)/?tls=false"
 	m := &Mysql{
-		Servers:          []string{testServer},
+		Servers:          []config.Secret{config.NewSecret(testServer)},
 		IntervalSlow:     "30s",
 		GatherGlobalVars: true,
 		MetricVersion:    2,
@@ -47,7 +48,7 @@ func TestMysqlMultipleInstancesIntegration(t *testing.T) {
 	require.True(t, acc.HasMeasurement("mysql_variables"))
 
 	m2 := &Mysql{
-		Servers:       []string{testServer},
+		Servers:       []config.Secret{config.NewSecret(testServer)},
 		MetricVersion: 2,
 	}
 	err = m2.Gather(&acc2)
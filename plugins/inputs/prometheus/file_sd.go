@@ -0,0 +1,227 @@
+package prometheus
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ghodss/yaml"
+	"gopkg.in/fsnotify.v1"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/config"
+	"github.com/influxdata/telegraf/plugins/common/relabel"
+)
+
+// FileSDConfig discovers targets from files in the format of the Prometheus
+// file_sd_configs.
+type FileSDConfig struct {
+	// Files are glob patterns of JSON or YAML files.
+	Files []string `toml:"files"`
+	// RefreshInterval to re-read the files in addition to watching them.
+	RefreshInterval config.Duration `toml:"refresh_interval"`
+	// Scheme and MetricsPath of the targets without __scheme__ and
+	// __metrics_path__ labels.
+	Scheme      string `toml:"scheme"`
+	MetricsPath string `toml:"metrics_path"`
+
+	// targets of each file, kept if reading the file fails
+	files   map[string]map[string]URLAndAddress
+	targets map[string]URLAndAddress
+	// directories added to the watcher
+	watched map[string]bool
+	// serializes refreshes
+	mu sync.Mutex
+}
+
+func (sd *FileSDConfig) init() error {
+	if len(sd.Files) == 0 {
+		return fmt.Errorf("no files configured")
+	}
+	for _, pattern := range sd.Files {
+		if _, err := filepath.Match(pattern, ""); err != nil {
+			return fmt.Errorf("invalid pattern %q: %w", pattern, err)
+		}
+	}
+	if sd.RefreshInterval <= 0 {
+		sd.RefreshInterval = config.Duration(5 * time.Minute)
+	}
+	sd.files = make(map[string]map[string]URLAndAddress)
+	sd.watched = make(map[string]bool)
+	return nil
+}
+
+func (p *Prometheus) startFileSD(ctx context.Context, sd *FileSDConfig) error {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return fmt.Errorf("creating file watcher: %w", err)
+	}
+
+	p.watchFileSD(watcher, sd)
+	p.refreshFileSD(sd)
+
+	p.wg.Add(1)
+	go func() {
+		defer p.wg.Done()
+		defer watcher.Close()
+
+		ticker := time.NewTicker(time.Duration(sd.RefreshInterval))
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case event := <-watcher.Events:
+				if event.Op&fsnotify.Create != 0 && sd.matchesDir(event.Name) {
+					p.watchFileSD(watcher, sd)
+					p.refreshFileSD(sd)
+				} else if sd.matches(event.Name) {
+					p.refreshFileSD(sd)
+				}
+			case err := <-watcher.Errors:
+				p.Log.Warnf("Error watching service discovery files: %v", err)
+			case <-ticker.C:
+				p.watchFileSD(watcher, sd)
+				p.refreshFileSD(sd)
+			}
+		}
+	}()
+	return nil
+}
+
+// watchFileSD adds the directories of the files to the watcher, as files are
+// usually replaced on updates. Directories given by glob patterns are
+// expanded, and their parent directory is watched for new directories
+// matching the pattern.
+func (p *Prometheus) watchFileSD(watcher *fsnotify.Watcher, sd *FileSDConfig) {
+	var dirs []string
+	for _, pattern := range sd.Files {
+		dir := filepath.Dir(pattern)
+		if !hasMeta(dir) {
+			dirs = append(dirs, dir)
+			continue
+		}
+		matches, err := filepath.Glob(dir)
+		if err != nil {
+			continue
+		}
+		dirs = append(dirs, matches...)
+
+		// Only directories up to the first pattern are known to exist
+		parent := filepath.Dir(dir)
+		for hasMeta(parent) {
+			parent = filepath.Dir(parent)
+		}
+		dirs = append(dirs, parent)
+	}
+
+	for _, dir := range dirs {
+		if sd.watched[dir] {
+			continue
+		}
+		sd.watched[dir] = true
+		if err := watcher.Add(dir); err != nil {
+			p.Log.Warnf("Unable to watch directory %q, relying on refresh interval: %v", dir, err)
+		}
+	}
+}
+
+// matches returns true if the file matches one of the patterns
+func (sd *FileSDConfig) matches(file string) bool {
+	for _, pattern := range sd.Files {
+		if ok, _ := filepath.Match(pattern, file); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// matchesDir returns true if the directory matches the directory of one of
+// the patterns given by a glob pattern itself
+func (sd *FileSDConfig) matchesDir(dir string) bool {
+	for _, pattern := range sd.Files {
+		if !hasMeta(filepath.Dir(pattern)) {
+			continue
+		}
+		if ok, _ := filepath.Match(filepath.Dir(pattern), dir); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// hasMeta returns true if the path contains glob meta characters
+func hasMeta(path string) bool {
+	magic := `*?[`
+	if runtime.GOOS != "windows" {
+		magic = `*?[\`
+	}
+	return strings.ContainsAny(path, magic)
+}
+
+// refreshFileSD reads all files matching the patterns. The targets of files
+// that cannot be read are kept from the last successful read.
+func (p *Prometheus) refreshFileSD(sd *FileSDConfig) {
+	sd.mu.Lock()
+	defer sd.mu.Unlock()
+
+	var files []string
+	for _, pattern := range sd.Files {
+		matches, err := filepath.Glob(pattern)
+		if err != nil {
+			p.Log.Errorf("Invalid service discovery pattern %q: %v", pattern, err)
+			continue
+		}
+		files = append(files, matches...)
+	}
+	sort.Strings(files)
+
+	byFile := make(map[string]map[string]URLAndAddress, len(files))
+	targets := make(map[string]URLAndAddress)
+	for _, file := range files {
+		urls, err := readFileSD(file, sd.Scheme, sd.MetricsPath, p.Relabel, p.Log)
+		if err != nil {
+			p.Log.Errorf("Unable to read service discovery file %q: %v", file, err)
+			urls = sd.files[file]
+		}
+		byFile[file] = urls
+		for k, v := range urls {
+			targets[k] = v
+		}
+	}
+	sd.files = byFile
+	p.Log.Debugf("Discovered %d targets in %d files", len(targets), len(files))
+
+	p.lock.Lock()
+	sd.targets = targets
+	p.lock.Unlock()
+}
+
+func readFileSD(file, scheme, metricsPath string, rules []*relabel.Config, log telegraf.Logger) (map[string]URLAndAddress, error) {
+	buf, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+
+	var groups []targetGroup
+	switch ext := filepath.Ext(file); ext {
+	case ".json":
+		err = json.Unmarshal(buf, &groups)
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(buf, &groups)
+	default:
+		return nil, fmt.Errorf("unknown file extension %q", ext)
+	}
+	if err != nil {
+		return nil, err
+	}
+	meta := map[string]string{metaFilepathLabel: file}
+	return targetURLs(groups, scheme, metricsPath, meta, rules, log), nil
+}
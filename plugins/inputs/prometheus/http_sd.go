@@ -0,0 +1,139 @@
+package prometheus
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/influxdata/telegraf/config"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/plugins/common/tls"
+)
+
+// HTTPSDConfig discovers targets from an endpoint in the format of the
+// Prometheus http_sd_configs.
+type HTTPSDConfig struct {
+	// URL of the endpoint returning the target groups.
+	URL string `toml:"url"`
+	// RefreshInterval to query the endpoint in.
+	RefreshInterval config.Duration `toml:"refresh_interval"`
+	// Scheme and MetricsPath of the targets without __scheme__ and
+	// __metrics_path__ labels.
+	Scheme      string `toml:"scheme"`
+	MetricsPath string `toml:"metrics_path"`
+
+	// Credentials of the endpoint, the credentials of the plugin are only
+	// sent to the targets.
+	BearerToken       string `toml:"bearer_token"`
+	BearerTokenString string `toml:"bearer_token_string"`
+	Username          string `toml:"username"`
+	Password          string `toml:"password"`
+	tls.ClientConfig
+
+	targets map[string]URLAndAddress
+}
+
+func (sd *HTTPSDConfig) init() error {
+	if sd.URL == "" {
+		return fmt.Errorf("no url configured")
+	}
+	if sd.RefreshInterval <= 0 {
+		sd.RefreshInterval = config.Duration(time.Minute)
+	}
+	return nil
+}
+
+func (p *Prometheus) startHTTPSD(ctx context.Context, sd *HTTPSDConfig) error {
+	tlsCfg, err := sd.ClientConfig.TLSConfig()
+	if err != nil {
+		return err
+	}
+	client := &http.Client{
+		Transport: &http.Transport{
+			TLSClientConfig:   tlsCfg,
+			DisableKeepAlives: true,
+		},
+		Timeout: time.Duration(p.ResponseTimeout),
+	}
+
+	refreshFailed := false
+	refresh := func() {
+		if err := p.refreshHTTPSD(ctx, client, sd); err != nil {
+			message := fmt.Sprintf("Unable to refresh targets from %s: %v", sd.URL, err)
+			if refreshFailed {
+				p.Log.Debug(message)
+			} else {
+				p.Log.Warn(message)
+			}
+			refreshFailed = true
+		} else if refreshFailed {
+			refreshFailed = false
+			p.Log.Infof("Successfully refreshed targets from %s after previous errors", sd.URL)
+		}
+	}
+	refresh()
+
+	p.wg.Add(1)
+	go func() {
+		defer p.wg.Done()
+		ticker := time.NewTicker(time.Duration(sd.RefreshInterval))
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				refresh()
+			}
+		}
+	}()
+	return nil
+}
+
+// refreshHTTPSD replaces the targets by the ones returned by the endpoint.
+// The targets are kept on errors.
+func (p *Prometheus) refreshHTTPSD(ctx context.Context, client *http.Client, sd *HTTPSDConfig) error {
+	req, err := http.NewRequest("GET", sd.URL, nil)
+	if err != nil {
+		return err
+	}
+	req = req.WithContext(ctx)
+	req.Header.Set("User-Agent", internal.ProductToken())
+	req.Header.Set("Accept", "application/json")
+	seconds := time.Duration(sd.RefreshInterval).Seconds()
+	req.Header.Set("X-Prometheus-Refresh-Interval-Seconds", strconv.FormatFloat(seconds, 'f', -1, 64))
+	if err := setAuthorization(req, sd.BearerToken, sd.BearerTokenString, sd.Username, sd.Password); err != nil {
+		return err
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("returned HTTP status %s", resp.Status)
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("error reading body: %w", err)
+	}
+	var groups []targetGroup
+	if err := json.Unmarshal(body, &groups); err != nil {
+		return fmt.Errorf("error parsing body: %w", err)
+	}
+	meta := map[string]string{metaURLLabel: sd.URL}
+	targets := targetURLs(groups, sd.Scheme, sd.MetricsPath, meta, p.Relabel, p.Log)
+	p.Log.Debugf("Discovered %d targets from %s", len(targets), sd.URL)
+
+	p.lock.Lock()
+	sd.targets = targets
+	p.lock.Unlock()
+	return nil
+}
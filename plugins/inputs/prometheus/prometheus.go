@@ -9,6 +9,7 @@ import (
 	"net/http"
 	"net/url"
 	"os"
+	"sort"
 	"strings"
 	"sync"
 	"time"
@@ -19,6 +20,7 @@ import (
 	"github.com/influxdata/telegraf"
 	"github.com/influxdata/telegraf/config"
 	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/plugins/common/relabel"
 	"github.com/influxdata/telegraf/plugins/common/tls"
 	"github.com/influxdata/telegraf/plugins/inputs"
 	parserV2 "github.com/influxdata/telegraf/plugins/parsers/prometheus"
@@ -45,6 +47,16 @@ type Prometheus struct {
 	// Consul SD configuration
 	ConsulConfig ConsulConfig `toml:"consul"`
 
+	// File and HTTP SD configurations
+	FileSD []*FileSDConfig `toml:"file_sd"`
+	HTTPSD []*HTTPSDConfig `toml:"http_sd"`
+
+	// Relabelling rules applied to the targets of the file and HTTP SD
+	Relabel []*relabel.Config `toml:"relabel"`
+
+	// Relabelling rules applied to the scraped metrics
+	MetricRelabel []*relabel.Config `toml:"metric_relabel"`
+
 	// Bearer Token authorization file path
 	BearerToken       string `toml:"bearer_token"`
 	BearerTokenString string `toml:"bearer_token_string"`
@@ -153,6 +165,62 @@ var sampleConfig = `
   #     [inputs.prometheus.consul.query.tags]
   #       host = "{{.Node}}"
 
+  ## Scrape targets listed in files in the format of the Prometheus file
+  ## based service discovery. Target labels are added as tags; the
+  ## __scheme__, __metrics_path__ and __param_<name> labels set the scheme,
+  ## path and query parameters of the target URL.
+  # [[inputs.prometheus.file_sd]]
+  #   ## JSON or YAML files, glob patterns are supported. The files are
+  #   ## watched for changes.
+  #   files = ["/etc/prometheus/targets/*.json"]
+  #   ## Interval to re-read the files in.
+  #   # refresh_interval = "5m"
+  #   ## Scheme and path of targets without __scheme__ or __metrics_path__.
+  #   # scheme = "http"
+  #   # metrics_path = "/metrics"
+
+  ## Scrape targets returned by an endpoint in the format of the Prometheus
+  ## HTTP based service discovery. Labels are handled as for file_sd.
+  # [[inputs.prometheus.http_sd]]
+  #   url = "http://localhost:8080/targets"
+  #   ## Interval to query the endpoint in.
+  #   # refresh_interval = "1m"
+  #   ## Scheme and path of targets without __scheme__ or __metrics_path__.
+  #   # scheme = "http"
+  #   # metrics_path = "/metrics"
+  #   ## Credentials of the endpoint, the credentials and TLS settings of the
+  #   ## plugin are only used for the targets.
+  #   # bearer_token = "/path/to/bearer/token"
+  #   # bearer_token_string = "abc_123"
+  #   # username = ""
+  #   # password = ""
+  #   # tls_ca = "/etc/telegraf/ca.pem"
+  #   # tls_cert = "/etc/telegraf/cert.pem"
+  #   # tls_key = "/etc/telegraf/key.pem"
+  #   # insecure_skip_verify = false
+
+  ## Relabelling rules applied in order to the targets of the file and HTTP
+  ## service discovery, like the relabel_configs of Prometheus. The rules see
+  ## the labels of the targets including __address__, __scheme__,
+  ## __metrics_path__, __param_<name> and __meta_<name>; targets are dropped
+  ## by the keep and drop actions. See the relabel processor for the options
+  ## and actions.
+  # [[inputs.prometheus.relabel]]
+  #   source_labels = ["__meta_datacenter"]
+  #   regex = "fra|ams"
+  #   action = "keep"
+
+  ## Relabelling rules applied in order to the scraped metrics, like the
+  ## metric_relabel_configs of Prometheus. The rules see the tags of the
+  ## metrics, including the url and target tags, and the __name__ label
+  ## holding the metric name; that is the measurement name with
+  ## metric_version = 1 and the field name with metric_version = 2. See the
+  ## relabel processor for the options and actions.
+  # [[inputs.prometheus.metric_relabel]]
+  #   source_labels = ["__name__"]
+  #   regex = "go_.*"
+  #   action = "drop"
+
   ## Use bearer token for authorization. ('bearer_token' takes priority)
   # bearer_token = "/path/to/bearer/token"
   ## OR
@@ -219,6 +287,27 @@ func (p *Prometheus) Init() error {
 		p.Log.Infof("Using the label selector: %v and field selector: %v", p.podLabelSelector, p.podFieldSelector)
 	}
 
+	for _, sd := range p.FileSD {
+		if err := sd.init(); err != nil {
+			return fmt.Errorf("file_sd: %w", err)
+		}
+	}
+	for _, sd := range p.HTTPSD {
+		if err := sd.init(); err != nil {
+			return fmt.Errorf("http_sd: %w", err)
+		}
+	}
+	for i, rule := range p.Relabel {
+		if err := rule.Init(); err != nil {
+			return fmt.Errorf("relabel rule %d: %w", i+1, err)
+		}
+	}
+	for i, rule := range p.MetricRelabel {
+		if err := rule.Init(); err != nil {
+			return fmt.Errorf("metric_relabel rule %d: %w", i+1, err)
+		}
+	}
+
 	return nil
 }
 
@@ -269,6 +358,8 @@ func (p *Prometheus) GetAllURLs() (map[string]URLAndAddress, error) {
 	for k, v := range p.kubernetesPods {
 		allURLs[k] = v
 	}
+	// add all targets of the file and HTTP service discovery
+	p.discoveredURLs(allURLs)
 
 	for _, service := range p.KubernetesServices {
 		address, err := url.Parse(service)
@@ -344,6 +435,23 @@ func (p *Prometheus) createHTTPClient() (*http.Client, error) {
 	return client, nil
 }
 
+// setAuthorization sets the bearer token read from the file or given as
+// string, or the basic authentication credentials, in this order.
+func setAuthorization(req *http.Request, bearerToken, bearerTokenString, username, password string) error {
+	if bearerToken != "" {
+		token, err := os.ReadFile(bearerToken)
+		if err != nil {
+			return err
+		}
+		req.Header.Set("Authorization", "Bearer "+string(token))
+	} else if bearerTokenString != "" {
+		req.Header.Set("Authorization", "Bearer "+bearerTokenString)
+	} else if username != "" || password != "" {
+		req.SetBasicAuth(username, password)
+	}
+	return nil
+}
+
 func (p *Prometheus) gatherURL(u URLAndAddress, acc telegraf.Accumulator) error {
 	var req *http.Request
 	var err error
@@ -385,16 +493,8 @@ func (p *Prometheus) gatherURL(u URLAndAddress, acc telegraf.Accumulator) error
 
 	p.addHeaders(req)
 
-	if p.BearerToken != "" {
-		token, err := os.ReadFile(p.BearerToken)
-		if err != nil {
-			return err
-		}
-		req.Header.Set("Authorization", "Bearer "+string(token))
-	} else if p.BearerTokenString != "" {
-		req.Header.Set("Authorization", "Bearer "+p.BearerTokenString)
-	} else if p.Username != "" || p.Password != "" {
-		req.SetBasicAuth(p.Username, p.Password)
+	if err := setAuthorization(req, p.BearerToken, p.BearerTokenString, p.Username, p.Password); err != nil {
+		return err
 	}
 
 	var resp *http.Response
@@ -448,23 +548,96 @@ func (p *Prometheus) gatherURL(u URLAndAddress, acc telegraf.Accumulator) error
 			tags[k] = v
 		}
 
-		switch metric.Type() {
-		case telegraf.Counter:
-			acc.AddCounter(metric.Name(), metric.Fields(), tags, metric.Time())
-		case telegraf.Gauge:
-			acc.AddGauge(metric.Name(), metric.Fields(), tags, metric.Time())
-		case telegraf.Summary:
-			acc.AddSummary(metric.Name(), metric.Fields(), tags, metric.Time())
-		case telegraf.Histogram:
-			acc.AddHistogram(metric.Name(), metric.Fields(), tags, metric.Time())
+		switch {
+		case len(p.MetricRelabel) == 0:
+			addMetric(acc, metric, metric.Name(), metric.Fields(), tags)
+		case p.MetricVersion == 2:
+			// The fields are named after the Prometheus metrics and are
+			// regrouped by the resulting tags
+			type group struct {
+				fields map[string]interface{}
+				tags   map[string]string
+			}
+			groups := make(map[string]*group)
+			for field, value := range metric.Fields() {
+				name, relabelled, ok := p.relabel(field, tags)
+				if !ok {
+					continue
+				}
+				key := tagsKey(relabelled)
+				g, found := groups[key]
+				if !found {
+					g = &group{fields: make(map[string]interface{}), tags: relabelled}
+					groups[key] = g
+				}
+				g.fields[name] = value
+			}
+			for _, g := range groups {
+				addMetric(acc, metric, metric.Name(), g.fields, g.tags)
+			}
 		default:
-			acc.AddFields(metric.Name(), metric.Fields(), tags, metric.Time())
+			if name, relabelled, ok := p.relabel(metric.Name(), tags); ok {
+				addMetric(acc, metric, name, metric.Fields(), relabelled)
+			}
 		}
 	}
 
 	return nil
 }
 
+// relabel applies the metric relabelling rules to the metric name and tags.
+// It returns false if the metric is dropped.
+func (p *Prometheus) relabel(name string, tags map[string]string) (string, map[string]string, bool) {
+	labelSet := make(map[string]string, len(tags)+1)
+	for k, v := range tags {
+		labelSet[k] = v
+	}
+	labelSet[relabel.NameLabel] = name
+
+	if !relabel.Apply(p.MetricRelabel, labelSet) {
+		return "", nil, false
+	}
+	name, ok := labelSet[relabel.NameLabel]
+	if !ok {
+		return "", nil, false
+	}
+	delete(labelSet, relabel.NameLabel)
+	return name, labelSet, true
+}
+
+// tagsKey returns a key identifying the tag set
+func tagsKey(tags map[string]string) string {
+	keys := make([]string, 0, len(tags))
+	for k := range tags {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var b strings.Builder
+	for _, k := range keys {
+		b.WriteString(k)
+		b.WriteByte(0)
+		b.WriteString(tags[k])
+		b.WriteByte(0)
+	}
+	return b.String()
+}
+
+func addMetric(acc telegraf.Accumulator, metric telegraf.Metric, name string, fields map[string]interface{}, tags map[string]string) {
+	switch metric.Type() {
+	case telegraf.Counter:
+		acc.AddCounter(name, fields, tags, metric.Time())
+	case telegraf.Gauge:
+		acc.AddGauge(name, fields, tags, metric.Time())
+	case telegraf.Summary:
+		acc.AddSummary(name, fields, tags, metric.Time())
+	case telegraf.Histogram:
+		acc.AddHistogram(name, fields, tags, metric.Time())
+	default:
+		acc.AddFields(name, fields, tags, metric.Time())
+	}
+}
+
 func (p *Prometheus) addHeaders(req *http.Request) {
 	for header, value := range p.headers {
 		req.Header.Add(header, value)
@@ -495,7 +668,7 @@ func fieldSelectorIsSupported(fieldSelector fields.Selector) (bool, string) {
 	return true, ""
 }
 
-// Start will start the Kubernetes and/or Consul scraping if enabled in the configuration
+// Start will start the Kubernetes, Consul, file and/or HTTP service discovery if enabled in the configuration
 func (p *Prometheus) Start(_ telegraf.Accumulator) error {
 	var ctx context.Context
 	p.wg = sync.WaitGroup{}
@@ -511,6 +684,16 @@ func (p *Prometheus) Start(_ telegraf.Accumulator) error {
 			return err
 		}
 	}
+	for _, sd := range p.FileSD {
+		if err := p.startFileSD(ctx, sd); err != nil {
+			return err
+		}
+	}
+	for _, sd := range p.HTTPSD {
+		if err := p.startHTTPSD(ctx, sd); err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
@@ -14,6 +14,7 @@ import (
 	"k8s.io/apimachinery/pkg/fields"
 
 	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/plugins/common/relabel"
 	"github.com/influxdata/telegraf/testutil"
 )
 
@@ -265,6 +266,115 @@ func TestPrometheusGeneratesMetricsWithIgnoreTimestamp(t *testing.T) {
 	require.WithinDuration(t, time.Now(), m.Time, 5*time.Second)
 }
 
+func TestPrometheusMetricRelabel(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, err := fmt.Fprint(w, sampleTextFormat)
+		require.NoError(t, err)
+	}))
+	defer ts.Close()
+
+	drop, keep := "go_gc_.*", ".*"
+	rename, replacement := "test_(.*);value", "${1}"
+	p := &Prometheus{
+		Log:    testutil.Logger{},
+		URLs:   []string{ts.URL},
+		URLTag: "url",
+		MetricRelabel: []*relabel.Config{
+			{SourceLabels: []string{"__name__"}, Regex: &drop, Action: "drop"},
+			{SourceLabels: []string{"__name__", "label"}, Regex: &rename, TargetLabel: "__name__", Replacement: &replacement},
+			{Regex: &keep, Action: "labelmap", Replacement: &replacement},
+			{SourceLabels: []string{"url"}, TargetLabel: "url", Replacement: &rename},
+		},
+	}
+	require.NoError(t, p.Init())
+
+	var acc testutil.Accumulator
+	require.NoError(t, acc.GatherError(p.Gather))
+
+	require.False(t, acc.HasMeasurement("go_gc_duration_seconds"))
+	require.True(t, acc.HasFloatField("go_goroutines", "gauge"))
+	require.False(t, acc.HasMeasurement("test_metric"))
+	require.True(t, acc.HasFloatField("metric", "value"))
+	require.Equal(t, "value", acc.TagValue("metric", "label"))
+	require.Equal(t, rename, acc.TagValue("metric", "url"))
+}
+
+func TestPrometheusMetricRelabelV2(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, err := fmt.Fprint(w, sampleTextFormat)
+		require.NoError(t, err)
+	}))
+	defer ts.Close()
+
+	keep, quantile := "go_gc_duration_seconds(_sum)?;(|0.5)", "0.5"
+	p := &Prometheus{
+		Log:           testutil.Logger{},
+		URLs:          []string{ts.URL},
+		URLTag:        "url",
+		MetricVersion: 2,
+		MetricRelabel: []*relabel.Config{
+			{SourceLabels: []string{"__name__", "quantile"}, Regex: &keep, Action: "keep"},
+			{Regex: &quantile, SourceLabels: []string{"quantile"}, TargetLabel: "median", Replacement: &quantile},
+		},
+	}
+	require.NoError(t, p.Init())
+
+	var acc testutil.Accumulator
+	require.NoError(t, acc.GatherError(p.Gather))
+
+	expected := []telegraf.Metric{
+		testutil.MustMetric(
+			"prometheus",
+			map[string]string{"url": ts.URL + "/metrics"},
+			map[string]interface{}{"go_gc_duration_seconds_sum": 0.0018183950000000002},
+			time.Unix(0, 0),
+			telegraf.Summary,
+		),
+		testutil.MustMetric(
+			"prometheus",
+			map[string]string{"url": ts.URL + "/metrics", "quantile": "0.5", "median": "0.5"},
+			map[string]interface{}{"go_gc_duration_seconds": 0.00015749400000000002},
+			time.Unix(0, 0),
+			telegraf.Summary,
+		),
+	}
+	testutil.RequireMetricsEqual(t, expected, acc.GetTelegrafMetrics(), testutil.IgnoreTime(), testutil.SortMetrics())
+}
+
+func TestPrometheusMetricRelabelV2Regroup(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, err := fmt.Fprint(w, sampleTextFormat)
+		require.NoError(t, err)
+	}))
+	defer ts.Close()
+
+	gather := func(rules []*relabel.Config) []telegraf.Metric {
+		p := &Prometheus{
+			Log:           testutil.Logger{},
+			URLs:          []string{ts.URL},
+			URLTag:        "url",
+			MetricVersion: 2,
+			MetricRelabel: rules,
+		}
+		require.NoError(t, p.Init())
+
+		var acc testutil.Accumulator
+		require.NoError(t, acc.GatherError(p.Gather))
+		return acc.GetTelegrafMetrics()
+	}
+
+	// Fields keeping their tags stay in the same metric
+	drop := "unknown_.*"
+	expected := gather(nil)
+	actual := gather([]*relabel.Config{{SourceLabels: []string{"__name__"}, Regex: &drop, Action: "drop"}})
+	testutil.RequireMetricsEqual(t, expected, actual, testutil.IgnoreTime(), testutil.SortMetrics())
+
+	// and are split up by tags added to some of them
+	sum, yes := "go_gc_duration_seconds_sum", "yes"
+	actual = gather([]*relabel.Config{{SourceLabels: []string{"__name__"}, Regex: &sum, TargetLabel: "sum", Replacement: &yes}})
+	require.Len(t, actual, len(expected)+1)
+}
+
 func TestUnsupportedFieldSelector(t *testing.T) {
 	fieldSelectorString := "spec.containerName=container"
 	prom := &Prometheus{Log: testutil.Logger{}, KubernetesFieldSelector: fieldSelectorString}
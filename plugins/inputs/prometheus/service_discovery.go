@@ -0,0 +1,133 @@
+package prometheus
+
+import (
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/plugins/common/relabel"
+)
+
+const (
+	addressLabel     = "__address__"
+	schemeLabel      = "__scheme__"
+	metricsPathLabel = "__metrics_path__"
+	paramLabelPrefix = "__param_"
+
+	// Meta labels holding the file or URL the targets were discovered in
+	metaFilepathLabel = "__meta_filepath"
+	metaURLLabel      = "__meta_url"
+)
+
+// targetGroup is a list of targets sharing the same labels as used by the
+// file and HTTP based service discovery of Prometheus.
+type targetGroup struct {
+	Targets []string          `json:"targets"`
+	Labels  map[string]string `json:"labels"`
+}
+
+// targetURLs returns the URLs of the targets in the groups. The labels of the
+// targets, including the __meta_<name> labels and the given meta labels of
+// the discovery, are relabelled by the rules first; targets dropped by the
+// rules are skipped. The scheme, metrics path
+// and query parameters of the URLs are taken from the __scheme__,
+// __metrics_path__ and __param_<name> labels, which default to the given
+// scheme and metrics path. All other labels not starting with "__" become
+// tags. Invalid targets are logged and skipped.
+func targetURLs(groups []targetGroup, scheme, metricsPath string, meta map[string]string, rules []*relabel.Config, log telegraf.Logger) map[string]URLAndAddress {
+	if scheme == "" {
+		scheme = "http"
+	}
+	if metricsPath == "" {
+		metricsPath = "/metrics"
+	}
+
+	urls := make(map[string]URLAndAddress)
+	for _, group := range groups {
+		for _, target := range group.Targets {
+			labels := map[string]string{
+				schemeLabel:      scheme,
+				metricsPathLabel: metricsPath,
+			}
+			for k, v := range group.Labels {
+				labels[k] = v
+			}
+			for k, v := range meta {
+				labels[k] = v
+			}
+			labels[addressLabel] = target
+
+			if !relabel.Apply(rules, labels) {
+				log.Debugf("Target %q dropped by relabelling", target)
+				continue
+			}
+
+			u, tags, err := targetURL(labels)
+			if err != nil {
+				log.Errorf("Skipping target %q: %v", target, err)
+				continue
+			}
+			urls[u.String()] = URLAndAddress{URL: u, OriginalURL: u, Tags: tags}
+		}
+	}
+	return urls
+}
+
+func targetURL(labels map[string]string) (*url.URL, map[string]string, error) {
+	address := labels[addressLabel]
+	if address == "" || strings.Contains(address, "/") {
+		return nil, nil, fmt.Errorf("invalid address %q", address)
+	}
+
+	scheme := labels[schemeLabel]
+	if scheme != "http" && scheme != "https" {
+		return nil, nil, fmt.Errorf("invalid scheme %q", scheme)
+	}
+
+	metricsPath := labels[metricsPathLabel]
+	if metricsPath == "" {
+		metricsPath = "/metrics"
+	}
+
+	query := url.Values{}
+	tags := make(map[string]string)
+	keys := make([]string, 0, len(labels))
+	for k := range labels {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		switch {
+		case strings.HasPrefix(k, paramLabelPrefix):
+			query.Set(strings.TrimPrefix(k, paramLabelPrefix), labels[k])
+		case strings.HasPrefix(k, "__"):
+		default:
+			tags[k] = labels[k]
+		}
+	}
+
+	u := &url.URL{
+		Scheme:   scheme,
+		Host:     address,
+		Path:     metricsPath,
+		RawQuery: query.Encode(),
+	}
+	return u, tags, nil
+}
+
+// discoveredURLs adds the targets of the file and HTTP service discovery to
+// the URLs. The caller has to hold the lock.
+func (p *Prometheus) discoveredURLs(allURLs map[string]URLAndAddress) {
+	for _, sd := range p.FileSD {
+		for k, v := range sd.targets {
+			allURLs[k] = v
+		}
+	}
+	for _, sd := range p.HTTPSD {
+		for k, v := range sd.targets {
+			allURLs[k] = v
+		}
+	}
+}
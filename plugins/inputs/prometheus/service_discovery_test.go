@@ -0,0 +1,330 @@
+package prometheus
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/influxdata/telegraf/config"
+	"github.com/influxdata/telegraf/plugins/common/relabel"
+	"github.com/influxdata/telegraf/testutil"
+)
+
+func TestTargetURLs(t *testing.T) {
+	groups := []targetGroup{
+		{
+			Targets: []string{"10.0.0.1:9100", "10.0.0.2:9100"},
+			Labels:  map[string]string{"env": "prod", "__meta_ignored": "x"},
+		},
+		{
+			Targets: []string{"10.0.0.3:443"},
+			Labels: map[string]string{
+				"__scheme__":       "https",
+				"__metrics_path__": "/probe",
+				"__param_module":   "http_2xx",
+				"__param_target":   "example.org",
+				"job":              "blackbox",
+			},
+		},
+	}
+
+	urls := targetURLs(groups, "", "", nil, nil, testutil.Logger{})
+
+	keys := make([]string, 0, len(urls))
+	for k := range urls {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	require.Equal(t, []string{
+		"http://10.0.0.1:9100/metrics",
+		"http://10.0.0.2:9100/metrics",
+		"https://10.0.0.3:443/probe?module=http_2xx&target=example.org",
+	}, keys)
+
+	require.Equal(t, map[string]string{"env": "prod"}, urls["http://10.0.0.1:9100/metrics"].Tags)
+	require.Equal(t, map[string]string{"job": "blackbox"}, urls["https://10.0.0.3:443/probe?module=http_2xx&target=example.org"].Tags)
+}
+
+func TestTargetURLsDefaults(t *testing.T) {
+	groups := []targetGroup{{Targets: []string{"localhost:9100"}}}
+	urls := targetURLs(groups, "https", "/federate", nil, nil, testutil.Logger{})
+	require.Contains(t, urls, "https://localhost:9100/federate")
+}
+
+func TestTargetURLsRelabel(t *testing.T) {
+	groups := []targetGroup{
+		{
+			Targets: []string{"10.0.0.1", "10.0.0.2"},
+			Labels:  map[string]string{"__meta_dc": "fra", "__meta_module": "if_mib"},
+		},
+		{
+			Targets: []string{"10.0.0.3"},
+			Labels:  map[string]string{"__meta_dc": "nyc"},
+		},
+	}
+
+	keep, drop := "fra", "10.0.0.2"
+	address, port := "(.*)", "${1}:9116"
+	https, snmp := "https", "/snmp"
+	rules := []*relabel.Config{
+		{SourceLabels: []string{"__meta_dc"}, Regex: &keep, Action: "keep"},
+		{SourceLabels: []string{"__address__"}, Regex: &drop, Action: "drop"},
+		{SourceLabels: []string{"__address__"}, Regex: &address, TargetLabel: "__param_target"},
+		{SourceLabels: []string{"__address__"}, Regex: &address, TargetLabel: "__address__", Replacement: &port},
+		{TargetLabel: "__scheme__", Replacement: &https},
+		{TargetLabel: "__metrics_path__", Replacement: &snmp},
+		{SourceLabels: []string{"__meta_module"}, TargetLabel: "__param_module"},
+		{SourceLabels: []string{"__meta_dc"}, TargetLabel: "dc"},
+	}
+	for _, rule := range rules {
+		require.NoError(t, rule.Init())
+	}
+
+	urls := targetURLs(groups, "", "", nil, rules, testutil.Logger{})
+	require.Len(t, urls, 1)
+	u, ok := urls["https://10.0.0.1:9116/snmp?module=if_mib&target=10.0.0.1"]
+	require.True(t, ok)
+	require.Equal(t, map[string]string{"dc": "fra"}, u.Tags)
+}
+
+func TestTargetURLsInvalid(t *testing.T) {
+	// Invalid targets are skipped without affecting the other targets
+	groups := []targetGroup{
+		{Targets: []string{"http://localhost:9100", "localhost:9100"}},
+		{Targets: []string{"localhost:9200"}, Labels: map[string]string{"__scheme__": "ftp"}},
+	}
+	urls := targetURLs(groups, "", "", nil, nil, testutil.Logger{})
+	require.Len(t, urls, 1)
+	require.Contains(t, urls, "http://localhost:9100/metrics")
+}
+
+func TestServiceDiscoveryMetaLabels(t *testing.T) {
+	rules := []*relabel.Config{
+		{SourceLabels: []string{"__meta_filepath"}, TargetLabel: "file"},
+		{SourceLabels: []string{"__meta_url"}, TargetLabel: "sd_url"},
+	}
+	for _, rule := range rules {
+		require.NoError(t, rule.Init())
+	}
+
+	// Targets of file_sd carry the file they were found in
+	file := filepath.Join(t.TempDir(), "targets.json")
+	require.NoError(t, os.WriteFile(file, []byte(`[{"targets": ["192.0.2.1:9100"]}]`), 0640))
+	urls, err := readFileSD(file, "", "", rules, testutil.Logger{})
+	require.NoError(t, err)
+	require.Equal(t, map[string]string{"file": file}, urls["http://192.0.2.1:9100/metrics"].Tags)
+
+	// and targets of http_sd the endpoint
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, err := fmt.Fprintln(w, `[{"targets": ["192.0.2.2:9100"]}]`)
+		require.NoError(t, err)
+	}))
+	defer ts.Close()
+
+	p := &Prometheus{
+		Log:     testutil.Logger{},
+		HTTPSD:  []*HTTPSDConfig{{URL: ts.URL + "/targets"}},
+		Relabel: rules,
+	}
+	require.NoError(t, p.Init())
+	client, err := p.createHTTPClient()
+	require.NoError(t, err)
+
+	sd := p.HTTPSD[0]
+	require.NoError(t, p.refreshHTTPSD(context.Background(), client, sd))
+	require.Equal(t, map[string]string{"sd_url": ts.URL + "/targets"}, sd.targets["http://192.0.2.2:9100/metrics"].Tags)
+}
+
+func TestFileSD(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, err := fmt.Fprint(w, sampleGaugeTextFormat)
+		require.NoError(t, err)
+	}))
+	defer ts.Close()
+	address := ts.Listener.Addr().String()
+
+	dir := t.TempDir()
+	jsonFile := filepath.Join(dir, "targets.json")
+	content := fmt.Sprintf(`[{"targets": [%q], "labels": {"env": "prod"}}]`, address)
+	require.NoError(t, os.WriteFile(jsonFile, []byte(content), 0640))
+
+	p := &Prometheus{
+		Log:    testutil.Logger{},
+		URLTag: "url",
+		FileSD: []*FileSDConfig{{Files: []string{filepath.Join(dir, "*.json"), filepath.Join(dir, "*.yaml")}}},
+	}
+	require.NoError(t, p.Init())
+	require.NoError(t, p.Start(nil))
+	defer p.Stop()
+
+	urls, err := p.GetAllURLs()
+	require.NoError(t, err)
+	require.Len(t, urls, 1)
+
+	var acc testutil.Accumulator
+	require.NoError(t, acc.GatherError(p.Gather))
+	require.True(t, acc.HasFloatField("go_goroutines", "gauge"))
+	require.Equal(t, "prod", acc.TagValue("go_goroutines", "env"))
+	require.Equal(t, "http://"+address+"/metrics", acc.TagValue("go_goroutines", "url"))
+
+	// New files are picked up on changes
+	yamlFile := filepath.Join(dir, "targets.yaml")
+	content = "- targets: [\"192.0.2.1:9100\"]\n  labels:\n    __metrics_path__: /federate\n"
+	require.NoError(t, os.WriteFile(yamlFile, []byte(content), 0640))
+	require.Eventually(t, func() bool {
+		urls, err := p.GetAllURLs()
+		require.NoError(t, err)
+		_, ok := urls["http://192.0.2.1:9100/federate"]
+		return ok && len(urls) == 2
+	}, 5*time.Second, 10*time.Millisecond)
+
+	// Targets of unreadable files are kept
+	require.NoError(t, os.WriteFile(yamlFile, []byte("invalid"), 0640))
+	p.refreshFileSD(p.FileSD[0])
+	urls, err = p.GetAllURLs()
+	require.NoError(t, err)
+	require.Len(t, urls, 2)
+
+	// Targets of removed files are dropped
+	require.NoError(t, os.Remove(yamlFile))
+	require.Eventually(t, func() bool {
+		urls, err := p.GetAllURLs()
+		require.NoError(t, err)
+		return len(urls) == 1
+	}, 5*time.Second, 10*time.Millisecond)
+}
+
+func TestFileSDDirectoryGlob(t *testing.T) {
+	dir := t.TempDir()
+	writeTargets := func(name, target string) {
+		require.NoError(t, os.MkdirAll(filepath.Join(dir, name), 0750))
+		content := fmt.Sprintf(`[{"targets": [%q]}]`, target)
+		require.NoError(t, os.WriteFile(filepath.Join(dir, name, "targets.json"), []byte(content), 0640))
+	}
+	writeTargets("a", "192.0.2.1:9100")
+
+	p := &Prometheus{
+		Log:    testutil.Logger{},
+		FileSD: []*FileSDConfig{{Files: []string{filepath.Join(dir, "*", "targets.json")}}},
+	}
+	require.NoError(t, p.Init())
+	require.NoError(t, p.Start(nil))
+	defer p.Stop()
+
+	urls, err := p.GetAllURLs()
+	require.NoError(t, err)
+	require.Contains(t, urls, "http://192.0.2.1:9100/metrics")
+
+	// Changes in expanded and new directories are picked up
+	writeTargets("a", "192.0.2.2:9100")
+	writeTargets("b", "192.0.2.3:9100")
+	require.Eventually(t, func() bool {
+		urls, err := p.GetAllURLs()
+		require.NoError(t, err)
+		_, okA := urls["http://192.0.2.2:9100/metrics"]
+		_, okB := urls["http://192.0.2.3:9100/metrics"]
+		return okA && okB && len(urls) == 2
+	}, 5*time.Second, 10*time.Millisecond)
+}
+
+func TestHTTPSD(t *testing.T) {
+	targets := `[{"targets": ["192.0.2.1:9100"], "labels": {"__param_module": "if_mib", "dc": "fra"}}]`
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Prometheus-Refresh-Interval-Seconds") != "60" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		_, err := fmt.Fprintln(w, targets)
+		require.NoError(t, err)
+	}))
+	defer ts.Close()
+
+	p := &Prometheus{
+		Log:    testutil.Logger{},
+		URLTag: "url",
+		HTTPSD: []*HTTPSDConfig{{URL: ts.URL}},
+	}
+	require.NoError(t, p.Init())
+	require.NoError(t, p.Start(nil))
+	defer p.Stop()
+
+	urls, err := p.GetAllURLs()
+	require.NoError(t, err)
+	require.Len(t, urls, 1)
+	u, ok := urls["http://192.0.2.1:9100/metrics?module=if_mib"]
+	require.True(t, ok)
+	require.Equal(t, map[string]string{"dc": "fra"}, u.Tags)
+}
+
+func TestHTTPSDCredentials(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Only the credentials of the endpoint are sent
+		username, password, ok := r.BasicAuth()
+		if !ok || username != "sd" || password != "secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		_, err := fmt.Fprintln(w, `[{"targets": ["192.0.2.1:9100"]}]`)
+		require.NoError(t, err)
+	}))
+	defer ts.Close()
+
+	p := &Prometheus{
+		Log:               testutil.Logger{},
+		BearerTokenString: "scrape",
+		HTTPSD:            []*HTTPSDConfig{{URL: ts.URL, Username: "sd", Password: "secret"}},
+	}
+	require.NoError(t, p.Init())
+	require.NoError(t, p.Start(nil))
+	defer p.Stop()
+
+	urls, err := p.GetAllURLs()
+	require.NoError(t, err)
+	require.Contains(t, urls, "http://192.0.2.1:9100/metrics")
+}
+
+func TestHTTPSDError(t *testing.T) {
+	status := http.StatusOK
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(status)
+		_, err := fmt.Fprintln(w, `[{"targets": ["192.0.2.1:9100"]}]`)
+		require.NoError(t, err)
+	}))
+	defer ts.Close()
+
+	p := &Prometheus{
+		Log:    testutil.Logger{},
+		HTTPSD: []*HTTPSDConfig{{URL: ts.URL, RefreshInterval: config.Duration(time.Hour)}},
+	}
+	require.NoError(t, p.Init())
+	client, err := p.createHTTPClient()
+	require.NoError(t, err)
+
+	sd := p.HTTPSD[0]
+	require.NoError(t, p.refreshHTTPSD(context.Background(), client, sd))
+	require.Len(t, sd.targets, 1)
+
+	// Targets are kept on errors
+	status = http.StatusInternalServerError
+	require.EqualError(t, p.refreshHTTPSD(context.Background(), client, sd), "returned HTTP status 500 Internal Server Error")
+	require.Len(t, sd.targets, 1)
+}
+
+func TestServiceDiscoveryInitErrors(t *testing.T) {
+	p := &Prometheus{Log: testutil.Logger{}, FileSD: []*FileSDConfig{{}}}
+	require.EqualError(t, p.Init(), "file_sd: no files configured")
+
+	p = &Prometheus{Log: testutil.Logger{}, FileSD: []*FileSDConfig{{Files: []string{"[.json"}}}}
+	require.EqualError(t, p.Init(), `file_sd: invalid pattern "[.json": syntax error in pattern`)
+
+	p = &Prometheus{Log: testutil.Logger{}, HTTPSD: []*HTTPSDConfig{{}}}
+	require.EqualError(t, p.Init(), "http_sd: no url configured")
+}
@@ -0,0 +1,407 @@
+package snmp
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gosnmp/gosnmp"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/config"
+	"github.com/influxdata/telegraf/internal/snmp"
+)
+
+const (
+	sysDescrOid    = ".1.3.6.1.2.1.1.1.0"
+	sysObjectIDOid = ".1.3.6.1.2.1.1.2.0"
+
+	// maxDiscoveryAddresses limits the size of the networks to sweep
+	maxDiscoveryAddresses = 1 << 16
+)
+
+// Discovery holds the configuration for finding agents by sweeping networks.
+type Discovery struct {
+	// Networks in CIDR notation to probe every address of.
+	Networks []string `toml:"networks"`
+	// Transport and port of the agents to probe.
+	Transport string `toml:"transport"`
+	Port      uint16 `toml:"port"`
+	// Interval to repeat the discovery in.
+	Interval config.Duration `toml:"interval"`
+	// Timeout and retries of the probes, independent of the collection.
+	Timeout config.Duration `toml:"timeout"`
+	Retries int             `toml:"retries"`
+	// MaxParallel is the number of addresses probed in parallel.
+	MaxParallel int `toml:"max_parallel"`
+	// RemoveAfter is the number of consecutive discoveries an agent has to
+	// miss before it is removed.
+	RemoveAfter int `toml:"remove_after"`
+	// Profiles are glob patterns of the profile files.
+	Profiles []string `toml:"profiles"`
+	// RequireProfile ignores devices without a matching profile.
+	RequireProfile bool `toml:"require_profile"`
+
+	addresses []string
+}
+
+// discoveredAgent is an agent found by discovery. It is replaced instead of
+// modified on changes, so it can be used by Gather without locking.
+type discoveredAgent struct {
+	address     string
+	sysObjectID string
+	sysDescr    string
+	profiles    []string
+	fields      []Field
+	tables      []Table
+
+	// conn is only used by Gather
+	conn snmpConnection
+}
+
+// init applies the defaults and enumerates the addresses to probe
+func (d *Discovery) init() error {
+	if d.Transport == "" {
+		d.Transport = "udp"
+	}
+	switch d.Transport {
+	case "udp", "udp4", "udp6", "tcp", "tcp4", "tcp6":
+	default:
+		return fmt.Errorf("unsupported transport %q", d.Transport)
+	}
+	if d.Port == 0 {
+		d.Port = 161
+	}
+	if d.Interval <= 0 {
+		d.Interval = config.Duration(time.Hour)
+	}
+	if d.Timeout <= 0 {
+		d.Timeout = config.Duration(time.Second)
+	}
+	if d.MaxParallel <= 0 {
+		d.MaxParallel = 64
+	}
+	if d.RemoveAfter <= 0 {
+		d.RemoveAfter = 3
+	}
+
+	if len(d.Networks) == 0 {
+		return fmt.Errorf("no networks to discover")
+	}
+	d.addresses = nil
+	for _, network := range d.Networks {
+		hosts, err := networkHosts(network)
+		if err != nil {
+			return err
+		}
+		for _, host := range hosts {
+			address := d.Transport + "://" + net.JoinHostPort(host, strconv.Itoa(int(d.Port)))
+			d.addresses = append(d.addresses, address)
+		}
+		if len(d.addresses) > maxDiscoveryAddresses {
+			return fmt.Errorf("more than %d addresses to discover", maxDiscoveryAddresses)
+		}
+	}
+	return nil
+}
+
+// exclude removes the addresses of the given agents from the addresses to
+// probe, so static agents are not collected twice
+func (d *Discovery) exclude(agents []string) {
+	static := make(map[string]bool, len(agents))
+	for _, agent := range agents {
+		if address, err := agentAddress(agent); err == nil {
+			static[address] = true
+		}
+	}
+
+	addresses := d.addresses[:0]
+	for _, address := range d.addresses {
+		if !static[address] {
+			addresses = append(addresses, address)
+		}
+	}
+	d.addresses = addresses
+}
+
+// agentAddress returns the agent in the form of the discovered addresses,
+// i.e. with the default scheme and port
+func agentAddress(agent string) (string, error) {
+	if !strings.Contains(agent, "://") {
+		agent = "udp://" + agent
+	}
+	u, err := url.Parse(agent)
+	if err != nil {
+		return "", err
+	}
+	port := u.Port()
+	if port == "" {
+		port = "161"
+	}
+	host := u.Hostname()
+	if ip := net.ParseIP(host); ip != nil {
+		host = ip.String()
+	}
+	return u.Scheme + "://" + net.JoinHostPort(host, port), nil
+}
+
+// networkHosts returns the host addresses of the network, excluding the
+// network and broadcast address of IPv4 networks with more than two
+// addresses
+func networkHosts(network string) ([]string, error) {
+	_, ipnet, err := net.ParseCIDR(network)
+	if err != nil {
+		return nil, fmt.Errorf("invalid network %q: %w", network, err)
+	}
+
+	ones, bits := ipnet.Mask.Size()
+	if bits-ones > 16 {
+		return nil, fmt.Errorf("network %q has more than %d addresses", network, maxDiscoveryAddresses)
+	}
+
+	var hosts []string
+	for ip := ipnet.IP; ipnet.Contains(ip); ip = nextIP(ip) {
+		hosts = append(hosts, ip.String())
+	}
+	if ipnet.IP.To4() != nil && bits-ones > 1 {
+		hosts = hosts[1 : len(hosts)-1]
+	}
+	return hosts, nil
+}
+
+func nextIP(ip net.IP) net.IP {
+	next := make(net.IP, len(ip))
+	copy(next, ip)
+	for i := len(next) - 1; i >= 0; i-- {
+		next[i]++
+		if next[i] != 0 {
+			break
+		}
+	}
+	return next
+}
+
+// Start runs the discovery if configured.
+func (s *Snmp) Start(acc telegraf.Accumulator) error {
+	if s.Discovery == nil {
+		return nil
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	s.cancel = cancel
+
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		ticker := time.NewTicker(time.Duration(s.Discovery.Interval))
+		defer ticker.Stop()
+		for {
+			if err := s.discover(ctx); err != nil {
+				acc.AddError(fmt.Errorf("discovery: %w", err))
+			}
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+	return nil
+}
+
+// Stop ends the discovery.
+func (s *Snmp) Stop() {
+	if s.cancel != nil {
+		s.cancel()
+	}
+	s.wg.Wait()
+}
+
+// discover probes all addresses and replaces the discovered agents by the
+// responding ones. Agents are only removed after missing several consecutive
+// discoveries to not lose them due to single lost probes.
+func (s *Snmp) discover(ctx context.Context) error {
+	type result struct {
+		address     string
+		sysObjectID string
+		sysDescr    string
+	}
+
+	addresses := make(chan string)
+	results := make(chan result)
+	var wg sync.WaitGroup
+	for i := 0; i < s.Discovery.MaxParallel; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for address := range addresses {
+				sysObjectID, sysDescr, err := s.probe(address)
+				if err != nil {
+					continue
+				}
+				results <- result{address: address, sysObjectID: sysObjectID, sysDescr: sysDescr}
+			}
+		}()
+	}
+	go func() {
+		defer close(addresses)
+		for _, address := range s.Discovery.addresses {
+			select {
+			case <-ctx.Done():
+				return
+			case addresses <- address:
+			}
+		}
+	}()
+	go func() {
+		wg.Wait()
+		close(results)
+	}()
+
+	found := make(map[string]result)
+	for r := range results {
+		found[r.address] = r
+	}
+	if ctx.Err() != nil {
+		return nil
+	}
+
+	s.discoveredLock.Lock()
+	defer s.discoveredLock.Unlock()
+
+	discovered := make(map[string]*discoveredAgent, len(found))
+	var added int
+	for address, r := range found {
+		if a, ok := s.discovered[address]; ok && a.sysObjectID == r.sysObjectID && a.sysDescr == r.sysDescr {
+			discovered[address] = a
+			continue
+		}
+
+		a := s.newDiscoveredAgent(address, r.sysObjectID, r.sysDescr)
+		if len(a.profiles) == 0 && s.Discovery.RequireProfile {
+			s.Log.Debugf("Ignoring agent %s without matching profile (sysObjectID %q)", address, r.sysObjectID)
+			continue
+		}
+		s.Log.Debugf("Discovered agent %s with profiles %v", address, a.profiles)
+		discovered[address] = a
+		added++
+	}
+
+	for address, a := range s.discovered {
+		if _, ok := found[address]; ok {
+			delete(s.misses, address)
+			continue
+		}
+		s.misses[address]++
+		if s.misses[address] < s.Discovery.RemoveAfter {
+			s.Log.Debugf("Agent %s did not respond to %d discoveries", address, s.misses[address])
+			discovered[address] = a
+			continue
+		}
+		delete(s.misses, address)
+	}
+
+	var removed int
+	for address, a := range s.discovered {
+		if discovered[address] != a {
+			s.retired = append(s.retired, a)
+			if _, ok := discovered[address]; !ok {
+				removed++
+			}
+		}
+	}
+	s.discovered = discovered
+	s.Log.Infof("Discovered %d agents, %d added, %d removed", len(discovered), added, removed)
+
+	return nil
+}
+
+// newDiscoveredAgent returns the agent with the fields and tables of the
+// plugin and of all matching profiles
+func (s *Snmp) newDiscoveredAgent(address, sysObjectID, sysDescr string) *discoveredAgent {
+	a := &discoveredAgent{
+		address:     address,
+		sysObjectID: sysObjectID,
+		sysDescr:    sysDescr,
+		fields:      append([]Field(nil), s.Fields...),
+		tables:      append([]Table(nil), s.Tables...),
+	}
+	for _, p := range s.profiles {
+		if !p.matches(sysObjectID, sysDescr) {
+			continue
+		}
+		a.profiles = append(a.profiles, p.Name)
+		a.fields = append(a.fields, p.Fields...)
+		a.tables = append(a.tables, p.Tables...)
+	}
+	return a
+}
+
+// discoveredAgents returns the current agents and the addresses of the
+// agents removed since the last call, and closes the connections of the
+// agents no longer in use
+func (s *Snmp) discoveredAgents() (agents []*discoveredAgent, removed []string) {
+	s.discoveredLock.Lock()
+	defer s.discoveredLock.Unlock()
+
+	for _, a := range s.retired {
+		if gs, ok := a.conn.(snmp.GosnmpWrapper); ok && gs.Conn != nil {
+			gs.Conn.Close()
+		}
+		if _, ok := s.discovered[a.address]; !ok {
+			removed = append(removed, a.address)
+		}
+	}
+	s.retired = nil
+
+	agents = make([]*discoveredAgent, 0, len(s.discovered))
+	for _, a := range s.discovered {
+		agents = append(agents, a)
+	}
+	return agents, removed
+}
+
+// probeAgent returns the sysObjectID and sysDescr of the agent
+func (s *Snmp) probeAgent(address string) (sysObjectID, sysDescr string, err error) {
+	cfg := s.ClientConfig
+	cfg.Timeout = s.Discovery.Timeout
+	cfg.Retries = s.Discovery.Retries
+
+	gs, err := snmp.NewWrapper(cfg)
+	if err != nil {
+		return "", "", err
+	}
+	if err := gs.SetAgent(address); err != nil {
+		return "", "", err
+	}
+	if err := gs.Connect(); err != nil {
+		return "", "", err
+	}
+	defer gs.Conn.Close()
+
+	packet, err := gs.Get([]string{sysObjectIDOid, sysDescrOid})
+	if err != nil {
+		return "", "", err
+	}
+	for _, v := range packet.Variables {
+		switch v.Type {
+		case gosnmp.NoSuchObject, gosnmp.NoSuchInstance, gosnmp.EndOfMibView, gosnmp.Null:
+			return "", "", fmt.Errorf("no value for %s", v.Name)
+		}
+		switch v.Name {
+		case sysObjectIDOid:
+			sysObjectID, _ = v.Value.(string)
+		case sysDescrOid:
+			if b, ok := v.Value.([]byte); ok {
+				sysDescr = string(b)
+			}
+		}
+	}
+	return sysObjectID, sysDescr, nil
+}
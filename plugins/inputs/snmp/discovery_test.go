@@ -0,0 +1,280 @@
+package snmp
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/influxdata/telegraf/selfstat"
+	"github.com/influxdata/telegraf/testutil"
+)
+
+const ciscoProfile = `
+name: cisco
+sys_object_id:
+  - 1.3.6.1.4.1.9
+sys_descr: "^Cisco"
+field:
+  - name: myfield1
+    oid: .1.0.0.1.1
+    is_tag: true
+table:
+  - name: myOtherTable
+    inherit_tags: [myfield1]
+    field:
+      - name: myOtherField
+        oid: .1.0.0.0.1.5
+`
+
+const genericProfile = `
+[[field]]
+  name = "myfield2"
+  oid = ".1.0.0.1.2"
+`
+
+// writeProfiles writes the profiles to a temporary directory, as the
+// testdata directory is used as MIB path
+func writeProfiles(t *testing.T) string {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "cisco.yaml"), []byte(ciscoProfile), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "generic.toml"), []byte(genericProfile), 0644))
+	return dir
+}
+
+func TestNetworkHosts(t *testing.T) {
+	hosts, err := networkHosts("192.0.2.0/30")
+	require.NoError(t, err)
+	require.Equal(t, []string{"192.0.2.1", "192.0.2.2"}, hosts)
+
+	hosts, err = networkHosts("192.0.2.7/32")
+	require.NoError(t, err)
+	require.Equal(t, []string{"192.0.2.7"}, hosts)
+
+	hosts, err = networkHosts("2001:db8::/127")
+	require.NoError(t, err)
+	require.Equal(t, []string{"2001:db8::", "2001:db8::1"}, hosts)
+
+	_, err = networkHosts("10.0.0.0/8")
+	require.EqualError(t, err, `network "10.0.0.0/8" has more than 65536 addresses`)
+}
+
+func TestLoadProfiles(t *testing.T) {
+	dir := writeProfiles(t)
+
+	profiles, err := loadProfiles([]string{filepath.Join(dir, "*")})
+	require.NoError(t, err)
+	require.Equal(t, []*Profile{
+		{
+			Name:        "cisco",
+			SysObjectID: []string{"1.3.6.1.4.1.9"},
+			SysDescr:    "^Cisco",
+			Fields:      []Field{{Name: "myfield1", Oid: ".1.0.0.1.1", IsTag: true}},
+			Tables: []Table{
+				{
+					Name:        "myOtherTable",
+					InheritTags: []string{"myfield1"},
+					Fields:      []Field{{Name: "myOtherField", Oid: ".1.0.0.0.1.5"}},
+				},
+			},
+		},
+		{
+			Name:   "generic",
+			Fields: []Field{{Name: "myfield2", Oid: ".1.0.0.1.2"}},
+		},
+	}, profiles)
+}
+
+func TestLoadProfilesUnknownKey(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "invalid.yml")
+	require.NoError(t, os.WriteFile(file, []byte("field:\n  - name: foo\n    oidd: .1.2.3\n"), 0644))
+
+	_, err := loadProfiles([]string{file})
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "unknown field")
+}
+
+func TestProfileMatches(t *testing.T) {
+	p := &Profile{SysObjectID: []string{".1.3.6.1.4.1.9"}, SysDescr: "IOS"}
+	require.NoError(t, p.init())
+
+	require.True(t, p.matches(".1.3.6.1.4.1.9.1.1208", "Cisco IOS Software"))
+	require.True(t, p.matches("1.3.6.1.4.1.9", "Cisco IOS Software"))
+	require.False(t, p.matches(".1.3.6.1.4.1.99.1", "Cisco IOS Software"))
+	require.False(t, p.matches(".1.3.6.1.4.1.9.1.1208", "Cisco NX-OS"))
+
+	// A profile without criteria matches all devices
+	require.True(t, (&Profile{}).matches(".1.3.6.1.4.1.2636", "Juniper"))
+}
+
+func TestDiscovery(t *testing.T) {
+	devices := map[string][2]string{
+		"udp://192.0.2.1:161": {".1.3.6.1.4.1.9.1.1208", "Cisco IOS Software"},
+		"udp://192.0.2.2:161": {".1.3.6.1.4.1.2636.1.1.1", "Juniper Networks"},
+	}
+
+	s := &Snmp{
+		Name: "mytable",
+		Discovery: &Discovery{
+			Networks: []string{"192.0.2.0/29"},
+			Profiles: []string{filepath.Join(writeProfiles(t), "*")},
+		},
+		probe: func(address string) (string, string, error) {
+			device, ok := devices[address]
+			if !ok {
+				return "", "", errors.New("timeout")
+			}
+			return device[0], device[1], nil
+		},
+		Log: testutil.Logger{},
+	}
+	require.NoError(t, s.Init())
+	require.Len(t, s.Discovery.addresses, 6)
+
+	require.NoError(t, s.discover(context.Background()))
+	agents := discoveredAgents(s)
+	sort.Slice(agents, func(i, j int) bool { return agents[i].address < agents[j].address })
+	require.Len(t, agents, 2)
+	require.Equal(t, "udp://192.0.2.1:161", agents[0].address)
+	require.Equal(t, []string{"cisco", "generic"}, agents[0].profiles)
+	require.Equal(t, "udp://192.0.2.2:161", agents[1].address)
+	require.Equal(t, []string{"generic"}, agents[1].profiles)
+
+	// Gather from the discovered agents using the profiles
+	agents[0].conn = tsc
+	agents[1].conn = &testSNMPConnection{host: "juniper", values: tsc.values}
+	acc := &testutil.Accumulator{}
+	require.NoError(t, s.Gather(acc))
+	require.Empty(t, acc.Errors)
+
+	cisco, ok := acc.Get("myOtherTable")
+	require.True(t, ok)
+	require.Equal(t, map[string]string{"agent_host": "tsc", "myfield1": "baz"}, cisco.Tags)
+	require.Equal(t, map[string]interface{}{"myOtherField": 123456}, cisco.Fields)
+	require.True(t, acc.HasPoint("mytable", map[string]string{"agent_host": "tsc", "myfield1": "baz"}, "myfield2", 234))
+	require.True(t, acc.HasPoint("mytable", map[string]string{"agent_host": "juniper"}, "myfield2", 234))
+
+	// Agents not responding anymore are removed after missing three
+	// discoveries, unchanged agents are kept including their connection
+	delete(devices, "udp://192.0.2.2:161")
+	for i := 0; i < 2; i++ {
+		require.NoError(t, s.discover(context.Background()))
+		require.Len(t, discoveredAgents(s), 2)
+	}
+	require.NoError(t, s.discover(context.Background()))
+	remaining, removed := s.discoveredAgents()
+	require.Len(t, remaining, 1)
+	require.Same(t, agents[0], remaining[0])
+	require.Equal(t, []string{"udp://192.0.2.2:161"}, removed)
+}
+
+func TestDiscoveryUnregistersStats(t *testing.T) {
+	responding := true
+	s := &Snmp{
+		Discovery: &Discovery{
+			Networks:    []string{"192.0.2.9/32"},
+			RemoveAfter: 1,
+		},
+		probe: func(string) (string, string, error) {
+			if !responding {
+				return "", "", errors.New("timeout")
+			}
+			return ".1.3.6.1.4.1.9.1.1208", "Cisco IOS Software", nil
+		},
+		Log: testutil.Logger{},
+	}
+	require.NoError(t, s.Init())
+
+	require.NoError(t, s.discover(context.Background()))
+	agents := discoveredAgents(s)
+	require.Len(t, agents, 1)
+	agents[0].conn = tsc
+	acc := &testutil.Accumulator{}
+	require.NoError(t, s.Gather(acc))
+	tags := map[string]string{"agent": "udp://192.0.2.9:161"}
+	require.NotEmpty(t, selfstat.Values("snmp", tags))
+
+	// The statistics of removed agents are dropped on the next gather
+	responding = false
+	require.NoError(t, s.discover(context.Background()))
+	require.NoError(t, s.Gather(acc))
+	require.Empty(t, selfstat.Values("snmp", tags))
+	require.NotContains(t, s.states, "udp://192.0.2.9:161")
+}
+
+// discoveredAgents returns the current agents of the plugin
+func discoveredAgents(s *Snmp) []*discoveredAgent {
+	agents, _ := s.discoveredAgents()
+	return agents
+}
+
+func TestDiscoveryMissesReset(t *testing.T) {
+	var responding bool
+	s := &Snmp{
+		Discovery: &Discovery{
+			Networks:    []string{"192.0.2.1/32"},
+			RemoveAfter: 2,
+		},
+		probe: func(string) (string, string, error) {
+			if !responding {
+				return "", "", errors.New("timeout")
+			}
+			return ".1.3.6.1.4.1.9.1.1208", "Cisco IOS Software", nil
+		},
+		Log: testutil.Logger{},
+	}
+	require.NoError(t, s.Init())
+
+	// Responding again resets the number of missed discoveries
+	for _, r := range []bool{true, false, true, false} {
+		responding = r
+		require.NoError(t, s.discover(context.Background()))
+		require.Len(t, discoveredAgents(s), 1)
+	}
+	require.NoError(t, s.discover(context.Background()))
+	require.Empty(t, discoveredAgents(s))
+}
+
+func TestDiscoveryExcludesAgents(t *testing.T) {
+	s := &Snmp{
+		Agents: []string{"192.0.2.1", "udp://192.0.2.2:161", "192.0.2.3:1161", "tcp://192.0.2.4"},
+		Discovery: &Discovery{
+			Networks: []string{"192.0.2.0/29"},
+		},
+		Log: testutil.Logger{},
+	}
+	require.NoError(t, s.Init())
+
+	expected := []string{"udp://192.0.2.3:161", "udp://192.0.2.4:161", "udp://192.0.2.5:161", "udp://192.0.2.6:161"}
+	require.Equal(t, expected, s.Discovery.addresses)
+}
+
+func TestDiscoveryRequireProfile(t *testing.T) {
+	s := &Snmp{
+		Discovery: &Discovery{
+			Networks:       []string{"192.0.2.1/32"},
+			RequireProfile: true,
+		},
+		probe: func(string) (string, string, error) {
+			return ".1.3.6.1.4.1.9.1.1208", "Cisco IOS Software", nil
+		},
+		Log: testutil.Logger{},
+	}
+	require.NoError(t, s.Init())
+
+	require.NoError(t, s.discover(context.Background()))
+	require.Empty(t, discoveredAgents(s))
+}
+
+func TestDiscoveryInitErrors(t *testing.T) {
+	s := &Snmp{Discovery: &Discovery{}}
+	require.EqualError(t, s.Init(), "initializing discovery: no networks to discover")
+
+	s = &Snmp{Discovery: &Discovery{Networks: []string{"192.0.2.1/32"}, Transport: "sctp"}}
+	require.EqualError(t, s.Init(), `initializing discovery: unsupported transport "sctp"`)
+}
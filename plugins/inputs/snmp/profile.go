@@ -0,0 +1,185 @@
+package snmp
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/ghodss/yaml"
+	"github.com/influxdata/toml"
+)
+
+// Profile is a reusable set of fields and tables collected from all
+// discovered devices matching the profile.
+type Profile struct {
+	// Name of the profile, defaults to the file name without extension.
+	Name string `toml:"name"`
+
+	// SysObjectID are the OIDs the sysObjectID of a device has to equal or
+	// to be a child of, e.g. the enterprise OID of a vendor.
+	SysObjectID []string `toml:"sys_object_id"`
+
+	// SysDescr is a regular expression the sysDescr of a device has to match.
+	SysDescr string `toml:"sys_descr"`
+
+	Fields []Field `toml:"field"`
+	Tables []Table `toml:"table"`
+
+	sysDescr *regexp.Regexp
+}
+
+// loadProfiles reads the profiles from the files matching the glob
+// patterns in the order of the patterns and the file names
+func loadProfiles(patterns []string) ([]*Profile, error) {
+	var profiles []*Profile
+	for _, pattern := range patterns {
+		files, err := filepath.Glob(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid profile pattern %q: %w", pattern, err)
+		}
+		sort.Strings(files)
+
+		for _, file := range files {
+			p, err := loadProfile(file)
+			if err != nil {
+				return nil, fmt.Errorf("loading profile %q: %w", file, err)
+			}
+			profiles = append(profiles, p)
+		}
+	}
+	return profiles, nil
+}
+
+func loadProfile(file string) (*Profile, error) {
+	buf, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &Profile{}
+	switch ext := filepath.Ext(file); ext {
+	case ".toml":
+		if err := toml.Unmarshal(buf, p); err != nil {
+			return nil, err
+		}
+	case ".yaml", ".yml":
+		if err := unmarshalYAML(buf, p); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("unknown file extension %q", ext)
+	}
+
+	if p.Name == "" {
+		p.Name = strings.TrimSuffix(filepath.Base(file), filepath.Ext(file))
+	}
+	return p, nil
+}
+
+// unmarshalYAML decodes YAML using the same keys as the TOML configuration,
+// i.e. snake case keys and "field"/"table" for the lists of fields and tables.
+func unmarshalYAML(buf []byte, p *Profile) error {
+	buf, err := yaml.YAMLToJSON(buf)
+	if err != nil {
+		return err
+	}
+
+	var raw interface{}
+	if err := json.Unmarshal(buf, &raw); err != nil {
+		return err
+	}
+	buf, err = json.Marshal(normalizeKeys(raw))
+	if err != nil {
+		return err
+	}
+
+	// Keys are matched case-insensitively against the field names
+	decoder := json.NewDecoder(strings.NewReader(string(buf)))
+	decoder.DisallowUnknownFields()
+	return decoder.Decode(p)
+}
+
+// normalizeKeys removes the underscores from the keys and renames the lists
+// of fields and tables to the names of the struct fields
+func normalizeKeys(v interface{}) interface{} {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		normalized := make(map[string]interface{}, len(v))
+		for key, value := range v {
+			key = strings.ReplaceAll(strings.ToLower(key), "_", "")
+			switch key {
+			case "field":
+				key = "fields"
+			case "table":
+				key = "tables"
+			}
+			normalized[key] = normalizeKeys(value)
+		}
+		return normalized
+	case []interface{}:
+		for i := range v {
+			v[i] = normalizeKeys(v[i])
+		}
+		return v
+	}
+	return v
+}
+
+// init translates the OIDs and initializes the fields and tables
+func (p *Profile) init() error {
+	for i, oid := range p.SysObjectID {
+		if strings.ContainsAny(oid, ":abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ") {
+			_, oidNum, _, _, _, err := SnmpTranslate(oid)
+			if err != nil {
+				return fmt.Errorf("translating sysObjectID %q: %w", oid, err)
+			}
+			oid = oidNum
+		}
+		p.SysObjectID[i] = strings.TrimPrefix(oid, ".")
+	}
+
+	if p.SysDescr != "" {
+		re, err := regexp.Compile(p.SysDescr)
+		if err != nil {
+			return fmt.Errorf("compiling sysDescr pattern: %w", err)
+		}
+		p.sysDescr = re
+	}
+
+	for i := range p.Tables {
+		if err := p.Tables[i].Init(); err != nil {
+			return fmt.Errorf("initializing table %s: %w", p.Tables[i].Name, err)
+		}
+	}
+	for i := range p.Fields {
+		if err := p.Fields[i].init(); err != nil {
+			return fmt.Errorf("initializing field %s: %w", p.Fields[i].Name, err)
+		}
+	}
+	return nil
+}
+
+// matches returns true if the device with the given sysObjectID and
+// sysDescr matches the profile. A profile without criteria matches all
+// devices.
+func (p *Profile) matches(sysObjectID, sysDescr string) bool {
+	if len(p.SysObjectID) > 0 {
+		sysObjectID = strings.TrimPrefix(sysObjectID, ".")
+		var found bool
+		for _, oid := range p.SysObjectID {
+			if sysObjectID == oid || strings.HasPrefix(sysObjectID, oid+".") {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+
+	return p.sysDescr == nil || p.sysDescr.MatchString(sysDescr)
+}
@@ -1,6 +1,7 @@
 package snmp
 
 import (
+	"context"
 	"encoding/binary"
 	"errors"
 	"fmt"
@@ -18,6 +19,7 @@ import (
 	"github.com/influxdata/telegraf/config"
 	"github.com/influxdata/telegraf/internal/snmp"
 	"github.com/influxdata/telegraf/plugins/inputs"
+	"github.com/influxdata/telegraf/selfstat"
 )
 
 const description = `Retrieves SNMP values from remote agents`
@@ -50,9 +52,17 @@ const sampleConfig = `
   ## Number of retries to attempt.
   # retries = 3
 
-  ## The GETBULK max-repetitions parameter.
+  ## The GETBULK max-repetitions parameter. It is halved for agents
+  ## responding with tooBig.
   # max_repetitions = 10
 
+  ## Maximum number of agents collected in parallel; 0 is unlimited.
+  # max_parallel_agents = 0
+
+  ## Skip agents for this duration after a request timed out, instead of
+  ## waiting for the timeout on every interval; 0 disables skipping.
+  # unreachable_backoff = "0s"
+
   ## SNMPv3 authentication and encryption options.
   ##
   ## Security Name.
@@ -73,6 +83,31 @@ const sampleConfig = `
   ## Add fields and tables defining the variables you wish to collect.  This
   ## example collects the system uptime and interface variables.  Reference the
   ## full plugin documentation for configuration details.
+
+  ## Discover agents by probing all addresses of the networks in addition to
+  ## the agents above. Discovered agents are collected using the fields and
+  ## tables of this plugin and of all profiles matching the device.
+  # [inputs.snmp.discovery]
+  #   ## Networks to probe in CIDR notation.
+  #   networks = ["10.0.0.0/24"]
+  #   ## Transport and port of the agents.
+  #   # transport = "udp"
+  #   # port = 161
+  #   ## Interval to repeat the discovery in; agents are added and removed
+  #   ## without restarting.
+  #   # interval = "1h"
+  #   ## Timeout and number of retries of the probes.
+  #   # timeout = "1s"
+  #   # retries = 0
+  #   ## Number of addresses to probe in parallel.
+  #   # max_parallel = 64
+  #   ## Number of consecutive discoveries an agent must not respond to before
+  #   ## it is removed.
+  #   # remove_after = 3
+  #   ## Profile files in TOML or YAML format, glob patterns are supported.
+  #   # profiles = ["/etc/telegraf/snmp/profiles/*.toml"]
+  #   ## Ignore devices without a matching profile.
+  #   # require_profile = false
 `
 
 // Snmp holds the configuration for the plugin.
@@ -94,8 +129,33 @@ type Snmp struct {
 	Name   string  // deprecated in 1.14; use name_override
 	Fields []Field `toml:"field"`
 
+	// Discovery of additional agents.
+	Discovery *Discovery `toml:"discovery"`
+
+	// MaxParallelAgents limits the number of agents collected concurrently.
+	MaxParallelAgents int `toml:"max_parallel_agents"`
+
+	// UnreachableBackoff is the duration to skip agents for after a timeout.
+	UnreachableBackoff config.Duration `toml:"unreachable_backoff"`
+
 	connectionCache []snmpConnection
 
+	// states holds the statistics and backoff of each agent; it is only
+	// modified by Gather
+	states map[string]*agentState
+	now    func() time.Time
+
+	profiles       []*Profile
+	discovered     map[string]*discoveredAgent
+	misses         map[string]int
+	retired        []*discoveredAgent
+	discoveredLock sync.Mutex
+	cancel         context.CancelFunc
+	wg             sync.WaitGroup
+
+	// probe returns the sysObjectID and sysDescr of a device
+	probe func(address string) (sysObjectID, sysDescr string, err error)
+
 	Log telegraf.Logger `toml:"-"`
 }
 
@@ -123,6 +183,36 @@ func (s *Snmp) Init() error {
 		s.AgentHostTag = "agent_host"
 	}
 
+	if s.MaxParallelAgents < 0 {
+		return fmt.Errorf("max_parallel_agents must not be negative")
+	}
+	if s.now == nil {
+		s.now = time.Now
+	}
+
+	if s.Discovery != nil {
+		if err := s.Discovery.init(); err != nil {
+			return fmt.Errorf("initializing discovery: %w", err)
+		}
+		s.Discovery.exclude(s.Agents)
+		s.misses = make(map[string]int)
+
+		profiles, err := loadProfiles(s.Discovery.Profiles)
+		if err != nil {
+			return err
+		}
+		for _, p := range profiles {
+			if err := p.init(); err != nil {
+				return fmt.Errorf("initializing profile %s: %w", p.Name, err)
+			}
+		}
+		s.profiles = profiles
+
+		if s.probe == nil {
+			s.probe = s.probeAgent
+		}
+	}
+
 	return nil
 }
 
@@ -350,39 +440,175 @@ func (s *Snmp) Description() string {
 // and returned at the end.
 func (s *Snmp) Gather(acc telegraf.Accumulator) error {
 	var wg sync.WaitGroup
-	for i, agent := range s.Agents {
+	var limit chan struct{}
+	if s.MaxParallelAgents > 0 {
+		limit = make(chan struct{}, s.MaxParallelAgents)
+	}
+	collect := func(agent string, connect func() (snmpConnection, error), fields []Field, tables []Table) {
+		state := s.agentState(agent)
+		if s.UnreachableBackoff > 0 && s.now().Before(state.skipUntil) {
+			state.skipped.Incr(1)
+			return
+		}
+
 		wg.Add(1)
-		go func(i int, agent string) {
+		go func() {
 			defer wg.Done()
-			gs, err := s.getConnection(i)
-			if err != nil {
-				acc.AddError(fmt.Errorf("agent %s: %w", agent, err))
-				return
+			if limit != nil {
+				limit <- struct{}{}
+				defer func() { <-limit }()
 			}
+			s.collect(acc, state, agent, connect, fields, tables)
+		}()
+	}
 
-			// First is the top-level fields. We treat the fields as table prefixes with an empty index.
-			t := Table{
-				Name:   s.Name,
-				Fields: s.Fields,
-			}
-			topTags := map[string]string{}
-			if err := s.gatherTable(acc, gs, t, topTags, false); err != nil {
-				acc.AddError(fmt.Errorf("agent %s: %w", agent, err))
-			}
+	for i, agent := range s.Agents {
+		i := i
+		connect := func() (snmpConnection, error) { return s.getConnection(i) }
+		collect(agent, connect, s.Fields, s.Tables)
+	}
 
-			// Now is the real tables.
-			for _, t := range s.Tables {
-				if err := s.gatherTable(acc, gs, t, topTags, true); err != nil {
-					acc.AddError(fmt.Errorf("agent %s: gathering table %s: %w", agent, t.Name, err))
+	agents, removed := s.discoveredAgents()
+	for _, agent := range removed {
+		if state, ok := s.states[agent]; ok {
+			state.unregister()
+			delete(s.states, agent)
+		}
+	}
+	for _, a := range agents {
+		a := a
+		connect := func() (snmpConnection, error) {
+			if a.conn == nil {
+				gs, err := s.connect(a.address)
+				if err != nil {
+					return nil, err
 				}
+				a.conn = gs
 			}
-		}(i, agent)
+			return a.conn, nil
+		}
+		collect(a.address, connect, a.fields, a.tables)
 	}
 	wg.Wait()
 
 	return nil
 }
 
+// agentState holds the statistics of an agent and the time until which it
+// is skipped because it is unreachable.
+type agentState struct {
+	skipUntil time.Time
+
+	gatherTime     selfstat.Stat
+	errors         selfstat.Stat
+	timeouts       selfstat.Stat
+	skipped        selfstat.Stat
+	maxRepetitions selfstat.Stat
+}
+
+// agentState returns the state of the agent, creating it if necessary
+func (s *Snmp) agentState(agent string) *agentState {
+	if state, ok := s.states[agent]; ok {
+		return state
+	}
+
+	tags := map[string]string{"agent": agent}
+	state := &agentState{
+		gatherTime:     selfstat.RegisterTiming("snmp", "gather_time_ns", tags),
+		errors:         selfstat.Register("snmp", "errors", tags),
+		timeouts:       selfstat.Register("snmp", "timeouts", tags),
+		skipped:        selfstat.Register("snmp", "skipped", tags),
+		maxRepetitions: selfstat.Register("snmp", "max_repetitions", tags),
+	}
+	if s.states == nil {
+		s.states = make(map[string]*agentState)
+	}
+	s.states[agent] = state
+	return state
+}
+
+// unregister removes the statistics of the agent
+func (state *agentState) unregister() {
+	selfstat.Unregister(state.gatherTime)
+	selfstat.Unregister(state.errors)
+	selfstat.Unregister(state.timeouts)
+	selfstat.Unregister(state.skipped)
+	selfstat.Unregister(state.maxRepetitions)
+}
+
+// collect gathers the agent, reports the errors and updates the statistics
+// of the agent. The agent is skipped for the backoff duration if a request
+// timed out.
+func (s *Snmp) collect(acc telegraf.Accumulator, state *agentState, agent string, connect func() (snmpConnection, error), fields []Field, tables []Table) {
+	start := time.Now()
+	var errs []error
+	gs, err := connect()
+	if err != nil {
+		errs = append(errs, fmt.Errorf("agent %s: %w", agent, err))
+	} else {
+		errs = s.gatherAgent(acc, gs, agent, fields, tables)
+	}
+	state.gatherTime.Set(time.Since(start).Nanoseconds())
+
+	var timedOut bool
+	for _, err := range errs {
+		acc.AddError(err)
+		state.errors.Incr(1)
+		if isTimeout(err) {
+			state.timeouts.Incr(1)
+			timedOut = true
+		}
+	}
+	if timedOut && s.UnreachableBackoff > 0 {
+		state.skipUntil = s.now().Add(time.Duration(s.UnreachableBackoff))
+		s.Log.Debugf("Skipping unreachable agent %s until %s", agent, state.skipUntil.Format(time.RFC3339))
+	}
+
+	if gs, ok := gs.(snmp.GosnmpWrapper); ok && gs.Version != gosnmp.Version1 {
+		state.maxRepetitions.Set(int64(gs.MaxRepetitions))
+	}
+}
+
+// isTimeout returns true if the error is caused by the agent not responding
+func isTimeout(err error) bool {
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return true
+	}
+	return errors.Is(err, context.DeadlineExceeded)
+}
+
+// gatherAgent retrieves the top-level fields and the tables from the agent
+// and returns the errors encountered. If skipping unreachable agents is
+// enabled, the remaining tables are not requested after a timeout.
+func (s *Snmp) gatherAgent(acc telegraf.Accumulator, gs snmpConnection, agent string, fields []Field, tables []Table) []error {
+	var errs []error
+
+	// First is the top-level fields. We treat the fields as table prefixes with an empty index.
+	t := Table{
+		Name:   s.Name,
+		Fields: fields,
+	}
+	topTags := map[string]string{}
+	if err := s.gatherTable(acc, gs, t, topTags, false); err != nil {
+		errs = append(errs, fmt.Errorf("agent %s: %w", agent, err))
+		if s.UnreachableBackoff > 0 && isTimeout(err) {
+			return errs
+		}
+	}
+
+	// Now is the real tables.
+	for _, t := range tables {
+		if err := s.gatherTable(acc, gs, t, topTags, true); err != nil {
+			errs = append(errs, fmt.Errorf("agent %s: gathering table %s: %w", agent, t.Name, err))
+			if s.UnreachableBackoff > 0 && isTimeout(err) {
+				return errs
+			}
+		}
+	}
+	return errs
+}
+
 func (s *Snmp) gatherTable(acc telegraf.Accumulator, gs snmpConnection, t Table, topTags map[string]string, walk bool) error {
 	rt, err := t.Build(gs, walk)
 	if err != nil {
@@ -615,11 +841,17 @@ func (s *Snmp) getConnection(idx int) (snmpConnection, error) {
 		return gs, nil
 	}
 
-	agent := s.Agents[idx]
+	gs, err := s.connect(s.Agents[idx])
+	if gs != nil {
+		s.connectionCache[idx] = gs
+	}
+	return gs, err
+}
 
-	var err error
-	var gs snmp.GosnmpWrapper
-	gs, err = snmp.NewWrapper(s.ClientConfig)
+// connect creates the connection to the agent. The connection is returned
+// along with connection errors to be cached by the caller.
+func (s *Snmp) connect(agent string) (snmpConnection, error) {
+	gs, err := snmp.NewWrapper(s.ClientConfig)
 	if err != nil {
 		return nil, err
 	}
@@ -629,10 +861,8 @@ func (s *Snmp) getConnection(idx int) (snmpConnection, error) {
 		return nil, err
 	}
 
-	s.connectionCache[idx] = gs
-
 	if err := gs.Connect(); err != nil {
-		return nil, fmt.Errorf("setting up connection: %w", err)
+		return gs, fmt.Errorf("setting up connection: %w", err)
 	}
 
 	return gs, nil
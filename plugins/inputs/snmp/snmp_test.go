@@ -1,10 +1,12 @@
 package snmp
 
 import (
+	"errors"
 	"fmt"
 	"net"
 	"path/filepath"
 	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -15,6 +17,7 @@ import (
 	"github.com/influxdata/telegraf/config"
 	"github.com/influxdata/telegraf/internal/snmp"
 	"github.com/influxdata/telegraf/plugins/inputs"
+	"github.com/influxdata/telegraf/selfstat"
 	"github.com/influxdata/telegraf/testutil"
 )
 
@@ -945,6 +948,145 @@ func TestGather_host(t *testing.T) {
 	require.Equal(t, "baz", m.Tags["host"])
 }
 
+// unreachableSNMPConnection times out on all requests
+type unreachableSNMPConnection struct {
+	requests int
+}
+
+func (*unreachableSNMPConnection) Host() string {
+	return "unreachable"
+}
+
+func (c *unreachableSNMPConnection) Get([]string) (*gosnmp.SnmpPacket, error) {
+	c.requests++
+	return nil, &snmp.TimeoutError{Err: errors.New("request timeout (after 3 retries)")}
+}
+
+func (c *unreachableSNMPConnection) Walk(string, gosnmp.WalkFunc) error {
+	c.requests++
+	return &snmp.TimeoutError{Err: errors.New("request timeout (after 3 retries)")}
+}
+
+func TestGatherUnreachableBackoff(t *testing.T) {
+	now := time.Unix(1650000000, 0)
+	conn := &unreachableSNMPConnection{}
+	s := &Snmp{
+		Agents:             []string{"TestGatherUnreachableBackoff_unreachable", "TestGatherUnreachableBackoff"},
+		Name:               "mytable",
+		UnreachableBackoff: config.Duration(time.Minute),
+		Fields:             []Field{{Name: "myfield2", Oid: ".1.0.0.1.2"}},
+		Tables: []Table{
+			{
+				Name:   "myOtherTable",
+				Fields: []Field{{Name: "myOtherField", Oid: ".1.0.0.0.1.5"}},
+			},
+		},
+		connectionCache: []snmpConnection{conn, tsc},
+		now:             func() time.Time { return now },
+		Log:             testutil.Logger{},
+	}
+
+	// The tables are not requested after the top-level fields timed out
+	acc := &testutil.Accumulator{}
+	require.NoError(t, s.Gather(acc))
+	require.Len(t, acc.Errors, 1)
+	require.Equal(t, 1, conn.requests)
+	require.Len(t, acc.Metrics, 2)
+
+	// The unreachable agent is skipped during the backoff
+	now = now.Add(30 * time.Second)
+	acc = &testutil.Accumulator{}
+	require.NoError(t, s.Gather(acc))
+	require.Empty(t, acc.Errors)
+	require.Equal(t, 1, conn.requests)
+	require.Len(t, acc.Metrics, 2)
+
+	now = now.Add(30 * time.Second)
+	require.NoError(t, s.Gather(acc))
+	require.Len(t, acc.Errors, 1)
+	require.Equal(t, 2, conn.requests)
+
+	values := selfstat.Values("snmp", map[string]string{"agent": "TestGatherUnreachableBackoff_unreachable"})
+	require.Equal(t, int64(2), values["errors"])
+	require.Equal(t, int64(2), values["timeouts"])
+	require.Equal(t, int64(1), values["skipped"])
+	values = selfstat.Values("snmp", map[string]string{"agent": "TestGatherUnreachableBackoff"})
+	require.Equal(t, int64(0), values["errors"])
+	require.Equal(t, int64(0), values["skipped"])
+}
+
+func TestGatherUnreachableNoBackoff(t *testing.T) {
+	conn := &unreachableSNMPConnection{}
+	s := &Snmp{
+		Agents: []string{"TestGatherUnreachableNoBackoff"},
+		Name:   "mytable",
+		Fields: []Field{{Name: "myfield2", Oid: ".1.0.0.1.2"}},
+		Tables: []Table{
+			{
+				Name:   "myOtherTable",
+				Fields: []Field{{Name: "myOtherField", Oid: ".1.0.0.0.1.5"}},
+			},
+		},
+		connectionCache: []snmpConnection{conn},
+	}
+
+	acc := &testutil.Accumulator{}
+	require.NoError(t, s.Gather(acc))
+	require.NoError(t, s.Gather(acc))
+	require.Len(t, acc.Errors, 4)
+	require.Equal(t, 4, conn.requests)
+
+	values := selfstat.Values("snmp", map[string]string{"agent": "TestGatherUnreachableNoBackoff"})
+	require.Equal(t, int64(4), values["timeouts"])
+	require.Equal(t, int64(0), values["skipped"])
+}
+
+// slowSNMPConnection records the maximum number of concurrent requests
+type slowSNMPConnection struct {
+	*testSNMPConnection
+	active    *int32
+	maxActive *int32
+}
+
+func (c *slowSNMPConnection) Get(oids []string) (*gosnmp.SnmpPacket, error) {
+	active := atomic.AddInt32(c.active, 1)
+	defer atomic.AddInt32(c.active, -1)
+	for {
+		max := atomic.LoadInt32(c.maxActive)
+		if active <= max || atomic.CompareAndSwapInt32(c.maxActive, max, active) {
+			break
+		}
+	}
+	time.Sleep(10 * time.Millisecond)
+	return c.testSNMPConnection.Get(oids)
+}
+
+func TestGatherMaxParallelAgents(t *testing.T) {
+	var active, maxActive int32
+	s := &Snmp{
+		Name:              "mytable",
+		MaxParallelAgents: 2,
+		Fields:            []Field{{Name: "myfield2", Oid: ".1.0.0.1.2"}},
+	}
+	for i := 0; i < 6; i++ {
+		s.Agents = append(s.Agents, fmt.Sprintf("TestGatherMaxParallelAgents%d", i))
+		s.connectionCache = append(s.connectionCache, &slowSNMPConnection{
+			testSNMPConnection: tsc,
+			active:             &active,
+			maxActive:          &maxActive,
+		})
+	}
+
+	acc := &testutil.Accumulator{}
+	require.NoError(t, s.Gather(acc))
+	require.Empty(t, acc.Errors)
+	require.Len(t, acc.Metrics, 6)
+	require.Equal(t, int32(2), maxActive)
+
+	values := selfstat.Values("snmp", map[string]string{"agent": "TestGatherMaxParallelAgents0"})
+	require.GreaterOrEqual(t, values["gather_time_ns"], int64(10*time.Millisecond))
+}
+
 func TestFieldConvert(t *testing.T) {
 	testTable := []struct {
 		input    interface{}
@@ -0,0 +1,200 @@
+package snmp_trap
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+
+	"github.com/influxdata/telegraf/internal/snmp"
+)
+
+// Rule maps traps with a given trap OID to an event.
+type Rule struct {
+	// Oid is the trap OID, numeric or textual.
+	Oid string `toml:"oid"`
+	// Measurement name of the matching traps.
+	Measurement string `toml:"measurement"`
+	// Severity is added as tag.
+	Severity string `toml:"severity"`
+	// Message is a template for the message field.
+	Message string `toml:"message"`
+	// Tags are added to the matching traps.
+	Tags map[string]string `toml:"tags"`
+	// Clears are the trap OIDs of the events cleared by this trap.
+	Clears []string `toml:"clears"`
+	// CorrelateBy are the fields identifying the object of the event, in
+	// addition to the source of the trap.
+	CorrelateBy []string `toml:"correlate_by"`
+
+	message *template.Template
+	raises  bool
+}
+
+// messageData is passed to the message templates
+type messageData struct {
+	Tags   map[string]string
+	Fields map[string]interface{}
+}
+
+// correlator keeps track of raised events until they are cleared
+type correlator struct {
+	timeout time.Duration
+
+	sync.Mutex
+	raised    map[string]time.Time
+	lastPrune time.Time
+}
+
+// initRules translates the OIDs, parses the templates and indexes the rules
+// by trap OID
+func (s *SnmpTrap) initRules() error {
+	s.rules = make(map[string]*Rule, len(s.Rules))
+	for i := range s.Rules {
+		r := &s.Rules[i]
+
+		oid, err := snmp.TranslateOid(r.Oid)
+		if err != nil {
+			return fmt.Errorf("translating rule OID %q: %w", r.Oid, err)
+		}
+		r.Oid = oid
+		if _, ok := s.rules[oid]; ok {
+			return fmt.Errorf("duplicate rule for OID %q", oid)
+		}
+
+		if r.Message != "" {
+			t, err := template.New(oid).Parse(r.Message)
+			if err != nil {
+				return fmt.Errorf("parsing message of rule %q: %w", oid, err)
+			}
+			r.message = t
+		}
+
+		for j, cleared := range r.Clears {
+			if r.Clears[j], err = snmp.TranslateOid(cleared); err != nil {
+				return fmt.Errorf("translating cleared OID %q: %w", cleared, err)
+			}
+		}
+		s.rules[oid] = r
+	}
+
+	// Traps cleared by another trap are tracked as raised events
+	for _, r := range s.rules {
+		for _, cleared := range r.Clears {
+			raising, ok := s.rules[cleared]
+			if !ok {
+				return fmt.Errorf("no rule for OID %q cleared by %q", cleared, r.Oid)
+			}
+			raising.raises = true
+		}
+	}
+
+	s.correlator = &correlator{
+		timeout: time.Duration(s.CorrelationTimeout),
+		raised:  make(map[string]time.Time),
+	}
+	return nil
+}
+
+// apply applies the rule to the trap and returns the measurement name
+func (s *SnmpTrap) apply(r *Rule, tags map[string]string, fields map[string]interface{}, tm time.Time) string {
+	if r.Severity != "" {
+		tags["severity"] = r.Severity
+	}
+	for k, v := range r.Tags {
+		tags[k] = v
+	}
+
+	if r.raises {
+		tags["state"] = "raised"
+		s.correlator.raise(correlationKey(r.Oid, r.CorrelateBy, tags, fields), tm)
+	}
+	if len(r.Clears) > 0 {
+		tags["state"] = "cleared"
+		for _, cleared := range r.Clears {
+			key := correlationKey(cleared, r.CorrelateBy, tags, fields)
+			if raised, ok := s.correlator.clear(key, tm); ok {
+				fields["duration_ns"] = tm.Sub(raised).Nanoseconds()
+				tags["cleared_oid"] = cleared
+				break
+			}
+		}
+	}
+
+	if r.message != nil {
+		var b strings.Builder
+		if err := r.message.Execute(&b, messageData{Tags: tags, Fields: fields}); err != nil {
+			s.Log.Errorf("Error executing message template of rule %q: %v", r.Oid, err)
+		} else {
+			fields["message"] = b.String()
+		}
+	}
+
+	if r.Measurement != "" {
+		return r.Measurement
+	}
+	return "snmp_trap"
+}
+
+// correlationKey identifies the object of an event by the trap OID, the
+// source and the values of the given fields. Fields may carry the instance
+// suffix of the variable.
+func correlationKey(oid string, correlateBy []string, tags map[string]string, fields map[string]interface{}) string {
+	parts := []string{oid, tags["source"]}
+	for _, name := range correlateBy {
+		parts = append(parts, fmt.Sprintf("%v", fieldValue(fields, name)))
+	}
+	return strings.Join(parts, "\x00")
+}
+
+func fieldValue(fields map[string]interface{}, name string) interface{} {
+	if v, ok := fields[name]; ok {
+		return v
+	}
+
+	// Use the instance with the lowest name for determinism
+	var keys []string
+	for k := range fields {
+		if strings.HasPrefix(k, name+".") {
+			keys = append(keys, k)
+		}
+	}
+	if len(keys) == 0 {
+		return nil
+	}
+	sort.Strings(keys)
+	return fields[keys[0]]
+}
+
+func (c *correlator) raise(key string, tm time.Time) {
+	c.Lock()
+	defer c.Unlock()
+
+	if _, ok := c.raised[key]; !ok {
+		c.raised[key] = tm
+	}
+
+	// Remove events never cleared at most once per minute
+	if c.timeout > 0 && tm.Sub(c.lastPrune) >= time.Minute {
+		for k, raised := range c.raised {
+			if tm.Sub(raised) > c.timeout {
+				delete(c.raised, k)
+			}
+		}
+		c.lastPrune = tm
+	}
+}
+
+func (c *correlator) clear(key string, tm time.Time) (time.Time, bool) {
+	c.Lock()
+	defer c.Unlock()
+
+	raised, ok := c.raised[key]
+	if !ok {
+		return time.Time{}, false
+	}
+	delete(c.raised, key)
+	return raised, c.timeout <= 0 || tm.Sub(raised) <= c.timeout
+}
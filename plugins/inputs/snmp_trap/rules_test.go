@@ -0,0 +1,195 @@
+package snmp_trap
+
+import (
+	"fmt"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/gosnmp/gosnmp"
+	"github.com/stretchr/testify/require"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/config"
+	"github.com/influxdata/telegraf/internal/snmp"
+	"github.com/influxdata/telegraf/testutil"
+)
+
+const (
+	linkDownOid = ".1.3.6.1.6.3.1.1.5.3"
+	linkUpOid   = ".1.3.6.1.6.3.1.1.5.4"
+	ifIndexOid  = ".1.3.6.1.2.1.2.2.1.1"
+	ifDescrOid  = ".1.3.6.1.2.1.2.2.1.2"
+)
+
+var linkEntries = map[string]snmp.MibEntry{
+	linkDownOid: {MibName: "IF-MIB", OidText: "linkDown", OidNum: linkDownOid},
+	linkUpOid:   {MibName: "IF-MIB", OidText: "linkUp", OidNum: linkUpOid},
+	ifIndexOid:  {MibName: "IF-MIB", OidText: "ifIndex", OidNum: ifIndexOid},
+	ifDescrOid:  {MibName: "IF-MIB", OidText: "ifDescr", OidNum: ifDescrOid},
+}
+
+// linkLookup resolves the link traps and the variables of all instances
+func linkLookup(oid string) (snmp.MibEntry, error) {
+	for prefix, e := range linkEntries {
+		if oid == prefix || len(oid) > len(prefix) && oid[:len(prefix)+1] == prefix+"." {
+			return e, nil
+		}
+	}
+	return snmp.MibEntry{}, fmt.Errorf("unexpected oid %q", oid)
+}
+
+func linkTrap(trapOid string, index int, descr string) *gosnmp.SnmpPacket {
+	return &gosnmp.SnmpPacket{
+		Version:   gosnmp.Version2c,
+		Community: "public",
+		Variables: []gosnmp.SnmpPDU{
+			{Name: ".1.3.6.1.6.3.1.1.4.1.0", Type: gosnmp.ObjectIdentifier, Value: trapOid},
+			{Name: fmt.Sprintf("%s.%d", ifIndexOid, index), Type: gosnmp.Integer, Value: index},
+			{Name: fmt.Sprintf("%s.%d", ifDescrOid, index), Type: gosnmp.OctetString, Value: descr},
+		},
+	}
+}
+
+func newLinkSnmpTrap(t *testing.T, now *time.Time, fieldInstance bool) (*SnmpTrap, *testutil.Accumulator) {
+	s := &SnmpTrap{
+		FieldInstance:      fieldInstance,
+		CorrelationTimeout: config.Duration(time.Hour),
+		Rules: []Rule{
+			{
+				Oid:         "1.3.6.1.6.3.1.1.5.3",
+				Measurement: "snmp_event",
+				Severity:    "major",
+				Message:     `Link {{index .Fields "ifDescr"}} down on {{.Tags.source}}`,
+				CorrelateBy: []string{"ifIndex"},
+				Tags:        map[string]string{"event": "link"},
+			},
+			{
+				Oid:         linkUpOid,
+				Measurement: "snmp_event",
+				Severity:    "clear",
+				Clears:      []string{linkDownOid},
+				CorrelateBy: []string{"ifIndex"},
+				Tags:        map[string]string{"event": "link"},
+			},
+		},
+		timeFunc:   func() time.Time { return *now },
+		lookupFunc: linkLookup,
+		Log:        testutil.Logger{},
+	}
+	require.NoError(t, s.Init())
+
+	acc := &testutil.Accumulator{}
+	s.acc = acc
+	return s, acc
+}
+
+func TestRules(t *testing.T) {
+	now := time.Unix(1650000000, 0)
+	s, acc := newLinkSnmpTrap(t, &now, false)
+	handler := makeTrapHandler(s)
+	source := &net.UDPAddr{IP: net.ParseIP("192.0.2.1")}
+
+	handler(linkTrap(linkDownOid, 2, "eth1"), source)
+	handler(linkTrap(linkDownOid, 3, "eth2"), source)
+	now = now.Add(time.Minute)
+	handler(linkTrap(linkUpOid, 2, "eth1"), source)
+	// Clearing traps without raised event
+	handler(linkTrap(linkUpOid, 4, "eth3"), source)
+
+	tags := func(oid, name, severity, state string) map[string]string {
+		return map[string]string{
+			"oid":       oid,
+			"name":      name,
+			"mib":       "IF-MIB",
+			"version":   "2c",
+			"source":    "192.0.2.1",
+			"community": "public",
+			"severity":  severity,
+			"state":     state,
+			"event":     "link",
+		}
+	}
+	cleared := tags(linkUpOid, "linkUp", "clear", "cleared")
+	cleared["cleared_oid"] = linkDownOid
+
+	expected := []telegraf.Metric{
+		testutil.MustMetric(
+			"snmp_event",
+			tags(linkDownOid, "linkDown", "major", "raised"),
+			map[string]interface{}{"ifIndex": 2, "ifDescr": "eth1", "message": "Link eth1 down on 192.0.2.1"},
+			time.Unix(1650000000, 0),
+		),
+		testutil.MustMetric(
+			"snmp_event",
+			tags(linkDownOid, "linkDown", "major", "raised"),
+			map[string]interface{}{"ifIndex": 3, "ifDescr": "eth2", "message": "Link eth2 down on 192.0.2.1"},
+			time.Unix(1650000000, 0),
+		),
+		testutil.MustMetric(
+			"snmp_event",
+			cleared,
+			map[string]interface{}{"ifIndex": 2, "ifDescr": "eth1", "duration_ns": int64(time.Minute)},
+			now,
+		),
+		testutil.MustMetric(
+			"snmp_event",
+			tags(linkUpOid, "linkUp", "clear", "cleared"),
+			map[string]interface{}{"ifIndex": 4, "ifDescr": "eth3"},
+			now,
+		),
+	}
+	testutil.RequireMetricsEqual(t, expected, acc.GetTelegrafMetrics())
+}
+
+func TestRulesCorrelationTimeout(t *testing.T) {
+	now := time.Unix(1650000000, 0)
+	s, acc := newLinkSnmpTrap(t, &now, true)
+	handler := makeTrapHandler(s)
+	source := &net.UDPAddr{IP: net.ParseIP("192.0.2.1")}
+
+	handler(linkTrap(linkDownOid, 2, "eth1"), source)
+	now = now.Add(2 * time.Hour)
+	handler(linkTrap(linkUpOid, 2, "eth1"), source)
+
+	metrics := acc.GetTelegrafMetrics()
+	require.Len(t, metrics, 2)
+	require.Equal(t, map[string]interface{}{"ifIndex.2": int64(2), "ifDescr.2": "eth1"}, metrics[1].Fields())
+	_, ok := metrics[1].GetTag("cleared_oid")
+	require.False(t, ok)
+}
+
+func TestFieldInstance(t *testing.T) {
+	now := time.Unix(1650000000, 0)
+	s, acc := newLinkSnmpTrap(t, &now, true)
+	s.Rules = nil
+	require.NoError(t, s.Init())
+	handler := makeTrapHandler(s)
+
+	handler(linkTrap(linkDownOid, 2, "eth1"), &net.UDPAddr{IP: net.ParseIP("192.0.2.1")})
+
+	metrics := acc.GetTelegrafMetrics()
+	require.Len(t, metrics, 1)
+	require.Equal(t, "snmp_trap", metrics[0].Name())
+	require.Equal(t, map[string]interface{}{"ifIndex.2": int64(2), "ifDescr.2": "eth1"}, metrics[0].Fields())
+}
+
+func TestRulesInitErrors(t *testing.T) {
+	s := &SnmpTrap{
+		Rules: []Rule{{Oid: linkUpOid, Clears: []string{linkDownOid}}},
+		Log:   testutil.Logger{},
+	}
+	require.EqualError(t, s.Init(), `no rule for OID ".1.3.6.1.6.3.1.1.5.3" cleared by ".1.3.6.1.6.3.1.1.5.4"`)
+
+	s = &SnmpTrap{
+		Rules: []Rule{{Oid: linkUpOid}, {Oid: linkUpOid[1:]}},
+		Log:   testutil.Logger{},
+	}
+	require.EqualError(t, s.Init(), `duplicate rule for OID ".1.3.6.1.6.3.1.1.5.4"`)
+
+	s = &SnmpTrap{
+		Rules: []Rule{{Oid: linkUpOid, Message: "{{.Fields"}},
+		Log:   testutil.Logger{},
+	}
+	require.Error(t, s.Init())
+}
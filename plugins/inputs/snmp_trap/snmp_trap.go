@@ -32,6 +32,16 @@ type SnmpTrap struct {
 	PrivProtocol string `toml:"priv_protocol"`
 	PrivPassword string `toml:"priv_password"`
 
+	// Append the instance of the variables to the field names
+	FieldInstance bool `toml:"field_instance"`
+
+	// Rules mapping trap OIDs to events
+	Rules              []Rule          `toml:"rule"`
+	CorrelationTimeout config.Duration `toml:"correlation_timeout"`
+
+	rules      map[string]*Rule
+	correlator *correlator
+
 	acc        telegraf.Accumulator
 	listener   *gosnmp.TrapListener
 	timeFunc   func() time.Time
@@ -72,6 +82,43 @@ var sampleConfig = `
   # priv_protocol = ""
   ## Privacy password used for encrypted messages.
   # priv_password = ""
+  ##
+  ## Append the instance of the variables to the field names, e.g. ifDescr.2
+  ## instead of ifDescr, to keep the variables of different instances apart
+  ## and to be able to forward the traps unchanged.
+  # field_instance = false
+  ##
+  ## Time to keep raised events for correlation with clearing traps.
+  # correlation_timeout = "24h"
+
+  ## Rules mapping traps to events by their trap OID.
+  # [[inputs.snmp_trap.rule]]
+  #   ## Trap OID, numeric or textual.
+  #   oid = "IF-MIB::linkDown"
+  #   ## Measurement name of the matching traps, defaults to "snmp_trap".
+  #   measurement = "snmp_event"
+  #   ## Value of the severity tag.
+  #   severity = "major"
+  #   ## Template of the message field using the Go template syntax; the
+  #   ## tags and fields of the trap are available as .Tags and .Fields.
+  #   message = "Link {{.Fields.ifDescr}} down on {{.Tags.source}}"
+  #   ## Fields identifying the object of the event in addition to the
+  #   ## source, used to correlate raising and clearing traps.
+  #   correlate_by = ["ifIndex"]
+  #   ## Additional tags.
+  #   [inputs.snmp_trap.rule.tags]
+  #     event = "link"
+  #
+  # [[inputs.snmp_trap.rule]]
+  #   oid = "IF-MIB::linkUp"
+  #   measurement = "snmp_event"
+  #   severity = "clear"
+  #   message = "Link {{.Fields.ifDescr}} up on {{.Tags.source}}"
+  #   ## Trap OIDs of the events cleared by this trap.
+  #   clears = ["IF-MIB::linkDown"]
+  #   correlate_by = ["ifIndex"]
+  #   [inputs.snmp_trap.rule.tags]
+  #     event = "link"
 `
 
 func (s *SnmpTrap) SampleConfig() string {
@@ -94,6 +141,8 @@ func init() {
 			ServiceAddress: "udp://:162",
 			Path:           []string{"/usr/share/snmp/mibs"},
 			Version:        "2c",
+
+			CorrelationTimeout: config.Duration(24 * time.Hour),
 		}
 	})
 }
@@ -103,7 +152,7 @@ func (s *SnmpTrap) Init() error {
 	if err != nil {
 		s.Log.Errorf("Could not get path %v", err)
 	}
-	return nil
+	return s.initRules()
 }
 
 func (s *SnmpTrap) Start(acc telegraf.Accumulator) error {
@@ -232,6 +281,14 @@ func (s *SnmpTrap) Stop() {
 	}
 }
 
+// fieldName returns the name of the field of the variable
+func (s *SnmpTrap) fieldName(oid string, e snmp.MibEntry) string {
+	if s.FieldInstance && e.OidNum != "" && strings.HasPrefix(oid, e.OidNum+".") {
+		return e.OidText + strings.TrimPrefix(oid, e.OidNum)
+	}
+	return e.OidText
+}
+
 func setTrapOid(tags map[string]string, oid string, e snmp.MibEntry) {
 	tags["oid"] = oid
 	tags["name"] = e.OidText
@@ -321,9 +378,7 @@ func makeTrapHandler(s *SnmpTrap) gosnmp.TrapHandlerFunc {
 				return
 			}
 
-			name := e.OidText
-
-			fields[name] = value
+			fields[s.fieldName(v.Name, e)] = value
 		}
 
 		if packet.Version == gosnmp.Version3 {
@@ -340,6 +395,11 @@ func makeTrapHandler(s *SnmpTrap) gosnmp.TrapHandlerFunc {
 			}
 		}
 
-		s.acc.AddFields("snmp_trap", fields, tags, tm)
+		name := "snmp_trap"
+		if r, ok := s.rules[tags["oid"]]; ok {
+			name = s.apply(r, tags, fields, tm)
+		}
+
+		s.acc.AddFields(name, fields, tags, tm)
 	}
 }
@@ -3,6 +3,8 @@ package socket_listener
 import (
 	"bufio"
 	"crypto/tls"
+	"encoding/binary"
+	"errors"
 	"fmt"
 	"io"
 	"net"
@@ -126,6 +128,9 @@ func (ssl *streamSocketListener) read(c net.Conn) {
 	}
 
 	scnr := bufio.NewScanner(decoder)
+	if ssl.SplittingStrategy == "varint" {
+		scnr.Split(scanVarintFrames)
+	}
 	for {
 		if ssl.ReadTimeout != nil && *ssl.ReadTimeout > 0 {
 			if err := c.SetReadDeadline(time.Now().Add(time.Duration(*ssl.ReadTimeout))); err != nil {
@@ -159,6 +164,25 @@ func (ssl *streamSocketListener) read(c net.Conn) {
 	}
 }
 
+// scanVarintFrames is a bufio.SplitFunc returning messages prefixed with
+// their varint encoded length
+func scanVarintFrames(data []byte, atEOF bool) (int, []byte, error) {
+	size, n := binary.Uvarint(data)
+	if n < 0 {
+		return 0, nil, errors.New("invalid message length")
+	}
+	if n == 0 || uint64(len(data)-n) < size {
+		if atEOF && len(data) > 0 {
+			return 0, nil, io.ErrUnexpectedEOF
+		}
+		// Request more data
+		return 0, nil, nil
+	}
+
+	end := n + int(size)
+	return end, data[n:end], nil
+}
+
 type packetSocketListener struct {
 	net.PacketConn
 	*SocketListener
@@ -194,13 +218,14 @@ func (psl *packetSocketListener) listen() {
 }
 
 type SocketListener struct {
-	ServiceAddress  string           `toml:"service_address"`
-	MaxConnections  int              `toml:"max_connections"`
-	ReadBufferSize  config.Size      `toml:"read_buffer_size"`
-	ReadTimeout     *config.Duration `toml:"read_timeout"`
-	KeepAlivePeriod *config.Duration `toml:"keep_alive_period"`
-	SocketMode      string           `toml:"socket_mode"`
-	ContentEncoding string           `toml:"content_encoding"`
+	ServiceAddress    string           `toml:"service_address"`
+	MaxConnections    int              `toml:"max_connections"`
+	ReadBufferSize    config.Size      `toml:"read_buffer_size"`
+	ReadTimeout       *config.Duration `toml:"read_timeout"`
+	KeepAlivePeriod   *config.Duration `toml:"keep_alive_period"`
+	SocketMode        string           `toml:"socket_mode"`
+	ContentEncoding   string           `toml:"content_encoding"`
+	SplittingStrategy string           `toml:"splitting_strategy"`
 	tlsint.ServerConfig
 
 	wg sync.WaitGroup
@@ -275,6 +300,11 @@ func (sl *SocketListener) SampleConfig() string {
   ## Content encoding for message payloads, can be set to "gzip" to or
   ## "identity" to apply no encoding.
   # content_encoding = "identity"
+
+  ## Strategy for separating messages on stream sockets (e.g. TCP), can be
+  ## "newline" or "varint" for messages prefixed with their varint encoded
+  ## length as used for length-delimited protocol-buffer messages.
+  # splitting_strategy = "newline"
 `
 }
 
@@ -296,6 +326,12 @@ func (sl *SocketListener) Start(acc telegraf.Accumulator) error {
 	protocol := spl[0]
 	addr := spl[1]
 
+	switch sl.SplittingStrategy {
+	case "", "newline", "varint":
+	default:
+		return fmt.Errorf("invalid splitting strategy %q", sl.SplittingStrategy)
+	}
+
 	if protocol == "unix" || protocol == "unixpacket" || protocol == "unixgram" {
 		// no good way of testing for "file does not exist".
 		// Instead just ignore error and blow up when we try to listen, which will
@@ -3,6 +3,7 @@ package socket_listener
 import (
 	"bytes"
 	"crypto/tls"
+	"encoding/binary"
 	"io"
 	"log"
 	"net"
@@ -234,6 +235,53 @@ func TestSocketListenerDecode_udp(t *testing.T) {
 	testSocketListener(t, sl, client)
 }
 
+func TestSocketListenerVarintFrames_tcp(t *testing.T) {
+	testEmptyLog := prepareLog(t)
+	defer testEmptyLog()
+
+	sl := newSocketListener()
+	sl.Log = testutil.Logger{}
+	sl.ServiceAddress = "tcp://127.0.0.1:0"
+	sl.SplittingStrategy = "varint"
+
+	acc := &testutil.Accumulator{}
+	err := sl.Start(acc)
+	require.NoError(t, err)
+	defer sl.Stop()
+
+	client, err := net.Dial("tcp", sl.Closer.(net.Listener).Addr().String())
+	require.NoError(t, err)
+
+	var buf []byte
+	for _, line := range []string{"test,foo=bar v=1i 123456789", "test,foo=baz v=2i 123456790"} {
+		size := make([]byte, binary.MaxVarintLen64)
+		n := binary.PutUvarint(size, uint64(len(line)))
+		buf = append(buf, size[:n]...)
+		buf = append(buf, line...)
+	}
+
+	// Split the data within the second frame
+	_, err = client.Write(buf[:35])
+	require.NoError(t, err)
+	_, err = client.Write(buf[35:])
+	require.NoError(t, err)
+
+	acc.Wait(2)
+	acc.Lock()
+	defer acc.Unlock()
+	require.Equal(t, map[string]string{"foo": "bar"}, acc.Metrics[0].Tags)
+	require.Equal(t, map[string]interface{}{"v": int64(2)}, acc.Metrics[1].Fields)
+}
+
+func TestSocketListenerInvalidSplittingStrategy(t *testing.T) {
+	sl := newSocketListener()
+	sl.Log = testutil.Logger{}
+	sl.ServiceAddress = "tcp://127.0.0.1:0"
+	sl.SplittingStrategy = "null"
+
+	require.Error(t, sl.Start(&testutil.Accumulator{}))
+}
+
 func testSocketListener(t *testing.T, sl *SocketListener, client net.Conn) {
 	mstr12 := []byte("test,foo=bar v=1i 123456789\ntest,foo=baz v=2i 123456790\n")
 	mstr3 := []byte("test,foo=zab v=3i 123456791\n")
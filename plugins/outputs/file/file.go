@@ -24,6 +24,7 @@ type File struct {
 	writer     io.Writer
 	closers    []io.Closer
 	serializer serializers.Serializer
+	started    bool
 }
 
 var sampleConfig = `
@@ -60,6 +61,7 @@ func (f *File) SetSerializer(serializer serializers.Serializer) {
 
 func (f *File) Connect() error {
 	writers := []io.Writer{}
+	f.started = false
 
 	if len(f.Files) == 0 {
 		f.Files = []string{"stdout"}
@@ -105,6 +107,8 @@ func (f *File) Description() string {
 func (f *File) Write(metrics []telegraf.Metric) error {
 	var writeErr error
 
+	f.startStream()
+
 	if f.UseBatchFormat {
 		octets, err := f.serializer.SerializeBatch(metrics)
 		if err != nil {
@@ -132,6 +136,53 @@ func (f *File) Write(metrics []telegraf.Metric) error {
 	return writeErr
 }
 
+// startStream tells serializers writing a header, if any of the files is
+// new or empty or on the first write to stdout, to write the header with the
+// next metrics.  The header is written to all files.  If one of the files
+// has a header already, its layout is kept.
+func (f *File) startStream() {
+	s, ok := f.serializer.(serializers.HeaderSerializer)
+	if !ok {
+		return
+	}
+
+	writeHeader := false
+	var existing []string
+	for _, file := range f.Files {
+		if file == "stdout" {
+			writeHeader = writeHeader || !f.started
+			continue
+		}
+		if info, err := os.Stat(file); err != nil || info.Size() == 0 {
+			writeHeader = true
+		} else {
+			existing = append(existing, file)
+		}
+	}
+	if writeHeader || !f.started {
+		if len(existing) == 0 || !f.resumeStream(s, existing[0], writeHeader) {
+			s.StartStream(writeHeader)
+		}
+	}
+	f.started = true
+}
+
+// resumeStream continues the stream of an existing file
+func (f *File) resumeStream(s serializers.HeaderSerializer, file string, writeHeader bool) bool {
+	r, err := os.Open(file)
+	if err != nil {
+		f.Log.Warnf("Could not read header of %q: %v", file, err)
+		return false
+	}
+	defer r.Close()
+
+	if err := s.ResumeStream(r, writeHeader); err != nil {
+		f.Log.Warnf("Could not read header of %q: %v", file, err)
+		return false
+	}
+	return true
+}
+
 func init() {
 	outputs.Add("file", func() telegraf.Output {
 		return &File{}
@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"io"
 	"os"
+	"path/filepath"
 	"testing"
 
 	"github.com/stretchr/testify/require"
@@ -203,3 +204,43 @@ func validateFile(t *testing.T, fileName, expS string) {
 	require.NoError(t, err)
 	require.Equal(t, expS, string(buf))
 }
+
+func TestFileCSVHeaderPerFile(t *testing.T) {
+	s, err := serializers.NewSerializer(&serializers.Config{DataFormat: "csv", CSVHeader: "once"})
+	require.NoError(t, err)
+	fn := filepath.Join(t.TempDir(), "metrics.csv")
+	f := File{
+		Files:      []string{fn},
+		serializer: s,
+	}
+	require.NoError(t, f.Connect())
+	defer f.Close()
+
+	// The header is written once to the new file
+	require.NoError(t, f.Write(testutil.MockMetrics()))
+	require.NoError(t, f.Write(testutil.MockMetrics()))
+	validateFile(t, fn, "timestamp,measurement,tag1,value\n1257894000,test1,value1,1\n1257894000,test1,value1,1\n")
+
+	// and again once the file is empty, e.g. after a rotation
+	require.NoError(t, os.Truncate(fn, 0))
+	require.NoError(t, f.Write(testutil.MockMetrics()))
+	validateFile(t, fn, "timestamp,measurement,tag1,value\n1257894000,test1,value1,1\n")
+}
+
+func TestFileCSVHeaderExistingFile(t *testing.T) {
+	s, err := serializers.NewSerializer(&serializers.Config{DataFormat: "csv", CSVHeader: "once"})
+	require.NoError(t, err)
+	fn := filepath.Join(t.TempDir(), "metrics.csv")
+	require.NoError(t, os.WriteFile(fn, []byte("measurement,value,timestamp,tag1\ntest0,0,1257893999,value0\n"), 0640))
+	f := File{
+		Files:      []string{fn},
+		serializer: s,
+		Log:        testutil.Logger{},
+	}
+	require.NoError(t, f.Connect())
+	defer f.Close()
+
+	// The column order of the existing header is kept
+	require.NoError(t, f.Write(testutil.MockMetrics()))
+	validateFile(t, fn, "measurement,value,timestamp,tag1\ntest0,0,1257893999,value0\ntest1,1,1257894000,value1\n")
+}
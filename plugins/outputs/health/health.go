@@ -35,6 +35,8 @@ var sampleConfig = `
   # write_timeout = "5s"
 
   ## Username and password to accept for HTTP basic authentication.
+  ## The password can reference a secret in a secret-store, e.g.
+  ##   basic_password = "@{vault:health_password}"
   # basic_username = "user1"
   # basic_password = "secret"
 
@@ -72,7 +74,7 @@ type Health struct {
 	ReadTimeout    config.Duration `toml:"read_timeout"`
 	WriteTimeout   config.Duration `toml:"write_timeout"`
 	BasicUsername  string          `toml:"basic_username"`
-	BasicPassword  string          `toml:"basic_password"`
+	BasicPassword  config.Secret   `toml:"basic_password"`
 	tlsint.ServerConfig
 
 	Compares []*Compares     `toml:"compares"`
@@ -137,7 +139,8 @@ func (h *Health) Init() error {
 
 // Connect starts the HTTP server.
 func (h *Health) Connect() error {
-	authHandler := internal.AuthHandler(h.BasicUsername, h.BasicPassword, "health", onAuthError)
+	enabled := h.BasicUsername != "" || !h.BasicPassword.Empty()
+	authHandler := internal.SecretAuthHandler(h.BasicUsername, h.BasicPassword.Get, enabled, "health", onAuthError)
 
 	h.server = &http.Server{
 		Addr:         h.ServiceAddress,
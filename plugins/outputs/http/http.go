@@ -14,6 +14,7 @@ import (
 	awsV2 "github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/aws/signer/v4"
 	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/config"
 	internalaws "github.com/influxdata/telegraf/config/aws"
 	"github.com/influxdata/telegraf/internal"
 	httpconfig "github.com/influxdata/telegraf/plugins/common/http"
@@ -37,6 +38,8 @@ var sampleConfig = `
   # method = "POST"
 
   ## HTTP Basic Auth credentials
+  ## Credentials can reference secrets in a secret-store, e.g.
+  ## password = "@{vault:http_password}"
   # username = "username"
   # password = "pa$$word"
 
@@ -120,7 +123,7 @@ type HTTP struct {
 	URL                     string            `toml:"url"`
 	Method                  string            `toml:"method"`
 	Username                string            `toml:"username"`
-	Password                string            `toml:"password"`
+	Password                config.Secret     `toml:"password"`
 	Headers                 map[string]string `toml:"headers"`
 	ContentEncoding         string            `toml:"content_encoding"`
 	UseBatchFormat          bool              `toml:"use_batch_format"`
@@ -256,8 +259,13 @@ func (h *HTTP) writeMetric(reqBody []byte) error {
 		}
 	}
 
-	if h.Username != "" || h.Password != "" {
-		req.SetBasicAuth(h.Username, h.Password)
+	if h.Username != "" || !h.Password.Empty() {
+		password, err := h.Password.Get()
+		if err != nil {
+			return fmt.Errorf("getting password failed: %w", err)
+		}
+		req.SetBasicAuth(h.Username, string(password))
+		config.ReleaseSecret(password)
 	}
 
 	req.Header.Set("User-Agent", internal.ProductToken())
@@ -279,20 +287,19 @@ func (h *HTTP) writeMetric(reqBody []byte) error {
 	defer resp.Body.Close()
 
 	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
-		for _, nonRetryableStatusCode := range h.NonRetryableStatusCodes {
-			if resp.StatusCode == nonRetryableStatusCode {
-				h.Log.Errorf("Received non-retryable status %v. Metrics are lost.", resp.StatusCode)
-				return nil
-			}
-		}
-
 		errorLine := ""
 		scanner := bufio.NewScanner(io.LimitReader(resp.Body, maxErrMsgLen))
 		if scanner.Scan() {
 			errorLine = scanner.Text()
 		}
 
-		return fmt.Errorf("when writing to [%s] received status code: %d. body: %s", h.URL, resp.StatusCode, errorLine)
+		err := fmt.Errorf("when writing to [%s] received status code: %d. body: %s", h.URL, resp.StatusCode, errorLine)
+		for _, nonRetryableStatusCode := range h.NonRetryableStatusCodes {
+			if resp.StatusCode == nonRetryableStatusCode {
+				return &internal.NonRetryableError{Err: err}
+			}
+		}
+		return err
 	}
 
 	_, err = io.ReadAll(resp.Body)
@@ -13,6 +13,7 @@ import (
 	"github.com/stretchr/testify/require"
 
 	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/config"
 	internalaws "github.com/influxdata/telegraf/config/aws"
 	"github.com/influxdata/telegraf/internal"
 	"github.com/influxdata/telegraf/metric"
@@ -186,7 +187,9 @@ func TestStatusCode(t *testing.T) {
 			},
 			statusCode: http.StatusConflict,
 			errFunc: func(t *testing.T, err error) {
-				require.NoError(t, err)
+				var rerr telegraf.RetryableError
+				require.ErrorAs(t, err, &rerr)
+				require.False(t, rerr.Retryable())
 			},
 		},
 	}
@@ -345,7 +348,7 @@ func TestBasicAuth(t *testing.T) {
 			name: "password only",
 			plugin: &HTTP{
 				URL:      u.String(),
-				Password: "pa$$word",
+				Password: config.NewSecret("pa$$word"),
 			},
 		},
 		{
@@ -353,7 +356,7 @@ func TestBasicAuth(t *testing.T) {
 			plugin: &HTTP{
 				URL:      u.String(),
 				Username: "username",
-				Password: "pa$$word",
+				Password: config.NewSecret("pa$$word"),
 			},
 		},
 	}
@@ -362,8 +365,10 @@ func TestBasicAuth(t *testing.T) {
 		t.Run(tt.name, func(t *testing.T) {
 			ts.Config.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 				username, password, _ := r.BasicAuth()
+				expected, err := tt.plugin.Password.Get()
+				require.NoError(t, err)
 				require.Equal(t, tt.plugin.Username, username)
-				require.Equal(t, tt.plugin.Password, password)
+				require.Equal(t, string(expected), password)
 				w.WriteHeader(http.StatusOK)
 			})
 
@@ -412,7 +417,7 @@ func TestOAuthClientCredentialsGrant(t *testing.T) {
 				HTTPClientConfig: httpconfig.HTTPClientConfig{
 					OAuth2Config: oauth.OAuth2Config{
 						ClientID:     "howdy",
-						ClientSecret: "secret",
+						ClientSecret: config.NewSecret("secret"),
 						TokenURL:     u.String() + "/token",
 						Scopes:       []string{"urn:opc:idm:__myscopes__"},
 					},
@@ -17,6 +17,7 @@ import (
 	"github.com/influxdata/telegraf"
 	"github.com/influxdata/telegraf/config"
 	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/plugins/common/oauth"
 	"github.com/influxdata/telegraf/plugins/common/tls"
 	"github.com/influxdata/telegraf/plugins/outputs"
 )
@@ -37,6 +38,8 @@ var sampleConfig = `
   # timeout = "5s"
 
   ## Basic auth credential
+  ## Credentials can reference secrets in a secret-store, e.g.
+  ## password = "@{vault:loki_password}"
   # username = "loki"
   # password = "pass"
 
@@ -57,10 +60,10 @@ type Loki struct {
 	Endpoint     string            `toml:"endpoint"`
 	Timeout      config.Duration   `toml:"timeout"`
 	Username     string            `toml:"username"`
-	Password     string            `toml:"password"`
+	Password     config.Secret     `toml:"password"`
 	Headers      map[string]string `toml:"http_headers"`
 	ClientID     string            `toml:"client_id"`
-	ClientSecret string            `toml:"client_secret"`
+	ClientSecret config.Secret     `toml:"client_secret"`
 	TokenURL     string            `toml:"token_url"`
 	Scopes       []string          `toml:"scopes"`
 	GZipRequest  bool              `toml:"gzip_request"`
@@ -92,15 +95,14 @@ func (l *Loki) createClient(ctx context.Context) (*http.Client, error) {
 		Timeout: time.Duration(l.Timeout),
 	}
 
-	if l.ClientID != "" && l.ClientSecret != "" && l.TokenURL != "" {
+	if l.ClientID != "" && !l.ClientSecret.Empty() && l.TokenURL != "" {
 		oauthConfig := clientcredentials.Config{
-			ClientID:     l.ClientID,
-			ClientSecret: l.ClientSecret,
-			TokenURL:     l.TokenURL,
-			Scopes:       l.Scopes,
+			ClientID: l.ClientID,
+			TokenURL: l.TokenURL,
+			Scopes:   l.Scopes,
 		}
 		ctx = context.WithValue(ctx, oauth2.HTTPClient, client)
-		client = oauthConfig.Client(ctx)
+		client = oauth2.NewClient(ctx, oauth.NewSecretTokenSource(ctx, oauthConfig, l.ClientSecret))
 	}
 
 	return client, nil
@@ -182,7 +184,12 @@ func (l *Loki) writeMetrics(s Streams) error {
 	}
 
 	if l.Username != "" {
-		req.SetBasicAuth(l.Username, l.Password)
+		password, err := l.Password.Get()
+		if err != nil {
+			return fmt.Errorf("getting password failed: %w", err)
+		}
+		req.SetBasicAuth(l.Username, string(password))
+		config.ReleaseSecret(password)
 	}
 
 	for k, v := range l.Headers {
@@ -14,6 +14,7 @@ import (
 	"github.com/stretchr/testify/require"
 
 	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/config"
 	"github.com/influxdata/telegraf/internal"
 	"github.com/influxdata/telegraf/testutil"
 )
@@ -264,7 +265,7 @@ func TestBasicAuth(t *testing.T) {
 			plugin: &Loki{
 				Domain:   u.String(),
 				Username: "username",
-				Password: "pa$$word",
+				Password: config.NewSecret("pa$$word"),
 			},
 		},
 	}
@@ -273,8 +274,10 @@ func TestBasicAuth(t *testing.T) {
 		t.Run(tt.name, func(t *testing.T) {
 			ts.Config.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 				username, password, _ := r.BasicAuth()
+				expected, err := tt.plugin.Password.Get()
+				require.NoError(t, err)
 				require.Equal(t, tt.plugin.Username, username)
-				require.Equal(t, tt.plugin.Password, password)
+				require.Equal(t, string(expected), password)
 				w.WriteHeader(http.StatusOK)
 			})
 
@@ -319,7 +322,7 @@ func TestOAuthClientCredentialsGrant(t *testing.T) {
 			plugin: &Loki{
 				Domain:       u.String(),
 				ClientID:     "howdy",
-				ClientSecret: "secret",
+				ClientSecret: config.NewSecret("secret"),
 				TokenURL:     u.String() + "/token",
 				Scopes:       []string{"urn:opc:idm:__myscopes__"},
 			},
@@ -0,0 +1,212 @@
+package opentelemetry
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+
+	"github.com/influxdata/influxdb-observability/common"
+	"google.golang.org/grpc"
+	"google.golang.org/protobuf/encoding/protowire"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/plugins/common/exphistogram"
+)
+
+const metricsExportMethod = "/opentelemetry.proto.collector.metrics.v1.MetricsService/Export"
+
+// aggregationTemporalityCumulative is the temporality of all histograms as
+// for the histograms converted by influx2otel
+const aggregationTemporalityCumulative = 2
+
+// exponentialHistograms collects exponential histograms as OTLP resource
+// metrics. The pdata package of the collector used does not support
+// exponential histograms, so they are encoded in the protobuf wire format
+// and sent along the other metrics in the same export request.
+type exponentialHistograms struct {
+	attributes map[string]string
+
+	// encoded holds the resource_metrics fields of an export request
+	encoded []byte
+}
+
+func newExponentialHistograms(attributes map[string]string) *exponentialHistograms {
+	return &exponentialHistograms{attributes: attributes}
+}
+
+// add adds the histograms of the metric as resource metrics. Tags are split
+// into resource attributes, instrumentation library and data point
+// attributes like influx2otel does.
+func (e *exponentialHistograms) add(m telegraf.Metric, histograms map[string]*exphistogram.Histogram) {
+	var libraryName, libraryVersion string
+	resourceAttributes := make(map[string]string)
+	attributes := make(map[string]string)
+	for _, tag := range m.TagList() {
+		switch {
+		case tag.Key == common.AttributeInstrumentationLibraryName:
+			libraryName = tag.Value
+		case tag.Key == common.AttributeInstrumentationLibraryVersion:
+			libraryVersion = tag.Value
+		case common.ResourceNamespace.MatchString(tag.Key):
+			resourceAttributes[tag.Key] = tag.Value
+		default:
+			attributes[tag.Key] = tag.Value
+		}
+	}
+	for k, v := range e.attributes {
+		resourceAttributes[k] = v
+	}
+
+	// Sort the fields for a stable encoding
+	fields := make([]string, 0, len(histograms))
+	for field := range histograms {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+
+	var library []byte
+	library = protowire.AppendTag(library, 1, protowire.BytesType)
+	library = protowire.AppendString(library, libraryName)
+	library = protowire.AppendTag(library, 2, protowire.BytesType)
+	library = protowire.AppendString(library, libraryVersion)
+
+	var libraryMetrics []byte
+	libraryMetrics = protowire.AppendTag(libraryMetrics, 1, protowire.BytesType)
+	libraryMetrics = protowire.AppendBytes(libraryMetrics, library)
+	for _, field := range fields {
+		libraryMetrics = protowire.AppendTag(libraryMetrics, 2, protowire.BytesType)
+		libraryMetrics = protowire.AppendBytes(libraryMetrics, encodeMetric(m.Name()+"_"+field, histograms[field], attributes, m.Time().UnixNano()))
+	}
+
+	var resource []byte
+	resource = appendAttributes(resource, 1, resourceAttributes)
+
+	var resourceMetrics []byte
+	resourceMetrics = protowire.AppendTag(resourceMetrics, 1, protowire.BytesType)
+	resourceMetrics = protowire.AppendBytes(resourceMetrics, resource)
+	resourceMetrics = protowire.AppendTag(resourceMetrics, 2, protowire.BytesType)
+	resourceMetrics = protowire.AppendBytes(resourceMetrics, libraryMetrics)
+
+	e.encoded = protowire.AppendTag(e.encoded, 1, protowire.BytesType)
+	e.encoded = protowire.AppendBytes(e.encoded, resourceMetrics)
+}
+
+// empty returns true if no histograms were added
+func (e *exponentialHistograms) empty() bool {
+	return len(e.encoded) == 0
+}
+
+// encodeMetric returns the metric with the histogram as exponential
+// histogram data point
+func encodeMetric(name string, h *exphistogram.Histogram, attributes map[string]string, ts int64) []byte {
+	var point []byte
+	point = appendAttributes(point, 1, attributes)
+	point = protowire.AppendTag(point, 3, protowire.Fixed64Type) // time_unix_nano
+	point = protowire.AppendFixed64(point, uint64(ts))
+	point = protowire.AppendTag(point, 4, protowire.Fixed64Type) // count
+	point = protowire.AppendFixed64(point, h.Count)
+	point = protowire.AppendTag(point, 5, protowire.Fixed64Type) // sum
+	point = protowire.AppendFixed64(point, math.Float64bits(h.Sum))
+	point = protowire.AppendTag(point, 6, protowire.VarintType) // scale
+	point = protowire.AppendVarint(point, protowire.EncodeZigZag(int64(h.Scale)))
+	point = protowire.AppendTag(point, 7, protowire.Fixed64Type) // zero_count
+	point = protowire.AppendFixed64(point, h.ZeroCount)
+	point = appendBuckets(point, 8, &h.Positive)
+	point = appendBuckets(point, 9, &h.Negative)
+	point = protowire.AppendTag(point, 14, protowire.Fixed64Type) // zero_threshold
+	point = protowire.AppendFixed64(point, math.Float64bits(h.ZeroThreshold))
+
+	var histogram []byte
+	histogram = protowire.AppendTag(histogram, 1, protowire.BytesType)
+	histogram = protowire.AppendBytes(histogram, point)
+	histogram = protowire.AppendTag(histogram, 2, protowire.VarintType)
+	histogram = protowire.AppendVarint(histogram, aggregationTemporalityCumulative)
+
+	var metric []byte
+	metric = protowire.AppendTag(metric, 1, protowire.BytesType)
+	metric = protowire.AppendString(metric, name)
+	metric = protowire.AppendTag(metric, 10, protowire.BytesType) // exponential_histogram
+	metric = protowire.AppendBytes(metric, histogram)
+	return metric
+}
+
+func appendBuckets(b []byte, field protowire.Number, buckets *exphistogram.Buckets) []byte {
+	if len(buckets.Counts) == 0 {
+		return b
+	}
+
+	var counts []byte
+	for _, c := range buckets.Counts {
+		counts = protowire.AppendVarint(counts, c)
+	}
+
+	var msg []byte
+	msg = protowire.AppendTag(msg, 1, protowire.VarintType)
+	msg = protowire.AppendVarint(msg, protowire.EncodeZigZag(int64(buckets.Offset)))
+	msg = protowire.AppendTag(msg, 2, protowire.BytesType)
+	msg = protowire.AppendBytes(msg, counts)
+
+	b = protowire.AppendTag(b, field, protowire.BytesType)
+	return protowire.AppendBytes(b, msg)
+}
+
+// appendAttributes appends the attributes as key-values with string values
+func appendAttributes(b []byte, field protowire.Number, attributes map[string]string) []byte {
+	keys := make([]string, 0, len(attributes))
+	for k := range attributes {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		var value []byte
+		value = protowire.AppendTag(value, 1, protowire.BytesType)
+		value = protowire.AppendString(value, attributes[k])
+
+		var kv []byte
+		kv = protowire.AppendTag(kv, 1, protowire.BytesType)
+		kv = protowire.AppendString(kv, k)
+		kv = protowire.AppendTag(kv, 2, protowire.BytesType)
+		kv = protowire.AppendBytes(kv, value)
+
+		b = protowire.AppendTag(b, field, protowire.BytesType)
+		b = protowire.AppendBytes(b, kv)
+	}
+	return b
+}
+
+// rawMessage is an encoded protobuf message
+type rawMessage []byte
+
+// rawCodec passes encoded messages through
+type rawCodec struct{}
+
+func (rawCodec) Marshal(v interface{}) ([]byte, error) {
+	msg, ok := v.(*rawMessage)
+	if !ok {
+		return nil, fmt.Errorf("unexpected message type %T", v)
+	}
+	return *msg, nil
+}
+
+func (rawCodec) Unmarshal(data []byte, v interface{}) error {
+	msg, ok := v.(*rawMessage)
+	if !ok {
+		return fmt.Errorf("unexpected message type %T", v)
+	}
+	*msg = append((*msg)[:0], data...)
+	return nil
+}
+
+func (rawCodec) Name() string {
+	return "proto"
+}
+
+// exportRaw sends the encoded export request
+func exportRaw(ctx context.Context, conn *grpc.ClientConn, request []byte, opts ...grpc.CallOption) error {
+	req := rawMessage(request)
+	var resp rawMessage
+	opts = append(opts[:len(opts):len(opts)], grpc.ForceCodec(rawCodec{}))
+	return conn.Invoke(ctx, metricsExportMethod, &req, &resp, opts...)
+}
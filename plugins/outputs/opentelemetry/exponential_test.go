@@ -0,0 +1,136 @@
+package opentelemetry
+
+import (
+	"math"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/influxdata/influxdb-observability/common"
+	"github.com/influxdata/influxdb-observability/influx2otel"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/model/otlp"
+	"google.golang.org/grpc"
+	"google.golang.org/protobuf/encoding/protowire"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/config"
+	"github.com/influxdata/telegraf/testutil"
+)
+
+func TestExponentialHistogram(t *testing.T) {
+	// Receive the raw requests as the collector model cannot decode
+	// exponential histograms
+	requests := make(chan []byte, 1)
+	methods := make(chan string, 1)
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	server := grpc.NewServer(
+		grpc.ForceServerCodec(rawCodec{}),
+		grpc.UnknownServiceHandler(func(_ interface{}, stream grpc.ServerStream) error {
+			method, _ := grpc.MethodFromServerStream(stream)
+			methods <- method
+			var req rawMessage
+			if err := stream.RecvMsg(&req); err != nil {
+				return err
+			}
+			requests <- req
+			return stream.SendMsg(&rawMessage{})
+		}),
+	)
+	go func() { _ = server.Serve(listener) }()
+	defer server.Stop()
+
+	conn, err := grpc.Dial(listener.Addr().String(), grpc.WithInsecure(), grpc.WithBlock())
+	require.NoError(t, err)
+	defer conn.Close()
+
+	metricsConverter, err := influx2otel.NewLineProtocolToOtelMetrics(common.NoopLogger{})
+	require.NoError(t, err)
+	plugin := &OpenTelemetry{
+		Timeout:          config.Duration(time.Second),
+		Attributes:       map[string]string{"attr-key": "attr-val"},
+		Log:              testutil.Logger{},
+		metricsConverter: metricsConverter,
+		grpcClientConn:   conn,
+	}
+
+	metrics := []telegraf.Metric{
+		testutil.MustMetric(
+			"http",
+			map[string]string{
+				"host.name":         "potato",
+				"otel.library.name": "My Library Name",
+				"method":            "GET",
+			},
+			map[string]interface{}{
+				"latency_count":          uint64(4),
+				"latency_sum":            7.5,
+				"latency_scale":          int64(1),
+				"latency_zero_threshold": 0.0,
+				"latency_zero_count":     uint64(1),
+				"latency_positive_-1":    uint64(2),
+				"latency_positive_1":     uint64(1),
+			},
+			time.Unix(0, 1622848686000000000),
+			telegraf.Histogram,
+		),
+		testutil.MustMetric(
+			"cpu_temp",
+			map[string]string{"host.name": "potato"},
+			map[string]interface{}{"gauge": 87.332},
+			time.Unix(0, 1622848686000000000),
+		),
+	}
+	require.NoError(t, plugin.Write(metrics))
+	require.Equal(t, metricsExportMethod, <-methods)
+	request := <-requests
+
+	// The other metrics are sent as usual
+	md, err := otlp.NewProtobufMetricsUnmarshaler().UnmarshalMetrics(request)
+	require.NoError(t, err)
+	require.Equal(t, 2, md.ResourceMetrics().Len())
+	rm := md.ResourceMetrics().At(0)
+	require.Equal(t, "cpu_temp", rm.InstrumentationLibraryMetrics().At(0).Metrics().At(0).Name())
+
+	resourceMetrics := testutil.DecodeProto(t, request)[1]
+	require.Len(t, resourceMetrics, 2)
+	rmFields := testutil.DecodeProto(t, resourceMetrics[1].([]byte))
+
+	resource := testutil.DecodeProto(t, rmFields[1][0].([]byte))
+	require.Equal(t, map[string]string{"host.name": "potato", "attr-key": "attr-val"}, decodeAttributes(t, resource[1]))
+
+	libraryMetrics := testutil.DecodeProto(t, rmFields[2][0].([]byte))
+	library := testutil.DecodeProto(t, libraryMetrics[1][0].([]byte))
+	require.Equal(t, []byte("My Library Name"), library[1][0])
+	require.Len(t, libraryMetrics[2], 1)
+
+	metric := testutil.DecodeProto(t, libraryMetrics[2][0].([]byte))
+	require.Equal(t, []byte("http_latency"), metric[1][0])
+	histogram := testutil.DecodeProto(t, metric[10][0].([]byte))
+	require.Equal(t, []interface{}{uint64(aggregationTemporalityCumulative)}, histogram[2])
+
+	point := testutil.DecodeProto(t, histogram[1][0].([]byte))
+	require.Equal(t, map[string]string{"method": "GET"}, decodeAttributes(t, point[1]))
+	require.Equal(t, []interface{}{uint64(1622848686000000000)}, point[3])
+	require.Equal(t, []interface{}{uint64(4)}, point[4])
+	require.Equal(t, []interface{}{math.Float64bits(7.5)}, point[5])
+	require.Equal(t, []interface{}{protowire.EncodeZigZag(1)}, point[6])
+	require.Equal(t, []interface{}{uint64(1)}, point[7])
+	require.NotContains(t, point, protowire.Number(9))
+
+	positive := testutil.DecodeProto(t, point[8][0].([]byte))
+	require.Equal(t, []interface{}{protowire.EncodeZigZag(-1)}, positive[1])
+	require.Equal(t, []interface{}{[]byte{2, 0, 1}}, positive[2])
+}
+
+// decodeAttributes returns the key-values with string values
+func decodeAttributes(t *testing.T, values []interface{}) map[string]string {
+	attributes := make(map[string]string)
+	for _, v := range values {
+		kv := testutil.DecodeProto(t, v.([]byte))
+		value := testutil.DecodeProto(t, kv[2][0].([]byte))
+		attributes[string(kv[1][0].([]byte))] = string(value[1][0].([]byte))
+	}
+	return attributes
+}
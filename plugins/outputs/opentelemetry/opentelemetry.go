@@ -2,15 +2,20 @@ package opentelemetry
 
 import (
 	"context"
+	"hash/fnv"
 	"time"
 
 	"github.com/influxdata/influxdb-observability/common"
 	"github.com/influxdata/influxdb-observability/influx2otel"
 	"github.com/influxdata/telegraf"
 	"github.com/influxdata/telegraf/config"
+	"github.com/influxdata/telegraf/internal/choice"
+	"github.com/influxdata/telegraf/metric"
+	"github.com/influxdata/telegraf/plugins/common/exphistogram"
 	"github.com/influxdata/telegraf/plugins/common/tls"
 	"github.com/influxdata/telegraf/plugins/outputs"
 	"go.opentelemetry.io/collector/model/otlpgrpc"
+	"go.opentelemetry.io/collector/model/pdata"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/credentials"
 	// This causes the gRPC library to register gzip compression.
@@ -27,12 +32,22 @@ type OpenTelemetry struct {
 	Headers     map[string]string `toml:"headers"`
 	Attributes  map[string]string `toml:"attributes"`
 
+	SpanMeasurements     []string `toml:"span_measurements"`
+	SpanLinkMeasurements []string `toml:"span_link_measurements"`
+	LogMeasurements      []string `toml:"log_measurements"`
+
 	Log telegraf.Logger `toml:"-"`
 
 	metricsConverter     *influx2otel.LineProtocolToOtelMetrics
 	grpcClientConn       *grpc.ClientConn
 	metricsServiceClient otlpgrpc.MetricsClient
+	tracesServiceClient  otlpgrpc.TracesClient
+	logsServiceClient    otlpgrpc.LogsClient
 	callOptions          []grpc.CallOption
+
+	// delivered holds the keys of the metrics exported by a write that
+	// failed for another signal, they are skipped when the write is retried.
+	delivered map[uint64]bool
 }
 
 const sampleConfig = `
@@ -60,6 +75,15 @@ const sampleConfig = `
   ## Supports: "gzip", "none"
   # compression = "gzip"
 
+  ## Measurements exported as OTLP spans, span links and log records, all
+  ## other metrics are exported as OTLP metrics.  The defaults match the
+  ## measurements produced by the OpenTelemetry input from traces and logs.
+  ## Span events are exported with their span if it is part of the same
+  ## batch and as log records otherwise.
+  # span_measurements = ["spans"]
+  # span_link_measurements = ["span-links"]
+  # log_measurements = ["logs"]
+
   ## Additional OpenTelemetry resource attributes
   # [outputs.opentelemetry.attributes]
   # "service.name" = "demo"
@@ -74,7 +98,7 @@ func (o *OpenTelemetry) SampleConfig() string {
 }
 
 func (o *OpenTelemetry) Description() string {
-	return "Send OpenTelemetry metrics over gRPC"
+	return "Send OpenTelemetry metrics, traces and logs over gRPC"
 }
 
 func (o *OpenTelemetry) Connect() error {
@@ -109,11 +133,11 @@ func (o *OpenTelemetry) Connect() error {
 		return err
 	}
 
-	metricsServiceClient := otlpgrpc.NewMetricsClient(grpcClientConn)
-
 	o.metricsConverter = metricsConverter
 	o.grpcClientConn = grpcClientConn
-	o.metricsServiceClient = metricsServiceClient
+	o.metricsServiceClient = otlpgrpc.NewMetricsClient(grpcClientConn)
+	o.tracesServiceClient = otlpgrpc.NewTracesClient(grpcClientConn)
+	o.logsServiceClient = otlpgrpc.NewLogsClient(grpcClientConn)
 
 	if o.Compression != "" && o.Compression != "none" {
 		o.callOptions = append(o.callOptions, grpc.UseCompressor(o.Compression))
@@ -131,9 +155,59 @@ func (o *OpenTelemetry) Close() error {
 	return nil
 }
 
+// Write exports the metrics, traces and logs of the batch with one request
+// each.  If some of the requests fail, the metrics of the successful requests
+// are not exported again when the batch is retried.
 func (o *OpenTelemetry) Write(metrics []telegraf.Metric) error {
 	batch := o.metricsConverter.NewBatch()
+	traces := newTraceConverter(o.Log)
+	exponential := newExponentialHistograms(o.Attributes)
+
+	// Metrics of each request, span events may be exported with the traces
+	// or the logs
+	var metricsSent, tracesSent, logsSent, eventsSent []telegraf.Metric
 	for _, metric := range metrics {
+		if len(o.delivered) > 0 && o.delivered[metricKey(metric)] {
+			continue
+		}
+
+		switch {
+		case choice.Contains(metric.Name(), o.SpanMeasurements):
+			tracesSent = append(tracesSent, metric)
+			if err := traces.addSpan(metric); err != nil {
+				o.Log.Warnf("failed to add span: %s", err)
+			}
+			continue
+		case choice.Contains(metric.Name(), o.SpanLinkMeasurements):
+			tracesSent = append(tracesSent, metric)
+			traces.addSpanLink(metric)
+			continue
+		case choice.Contains(metric.Name(), o.LogMeasurements):
+			if traces.addSpanEvent(metric) {
+				eventsSent = append(eventsSent, metric)
+				continue
+			}
+			logsSent = append(logsSent, metric)
+			if err := traces.addLogRecord(metric); err != nil {
+				o.Log.Warnf("failed to add log record: %s", err)
+			}
+			continue
+		}
+		metricsSent = append(metricsSent, metric)
+
+		// Exponential histograms are exported natively, remaining fields as
+		// any other metric
+		if histograms, used := exphistogram.FromMetric(metric); len(histograms) > 0 {
+			exponential.add(metric, histograms)
+			metric = metric.Copy()
+			for key := range used {
+				metric.RemoveField(key)
+			}
+			if len(metric.FieldList()) == 0 {
+				continue
+			}
+		}
+
 		var vType common.InfluxMetricValueType
 		switch metric.Type() {
 		case telegraf.Gauge:
@@ -159,16 +233,16 @@ func (o *OpenTelemetry) Write(metrics []telegraf.Metric) error {
 
 	md := otlpgrpc.NewMetricsRequest()
 	md.SetMetrics(batch.GetMetrics())
-	if md.Metrics().ResourceMetrics().Len() == 0 {
-		return nil
+	for i := 0; i < md.Metrics().ResourceMetrics().Len(); i++ {
+		o.addAttributes(md.Metrics().ResourceMetrics().At(i).Resource())
 	}
 
-	if len(o.Attributes) > 0 {
-		for i := 0; i < md.Metrics().ResourceMetrics().Len(); i++ {
-			for k, v := range o.Attributes {
-				md.Metrics().ResourceMetrics().At(i).Resource().Attributes().UpsertString(k, v)
-			}
-		}
+	td, ld := traces.finish()
+	for i := 0; i < td.ResourceSpans().Len(); i++ {
+		o.addAttributes(td.ResourceSpans().At(i).Resource())
+	}
+	for i := 0; i < ld.ResourceLogs().Len(); i++ {
+		o.addAttributes(ld.ResourceLogs().At(i).Resource())
 	}
 
 	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(o.Timeout))
@@ -177,8 +251,93 @@ func (o *OpenTelemetry) Write(metrics []telegraf.Metric) error {
 		ctx = metadata.NewOutgoingContext(ctx, metadata.New(o.Headers))
 	}
 	defer cancel()
-	_, err := o.metricsServiceClient.Export(ctx, md, o.callOptions...)
-	return err
+
+	metricsErr := o.exportMetrics(ctx, md, exponential)
+	var tracesErr error
+	if td.ResourceSpans().Len() > 0 {
+		tr := otlpgrpc.NewTracesRequest()
+		tr.SetTraces(td)
+		_, tracesErr = o.tracesServiceClient.Export(ctx, tr, o.callOptions...)
+	}
+	var logsErr error
+	if ld.ResourceLogs().Len() > 0 {
+		lr := otlpgrpc.NewLogsRequest()
+		lr.SetLogs(ld)
+		_, logsErr = o.logsServiceClient.Export(ctx, lr, o.callOptions...)
+	}
+
+	if metricsErr == nil && tracesErr == nil && logsErr == nil {
+		o.delivered = nil
+		return nil
+	}
+
+	if metricsErr == nil {
+		o.markDelivered(metricsSent)
+	}
+	if tracesErr == nil {
+		o.markDelivered(tracesSent)
+	}
+	if logsErr == nil {
+		o.markDelivered(logsSent)
+	}
+	if tracesErr == nil && logsErr == nil {
+		o.markDelivered(eventsSent)
+	}
+	for _, err := range []error{metricsErr, tracesErr, logsErr} {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// exportMetrics exports the metrics request, including the exponential
+// histograms, if it is not empty.
+func (o *OpenTelemetry) exportMetrics(ctx context.Context, md otlpgrpc.MetricsRequest, exponential *exponentialHistograms) error {
+	if !exponential.empty() {
+		request, err := md.Marshal()
+		if err != nil {
+			return err
+		}
+		return exportRaw(ctx, o.grpcClientConn, append(request, exponential.encoded...), o.callOptions...)
+	}
+	if md.Metrics().ResourceMetrics().Len() > 0 {
+		_, err := o.metricsServiceClient.Export(ctx, md, o.callOptions...)
+		return err
+	}
+	return nil
+}
+
+// markDelivered remembers the metrics as exported until the next write
+// succeeds completely.
+func (o *OpenTelemetry) markDelivered(metrics []telegraf.Metric) {
+	if len(metrics) == 0 {
+		return
+	}
+	if o.delivered == nil {
+		o.delivered = make(map[uint64]bool)
+	}
+	for _, m := range metrics {
+		o.delivered[metricKey(m)] = true
+	}
+}
+
+// metricKey identifies the metric by its content, as retried metrics are not
+// necessarily the same instances, e.g. when read from a disk buffer.
+func metricKey(m telegraf.Metric) uint64 {
+	octets, err := metric.ToBytes(m)
+	if err != nil {
+		return m.HashID() ^ uint64(m.Time().UnixNano())
+	}
+	h := fnv.New64a()
+	h.Write(octets)
+	return h.Sum64()
+}
+
+func (o *OpenTelemetry) addAttributes(resource pdata.Resource) {
+	for k, v := range o.Attributes {
+		resource.Attributes().UpsertString(k, v)
+	}
 }
 
 const (
@@ -193,6 +352,10 @@ func init() {
 			ServiceAddress: defaultServiceAddress,
 			Timeout:        defaultTimeout,
 			Compression:    defaultCompression,
+
+			SpanMeasurements:     []string{common.MeasurementSpans},
+			SpanLinkMeasurements: []string{common.MeasurementSpanLinks},
+			LogMeasurements:      []string{common.MeasurementLogs},
 		}
 	})
 }
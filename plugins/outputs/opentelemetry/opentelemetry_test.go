@@ -2,6 +2,7 @@ package opentelemetry
 
 import (
 	"context"
+	"errors"
 	"net"
 	"strings"
 	"testing"
@@ -9,6 +10,7 @@ import (
 
 	"github.com/influxdata/influxdb-observability/common"
 	"github.com/influxdata/influxdb-observability/influx2otel"
+	"github.com/influxdata/influxdb-observability/otel2influx"
 	"github.com/influxdata/telegraf"
 	"github.com/influxdata/telegraf/config"
 	"github.com/influxdata/telegraf/testutil"
@@ -84,6 +86,211 @@ func TestOpenTelemetry(t *testing.T) {
 	assert.JSONEq(t, string(expectJSON), string(gotJSON))
 }
 
+func TestOpenTelemetryTracesAndLogs(t *testing.T) {
+	expectTraces := pdata.NewTraces()
+	{
+		rs := expectTraces.ResourceSpans().AppendEmpty()
+		rs.Resource().Attributes().InsertString("service.name", "checkout")
+		ils := rs.InstrumentationLibrarySpans().AppendEmpty()
+		ils.InstrumentationLibrary().SetName("My Library Name")
+		span := ils.Spans().AppendEmpty()
+		span.SetTraceID(pdata.NewTraceID([16]byte{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15, 16}))
+		span.SetSpanID(pdata.NewSpanID([8]byte{1, 2, 3, 4, 5, 6, 7, 8}))
+		span.SetParentSpanID(pdata.NewSpanID([8]byte{8, 7, 6, 5, 4, 3, 2, 1}))
+		span.SetName("place-order")
+		span.SetKind(pdata.SpanKindServer)
+		span.SetStartTimestamp(pdata.Timestamp(1622848686000000000))
+		span.SetEndTimestamp(pdata.Timestamp(1622848687000000000))
+		span.Attributes().InsertString("http.method", "POST")
+		span.Attributes().InsertInt("http.status_code", 500)
+		span.Status().SetCode(pdata.StatusCodeError)
+		span.Status().SetMessage("payment failed")
+		event := span.Events().AppendEmpty()
+		event.SetName("retry")
+		event.SetTimestamp(pdata.Timestamp(1622848686500000000))
+		event.Attributes().InsertInt("attempt", 2)
+		link := span.Links().AppendEmpty()
+		link.SetTraceID(pdata.NewTraceID([16]byte{16, 15, 14, 13, 12, 11, 10, 9, 8, 7, 6, 5, 4, 3, 2, 1}))
+		link.SetSpanID(pdata.NewSpanID([8]byte{1, 1, 1, 1, 1, 1, 1, 1}))
+	}
+	expectLogs := pdata.NewLogs()
+	{
+		rl := expectLogs.ResourceLogs().AppendEmpty()
+		rl.Resource().Attributes().InsertString("service.name", "checkout")
+		ill := rl.InstrumentationLibraryLogs().AppendEmpty()
+		record := ill.Logs().AppendEmpty()
+		record.SetTimestamp(pdata.Timestamp(1622848686600000000))
+		record.SetTraceID(pdata.NewTraceID([16]byte{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15, 16}))
+		record.SetSpanID(pdata.NewSpanID([8]byte{1, 2, 3, 4, 5, 6, 7, 8}))
+		record.SetSeverityNumber(pdata.SeverityNumberERROR)
+		record.SetSeverityText("ERROR")
+		record.Body().SetStringVal("card declined")
+		record.Attributes().InsertString("order.id", "42")
+	}
+
+	// Convert the expected data like the OpenTelemetry input does.
+	acc := &testutil.Accumulator{}
+	w := &accumulatorWriter{acc: acc}
+	ctx := context.Background()
+	require.NoError(t, otel2influx.NewOtelTracesToLineProtocol(common.NoopLogger{}).WriteTraces(ctx, expectTraces, w))
+	require.NoError(t, otel2influx.NewOtelLogsToLineProtocol(common.NoopLogger{}).WriteLogs(ctx, expectLogs, w))
+
+	m := newMockOtelService(t)
+	t.Cleanup(m.Cleanup)
+
+	metricsConverter, err := influx2otel.NewLineProtocolToOtelMetrics(common.NoopLogger{})
+	require.NoError(t, err)
+	plugin := &OpenTelemetry{
+		ServiceAddress:       m.Address(),
+		Timeout:              config.Duration(time.Second),
+		Headers:              map[string]string{"test": "header1"},
+		SpanMeasurements:     []string{common.MeasurementSpans},
+		SpanLinkMeasurements: []string{common.MeasurementSpanLinks},
+		LogMeasurements:      []string{common.MeasurementLogs},
+		Log:                  testutil.Logger{},
+		metricsConverter:     metricsConverter,
+		grpcClientConn:       m.GrpcClient(),
+		metricsServiceClient: otlpgrpc.NewMetricsClient(m.GrpcClient()),
+		tracesServiceClient:  otlpgrpc.NewTracesClient(m.GrpcClient()),
+		logsServiceClient:    otlpgrpc.NewLogsClient(m.GrpcClient()),
+	}
+	require.NoError(t, plugin.Write(acc.GetTelegrafMetrics()))
+
+	// The metrics service is not called as there are no metrics.
+	require.Equal(t, 0, m.GotMetrics().ResourceMetrics().Len())
+
+	got := m.traces.traces
+	require.Equal(t, 1, got.SpanCount())
+	require.Equal(t, 1, m.logs.logs.LogRecordCount())
+	sortSpanAttributes(expectTraces)
+	sortSpanAttributes(got)
+	expectJSON, err := otlp.NewJSONTracesMarshaler().MarshalTraces(expectTraces)
+	require.NoError(t, err)
+	gotJSON, err := otlp.NewJSONTracesMarshaler().MarshalTraces(got)
+	require.NoError(t, err)
+	require.JSONEq(t, string(expectJSON), string(gotJSON))
+
+	expectJSON, err = otlp.NewJSONLogsMarshaler().MarshalLogs(expectLogs)
+	require.NoError(t, err)
+	gotJSON, err = otlp.NewJSONLogsMarshaler().MarshalLogs(m.logs.logs)
+	require.NoError(t, err)
+	require.JSONEq(t, string(expectJSON), string(gotJSON))
+}
+
+func TestOpenTelemetrySpanEventWithoutSpan(t *testing.T) {
+	c := newTraceConverter(testutil.Logger{})
+	event := testutil.MustMetric(
+		"logs",
+		map[string]string{
+			"trace_id": "0102030405060708090a0b0c0d0e0f10",
+			"span_id":  "0102030405060708",
+			"name":     "retry",
+		},
+		map[string]interface{}{
+			"attempt": int64(2),
+		},
+		time.Unix(0, 1622848686500000000))
+	require.True(t, c.addSpanEvent(event))
+
+	traces, logs := c.finish()
+	require.Equal(t, 0, traces.SpanCount())
+	require.Equal(t, 1, logs.LogRecordCount())
+	record := logs.ResourceLogs().At(0).InstrumentationLibraryLogs().At(0).Logs().At(0)
+	require.Equal(t, "retry", record.Name())
+	require.Equal(t, "0102030405060708", record.SpanID().HexString())
+}
+
+func TestOpenTelemetryPartialFailure(t *testing.T) {
+	m := newMockOtelService(t)
+	t.Cleanup(m.Cleanup)
+	m.logs.err = errors.New("unavailable")
+
+	metricsConverter, err := influx2otel.NewLineProtocolToOtelMetrics(common.NoopLogger{})
+	require.NoError(t, err)
+	plugin := &OpenTelemetry{
+		ServiceAddress:       m.Address(),
+		Timeout:              config.Duration(time.Second),
+		SpanMeasurements:     []string{common.MeasurementSpans},
+		SpanLinkMeasurements: []string{common.MeasurementSpanLinks},
+		LogMeasurements:      []string{common.MeasurementLogs},
+		Log:                  testutil.Logger{},
+		metricsConverter:     metricsConverter,
+		grpcClientConn:       m.GrpcClient(),
+		metricsServiceClient: otlpgrpc.NewMetricsClient(m.GrpcClient()),
+		tracesServiceClient:  otlpgrpc.NewTracesClient(m.GrpcClient()),
+		logsServiceClient:    otlpgrpc.NewLogsClient(m.GrpcClient()),
+	}
+
+	metrics := []telegraf.Metric{
+		testutil.MustMetric(
+			"spans",
+			map[string]string{
+				"trace_id": "0102030405060708090a0b0c0d0e0f10",
+				"span_id":  "0102030405060708",
+				"name":     "place-order",
+			},
+			map[string]interface{}{"duration_nano": int64(1000)},
+			time.Unix(0, 1622848686000000000)),
+		testutil.MustMetric(
+			"logs",
+			map[string]string{},
+			map[string]interface{}{"body": "card declined"},
+			time.Unix(0, 1622848686600000000)),
+	}
+
+	require.Error(t, plugin.Write(metrics))
+	require.Equal(t, 1, m.traces.exports)
+
+	// The spans are not exported again when the batch is retried
+	m.logs.err = nil
+	require.NoError(t, plugin.Write(metrics))
+	require.Equal(t, 1, m.traces.exports)
+	require.Equal(t, 1, m.logs.logs.LogRecordCount())
+	require.Empty(t, plugin.delivered)
+
+	require.NoError(t, plugin.Write(metrics))
+	require.Equal(t, 2, m.traces.exports)
+}
+
+func TestOpenTelemetryInvalidParentSpan(t *testing.T) {
+	c := newTraceConverter(testutil.Logger{})
+	span := testutil.MustMetric(
+		"spans",
+		map[string]string{
+			"trace_id":       "0102030405060708090a0b0c0d0e0f10",
+			"span_id":        "0102030405060708",
+			"parent_span_id": "invalid",
+		},
+		map[string]interface{}{"duration_nano": int64(1000)},
+		time.Unix(0, 1622848686000000000))
+	require.Error(t, c.addSpan(span))
+
+	traces, _ := c.finish()
+	require.Equal(t, 0, traces.ResourceSpans().Len())
+}
+
+func sortSpanAttributes(td pdata.Traces) {
+	for i := 0; i < td.ResourceSpans().Len(); i++ {
+		rs := td.ResourceSpans().At(i)
+		rs.Resource().Attributes().Sort()
+		for j := 0; j < rs.InstrumentationLibrarySpans().Len(); j++ {
+			spans := rs.InstrumentationLibrarySpans().At(j).Spans()
+			for k := 0; k < spans.Len(); k++ {
+				spans.At(k).Attributes().Sort()
+			}
+		}
+	}
+}
+
+type accumulatorWriter struct {
+	acc *testutil.Accumulator
+}
+
+func (w *accumulatorWriter) WritePoint(_ context.Context, measurement string, tags map[string]string, fields map[string]interface{}, ts time.Time, _ common.InfluxMetricValueType) error {
+	w.acc.AddFields(measurement, fields, tags, ts)
+	return nil
+}
+
 var _ otlpgrpc.MetricsServer = (*mockOtelService)(nil)
 
 type mockOtelService struct {
@@ -93,6 +300,32 @@ type mockOtelService struct {
 	grpcClient *grpc.ClientConn
 
 	metrics pdata.Metrics
+	traces  *mockTracesService
+	logs    *mockLogsService
+}
+
+type mockTracesService struct {
+	traces  pdata.Traces
+	exports int
+}
+
+func (m *mockTracesService) Export(_ context.Context, request otlpgrpc.TracesRequest) (otlpgrpc.TracesResponse, error) {
+	m.traces = request.Traces().Clone()
+	m.exports++
+	return otlpgrpc.NewTracesResponse(), nil
+}
+
+type mockLogsService struct {
+	logs pdata.Logs
+	err  error
+}
+
+func (m *mockLogsService) Export(_ context.Context, request otlpgrpc.LogsRequest) (otlpgrpc.LogsResponse, error) {
+	if m.err != nil {
+		return otlpgrpc.NewLogsResponse(), m.err
+	}
+	m.logs = request.Logs().Clone()
+	return otlpgrpc.NewLogsResponse(), nil
 }
 
 func newMockOtelService(t *testing.T) *mockOtelService {
@@ -104,9 +337,14 @@ func newMockOtelService(t *testing.T) *mockOtelService {
 		t:          t,
 		listener:   listener,
 		grpcServer: grpcServer,
+		metrics:    pdata.NewMetrics(),
+		traces:     &mockTracesService{},
+		logs:       &mockLogsService{},
 	}
 
 	otlpgrpc.RegisterMetricsServer(grpcServer, mockOtelService)
+	otlpgrpc.RegisterTracesServer(grpcServer, mockOtelService.traces)
+	otlpgrpc.RegisterLogsServer(grpcServer, mockOtelService.logs)
 	go func() { assert.NoError(t, grpcServer.Serve(listener)) }()
 
 	grpcClient, err := grpc.Dial(listener.Addr().String(), grpc.WithInsecure(), grpc.WithBlock())
@@ -0,0 +1,432 @@
+package opentelemetry
+
+import (
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/influxdata/influxdb-observability/common"
+	"github.com/influxdata/telegraf"
+	"go.opentelemetry.io/collector/model/pdata"
+)
+
+// The tags and fields below are set by the OpenTelemetry input, following the
+// influxdb-observability schema, and are converted back to the corresponding
+// OTLP properties instead of attributes.
+var spanKeys = map[string]bool{
+	common.AttributeTraceID:                    true,
+	common.AttributeSpanID:                     true,
+	common.AttributeTraceState:                 true,
+	common.AttributeParentSpanID:               true,
+	common.AttributeName:                       true,
+	common.AttributeSpanKind:                   true,
+	common.AttributeEndTimeUnixNano:            true,
+	common.AttributeDurationNano:               true,
+	common.AttributeDroppedSpanAttributesCount: true,
+	common.AttributeDroppedEventsCount:         true,
+	common.AttributeDroppedLinksCount:          true,
+	common.AttributeStatusCode:                 true,
+	common.AttributeStatusMessage:              true,
+}
+
+var logKeys = map[string]bool{
+	common.AttributeTraceID:                    true,
+	common.AttributeSpanID:                     true,
+	common.AttributeName:                       true,
+	common.AttributeSeverityNumber:             true,
+	common.AttributeSeverityText:               true,
+	common.AttributeBody:                       true,
+	common.AttributeDroppedSpanAttributesCount: true,
+}
+
+var linkKeys = map[string]bool{
+	common.AttributeTraceID:                    true,
+	common.AttributeSpanID:                     true,
+	common.AttributeLinkedTraceID:              true,
+	common.AttributeLinkedSpanID:               true,
+	common.AttributeTraceState:                 true,
+	common.AttributeDroppedLinkAttributesCount: true,
+}
+
+var libraryKeys = map[string]bool{
+	common.AttributeInstrumentationLibraryName:    true,
+	common.AttributeInstrumentationLibraryVersion: true,
+}
+
+// traceConverter collects the spans, span events and span links of a batch
+// of metrics.  Span events and links are attached to their span if the span
+// is part of the same batch, span events without their span are exported as
+// log records.
+type traceConverter struct {
+	log telegraf.Logger
+
+	traces    pdata.Traces
+	libraries map[string]pdata.InstrumentationLibrarySpans
+	spans     map[string]pdata.Span
+
+	logs          pdata.Logs
+	logLibraries  map[string]pdata.InstrumentationLibraryLogs
+	pendingEvents []telegraf.Metric
+	pendingLinks  []telegraf.Metric
+}
+
+func newTraceConverter(log telegraf.Logger) *traceConverter {
+	return &traceConverter{
+		log:          log,
+		traces:       pdata.NewTraces(),
+		libraries:    make(map[string]pdata.InstrumentationLibrarySpans),
+		spans:        make(map[string]pdata.Span),
+		logs:         pdata.NewLogs(),
+		logLibraries: make(map[string]pdata.InstrumentationLibraryLogs),
+	}
+}
+
+// addSpan converts a metric of a span measurement to a span.
+func (c *traceConverter) addSpan(metric telegraf.Metric) error {
+	traceID, err := parseTraceID(metric, common.AttributeTraceID)
+	if err != nil {
+		return err
+	}
+	spanID, err := parseSpanID(metric, common.AttributeSpanID)
+	if err != nil {
+		return err
+	}
+	parentID := pdata.InvalidSpanID()
+	if _, ok := metric.GetTag(common.AttributeParentSpanID); ok {
+		parentID, err = parseSpanID(metric, common.AttributeParentSpanID)
+		if err != nil {
+			return err
+		}
+	}
+
+	// Nothing is added to the traces before the span is valid
+	key := resourceKey(metric, spanKeys)
+	ils, ok := c.libraries[key]
+	if !ok {
+		rs := c.traces.ResourceSpans().AppendEmpty()
+		setResource(rs.Resource(), metric, spanKeys)
+		ils = rs.InstrumentationLibrarySpans().AppendEmpty()
+		setLibrary(ils.InstrumentationLibrary(), metric)
+		c.libraries[key] = ils
+	}
+
+	span := ils.Spans().AppendEmpty()
+	span.SetTraceID(traceID)
+	span.SetSpanID(spanID)
+	span.SetParentSpanID(parentID)
+	if v, ok := metric.GetTag(common.AttributeTraceState); ok {
+		span.SetTraceState(pdata.TraceState(v))
+	}
+	if v, ok := metric.GetTag(common.AttributeName); ok {
+		span.SetName(v)
+	}
+	if v, ok := metric.GetTag(common.AttributeSpanKind); ok {
+		span.SetKind(parseSpanKind(v))
+	}
+
+	span.SetStartTimestamp(pdata.NewTimestampFromTime(metric.Time()))
+	if v, ok := intField(metric, common.AttributeEndTimeUnixNano); ok {
+		span.SetEndTimestamp(pdata.Timestamp(v))
+	} else if v, ok := intField(metric, common.AttributeDurationNano); ok {
+		span.SetEndTimestamp(pdata.NewTimestampFromTime(metric.Time().Add(time.Duration(v))))
+	}
+
+	if v, ok := intField(metric, common.AttributeDroppedSpanAttributesCount); ok {
+		span.SetDroppedAttributesCount(uint32(v))
+	}
+	if v, ok := intField(metric, common.AttributeDroppedEventsCount); ok {
+		span.SetDroppedEventsCount(uint32(v))
+	}
+	if v, ok := intField(metric, common.AttributeDroppedLinksCount); ok {
+		span.SetDroppedLinksCount(uint32(v))
+	}
+
+	if v, ok := metric.GetField(common.AttributeStatusCode); ok {
+		switch v {
+		case common.AttributeStatusCodeOK:
+			span.Status().SetCode(pdata.StatusCodeOk)
+		case common.AttributeStatusCodeError:
+			span.Status().SetCode(pdata.StatusCodeError)
+		}
+	}
+	if v, ok := metric.GetField(common.AttributeStatusMessage); ok {
+		if s, ok := v.(string); ok {
+			span.Status().SetMessage(s)
+		}
+	}
+
+	setAttributes(span.Attributes(), metric, spanKeys)
+	c.spans[traceID.HexString()+spanID.HexString()] = span
+	return nil
+}
+
+// addSpanEvent keeps a span event for attaching it to its span.  A metric
+// of a log measurement is a span event if its name is a tag and it
+// references a span.
+func (c *traceConverter) addSpanEvent(metric telegraf.Metric) bool {
+	if !metric.HasTag(common.AttributeName) || !metric.HasTag(common.AttributeTraceID) || !metric.HasTag(common.AttributeSpanID) {
+		return false
+	}
+	c.pendingEvents = append(c.pendingEvents, metric)
+	return true
+}
+
+// addSpanLink keeps a span link for attaching it to its span.
+func (c *traceConverter) addSpanLink(metric telegraf.Metric) {
+	c.pendingLinks = append(c.pendingLinks, metric)
+}
+
+// addLogRecord converts a metric of a log measurement to a log record.
+func (c *traceConverter) addLogRecord(metric telegraf.Metric) error {
+	traceID := pdata.InvalidTraceID()
+	if _, ok := metric.GetTag(common.AttributeTraceID); ok {
+		var err error
+		if traceID, err = parseTraceID(metric, common.AttributeTraceID); err != nil {
+			return err
+		}
+	}
+	spanID := pdata.InvalidSpanID()
+	if _, ok := metric.GetTag(common.AttributeSpanID); ok {
+		var err error
+		if spanID, err = parseSpanID(metric, common.AttributeSpanID); err != nil {
+			return err
+		}
+	}
+
+	// Nothing is added to the logs before the record is valid
+	key := resourceKey(metric, logKeys)
+	ill, ok := c.logLibraries[key]
+	if !ok {
+		rl := c.logs.ResourceLogs().AppendEmpty()
+		setResource(rl.Resource(), metric, logKeys)
+		ill = rl.InstrumentationLibraryLogs().AppendEmpty()
+		setLibrary(ill.InstrumentationLibrary(), metric)
+		c.logLibraries[key] = ill
+	}
+
+	record := ill.Logs().AppendEmpty()
+	record.SetTimestamp(pdata.NewTimestampFromTime(metric.Time()))
+	record.SetTraceID(traceID)
+	record.SetSpanID(spanID)
+
+	// Span events exported as log records carry their name as tag.
+	if v, ok := metric.GetTag(common.AttributeName); ok {
+		record.SetName(v)
+	}
+	if v, ok := metric.GetField(common.AttributeName); ok {
+		if s, ok := v.(string); ok {
+			record.SetName(s)
+		}
+	}
+	if v, ok := intField(metric, common.AttributeSeverityNumber); ok {
+		record.SetSeverityNumber(pdata.SeverityNumber(v))
+	}
+	if v, ok := metric.GetField(common.AttributeSeverityText); ok {
+		if s, ok := v.(string); ok {
+			record.SetSeverityText(s)
+		}
+	}
+	if v, ok := metric.GetField(common.AttributeBody); ok {
+		setAttributeValue(record.Body(), v)
+	}
+	if v, ok := intField(metric, common.AttributeDroppedSpanAttributesCount); ok {
+		record.SetDroppedAttributesCount(uint32(v))
+	}
+
+	setAttributes(record.Attributes(), metric, logKeys)
+	return nil
+}
+
+// finish attaches the span events and links to their spans and returns the
+// traces and logs.
+func (c *traceConverter) finish() (pdata.Traces, pdata.Logs) {
+	for _, metric := range c.pendingEvents {
+		traceID, _ := metric.GetTag(common.AttributeTraceID)
+		spanID, _ := metric.GetTag(common.AttributeSpanID)
+		span, ok := c.spans[traceID+spanID]
+		if !ok {
+			if err := c.addLogRecord(metric); err != nil {
+				c.log.Debugf("Dropping span event: %v", err)
+			}
+			continue
+		}
+
+		event := span.Events().AppendEmpty()
+		name, _ := metric.GetTag(common.AttributeName)
+		event.SetName(name)
+		event.SetTimestamp(pdata.NewTimestampFromTime(metric.Time()))
+		if v, ok := intField(metric, common.AttributeDroppedEventAttributesCount); ok {
+			event.SetDroppedAttributesCount(uint32(v))
+		}
+		if !isCountOnly(metric) {
+			setAttributes(event.Attributes(), metric, map[string]bool{
+				common.AttributeDroppedEventAttributesCount: true,
+			})
+		}
+	}
+
+	for _, metric := range c.pendingLinks {
+		traceID, _ := metric.GetTag(common.AttributeTraceID)
+		spanID, _ := metric.GetTag(common.AttributeSpanID)
+		span, ok := c.spans[traceID+spanID]
+		if !ok {
+			c.log.Debugf("Dropping link of span %s not part of the batch", spanID)
+			continue
+		}
+
+		linkedTraceID, err := parseTraceID(metric, common.AttributeLinkedTraceID)
+		if err != nil {
+			c.log.Debugf("Dropping span link: %v", err)
+			continue
+		}
+		linkedSpanID, err := parseSpanID(metric, common.AttributeLinkedSpanID)
+		if err != nil {
+			c.log.Debugf("Dropping span link: %v", err)
+			continue
+		}
+
+		link := span.Links().AppendEmpty()
+		link.SetTraceID(linkedTraceID)
+		link.SetSpanID(linkedSpanID)
+		if v, ok := metric.GetTag(common.AttributeTraceState); ok {
+			link.SetTraceState(pdata.TraceState(v))
+		}
+		if v, ok := intField(metric, common.AttributeDroppedLinkAttributesCount); ok {
+			link.SetDroppedAttributesCount(uint32(v))
+		}
+		if !isCountOnly(metric) {
+			setAttributes(link.Attributes(), metric, linkKeys)
+		}
+	}
+
+	return c.traces, c.logs
+}
+
+// resourceKey identifies the resource and instrumentation library of the
+// metric, all tags not in keys are resource attributes.
+func resourceKey(metric telegraf.Metric, keys map[string]bool) string {
+	var parts []string
+	for _, tag := range metric.TagList() {
+		if keys[tag.Key] {
+			continue
+		}
+		parts = append(parts, tag.Key+"="+tag.Value)
+	}
+	sort.Strings(parts)
+	return strings.Join(parts, ",")
+}
+
+func setResource(resource pdata.Resource, metric telegraf.Metric, keys map[string]bool) {
+	for _, tag := range metric.TagList() {
+		if keys[tag.Key] || libraryKeys[tag.Key] {
+			continue
+		}
+		resource.Attributes().UpsertString(tag.Key, tag.Value)
+	}
+}
+
+func setLibrary(library pdata.InstrumentationLibrary, metric telegraf.Metric) {
+	if v, ok := metric.GetTag(common.AttributeInstrumentationLibraryName); ok {
+		library.SetName(v)
+	}
+	if v, ok := metric.GetTag(common.AttributeInstrumentationLibraryVersion); ok {
+		library.SetVersion(v)
+	}
+}
+
+// setAttributes converts all fields not in keys to attributes.
+func setAttributes(attributes pdata.AttributeMap, metric telegraf.Metric, keys map[string]bool) {
+	for _, field := range metric.FieldList() {
+		if keys[field.Key] {
+			continue
+		}
+		value := pdata.NewAttributeValueEmpty()
+		if setAttributeValue(value, field.Value) {
+			attributes.Upsert(field.Key, value)
+		}
+	}
+}
+
+func setAttributeValue(attribute pdata.AttributeValue, value interface{}) bool {
+	switch v := value.(type) {
+	case string:
+		attribute.SetStringVal(v)
+	case int64:
+		attribute.SetIntVal(v)
+	case uint64:
+		attribute.SetIntVal(int64(v))
+	case float64:
+		attribute.SetDoubleVal(v)
+	case bool:
+		attribute.SetBoolVal(v)
+	default:
+		return false
+	}
+	return true
+}
+
+// isCountOnly returns true for span events and links without attributes,
+// which the OpenTelemetry input stores with a single count field.
+func isCountOnly(metric telegraf.Metric) bool {
+	fields := metric.FieldList()
+	return len(fields) == 1 && fields[0].Key == "count" && fields[0].Value == uint64(1)
+}
+
+func intField(metric telegraf.Metric, key string) (int64, bool) {
+	v, ok := metric.GetField(key)
+	if !ok {
+		return 0, false
+	}
+	switch v := v.(type) {
+	case int64:
+		return v, true
+	case uint64:
+		return int64(v), true
+	case float64:
+		return int64(v), true
+	}
+	return 0, false
+}
+
+func parseTraceID(metric telegraf.Metric, key string) (pdata.TraceID, error) {
+	var id [16]byte
+	v, _ := metric.GetTag(key)
+	if err := decodeID(v, id[:]); err != nil {
+		return pdata.InvalidTraceID(), fmt.Errorf("invalid %s %q: %w", key, v, err)
+	}
+	return pdata.NewTraceID(id), nil
+}
+
+func parseSpanID(metric telegraf.Metric, key string) (pdata.SpanID, error) {
+	var id [8]byte
+	v, _ := metric.GetTag(key)
+	if err := decodeID(v, id[:]); err != nil {
+		return pdata.InvalidSpanID(), fmt.Errorf("invalid %s %q: %w", key, v, err)
+	}
+	return pdata.NewSpanID(id), nil
+}
+
+func decodeID(s string, id []byte) error {
+	if len(s) != 2*len(id) {
+		return fmt.Errorf("expected %d hex digits", 2*len(id))
+	}
+	_, err := hex.Decode(id, []byte(s))
+	return err
+}
+
+func parseSpanKind(s string) pdata.SpanKind {
+	for _, kind := range []pdata.SpanKind{
+		pdata.SpanKindInternal,
+		pdata.SpanKindServer,
+		pdata.SpanKindClient,
+		pdata.SpanKindProducer,
+		pdata.SpanKindConsumer,
+	} {
+		if kind.String() == s {
+			return kind
+		}
+	}
+	return pdata.SpanKindUnspecified
+}
@@ -0,0 +1,567 @@
+package parquet
+
+import (
+	"fmt"
+	"io/fs"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/xitongsys/parquet-go/parquet"
+	"github.com/xitongsys/parquet-go/writer"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/config"
+	"github.com/influxdata/telegraf/plugins/outputs"
+)
+
+const sampleConfig = `
+  ## Directory to write the files to.
+  directory = "/var/lib/telegraf/parquet"
+
+  ## Time window for partitioning the files by the metric timestamps.
+  # partition_interval = "1h"
+
+  ## Tags to partition the files by, in the given order. The tags are used in
+  ## the directory layout and are not written as columns.
+  # partition_tags = []
+
+  ## Compression codec for the column chunks, one of "uncompressed",
+  ## "snappy", "gzip", "lz4" or "zstd".
+  # compression = "snappy"
+
+  ## Size of the buffered data after which a row group is written.
+  # row_group_size = "16MiB"
+
+  ## Write each batch to new files completed before the write returns,
+  ## instead of keeping one file per partition open until its time window
+  ## has passed. This avoids losing metrics on a crash at the cost of many
+  ## small files.
+  # file_per_batch = false
+`
+
+// timeColumn is the name of the column holding the metric timestamp
+const timeColumn = "time"
+
+var compressionCodecs = map[string]parquet.CompressionCodec{
+	"uncompressed": parquet.CompressionCodec_UNCOMPRESSED,
+	"snappy":       parquet.CompressionCodec_SNAPPY,
+	"gzip":         parquet.CompressionCodec_GZIP,
+	"lz4":          parquet.CompressionCodec_LZ4,
+	"zstd":         parquet.CompressionCodec_ZSTD,
+}
+
+type Parquet struct {
+	Directory         string          `toml:"directory"`
+	PartitionInterval config.Duration `toml:"partition_interval"`
+	PartitionTags     []string        `toml:"partition_tags"`
+	Compression       string          `toml:"compression"`
+	RowGroupSize      config.Size     `toml:"row_group_size"`
+	FilePerBatch      bool            `toml:"file_per_batch"`
+	Log               telegraf.Logger `toml:"-"`
+
+	codec         parquet.CompressionCodec
+	partitionTags map[string]bool
+	schemas       map[string]*schema
+
+	// files are the open files by partition directory
+	files map[string]*file
+
+	// lastName is the timestamp of the last file name, file names are
+	// unique even if created within the same nanosecond
+	lastName int64
+}
+
+// file is a parquet file of a partition. Data is written to a temporary
+// file renamed once the file is complete.
+type file struct {
+	tmpPath string
+	path    string
+	handle  *os.File
+	writer  *writer.CSVWriter
+
+	// end of the partition's time window
+	end time.Time
+	// number of schema columns and rows written
+	columns int
+	rows    int
+}
+
+func (*Parquet) SampleConfig() string {
+	return sampleConfig
+}
+
+func (*Parquet) Description() string {
+	return "Write metrics to Apache Parquet files partitioned by time and tags"
+}
+
+func (p *Parquet) Init() error {
+	if p.Directory == "" {
+		return fmt.Errorf("directory must be specified")
+	}
+
+	codec, found := compressionCodecs[p.Compression]
+	if !found {
+		return fmt.Errorf("invalid compression %q", p.Compression)
+	}
+	p.codec = codec
+
+	if p.PartitionInterval <= 0 {
+		return fmt.Errorf("partition_interval must be positive")
+	}
+
+	p.partitionTags = make(map[string]bool, len(p.PartitionTags))
+	for _, tag := range p.PartitionTags {
+		p.partitionTags[tag] = true
+	}
+
+	return nil
+}
+
+func (p *Parquet) Connect() error {
+	if err := os.MkdirAll(p.Directory, 0755); err != nil {
+		return fmt.Errorf("creating directory failed: %w", err)
+	}
+
+	// Temporary files are left by writes interrupted by a crash, their
+	// metrics were not acknowledged and are written again
+	err := filepath.WalkDir(p.Directory, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		if !strings.HasPrefix(d.Name(), ".part-") || !strings.HasSuffix(d.Name(), ".parquet.tmp") {
+			return nil
+		}
+		p.Log.Warnf("Removing incomplete file %q", path)
+		return os.Remove(path)
+	})
+	if err != nil {
+		return fmt.Errorf("removing incomplete files failed: %w", err)
+	}
+
+	p.schemas = make(map[string]*schema)
+	p.files = make(map[string]*file)
+
+	return nil
+}
+
+// Close completes all open files
+func (p *Parquet) Close() error {
+	for dir, f := range p.files {
+		p.publish(f)
+		delete(p.files, dir)
+	}
+	return nil
+}
+
+func (p *Parquet) Write(metrics []telegraf.Metric) error {
+	for _, m := range metrics {
+		s, found := p.schemas[m.Name()]
+		if !found {
+			s = newSchema()
+			p.schemas[m.Name()] = s
+		}
+		s.merge(m, p.partitionTags, p.Log)
+	}
+
+	if p.FilePerBatch {
+		return p.writeBatch(metrics)
+	}
+	return p.writeWindows(metrics)
+}
+
+// writeWindows appends the metrics to the open file of their partition. A
+// file is completed once the time window of its partition has passed or
+// columns are added to the schema of its measurement, as the schema of a
+// file cannot be extended.
+func (p *Parquet) writeWindows(metrics []telegraf.Metric) error {
+	// Open all files first to not write parts of the batch if this fails
+	dirs := make([]string, 0, len(metrics))
+	for _, m := range metrics {
+		s := p.schemas[m.Name()]
+		dir, end := p.partition(m)
+		dirs = append(dirs, dir)
+
+		f, found := p.files[dir]
+		if found && f.columns != len(s.columns) {
+			p.publish(f)
+			delete(p.files, dir)
+			found = false
+		}
+		if !found {
+			f, err := p.create(dir, end, s)
+			if err != nil {
+				return err
+			}
+			p.files[dir] = f
+		}
+	}
+
+	for i, m := range metrics {
+		f := p.files[dirs[i]]
+		if err := f.writer.Write(p.schemas[m.Name()].row(m, p.partitionTags)); err != nil {
+			p.Log.Errorf("Dropping %d metrics of %q", f.rows, f.tmpPath)
+			p.remove(f)
+			delete(p.files, dirs[i])
+			return fmt.Errorf("writing to %q failed: %w", f.tmpPath, err)
+		}
+		f.rows++
+	}
+
+	now := time.Now()
+	for dir, f := range p.files {
+		if !now.Before(f.end) {
+			p.publish(f)
+			delete(p.files, dir)
+		}
+	}
+
+	return nil
+}
+
+// publish completes the file and renames it. The metrics of the file were
+// acknowledged already, so errors are only logged.
+func (p *Parquet) publish(f *file) {
+	if f.rows == 0 {
+		p.remove(f)
+		return
+	}
+	if err := p.finish(f); err != nil {
+		p.Log.Errorf("Dropping %d metrics: %v", f.rows, err)
+		p.remove(f)
+		return
+	}
+	if err := os.Rename(f.tmpPath, f.path); err != nil {
+		p.Log.Errorf("Dropping %d metrics: renaming %q failed: %v", f.rows, f.tmpPath, err)
+		p.remove(f)
+		return
+	}
+	syncDir(filepath.Dir(f.path))
+	p.Log.Debugf("Wrote %q", f.path)
+}
+
+// writeBatch writes the metrics of each partition to a new file. The files
+// are complete and synced to disk when Write returns, if any of them fails
+// none of the files is kept to not duplicate metrics when the batch is
+// retried.
+func (p *Parquet) writeBatch(metrics []telegraf.Metric) error {
+	files := make(map[string]*file)
+	for _, m := range metrics {
+		s := p.schemas[m.Name()]
+		dir, end := p.partition(m)
+
+		f, found := files[dir]
+		if !found {
+			var err error
+			if f, err = p.create(dir, end, s); err != nil {
+				p.abort(files)
+				return err
+			}
+			files[dir] = f
+		}
+
+		if err := f.writer.Write(s.row(m, p.partitionTags)); err != nil {
+			p.abort(files)
+			return fmt.Errorf("writing to %q failed: %w", f.tmpPath, err)
+		}
+	}
+
+	for _, f := range files {
+		if err := p.finish(f); err != nil {
+			p.abort(files)
+			return err
+		}
+	}
+
+	// Only publish the files once all of them are complete
+	published := make([]*file, 0, len(files))
+	for _, f := range files {
+		if err := os.Rename(f.tmpPath, f.path); err != nil {
+			for _, done := range published {
+				os.Remove(done.path)
+			}
+			p.abort(files)
+			return fmt.Errorf("renaming %q failed: %w", f.tmpPath, err)
+		}
+		published = append(published, f)
+		syncDir(filepath.Dir(f.path))
+		p.Log.Debugf("Wrote %q", f.path)
+	}
+
+	return nil
+}
+
+// partition returns the directory of the metric's partition and the end of
+// its time window
+func (p *Parquet) partition(m telegraf.Metric) (string, time.Time) {
+	elements := make([]string, 0, len(p.PartitionTags)+3)
+	elements = append(elements, p.Directory, escape(m.Name()))
+	for _, tag := range p.PartitionTags {
+		value, _ := m.GetTag(tag)
+		elements = append(elements, escape(tag)+"="+escape(value))
+	}
+	window := m.Time().Truncate(time.Duration(p.PartitionInterval)).UTC()
+	elements = append(elements, window.Format("20060102T150405Z"))
+
+	return filepath.Join(elements...), window.Add(time.Duration(p.PartitionInterval))
+}
+
+// escape path escapes the element, including the dots of the "." and ".."
+// elements referring to the current and parent directory
+func escape(element string) string {
+	escaped := url.PathEscape(element)
+	if escaped == "." || escaped == ".." {
+		return strings.ReplaceAll(escaped, ".", "%2E")
+	}
+	return escaped
+}
+
+func (p *Parquet) create(dir string, end time.Time, s *schema) (*file, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("creating partition directory failed: %w", err)
+	}
+
+	ts := time.Now().UnixNano()
+	if ts <= p.lastName {
+		ts = p.lastName + 1
+	}
+	p.lastName = ts
+
+	name := fmt.Sprintf("part-%d.parquet", ts)
+	f := &file{
+		path:    filepath.Join(dir, name),
+		tmpPath: filepath.Join(dir, "."+name+".tmp"),
+		end:     end,
+		columns: len(s.columns),
+	}
+
+	handle, err := os.Create(f.tmpPath)
+	if err != nil {
+		return nil, fmt.Errorf("creating file failed: %w", err)
+	}
+
+	w, err := writer.NewCSVWriterFromWriter(s.metadata(), handle, 1)
+	if err != nil {
+		handle.Close()
+		os.Remove(f.tmpPath)
+		return nil, fmt.Errorf("creating parquet writer failed: %w", err)
+	}
+	w.CompressionType = p.codec
+	if p.RowGroupSize > 0 {
+		w.RowGroupSize = int64(p.RowGroupSize)
+	}
+
+	f.handle = handle
+	f.writer = w
+	p.Log.Debugf("Created %q", f.tmpPath)
+
+	return f, nil
+}
+
+// finish writes the footer of the file and syncs it to disk
+func (p *Parquet) finish(f *file) error {
+	if err := f.writer.WriteStop(); err != nil {
+		return fmt.Errorf("writing footer of %q failed: %w", f.tmpPath, err)
+	}
+	if err := f.handle.Sync(); err != nil {
+		return fmt.Errorf("syncing %q failed: %w", f.tmpPath, err)
+	}
+	if err := f.handle.Close(); err != nil {
+		return fmt.Errorf("closing %q failed: %w", f.tmpPath, err)
+	}
+	f.handle = nil
+	return nil
+}
+
+// abort removes the temporary files of a failed write
+func (p *Parquet) abort(files map[string]*file) {
+	for _, f := range files {
+		p.remove(f)
+	}
+}
+
+// remove closes and removes the temporary file
+func (p *Parquet) remove(f *file) {
+	if f.handle != nil {
+		f.handle.Close()
+		f.handle = nil
+	}
+	if err := os.Remove(f.tmpPath); err != nil && !os.IsNotExist(err) {
+		p.Log.Errorf("Removing %q failed: %v", f.tmpPath, err)
+	}
+}
+
+// syncDir persists renames in the directory, directories cannot be synced
+// on all platforms
+func syncDir(path string) {
+	if dir, err := os.Open(path); err == nil {
+		_ = dir.Sync()
+		dir.Close()
+	}
+}
+
+type columnType int
+
+const (
+	columnTag columnType = iota
+	columnFloat
+	columnInt
+	columnUint
+	columnBool
+	columnString
+)
+
+type column struct {
+	key  string
+	name string
+	typ  columnType
+}
+
+// schema holds the columns of a measurement. Columns are only ever added.
+// Tags and fields are indexed separately, a column sharing its name with an
+// existing column is suffixed with "_tag" or "_field".
+type schema struct {
+	columns []column
+	tags    map[string]int
+	fields  map[string]int
+	names   map[string]bool
+}
+
+func newSchema() *schema {
+	return &schema{
+		tags:   make(map[string]int),
+		fields: make(map[string]int),
+		names:  map[string]bool{timeColumn: true},
+	}
+}
+
+// merge adds columns for unknown tags and fields of the metric
+func (s *schema) merge(m telegraf.Metric, partitionTags map[string]bool, log telegraf.Logger) {
+	var added []column
+	for _, tag := range m.TagList() {
+		if _, found := s.tags[tag.Key]; !found && !partitionTags[tag.Key] {
+			added = append(added, column{key: tag.Key, typ: columnTag})
+		}
+	}
+	for _, field := range m.FieldList() {
+		if _, found := s.fields[field.Key]; found {
+			continue
+		}
+		typ, ok := fieldType(field.Value)
+		if !ok {
+			log.Debugf("Unsupported type %T of field %q", field.Value, field.Key)
+			continue
+		}
+		added = append(added, column{key: field.Key, typ: typ})
+	}
+
+	// Tags take the name before fields of the same name
+	sort.Slice(added, func(i, j int) bool {
+		if added[i].key != added[j].key {
+			return added[i].key < added[j].key
+		}
+		return added[i].typ == columnTag
+	})
+	for _, c := range added {
+		index := s.fields
+		suffix := "_field"
+		if c.typ == columnTag {
+			index = s.tags
+			suffix = "_tag"
+		}
+
+		c.name = c.key
+		if s.names[c.name] {
+			c.name += suffix
+			log.Debugf("Column %q of measurement %q exists, writing %q", c.key, m.Name(), c.name)
+		}
+		s.names[c.name] = true
+		index[c.key] = len(s.columns)
+		s.columns = append(s.columns, c)
+	}
+}
+
+// metadata returns the schema description of the writer
+func (s *schema) metadata() []string {
+	md := make([]string, 0, len(s.columns)+1)
+	md = append(md, "name="+timeColumn+", type=INT64, logicaltype=TIMESTAMP, logicaltype.isadjustedtoutc=true, logicaltype.unit=NANOS, repetitiontype=REQUIRED")
+	for _, c := range s.columns {
+		name := "name=" + sanitize(c.name)
+		switch c.typ {
+		case columnTag, columnString:
+			md = append(md, name+", type=BYTE_ARRAY, convertedtype=UTF8, repetitiontype=OPTIONAL")
+		case columnFloat:
+			md = append(md, name+", type=DOUBLE, repetitiontype=OPTIONAL")
+		case columnInt:
+			md = append(md, name+", type=INT64, repetitiontype=OPTIONAL")
+		case columnUint:
+			md = append(md, name+", type=INT64, convertedtype=UINT_64, repetitiontype=OPTIONAL")
+		case columnBool:
+			md = append(md, name+", type=BOOLEAN, repetitiontype=OPTIONAL")
+		}
+	}
+	return md
+}
+
+// row returns the values of the metric in column order. Values not matching
+// the column type are omitted.
+func (s *schema) row(m telegraf.Metric, partitionTags map[string]bool) []interface{} {
+	row := make([]interface{}, len(s.columns)+1)
+	row[0] = m.Time().UnixNano()
+	for _, tag := range m.TagList() {
+		if i, found := s.tags[tag.Key]; found && !partitionTags[tag.Key] {
+			row[i+1] = tag.Value
+		}
+	}
+	for _, field := range m.FieldList() {
+		i, found := s.fields[field.Key]
+		if !found {
+			continue
+		}
+		if typ, ok := fieldType(field.Value); !ok || typ != s.columns[i].typ {
+			continue
+		}
+		switch v := field.Value.(type) {
+		case uint64:
+			row[i+1] = int64(v)
+		default:
+			row[i+1] = v
+		}
+	}
+	return row
+}
+
+func fieldType(value interface{}) (columnType, bool) {
+	switch value.(type) {
+	case float64:
+		return columnFloat, true
+	case int64:
+		return columnInt, true
+	case uint64:
+		return columnUint, true
+	case bool:
+		return columnBool, true
+	case string:
+		return columnString, true
+	}
+	return 0, false
+}
+
+// sanitize removes the characters used as separators of the key-value pairs
+// in the schema metadata
+func sanitize(name string) string {
+	return strings.NewReplacer(",", "_", "=", "_", "\t", "_").Replace(name)
+}
+
+func init() {
+	outputs.Add("parquet", func() telegraf.Output {
+		return &Parquet{
+			PartitionInterval: config.Duration(time.Hour),
+			Compression:       "snappy",
+			RowGroupSize:      config.Size(16 * 1024 * 1024),
+		}
+	})
+}
@@ -0,0 +1,338 @@
+package parquet
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/xitongsys/parquet-go-source/local"
+	"github.com/xitongsys/parquet-go/reader"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/config"
+	"github.com/influxdata/telegraf/metric"
+	"github.com/influxdata/telegraf/testutil"
+)
+
+func newParquet(t *testing.T) *Parquet {
+	p := &Parquet{
+		Directory:         t.TempDir(),
+		PartitionInterval: config.Duration(time.Hour),
+		Compression:       "snappy",
+		Log:               testutil.Logger{},
+	}
+	require.NoError(t, p.Init())
+	require.NoError(t, p.Connect())
+	return p
+}
+
+// readColumns returns the values of all columns of the file by column name
+func readColumns(t *testing.T, path string) map[string][]interface{} {
+	pf, err := local.NewLocalFileReader(path)
+	require.NoError(t, err)
+	defer pf.Close()
+
+	pr, err := reader.NewParquetColumnReader(pf, 1)
+	require.NoError(t, err)
+	defer pr.ReadStop()
+
+	rows := pr.GetNumRows()
+	columns := make(map[string][]interface{})
+	for i := range pr.Footer.Schema[1:] {
+		values, _, _, err := pr.ReadColumnByIndex(int64(i), rows)
+		require.NoError(t, err)
+		columns[pr.SchemaHandler.GetExName(i+1)] = values
+	}
+	return columns
+}
+
+func findFiles(t *testing.T, dir string) []string {
+	files, err := filepath.Glob(filepath.Join(dir, "*.parquet"))
+	require.NoError(t, err)
+	return files
+}
+
+func TestWrite(t *testing.T) {
+	p := newParquet(t)
+
+	ts := time.Date(2022, 4, 15, 5, 20, 0, 0, time.UTC)
+	metrics := []telegraf.Metric{
+		metric.New(
+			"cpu",
+			map[string]string{"host": "server01"},
+			map[string]interface{}{"usage": 42.5, "count": int64(3), "total": uint64(7), "ok": true, "state": "running"},
+			ts,
+		),
+		metric.New(
+			"cpu",
+			map[string]string{"host": "server02"},
+			map[string]interface{}{"usage": 1.5},
+			ts.Add(time.Second),
+		),
+	}
+	require.NoError(t, p.Write(metrics))
+
+	// Files are complete once written
+	dir := filepath.Join(p.Directory, "cpu", "20220415T050000Z")
+	files := findFiles(t, dir)
+	require.Len(t, files, 1)
+
+	expected := map[string][]interface{}{
+		"time":  {ts.UnixNano(), ts.Add(time.Second).UnixNano()},
+		"host":  {"server01", "server02"},
+		"count": {int64(3), nil},
+		"ok":    {true, nil},
+		"state": {"running", nil},
+		"total": {int64(7), nil},
+		"usage": {42.5, 1.5},
+	}
+	require.Equal(t, expected, readColumns(t, files[0]))
+}
+
+func TestPartitioning(t *testing.T) {
+	p := newParquet(t)
+	p.PartitionTags = []string{"region"}
+	require.NoError(t, p.Init())
+
+	ts := time.Date(2022, 4, 15, 5, 59, 59, 0, time.UTC)
+	metrics := []telegraf.Metric{
+		metric.New("cpu", map[string]string{"region": "eu/west", "host": "a"}, map[string]interface{}{"value": 1.0}, ts),
+		metric.New("cpu", map[string]string{"region": "us", "host": "b"}, map[string]interface{}{"value": 2.0}, ts),
+		metric.New("cpu", map[string]string{"region": "us", "host": "c"}, map[string]interface{}{"value": 3.0}, ts.Add(time.Second)),
+		metric.New("mem", map[string]string{}, map[string]interface{}{"value": int64(4)}, ts),
+	}
+	require.NoError(t, p.Write(metrics))
+
+	files := findFiles(t, filepath.Join(p.Directory, "cpu", "region=eu%2Fwest", "20220415T050000Z"))
+	require.Len(t, files, 1)
+	require.Equal(t, map[string][]interface{}{
+		"time":  {ts.UnixNano()},
+		"host":  {"a"},
+		"value": {1.0},
+	}, readColumns(t, files[0]))
+
+	require.Len(t, findFiles(t, filepath.Join(p.Directory, "cpu", "region=us", "20220415T050000Z")), 1)
+	require.Len(t, findFiles(t, filepath.Join(p.Directory, "cpu", "region=us", "20220415T060000Z")), 1)
+	require.Len(t, findFiles(t, filepath.Join(p.Directory, "mem", "region=", "20220415T050000Z")), 1)
+}
+
+func TestSchemaEvolution(t *testing.T) {
+	p := newParquet(t)
+	dir := filepath.Join(p.Directory, "cpu", "20220415T050000Z")
+
+	ts := time.Date(2022, 4, 15, 5, 20, 0, 0, time.UTC)
+	require.NoError(t, p.Write([]telegraf.Metric{
+		metric.New("cpu", map[string]string{}, map[string]interface{}{"a": 1.0}, ts),
+		metric.New("cpu", map[string]string{}, map[string]interface{}{"a": "text"}, ts.Add(time.Second)),
+	}))
+
+	// Files of passed windows are completed by each write with the columns
+	// known so far
+	require.NoError(t, p.Write([]telegraf.Metric{
+		metric.New("cpu", map[string]string{}, map[string]interface{}{"a": 2.0, "b": int64(1)}, ts.Add(2*time.Second)),
+	}))
+	require.NoError(t, p.Write([]telegraf.Metric{
+		metric.New("cpu", map[string]string{}, map[string]interface{}{"a": 3.0}, ts.Add(3*time.Second)),
+	}))
+	files := findFiles(t, dir)
+	require.Len(t, files, 3)
+	require.Equal(t, map[string][]interface{}{
+		"time": {ts.UnixNano(), ts.Add(time.Second).UnixNano()},
+		"a":    {1.0, nil},
+	}, readColumns(t, files[0]))
+	require.Equal(t, map[string][]interface{}{
+		"time": {ts.Add(2 * time.Second).UnixNano()},
+		"a":    {2.0},
+		"b":    {int64(1)},
+	}, readColumns(t, files[1]))
+	require.Equal(t, map[string][]interface{}{
+		"time": {ts.Add(3 * time.Second).UnixNano()},
+		"a":    {3.0},
+		"b":    {nil},
+	}, readColumns(t, files[2]))
+}
+
+func TestTagFieldCollision(t *testing.T) {
+	p := newParquet(t)
+
+	ts := time.Date(2022, 4, 15, 5, 20, 0, 0, time.UTC)
+	require.NoError(t, p.Write([]telegraf.Metric{
+		metric.New("cpu", map[string]string{"state": "a", "time": "b"}, map[string]interface{}{"state": "c"}, ts),
+		metric.New("cpu", map[string]string{}, map[string]interface{}{"host": "d"}, ts),
+		metric.New("cpu", map[string]string{"host": "e"}, map[string]interface{}{"value": 1.0}, ts),
+	}))
+
+	files := findFiles(t, filepath.Join(p.Directory, "cpu", "20220415T050000Z"))
+	require.Len(t, files, 1)
+	require.Equal(t, map[string][]interface{}{
+		"time":        {ts.UnixNano(), ts.UnixNano(), ts.UnixNano()},
+		"state":       {"a", nil, nil},
+		"state_field": {"c", nil, nil},
+		"time_tag":    {"b", nil, nil},
+		"host":        {nil, "d", nil},
+		"host_tag":    {nil, nil, "e"},
+		"value":       {nil, nil, 1.0},
+	}, readColumns(t, files[0]))
+}
+
+func TestSanitizeColumnNames(t *testing.T) {
+	p := newParquet(t)
+
+	ts := time.Date(2022, 4, 15, 5, 20, 0, 0, time.UTC)
+	require.NoError(t, p.Write([]telegraf.Metric{
+		metric.New("cpu", map[string]string{"a=b": "x"}, map[string]interface{}{"c,d": 1.0, "type=INT64": 2.0, "e\tf": 3.0}, ts),
+	}))
+
+	files := findFiles(t, filepath.Join(p.Directory, "cpu", "20220415T050000Z"))
+	require.Len(t, files, 1)
+	require.Equal(t, map[string][]interface{}{
+		"time":       {ts.UnixNano()},
+		"a_b":        {"x"},
+		"c_d":        {1.0},
+		"type_INT64": {2.0},
+		"e_f":        {3.0},
+	}, readColumns(t, files[0]))
+}
+
+func TestWriteFailure(t *testing.T) {
+	for _, perBatch := range []bool{false, true} {
+		t.Run(fmt.Sprintf("file_per_batch=%v", perBatch), func(t *testing.T) {
+			p := newParquet(t)
+			p.FilePerBatch = perBatch
+
+			// The partition directory of the second measurement can not be created
+			require.NoError(t, os.WriteFile(filepath.Join(p.Directory, "mem"), nil, 0640))
+
+			ts := time.Date(2022, 4, 15, 5, 20, 0, 0, time.UTC)
+			require.Error(t, p.Write([]telegraf.Metric{
+				metric.New("cpu", map[string]string{}, map[string]interface{}{"value": 1.0}, ts),
+				metric.New("mem", map[string]string{}, map[string]interface{}{"value": 2.0}, ts),
+			}))
+			require.NoError(t, p.Close())
+
+			// No metric of the failed write is kept
+			entries, err := os.ReadDir(filepath.Join(p.Directory, "cpu", "20220415T050000Z"))
+			require.NoError(t, err)
+			require.Empty(t, entries)
+		})
+	}
+}
+
+func TestWriteWindow(t *testing.T) {
+	p := newParquet(t)
+
+	// Files of windows not passed yet are kept open across writes
+	ts := time.Now().Add(24 * time.Hour).Truncate(time.Hour)
+	dir := filepath.Join(p.Directory, "cpu", ts.UTC().Format("20060102T150405Z"))
+	require.NoError(t, p.Write([]telegraf.Metric{
+		metric.New("cpu", map[string]string{}, map[string]interface{}{"a": 1.0}, ts),
+	}))
+	require.NoError(t, p.Write([]telegraf.Metric{
+		metric.New("cpu", map[string]string{}, map[string]interface{}{"a": 2.0}, ts.Add(time.Second)),
+	}))
+	require.Empty(t, findFiles(t, dir))
+
+	// New columns complete the open file
+	require.NoError(t, p.Write([]telegraf.Metric{
+		metric.New("cpu", map[string]string{}, map[string]interface{}{"a": 3.0, "b": int64(1)}, ts.Add(2*time.Second)),
+	}))
+	files := findFiles(t, dir)
+	require.Len(t, files, 1)
+	require.Equal(t, map[string][]interface{}{
+		"time": {ts.UnixNano(), ts.Add(time.Second).UnixNano()},
+		"a":    {1.0, 2.0},
+	}, readColumns(t, files[0]))
+
+	// Open files are completed on close
+	require.NoError(t, p.Write([]telegraf.Metric{
+		metric.New("cpu", map[string]string{}, map[string]interface{}{"a": 4.0}, ts.Add(3*time.Second)),
+	}))
+	require.NoError(t, p.Close())
+	files = findFiles(t, dir)
+	require.Len(t, files, 2)
+	require.Equal(t, map[string][]interface{}{
+		"time": {ts.Add(2 * time.Second).UnixNano(), ts.Add(3 * time.Second).UnixNano()},
+		"a":    {3.0, 4.0},
+		"b":    {int64(1), nil},
+	}, readColumns(t, files[1]))
+}
+
+func TestFilePerBatch(t *testing.T) {
+	p := newParquet(t)
+	p.FilePerBatch = true
+
+	// Each write creates complete files, even if the window has not passed
+	ts := time.Now().Add(24 * time.Hour).Truncate(time.Hour)
+	dir := filepath.Join(p.Directory, "cpu", ts.UTC().Format("20060102T150405Z"))
+	for i := 0; i < 2; i++ {
+		require.NoError(t, p.Write([]telegraf.Metric{
+			metric.New("cpu", map[string]string{}, map[string]interface{}{"a": float64(i)}, ts),
+		}))
+		require.Len(t, findFiles(t, dir), i+1)
+	}
+}
+
+func TestConnectRemovesIncompleteFiles(t *testing.T) {
+	p := newParquet(t)
+
+	dir := filepath.Join(p.Directory, "cpu", "20220415T050000Z")
+	require.NoError(t, os.MkdirAll(dir, 0755))
+	incomplete := filepath.Join(dir, ".part-1650000000000000000.parquet.tmp")
+	require.NoError(t, os.WriteFile(incomplete, []byte("PAR1"), 0640))
+	other := filepath.Join(dir, "other.tmp")
+	require.NoError(t, os.WriteFile(other, nil, 0640))
+
+	require.NoError(t, p.Connect())
+	require.NoFileExists(t, incomplete)
+	require.FileExists(t, other)
+}
+
+func TestPartitionEscaping(t *testing.T) {
+	p := newParquet(t)
+	p.PartitionTags = []string{"region"}
+	require.NoError(t, p.Init())
+
+	ts := time.Date(2022, 4, 15, 5, 20, 0, 0, time.UTC)
+	require.NoError(t, p.Write([]telegraf.Metric{
+		metric.New("..", map[string]string{"region": ".."}, map[string]interface{}{"value": 1.0}, ts),
+	}))
+	require.Len(t, findFiles(t, filepath.Join(p.Directory, "%2E%2E", "region=%2E%2E", "20220415T050000Z")), 1)
+}
+
+func TestCompression(t *testing.T) {
+	for codec := range compressionCodecs {
+		t.Run(codec, func(t *testing.T) {
+			p := newParquet(t)
+			p.Compression = codec
+			require.NoError(t, p.Init())
+
+			ts := time.Date(2022, 4, 15, 5, 20, 0, 0, time.UTC)
+			require.NoError(t, p.Write([]telegraf.Metric{
+				metric.New("cpu", map[string]string{"host": "a"}, map[string]interface{}{"value": 1.0}, ts),
+			}))
+
+			files := findFiles(t, filepath.Join(p.Directory, "cpu", "20220415T050000Z"))
+			require.Len(t, files, 1)
+			require.Equal(t, []interface{}{1.0}, readColumns(t, files[0])["value"])
+		})
+	}
+}
+
+func TestInitErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		plugin *Parquet
+	}{
+		{"no directory", &Parquet{Compression: "snappy", PartitionInterval: config.Duration(time.Hour)}},
+		{"invalid compression", &Parquet{Directory: "out", Compression: "brotli", PartitionInterval: config.Duration(time.Hour)}},
+		{"invalid interval", &Parquet{Directory: "out", Compression: "snappy"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.Error(t, tt.plugin.Init())
+		})
+	}
+}
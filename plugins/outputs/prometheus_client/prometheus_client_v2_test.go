@@ -9,6 +9,9 @@ import (
 	"testing"
 	"time"
 
+	"github.com/matttproud/golang_protobuf_extensions/pbutil"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/prometheus/common/expfmt"
 	"github.com/stretchr/testify/require"
 
 	"github.com/influxdata/telegraf"
@@ -464,3 +467,56 @@ rpc_duration_seconds_count 2693
 		})
 	}
 }
+
+func TestNativeHistogramMetricVersion2(t *testing.T) {
+	output := &PrometheusClient{
+		Listen:            ":0",
+		MetricVersion:     2,
+		CollectorsExclude: []string{"gocollector", "process"},
+		Path:              "/metrics",
+		Log:               testutil.Logger{Name: "outputs.prometheus_client"},
+	}
+	require.NoError(t, output.Init())
+	require.NoError(t, output.Connect())
+	defer func() {
+		require.NoError(t, output.Close())
+	}()
+
+	m := testutil.MustMetric(
+		"http",
+		map[string]string{},
+		map[string]interface{}{
+			"latency_count":          uint64(3),
+			"latency_sum":            6.5,
+			"latency_scale":          int64(0),
+			"latency_zero_threshold": 0.0,
+			"latency_zero_count":     uint64(0),
+			"latency_positive_0":     uint64(1),
+			"latency_positive_1":     uint64(2),
+		},
+		time.Unix(0, 0),
+		telegraf.Histogram,
+	)
+	require.NoError(t, output.Write([]telegraf.Metric{m}))
+
+	// Native histograms are only exposed in the protobuf format
+	req, err := http.NewRequest("GET", output.URL(), nil)
+	require.NoError(t, err)
+	req.Header.Set("Accept", string(expfmt.FmtProtoDelim))
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	var family dto.MetricFamily
+	_, err = pbutil.ReadDelimited(resp.Body, &family)
+	require.NoError(t, err)
+	require.Equal(t, "http_latency", family.GetName())
+	h := family.GetMetric()[0].GetHistogram()
+	require.Equal(t, uint64(3), h.GetSampleCount())
+	require.Equal(t, int32(0), h.GetSchema())
+	require.Len(t, h.GetPositiveSpan(), 1)
+	require.Equal(t, int32(1), h.GetPositiveSpan()[0].GetOffset())
+	require.Equal(t, uint32(2), h.GetPositiveSpan()[0].GetLength())
+	require.Equal(t, []int64{1, 1}, h.GetPositiveDelta())
+}
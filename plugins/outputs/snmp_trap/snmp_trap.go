@@ -0,0 +1,379 @@
+package snmp_trap
+
+import (
+	"encoding/hex"
+	"fmt"
+	"hash/fnv"
+	"math"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gosnmp/gosnmp"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/config"
+	"github.com/influxdata/telegraf/internal/snmp"
+	"github.com/influxdata/telegraf/metric"
+	"github.com/influxdata/telegraf/plugins/outputs"
+)
+
+const (
+	sysUpTimeOid   = ".1.3.6.1.2.1.1.3.0"
+	snmpTrapOIDOid = ".1.3.6.1.6.3.1.1.4.1.0"
+)
+
+var sampleConfig = `
+  ## Managers to send the traps to.
+  ##   format:  agents = ["<scheme://><hostname>:<port>"]
+  ##   scheme:  optional, either udp, udp4, udp6, tcp, tcp4, tcp6.
+  ##            default is udp
+  ##   port:    optional, default is 162
+  agents = ["udp://127.0.0.1:162"]
+
+  ## Send INFORM requests acknowledged by the managers instead of traps.
+  # inform = false
+
+  ## Tag holding the trap OID of a metric; metrics without the tag are sent
+  ## with the default trap OID or dropped if none is set.
+  # trap_oid_tag = "oid"
+  # default_trap_oid = ""
+
+  ## Field holding the sysUpTime of the trap in hundredths of a second;
+  ## defaults to the time since Telegraf started if missing.
+  # uptime_field = "sysUpTimeInstance"
+
+  ## Timeout of INFORM requests.
+  # timeout = "5s"
+
+  ## Number of retries of INFORM requests.
+  # retries = 3
+
+  ## Path to mib files used to translate the field names to OIDs.
+  # path = ["/usr/share/snmp/mibs"]
+
+  ## SNMP version; can be 2 or 3.
+  # version = 2
+
+  ## SNMP community string.
+  # community = "public"
+
+  ## SNMPv3 authentication and encryption options.
+  ##
+  ## Security Name.
+  # sec_name = "myuser"
+  ## Authentication protocol; one of "MD5", "SHA", "SHA224", "SHA256", "SHA384", "SHA512" or "".
+  # auth_protocol = "MD5"
+  ## Authentication password.
+  # auth_password = "pass"
+  ## Security Level; one of "noAuthNoPriv", "authNoPriv", or "authPriv".
+  # sec_level = "authNoPriv"
+  ## Context Name.
+  # context_name = ""
+  ## Privacy protocol used for encrypted messages; one of "DES", "AES" or "".
+  # priv_protocol = ""
+  ## Privacy password used for encrypted messages.
+  # priv_password = ""
+  ## Authoritative engine ID of Telegraf in hex, required for traps; the
+  ## engine ID of the managers is discovered for INFORM requests.
+  # engine_id = "80001f8880"
+`
+
+type SnmpTrap struct {
+	Agents         []string `toml:"agents"`
+	Inform         bool     `toml:"inform"`
+	TrapOidTag     string   `toml:"trap_oid_tag"`
+	DefaultTrapOid string   `toml:"default_trap_oid"`
+	UptimeField    string   `toml:"uptime_field"`
+	EngineID       string   `toml:"engine_id"`
+
+	snmp.ClientConfig
+
+	Log telegraf.Logger `toml:"-"`
+
+	conns []snmp.GosnmpWrapper
+	start time.Time
+
+	// translate returns the numeric OID of a field name or trap OID
+	translate func(string) (string, error)
+	oids      map[string]string
+
+	// delivered holds the traps sent by a write that failed for another
+	// manager, they are skipped when the write is retried.
+	delivered map[delivery]bool
+}
+
+// delivery identifies a metric sent to a manager
+type delivery struct {
+	metric uint64
+	agent  int
+}
+
+func (s *SnmpTrap) SampleConfig() string {
+	return sampleConfig
+}
+
+func (s *SnmpTrap) Description() string {
+	return "Send metrics as SNMP traps or INFORM requests"
+}
+
+func (s *SnmpTrap) Init() error {
+	if s.Version == 1 {
+		return fmt.Errorf("version 1 is not supported")
+	}
+	if len(s.Agents) == 0 {
+		return fmt.Errorf("no agents configured")
+	}
+	if s.Version == 3 && !s.Inform {
+		if s.EngineID == "" {
+			return fmt.Errorf("engine_id is required for SNMPv3 traps")
+		}
+		engineID, err := hex.DecodeString(s.EngineID)
+		if err != nil {
+			return fmt.Errorf("decoding engine_id: %w", err)
+		}
+		s.ClientConfig.EngineID = string(engineID)
+		s.ClientConfig.EngineBoots = 1
+	}
+
+	if err := snmp.LoadMibsFromPath(s.Path, s.Log); err != nil {
+		return err
+	}
+	if s.translate == nil {
+		s.translate = snmp.TranslateOid
+	}
+	s.oids = make(map[string]string)
+
+	if s.DefaultTrapOid != "" {
+		oid, err := s.translate(s.DefaultTrapOid)
+		if err != nil {
+			return fmt.Errorf("translating default trap OID %q: %w", s.DefaultTrapOid, err)
+		}
+		s.DefaultTrapOid = oid
+	}
+	return nil
+}
+
+func (s *SnmpTrap) Connect() error {
+	s.start = time.Now()
+	for _, agent := range s.Agents {
+		gs, err := snmp.NewWrapper(s.ClientConfig)
+		if err != nil {
+			return err
+		}
+		if err := setAgent(&gs, agent); err != nil {
+			return fmt.Errorf("agent %s: %w", agent, err)
+		}
+		if err := gs.Connect(); err != nil {
+			return fmt.Errorf("agent %s: setting up connection: %w", agent, err)
+		}
+		s.conns = append(s.conns, gs)
+	}
+	return nil
+}
+
+// setAgent sets the address of the manager, defaulting to the trap port
+func setAgent(gs *snmp.GosnmpWrapper, agent string) error {
+	if !strings.Contains(agent, "://") {
+		agent = "udp://" + agent
+	}
+	u, err := url.Parse(agent)
+	if err != nil {
+		return err
+	}
+	if u.Port() == "" {
+		u.Host += ":162"
+	}
+	return gs.SetAgent(u.String())
+}
+
+func (s *SnmpTrap) Close() error {
+	for _, gs := range s.conns {
+		if gs.Conn != nil {
+			gs.Conn.Close()
+		}
+	}
+	s.conns = nil
+	return nil
+}
+
+// Write sends the metrics to all managers. Managers failing are skipped for
+// the remaining metrics and the write fails; the traps already sent to the
+// other managers are not sent again when the write is retried.
+func (s *SnmpTrap) Write(metrics []telegraf.Metric) error {
+	sent := make(map[delivery]bool)
+	failed := make(map[int]bool)
+	var firstErr error
+	for _, m := range metrics {
+		trap, err := s.makeTrap(m)
+		if err != nil {
+			s.Log.Errorf("Dropping metric %s: %v", m.Name(), err)
+			continue
+		}
+
+		key := metricKey(m)
+		for i, gs := range s.conns {
+			d := delivery{metric: key, agent: i}
+			if failed[i] || s.delivered[d] {
+				continue
+			}
+			if _, err := gs.SendTrap(trap); err != nil {
+				s.Log.Errorf("Sending trap to %s failed: %v", s.Agents[i], err)
+				if firstErr == nil {
+					firstErr = fmt.Errorf("sending trap to %s: %w", s.Agents[i], err)
+				}
+				failed[i] = true
+				continue
+			}
+			sent[d] = true
+		}
+	}
+
+	if firstErr == nil {
+		s.delivered = nil
+		return nil
+	}
+	if s.delivered == nil {
+		s.delivered = make(map[delivery]bool, len(sent))
+	}
+	for d := range sent {
+		s.delivered[d] = true
+	}
+	return firstErr
+}
+
+// metricKey identifies the metric by its content, as retried metrics are not
+// necessarily the same instances, e.g. when read from a disk buffer.
+func metricKey(m telegraf.Metric) uint64 {
+	octets, err := metric.ToBytes(m)
+	if err != nil {
+		return m.HashID() ^ uint64(m.Time().UnixNano())
+	}
+	h := fnv.New64a()
+	h.Write(octets) //nolint:revive // from hash.go: "It never returns an error"
+	return h.Sum64()
+}
+
+// makeTrap returns the trap of the metric with the fields as variables
+func (s *SnmpTrap) makeTrap(m telegraf.Metric) (gosnmp.SnmpTrap, error) {
+	trapOid := s.DefaultTrapOid
+	if v, ok := m.GetTag(s.TrapOidTag); ok {
+		oid, err := s.lookup(v)
+		if err != nil {
+			return gosnmp.SnmpTrap{}, fmt.Errorf("translating trap OID %q: %w", v, err)
+		}
+		trapOid = oid
+	}
+	if trapOid == "" {
+		return gosnmp.SnmpTrap{}, fmt.Errorf("no trap OID")
+	}
+
+	uptime := uint32(time.Since(s.start) / (10 * time.Millisecond))
+	if v, ok := m.GetField(s.UptimeField); ok {
+		if u, ok := toUptime(v); ok {
+			uptime = u
+		}
+	}
+
+	trap := gosnmp.SnmpTrap{
+		IsInform: s.Inform,
+		Variables: []gosnmp.SnmpPDU{
+			{Name: sysUpTimeOid, Type: gosnmp.TimeTicks, Value: uptime},
+			{Name: snmpTrapOIDOid, Type: gosnmp.ObjectIdentifier, Value: trapOid},
+		},
+	}
+
+	fields := m.FieldList()
+	sort.Slice(fields, func(i, j int) bool { return fields[i].Key < fields[j].Key })
+	for _, field := range fields {
+		if field.Key == s.UptimeField {
+			continue
+		}
+		oid, err := s.lookup(field.Key)
+		if err != nil {
+			s.Log.Debugf("Skipping field %q: %v", field.Key, err)
+			continue
+		}
+		trap.Variables = append(trap.Variables, variable(oid, field.Value))
+	}
+	return trap, nil
+}
+
+// lookup returns the cached translation of the name, caching failures as
+// empty OID
+func (s *SnmpTrap) lookup(name string) (string, error) {
+	if oid, ok := s.oids[name]; ok {
+		if oid == "" {
+			return "", fmt.Errorf("cannot translate %q", name)
+		}
+		return oid, nil
+	}
+
+	oid, err := s.translate(name)
+	s.oids[name] = oid
+	return oid, err
+}
+
+func toUptime(v interface{}) (uint32, bool) {
+	switch v := v.(type) {
+	case int64:
+		if v >= 0 && v <= math.MaxUint32 {
+			return uint32(v), true
+		}
+	case uint64:
+		if v <= math.MaxUint32 {
+			return uint32(v), true
+		}
+	}
+	return 0, false
+}
+
+// variable returns the variable of the field value. Integers are sent as
+// Integer32, Gauge32 or Counter64 depending on their range, booleans as
+// TruthValue and all other values as OCTET STRING.
+func variable(oid string, v interface{}) gosnmp.SnmpPDU {
+	switch v := v.(type) {
+	case int64:
+		if v >= math.MinInt32 && v <= math.MaxInt32 {
+			return gosnmp.SnmpPDU{Name: oid, Type: gosnmp.Integer, Value: int(v)}
+		}
+		if v >= 0 {
+			return gosnmp.SnmpPDU{Name: oid, Type: gosnmp.Counter64, Value: uint64(v)}
+		}
+		return gosnmp.SnmpPDU{Name: oid, Type: gosnmp.OctetString, Value: strconv.FormatInt(v, 10)}
+	case uint64:
+		if v <= math.MaxUint32 {
+			return gosnmp.SnmpPDU{Name: oid, Type: gosnmp.Gauge32, Value: uint32(v)}
+		}
+		return gosnmp.SnmpPDU{Name: oid, Type: gosnmp.Counter64, Value: v}
+	case bool:
+		// TruthValue of SNMPv2-TC
+		value := 2
+		if v {
+			value = 1
+		}
+		return gosnmp.SnmpPDU{Name: oid, Type: gosnmp.Integer, Value: value}
+	case float64:
+		return gosnmp.SnmpPDU{Name: oid, Type: gosnmp.OctetString, Value: strconv.FormatFloat(v, 'f', -1, 64)}
+	default:
+		return gosnmp.SnmpPDU{Name: oid, Type: gosnmp.OctetString, Value: fmt.Sprintf("%v", v)}
+	}
+}
+
+func init() {
+	outputs.Add("snmp_trap", func() telegraf.Output {
+		return &SnmpTrap{
+			TrapOidTag:  "oid",
+			UptimeField: "sysUpTimeInstance",
+			ClientConfig: snmp.ClientConfig{
+				Retries:   3,
+				Timeout:   config.Duration(5 * time.Second),
+				Version:   2,
+				Path:      []string{"/usr/share/snmp/mibs"},
+				Community: "public",
+			},
+		}
+	})
+}
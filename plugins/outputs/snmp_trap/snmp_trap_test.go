@@ -0,0 +1,228 @@
+package snmp_trap
+
+import (
+	"fmt"
+	"net"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gosnmp/gosnmp"
+	"github.com/stretchr/testify/require"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/config"
+	"github.com/influxdata/telegraf/internal/snmp"
+	"github.com/influxdata/telegraf/testutil"
+)
+
+// manager receives traps and acknowledges INFORM requests unless silent
+type manager struct {
+	conn    net.PacketConn
+	packets chan *gosnmp.SnmpPacket
+	silent  int32
+}
+
+func startManager(t *testing.T) *manager {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+
+	m := &manager{conn: conn, packets: make(chan *gosnmp.SnmpPacket, 10)}
+	go m.serve()
+	return m
+}
+
+func (m *manager) serve() {
+	decoder := &gosnmp.GoSNMP{Version: gosnmp.Version2c, Community: "public"}
+	buf := make([]byte, 65535)
+	for {
+		n, addr, err := m.conn.ReadFrom(buf)
+		if err != nil {
+			return
+		}
+		packet, err := decoder.SnmpDecodePacket(buf[:n])
+		if err != nil {
+			continue
+		}
+		m.packets <- packet
+
+		if packet.PDUType == gosnmp.InformRequest && atomic.LoadInt32(&m.silent) == 0 {
+			response := *packet
+			response.PDUType = gosnmp.GetResponse
+			out, err := response.MarshalMsg()
+			if err != nil {
+				continue
+			}
+			if _, err := m.conn.WriteTo(out, addr); err != nil {
+				return
+			}
+		}
+	}
+}
+
+func (m *manager) receive(t *testing.T) *gosnmp.SnmpPacket {
+	select {
+	case p := <-m.packets:
+		return p
+	case <-time.After(2 * time.Second):
+		require.FailNow(t, "timed out waiting for trap")
+		return nil
+	}
+}
+
+func newSnmpTrap(t *testing.T, m *manager, inform bool) *SnmpTrap {
+	s := &SnmpTrap{
+		Agents:      []string{"udp://" + m.conn.LocalAddr().String()},
+		Inform:      inform,
+		TrapOidTag:  "oid",
+		UptimeField: "sysUpTimeInstance",
+		ClientConfig: snmp.ClientConfig{
+			Timeout:   config.Duration(time.Second),
+			Version:   2,
+			Community: "public",
+		},
+		translate: func(name string) (string, error) {
+			oids := map[string]string{
+				".1.3.6.1.6.3.1.1.5.3": ".1.3.6.1.6.3.1.1.5.3",
+				"ifIndex.2":            ".1.3.6.1.2.1.2.2.1.1.2",
+				"ifDescr.2":            ".1.3.6.1.2.1.2.2.1.2.2",
+				"ifSpeed.2":            ".1.3.6.1.2.1.2.2.1.5.2",
+				"ifHCInOctets.2":       ".1.3.6.1.2.1.31.1.1.1.6.2",
+				"ifPromiscuousMode.2":  ".1.3.6.1.2.1.31.1.1.1.16.2",
+			}
+			if oid, ok := oids[name]; ok {
+				return oid, nil
+			}
+			return "", fmt.Errorf("unknown name %q", name)
+		},
+		Log: testutil.Logger{},
+	}
+	require.NoError(t, s.Init())
+	require.NoError(t, s.Connect())
+	t.Cleanup(func() { s.Close() })
+	return s
+}
+
+func linkDown() telegraf.Metric {
+	return testutil.MustMetric(
+		"snmp_trap",
+		map[string]string{"oid": ".1.3.6.1.6.3.1.1.5.3", "source": "192.0.2.1"},
+		map[string]interface{}{
+			"sysUpTimeInstance":   uint64(12345),
+			"ifIndex.2":           int64(2),
+			"ifDescr.2":           "eth1",
+			"ifSpeed.2":           uint64(1000000000),
+			"ifHCInOctets.2":      uint64(1 << 40),
+			"ifPromiscuousMode.2": false,
+			"message":             "Link eth1 down",
+		},
+		time.Unix(0, 0),
+	)
+}
+
+func TestWriteTrap(t *testing.T) {
+	m := startManager(t)
+	s := newSnmpTrap(t, m, false)
+
+	require.NoError(t, s.Write([]telegraf.Metric{linkDown()}))
+
+	p := m.receive(t)
+	require.Equal(t, gosnmp.SNMPv2Trap, p.PDUType)
+	require.Equal(t, "public", p.Community)
+	require.Equal(t, []gosnmp.SnmpPDU{
+		{Name: ".1.3.6.1.2.1.1.3.0", Type: gosnmp.TimeTicks, Value: uint32(12345)},
+		{Name: ".1.3.6.1.6.3.1.1.4.1.0", Type: gosnmp.ObjectIdentifier, Value: ".1.3.6.1.6.3.1.1.5.3"},
+		{Name: ".1.3.6.1.2.1.2.2.1.2.2", Type: gosnmp.OctetString, Value: []byte("eth1")},
+		{Name: ".1.3.6.1.2.1.31.1.1.1.6.2", Type: gosnmp.Counter64, Value: uint64(1 << 40)},
+		{Name: ".1.3.6.1.2.1.2.2.1.1.2", Type: gosnmp.Integer, Value: 2},
+		{Name: ".1.3.6.1.2.1.31.1.1.1.16.2", Type: gosnmp.Integer, Value: 2},
+		{Name: ".1.3.6.1.2.1.2.2.1.5.2", Type: gosnmp.Gauge32, Value: uint(1000000000)},
+	}, p.Variables)
+}
+
+func TestWriteInform(t *testing.T) {
+	m := startManager(t)
+	s := newSnmpTrap(t, m, true)
+
+	require.NoError(t, s.Write([]telegraf.Metric{linkDown()}))
+
+	p := m.receive(t)
+	require.Equal(t, gosnmp.InformRequest, p.PDUType)
+	require.Len(t, p.Variables, 7)
+}
+
+func TestWritePartialFailure(t *testing.T) {
+	m1 := startManager(t)
+	m2 := startManager(t)
+	atomic.StoreInt32(&m2.silent, 1)
+
+	s := newSnmpTrap(t, m1, true)
+	require.NoError(t, s.Close())
+	s.Agents = append(s.Agents, "udp://"+m2.conn.LocalAddr().String())
+	s.Timeout = config.Duration(100 * time.Millisecond)
+	require.NoError(t, s.Connect())
+
+	// The second manager does not acknowledge the INFORM request
+	metrics := []telegraf.Metric{linkDown()}
+	require.Error(t, s.Write(metrics))
+	require.Equal(t, gosnmp.InformRequest, m1.receive(t).PDUType)
+	for len(m2.packets) > 0 {
+		<-m2.packets
+	}
+
+	// Retrying only sends to the failed manager
+	atomic.StoreInt32(&m2.silent, 0)
+	require.NoError(t, s.Write(metrics))
+	require.Equal(t, gosnmp.InformRequest, m2.receive(t).PDUType)
+	select {
+	case <-m1.packets:
+		require.FailNow(t, "trap sent twice")
+	case <-time.After(100 * time.Millisecond):
+	}
+	require.Nil(t, s.delivered)
+}
+
+func TestWriteDefaultTrapOid(t *testing.T) {
+	m := startManager(t)
+	s := newSnmpTrap(t, m, false)
+
+	// Metrics without trap OID are dropped
+	noOid := testutil.MustMetric("event", map[string]string{}, map[string]interface{}{"ifIndex.2": 2}, time.Unix(0, 0))
+	require.NoError(t, s.Write([]telegraf.Metric{noOid}))
+	select {
+	case <-m.packets:
+		require.FailNow(t, "unexpected trap")
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	s.DefaultTrapOid = ".1.3.6.1.6.3.1.1.5.3"
+	require.NoError(t, s.Write([]telegraf.Metric{noOid}))
+	p := m.receive(t)
+	require.Equal(t, ".1.3.6.1.6.3.1.1.5.3", p.Variables[1].Value)
+	require.Equal(t, []gosnmp.SnmpPDU{{Name: ".1.3.6.1.2.1.2.2.1.1.2", Type: gosnmp.Integer, Value: 2}}, p.Variables[2:])
+}
+
+func TestInitErrors(t *testing.T) {
+	s := &SnmpTrap{Agents: []string{"127.0.0.1"}, ClientConfig: snmp.ClientConfig{Version: 1}}
+	require.EqualError(t, s.Init(), "version 1 is not supported")
+
+	s = &SnmpTrap{ClientConfig: snmp.ClientConfig{Version: 2}}
+	require.EqualError(t, s.Init(), "no agents configured")
+
+	s = &SnmpTrap{Agents: []string{"127.0.0.1"}, ClientConfig: snmp.ClientConfig{Version: 3}}
+	require.EqualError(t, s.Init(), "engine_id is required for SNMPv3 traps")
+}
+
+func TestSetAgent(t *testing.T) {
+	gs, err := snmp.NewWrapper(snmp.ClientConfig{})
+	require.NoError(t, err)
+
+	require.NoError(t, setAgent(&gs, "192.0.2.1"))
+	require.Equal(t, "udp", gs.Transport)
+	require.Equal(t, uint16(162), gs.Port)
+
+	require.NoError(t, setAgent(&gs, "tcp://192.0.2.1:1162"))
+	require.Equal(t, "tcp", gs.Transport)
+	require.Equal(t, uint16(1162), gs.Port)
+}
@@ -2,5 +2,7 @@ package all
 
 import (
 	//Blank imports for plugins to register themselves
+	_ "github.com/influxdata/telegraf/plugins/parsers/avro"
 	_ "github.com/influxdata/telegraf/plugins/parsers/csv"
+	_ "github.com/influxdata/telegraf/plugins/parsers/protobuf"
 )
@@ -0,0 +1,494 @@
+package avro
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/linkedin/goavro/v2"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/metric"
+	"github.com/influxdata/telegraf/plugins/common/avro"
+	"github.com/influxdata/telegraf/plugins/parsers"
+)
+
+type Parser struct {
+	MetricName       string          `toml:"metric_name"`
+	Schema           string          `toml:"avro_schema"`
+	SchemaRegistry   string          `toml:"avro_schema_registry"`
+	SchemaID         int             `toml:"avro_schema_id"`
+	Measurement      string          `toml:"avro_measurement"`
+	MeasurementField string          `toml:"avro_measurement_field"`
+	Tags             []string        `toml:"avro_tags"`
+	Fields           []string        `toml:"avro_fields"`
+	FieldSeparator   string          `toml:"avro_field_separator"`
+	Timestamp        string          `toml:"avro_timestamp"`
+	TimestampFormat  string          `toml:"avro_timestamp_format"`
+	Log              telegraf.Logger `toml:"-"`
+
+	DefaultTags map[string]string
+	TimeFunc    func() time.Time
+
+	codec    *goavro.Codec
+	registry *avro.SchemaRegistry
+
+	// schemas caches the parsed schema of each codec used to unwrap union
+	// values when flattening records
+	schemas map[*goavro.Codec]*schemaTree
+	sync.Mutex
+}
+
+func (p *Parser) Init() error {
+	switch {
+	case p.Schema == "" && p.SchemaRegistry == "":
+		return errors.New("either `avro_schema` or `avro_schema_registry` must be specified")
+	case p.Schema != "" && p.SchemaRegistry != "":
+		return errors.New("`avro_schema` and `avro_schema_registry` are mutually exclusive")
+	case p.SchemaID < 0:
+		return fmt.Errorf("invalid schema ID %d", p.SchemaID)
+	case p.SchemaID > 0 && p.Schema == "":
+		return errors.New("`avro_schema_id` requires `avro_schema`")
+	case p.Schema != "":
+		codec, err := goavro.NewCodec(p.Schema)
+		if err != nil {
+			return fmt.Errorf("invalid schema: %w", err)
+		}
+		p.codec = codec
+	default:
+		registry, err := avro.NewSchemaRegistry(p.SchemaRegistry, avro.DefaultTimeout)
+		if err != nil {
+			return err
+		}
+		p.registry = registry
+	}
+
+	switch p.TimestampFormat {
+	case "":
+		p.TimestampFormat = "unix"
+	case "unix", "unix_ms", "unix_us", "unix_ns":
+	default:
+		return fmt.Errorf("invalid timestamp format %q", p.TimestampFormat)
+	}
+
+	if p.FieldSeparator == "" {
+		p.FieldSeparator = "_"
+	}
+
+	if p.TimeFunc == nil {
+		p.TimeFunc = time.Now
+	}
+	p.schemas = make(map[*goavro.Codec]*schemaTree)
+
+	return nil
+}
+
+func (p *Parser) Parse(buf []byte) ([]telegraf.Metric, error) {
+	codec := p.codec
+	payload := buf
+	switch {
+	case p.registry != nil:
+		id, data, err := avro.SplitWireFormat(buf)
+		if err != nil {
+			return nil, err
+		}
+		codec, err = p.registry.Codec(id)
+		if err != nil {
+			return nil, err
+		}
+		payload = data
+	case p.SchemaID > 0:
+		id, data, err := avro.SplitWireFormat(buf)
+		if err != nil {
+			return nil, err
+		}
+		if id != p.SchemaID {
+			return nil, fmt.Errorf("schema ID %d of message does not match %d", id, p.SchemaID)
+		}
+		payload = data
+	}
+
+	native, _, err := codec.NativeFromBinary(payload)
+	if err != nil {
+		return nil, fmt.Errorf("decoding message failed: %w", err)
+	}
+	record, ok := native.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("message is not a record but %T", native)
+	}
+
+	tree := p.schemaOf(codec)
+	flat := make(map[string]interface{})
+	p.flatten(flat, "", record, tree, tree.root, "")
+
+	m, err := p.createMetric(flat)
+	if err != nil {
+		return nil, err
+	}
+
+	return []telegraf.Metric{m}, nil
+}
+
+func (p *Parser) ParseLine(line string) (telegraf.Metric, error) {
+	metrics, err := p.Parse([]byte(line))
+	if err != nil {
+		return nil, err
+	}
+
+	if len(metrics) < 1 {
+		return nil, fmt.Errorf("can not parse the line: %s, for data format: avro ", line)
+	}
+
+	return metrics[0], nil
+}
+
+func (p *Parser) SetDefaultTags(tags map[string]string) {
+	p.DefaultTags = tags
+}
+
+func (p *Parser) createMetric(flat map[string]interface{}) (telegraf.Metric, error) {
+	name := p.MetricName
+	if p.Measurement != "" {
+		name = p.Measurement
+	}
+	if p.MeasurementField != "" {
+		value, found := flat[p.MeasurementField]
+		if !found {
+			return nil, fmt.Errorf("measurement field %q not found", p.MeasurementField)
+		}
+		name = toString(value)
+		delete(flat, p.MeasurementField)
+	}
+
+	timestamp := p.TimeFunc()
+	if p.Timestamp != "" {
+		value, found := flat[p.Timestamp]
+		if !found {
+			return nil, fmt.Errorf("timestamp field %q not found", p.Timestamp)
+		}
+		if t, ok := value.(time.Time); ok {
+			timestamp = t
+		} else {
+			t, err := internal.ParseTimestamp(p.TimestampFormat, value, "")
+			if err != nil {
+				return nil, fmt.Errorf("parsing timestamp %v failed: %w", value, err)
+			}
+			timestamp = t
+		}
+		delete(flat, p.Timestamp)
+	}
+
+	tags := make(map[string]string, len(p.DefaultTags)+len(p.Tags))
+	for k, v := range p.DefaultTags {
+		tags[k] = v
+	}
+	for _, key := range p.Tags {
+		if value, found := flat[key]; found && value != nil {
+			tags[key] = toString(value)
+		}
+		delete(flat, key)
+	}
+
+	fields := make(map[string]interface{}, len(flat))
+	if len(p.Fields) > 0 {
+		for _, key := range p.Fields {
+			if value, found := flat[key]; found && value != nil {
+				fields[key] = toFieldValue(value)
+			}
+		}
+	} else {
+		for key, value := range flat {
+			if value != nil {
+				fields[key] = toFieldValue(value)
+			}
+		}
+	}
+
+	return metric.New(name, tags, fields, timestamp), nil
+}
+
+// flatten converts nested records, maps and arrays into a flat map joining
+// the keys with the field separator. Values of fields declared as union in
+// the schema are unwrapped.
+func (p *Parser) flatten(flat map[string]interface{}, prefix string, value interface{}, tree *schemaTree, schema interface{}, namespace string) {
+	schema, namespace = tree.resolve(schema, namespace)
+	switch s := schema.(type) {
+	case []interface{}:
+		if wrapped, ok := value.(map[string]interface{}); ok && len(wrapped) == 1 {
+			for key, inner := range wrapped {
+				p.flatten(flat, prefix, inner, tree, tree.member(s, key, namespace), namespace)
+			}
+			return
+		}
+	case map[string]interface{}:
+		switch s["type"] {
+		case "record":
+			record, ok := value.(map[string]interface{})
+			if !ok {
+				break
+			}
+			fields, _ := s["fields"].([]interface{})
+			for _, field := range fields {
+				f, ok := field.(map[string]interface{})
+				if !ok {
+					continue
+				}
+				name, _ := f["name"].(string)
+				if inner, found := record[name]; found {
+					p.flatten(flat, p.join(prefix, name), inner, tree, f["type"], namespace)
+				}
+			}
+			return
+		case "array":
+			if items, ok := value.([]interface{}); ok {
+				for i, inner := range items {
+					p.flatten(flat, p.join(prefix, strconv.Itoa(i)), inner, tree, s["items"], namespace)
+				}
+				return
+			}
+		case "map":
+			if values, ok := value.(map[string]interface{}); ok {
+				for key, inner := range values {
+					p.flatten(flat, p.join(prefix, key), inner, tree, s["values"], namespace)
+				}
+				return
+			}
+		}
+	}
+
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, inner := range v {
+			p.flatten(flat, p.join(prefix, key), inner, tree, nil, namespace)
+		}
+	case []interface{}:
+		for i, inner := range v {
+			p.flatten(flat, p.join(prefix, strconv.Itoa(i)), inner, tree, nil, namespace)
+		}
+	default:
+		flat[prefix] = v
+	}
+}
+
+func (p *Parser) join(prefix, key string) string {
+	if prefix == "" {
+		return key
+	}
+	return prefix + p.FieldSeparator + key
+}
+
+// schemaTree is the parsed schema of a codec used to flatten its records
+type schemaTree struct {
+	root interface{}
+	// named type definitions by their full name
+	named map[string]map[string]interface{}
+}
+
+// schemaOf returns the parsed schema of the codec
+func (p *Parser) schemaOf(codec *goavro.Codec) *schemaTree {
+	p.Lock()
+	defer p.Unlock()
+
+	if tree, found := p.schemas[codec]; found {
+		return tree
+	}
+
+	tree := &schemaTree{named: make(map[string]map[string]interface{})}
+	if err := json.Unmarshal([]byte(codec.Schema()), &tree.root); err == nil {
+		tree.collect(tree.root, "")
+	}
+	p.schemas[codec] = tree
+
+	return tree
+}
+
+// collect registers the named types defined in the schema
+func (t *schemaTree) collect(schema interface{}, namespace string) {
+	switch s := schema.(type) {
+	case []interface{}:
+		for _, member := range s {
+			t.collect(member, namespace)
+		}
+	case map[string]interface{}:
+		typ, ok := s["type"].(string)
+		if !ok {
+			t.collect(s["type"], namespace)
+			return
+		}
+
+		switch typ {
+		case "record", "enum", "fixed":
+			name := fullName(s, namespace)
+			if name != "" {
+				t.named[name] = s
+				namespace = namespaceOf(name)
+			}
+			if typ == "record" {
+				fields, _ := s["fields"].([]interface{})
+				for _, field := range fields {
+					if f, ok := field.(map[string]interface{}); ok {
+						t.collect(f["type"], namespace)
+					}
+				}
+			}
+		case "array":
+			t.collect(s["items"], namespace)
+		case "map":
+			t.collect(s["values"], namespace)
+		}
+	}
+}
+
+// resolve dereferences named types and nested type declarations, returning
+// the schema and the namespace of its fields
+func (t *schemaTree) resolve(schema interface{}, namespace string) (interface{}, string) {
+	for {
+		switch s := schema.(type) {
+		case string:
+			def, name := t.lookup(s, namespace)
+			if def == nil {
+				return s, namespace
+			}
+			return def, namespaceOf(name)
+		case map[string]interface{}:
+			typ, ok := s["type"].(string)
+			if !ok {
+				schema = s["type"]
+				continue
+			}
+			switch typ {
+			case "record", "enum", "fixed":
+				if name := fullName(s, namespace); name != "" {
+					namespace = namespaceOf(name)
+				}
+				return s, namespace
+			}
+			if def, name := t.lookup(typ, namespace); def != nil {
+				return def, namespaceOf(name)
+			}
+			return s, namespace
+		default:
+			return schema, namespace
+		}
+	}
+}
+
+// lookup returns the definition and full name of a named type
+func (t *schemaTree) lookup(name, namespace string) (map[string]interface{}, string) {
+	if namespace != "" && !strings.Contains(name, ".") {
+		if def, found := t.named[namespace+"."+name]; found {
+			return def, namespace + "." + name
+		}
+	}
+	return t.named[name], name
+}
+
+// member returns the schema of the union member with the given type name as
+// used by goavro to wrap union values
+func (t *schemaTree) member(union []interface{}, key, namespace string) interface{} {
+	for _, member := range union {
+		schema, ns := t.resolve(member, namespace)
+		var name string
+		switch s := schema.(type) {
+		case string:
+			name = s
+		case map[string]interface{}:
+			typ, _ := s["type"].(string)
+			switch typ {
+			case "record", "enum", "fixed":
+				name = fullName(s, ns)
+			default:
+				name = typ
+				if logical, ok := s["logicalType"].(string); ok {
+					name += "." + logical
+				}
+			}
+		}
+		if name == key {
+			return member
+		}
+	}
+	return nil
+}
+
+// fullName returns the name of a named type qualified by its namespace
+func fullName(schema map[string]interface{}, namespace string) string {
+	name, _ := schema["name"].(string)
+	if name == "" || strings.Contains(name, ".") {
+		return name
+	}
+	if ns, ok := schema["namespace"].(string); ok {
+		namespace = ns
+	}
+	if namespace == "" {
+		return name
+	}
+	return namespace + "." + name
+}
+
+func namespaceOf(name string) string {
+	if i := strings.LastIndex(name, "."); i >= 0 {
+		return name[:i]
+	}
+	return ""
+}
+
+func toString(value interface{}) string {
+	switch v := value.(type) {
+	case string:
+		return v
+	case []byte:
+		return string(v)
+	default:
+		return fmt.Sprintf("%v", toFieldValue(v))
+	}
+}
+
+func toFieldValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case int32:
+		return int64(v)
+	case float32:
+		return float64(v)
+	case []byte:
+		return string(v)
+	case time.Time:
+		return v.UnixNano()
+	case time.Duration:
+		return v.Nanoseconds()
+	case *big.Rat:
+		f, _ := v.Float64()
+		return f
+	default:
+		return v
+	}
+}
+
+func (p *Parser) InitFromConfig(config *parsers.Config) error {
+	p.Schema = config.AvroSchema
+	p.SchemaRegistry = config.AvroSchemaRegistry
+	p.SchemaID = config.AvroSchemaID
+	p.Measurement = config.AvroMeasurement
+	p.MeasurementField = config.AvroMeasurementField
+	p.Tags = config.AvroTags
+	p.Fields = config.AvroFields
+	p.FieldSeparator = config.AvroFieldSeparator
+	p.Timestamp = config.AvroTimestamp
+	p.TimestampFormat = config.AvroTimestampFormat
+	p.DefaultTags = config.DefaultTags
+
+	return p.Init()
+}
+
+func init() {
+	parsers.Add("avro",
+		func(defaultMetricName string) telegraf.Parser {
+			return &Parser{MetricName: defaultMetricName}
+		})
+}
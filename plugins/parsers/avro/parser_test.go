@@ -0,0 +1,285 @@
+package avro
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/linkedin/goavro/v2"
+	"github.com/stretchr/testify/require"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/metric"
+	"github.com/influxdata/telegraf/plugins/common/avro"
+	serializer "github.com/influxdata/telegraf/plugins/serializers/avro"
+	"github.com/influxdata/telegraf/testutil"
+)
+
+const schema = `{
+  "type": "record",
+  "name": "Measurement",
+  "namespace": "com.example",
+  "fields": [
+    {"name": "name", "type": "string"},
+    {"name": "host", "type": "string"},
+    {"name": "value", "type": "double"},
+    {"name": "count", "type": ["null", "long"], "default": null},
+    {"name": "time", "type": "long"},
+    {"name": "location", "type": {
+      "type": "record",
+      "name": "Location",
+      "fields": [
+        {"name": "rack", "type": "string"},
+        {"name": "slot", "type": "int"}
+      ]
+    }}
+  ]
+}`
+
+func encode(t *testing.T, record map[string]interface{}) []byte {
+	codec, err := goavro.NewCodec(schema)
+	require.NoError(t, err)
+	buf, err := codec.BinaryFromNative(nil, record)
+	require.NoError(t, err)
+	return buf
+}
+
+var record = map[string]interface{}{
+	"name":     "cpu",
+	"host":     "server01",
+	"value":    42.5,
+	"count":    goavro.Union("long", int64(3)),
+	"time":     int64(1650000000),
+	"location": map[string]interface{}{"rack": "r1", "slot": int32(4)},
+}
+
+func TestParseInlineSchema(t *testing.T) {
+	parser := &Parser{
+		MetricName:       "avro",
+		Schema:           schema,
+		MeasurementField: "name",
+		Tags:             []string{"host", "location_rack"},
+		Timestamp:        "time",
+	}
+	require.NoError(t, parser.Init())
+
+	metrics, err := parser.Parse(encode(t, record))
+	require.NoError(t, err)
+
+	expected := []telegraf.Metric{
+		metric.New(
+			"cpu",
+			map[string]string{"host": "server01", "location_rack": "r1"},
+			map[string]interface{}{"value": 42.5, "count": int64(3), "location_slot": int64(4)},
+			time.Unix(1650000000, 0),
+		),
+	}
+	testutil.RequireMetricsEqual(t, expected, metrics)
+}
+
+func TestParseSelectedFields(t *testing.T) {
+	now := time.Unix(1650000000, 0)
+	parser := &Parser{
+		MetricName:     "avro",
+		Schema:         schema,
+		Measurement:    "measurement",
+		Fields:         []string{"value", "location.slot"},
+		FieldSeparator: ".",
+		TimeFunc:       func() time.Time { return now },
+	}
+	require.NoError(t, parser.Init())
+	parser.SetDefaultTags(map[string]string{"source": "kafka"})
+
+	nullCount := map[string]interface{}{}
+	for k, v := range record {
+		nullCount[k] = v
+	}
+	nullCount["count"] = nil
+
+	m, err := parser.ParseLine(string(encode(t, nullCount)))
+	require.NoError(t, err)
+
+	expected := metric.New(
+		"measurement",
+		map[string]string{"source": "kafka"},
+		map[string]interface{}{"value": 42.5, "location.slot": int64(4)},
+		now,
+	)
+	testutil.RequireMetricsEqual(t, []telegraf.Metric{expected}, []telegraf.Metric{m})
+}
+
+func TestParseNestedTypeNames(t *testing.T) {
+	nested := `{
+  "type": "record",
+  "name": "Measurement",
+  "fields": [
+    {"name": "value", "type": {
+      "type": "record",
+      "name": "Value",
+      "fields": [{"name": "double", "type": "double"}]
+    }},
+    {"name": "label", "type": ["null", {
+      "type": "record",
+      "name": "Label",
+      "fields": [{"name": "string", "type": ["null", "string"]}]
+    }]},
+    {"name": "counters", "type": {"type": "map", "values": ["null", "Value"]}}
+  ]
+}`
+	codec, err := goavro.NewCodec(nested)
+	require.NoError(t, err)
+	buf, err := codec.BinaryFromNative(nil, map[string]interface{}{
+		"value": map[string]interface{}{"double": 1.5},
+		"label": goavro.Union("Label", map[string]interface{}{
+			"string": goavro.Union("string", "a"),
+		}),
+		"counters": map[string]interface{}{
+			"long": goavro.Union("Value", map[string]interface{}{"double": 2.5}),
+		},
+	})
+	require.NoError(t, err)
+
+	now := time.Unix(1650000000, 0)
+	parser := &Parser{
+		MetricName: "avro",
+		Schema:     nested,
+		TimeFunc:   func() time.Time { return now },
+	}
+	require.NoError(t, parser.Init())
+
+	metrics, err := parser.Parse(buf)
+	require.NoError(t, err)
+
+	expected := []telegraf.Metric{
+		metric.New(
+			"avro",
+			map[string]string{},
+			map[string]interface{}{"value_double": 1.5, "label_string": "a", "counters_long_double": 2.5},
+			now,
+		),
+	}
+	testutil.RequireMetricsEqual(t, expected, metrics)
+}
+
+func TestParseSerializerRoundTrip(t *testing.T) {
+	flat := `{
+  "type": "record",
+  "name": "Measurement",
+  "fields": [
+    {"name": "name", "type": "string"},
+    {"name": "host", "type": "string"},
+    {"name": "value", "type": "double"},
+    {"name": "count", "type": ["null", "long"]},
+    {"name": "time", "type": {"type": "long", "logicalType": "timestamp-millis"}}
+  ]
+}`
+	s, err := serializer.NewSerializer(&serializer.Serializer{
+		Schema:           flat,
+		SchemaID:         5,
+		MeasurementField: "name",
+		Timestamp:        "time",
+	})
+	require.NoError(t, err)
+
+	expected := []telegraf.Metric{
+		metric.New(
+			"cpu",
+			map[string]string{"host": "server01"},
+			map[string]interface{}{"value": 42.5, "count": int64(3)},
+			time.Unix(1650000000, 0),
+		),
+	}
+	buf, err := s.Serialize(expected[0])
+	require.NoError(t, err)
+
+	parser := &Parser{
+		MetricName:       "avro",
+		Schema:           flat,
+		SchemaID:         5,
+		MeasurementField: "name",
+		Tags:             []string{"host"},
+		Timestamp:        "time",
+	}
+	require.NoError(t, parser.Init())
+
+	metrics, err := parser.Parse(buf)
+	require.NoError(t, err)
+	testutil.RequireMetricsEqual(t, expected, metrics)
+
+	// Messages of other schemas or without header are rejected
+	other := append(avro.AppendWireHeader(nil, 6), buf[5:]...)
+	_, err = parser.Parse(other)
+	require.Error(t, err)
+
+	_, err = parser.Parse(buf[5:])
+	require.Error(t, err)
+}
+
+func TestParseSchemaRegistry(t *testing.T) {
+	var requests int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		if r.URL.Path != "/schemas/ids/7" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		require.NoError(t, json.NewEncoder(w).Encode(map[string]string{"schema": schema}))
+	}))
+	defer server.Close()
+
+	parser := &Parser{
+		MetricName:      "avro",
+		SchemaRegistry:  server.URL,
+		Tags:            []string{"host"},
+		Fields:          []string{"value"},
+		Timestamp:       "time",
+		TimestampFormat: "unix_ms",
+	}
+	require.NoError(t, parser.Init())
+
+	msg := append(avro.AppendWireHeader(nil, 7), encode(t, record)...)
+	for i := 0; i < 2; i++ {
+		metrics, err := parser.Parse(msg)
+		require.NoError(t, err)
+
+		expected := []telegraf.Metric{
+			metric.New(
+				"avro",
+				map[string]string{"host": "server01"},
+				map[string]interface{}{"value": 42.5},
+				time.Unix(0, 1650000000*int64(time.Millisecond)),
+			),
+		}
+		testutil.RequireMetricsEqual(t, expected, metrics)
+	}
+	require.Equal(t, int32(1), atomic.LoadInt32(&requests), "schema should be cached")
+
+	_, err := parser.Parse(append(avro.AppendWireHeader(nil, 8), encode(t, record)...))
+	require.Error(t, err)
+
+	_, err = parser.Parse([]byte{0x01, 0x00, 0x00, 0x00, 0x07})
+	require.Error(t, err)
+}
+
+func TestInitErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		parser *Parser
+	}{
+		{"no schema", &Parser{}},
+		{"both schema sources", &Parser{Schema: schema, SchemaRegistry: "http://localhost:8081"}},
+		{"invalid schema", &Parser{Schema: `{"type": "unknown"}`}},
+		{"invalid registry", &Parser{SchemaRegistry: "localhost:8081"}},
+		{"invalid timestamp format", &Parser{Schema: schema, TimestampFormat: "RFC3339"}},
+		{"negative schema id", &Parser{Schema: schema, SchemaID: -1}},
+		{"schema id without schema", &Parser{SchemaRegistry: "http://localhost:8081", SchemaID: 1}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.Error(t, tt.parser.Init())
+		})
+	}
+}
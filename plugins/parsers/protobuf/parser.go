@@ -0,0 +1,356 @@
+package protobuf
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"google.golang.org/protobuf/encoding/protowire"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/dynamicpb"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/metric"
+	"github.com/influxdata/telegraf/plugins/common/protobuf"
+	"github.com/influxdata/telegraf/plugins/parsers"
+)
+
+type Parser struct {
+	protobuf.Descriptor
+	MetricName       string          `toml:"metric_name"`
+	Measurement      string          `toml:"protobuf_measurement"`
+	MeasurementField string          `toml:"protobuf_measurement_field"`
+	MetricPath       string          `toml:"protobuf_metric_path"`
+	Tags             []string        `toml:"protobuf_tags"`
+	Fields           []string        `toml:"protobuf_fields"`
+	FieldSeparator   string          `toml:"protobuf_field_separator"`
+	Timestamp        string          `toml:"protobuf_timestamp"`
+	TimestampFormat  string          `toml:"protobuf_timestamp_format"`
+	LengthDelimited  bool            `toml:"protobuf_length_delimited"`
+	Log              telegraf.Logger `toml:"-"`
+
+	DefaultTags map[string]string
+	TimeFunc    func() time.Time
+
+	msgDesc    protoreflect.MessageDescriptor
+	metricPath []protoreflect.FieldDescriptor
+}
+
+func (p *Parser) Init() error {
+	msgDesc, err := p.Descriptor.Load()
+	if err != nil {
+		return err
+	}
+	p.msgDesc = msgDesc
+
+	// Resolve the path to the repeated message producing the metrics
+	if p.MetricPath != "" {
+		current := msgDesc
+		parts := strings.Split(p.MetricPath, ".")
+		for i, part := range parts {
+			if current == nil {
+				return fmt.Errorf("metric path %q: %q is not a message", p.MetricPath, parts[i-1])
+			}
+			fd := current.Fields().ByName(protoreflect.Name(part))
+			if fd == nil {
+				return fmt.Errorf("metric path %q: field %q not found in %q", p.MetricPath, part, current.FullName())
+			}
+			last := i == len(parts)-1
+			if last && (!fd.IsList() || fd.Message() == nil) {
+				return fmt.Errorf("metric path %q: %q is not a repeated message", p.MetricPath, part)
+			}
+			if !last && (fd.IsList() || fd.IsMap()) {
+				return fmt.Errorf("metric path %q: %q must not be repeated", p.MetricPath, part)
+			}
+			p.metricPath = append(p.metricPath, fd)
+			current = fd.Message()
+		}
+	}
+
+	switch p.TimestampFormat {
+	case "":
+		p.TimestampFormat = "unix"
+	case "unix", "unix_ms", "unix_us", "unix_ns":
+	default:
+		return fmt.Errorf("invalid timestamp format %q", p.TimestampFormat)
+	}
+
+	if p.FieldSeparator == "" {
+		p.FieldSeparator = "_"
+	}
+
+	if p.TimeFunc == nil {
+		p.TimeFunc = time.Now
+	}
+
+	return nil
+}
+
+func (p *Parser) Parse(buf []byte) ([]telegraf.Metric, error) {
+	if !p.LengthDelimited {
+		return p.parseMessage(buf)
+	}
+
+	var metrics []telegraf.Metric
+	for len(buf) > 0 {
+		size, n := protowire.ConsumeVarint(buf)
+		if n < 0 {
+			return nil, fmt.Errorf("reading message length failed: %w", protowire.ParseError(n))
+		}
+		buf = buf[n:]
+		if uint64(len(buf)) < size {
+			return nil, fmt.Errorf("message length %d exceeds remaining %d bytes", size, len(buf))
+		}
+
+		m, err := p.parseMessage(buf[:size])
+		if err != nil {
+			return nil, err
+		}
+		metrics = append(metrics, m...)
+		buf = buf[size:]
+	}
+
+	return metrics, nil
+}
+
+func (p *Parser) ParseLine(line string) (telegraf.Metric, error) {
+	metrics, err := p.Parse([]byte(line))
+	if err != nil {
+		return nil, err
+	}
+
+	if len(metrics) < 1 {
+		return nil, fmt.Errorf("can not parse the line: %s, for data format: protobuf ", line)
+	}
+
+	return metrics[0], nil
+}
+
+func (p *Parser) SetDefaultTags(tags map[string]string) {
+	p.DefaultTags = tags
+}
+
+func (p *Parser) parseMessage(buf []byte) ([]telegraf.Metric, error) {
+	msg := dynamicpb.NewMessage(p.msgDesc)
+	if err := proto.Unmarshal(buf, msg); err != nil {
+		return nil, fmt.Errorf("unmarshalling message failed: %w", err)
+	}
+
+	if len(p.metricPath) == 0 {
+		flat := make(map[string]interface{})
+		flatten(flat, "", msg, nil)
+
+		m, err := p.createMetric(flat)
+		if err != nil {
+			return nil, err
+		}
+		return []telegraf.Metric{m}, nil
+	}
+
+	// Values outside of the repeated message are shared by all metrics
+	selected := p.metricPath[len(p.metricPath)-1]
+	shared := make(map[string]interface{})
+	flatten(shared, "", msg, selected)
+
+	parent := protoreflect.Message(msg)
+	for _, fd := range p.metricPath[:len(p.metricPath)-1] {
+		if !parent.Has(fd) {
+			return nil, nil
+		}
+		parent = parent.Get(fd).Message()
+	}
+
+	list := parent.Get(selected).List()
+	metrics := make([]telegraf.Metric, 0, list.Len())
+	for i := 0; i < list.Len(); i++ {
+		flat := make(map[string]interface{}, len(shared))
+		for k, v := range shared {
+			flat[k] = v
+		}
+		flatten(flat, "", list.Get(i).Message(), nil)
+
+		m, err := p.createMetric(flat)
+		if err != nil {
+			return nil, err
+		}
+		metrics = append(metrics, m)
+	}
+
+	return metrics, nil
+}
+
+func (p *Parser) createMetric(flat map[string]interface{}) (telegraf.Metric, error) {
+	name := p.MetricName
+	if p.Measurement != "" {
+		name = p.Measurement
+	}
+	if p.MeasurementField != "" {
+		value, found := flat[p.MeasurementField]
+		if !found {
+			return nil, fmt.Errorf("measurement field %q not found", p.MeasurementField)
+		}
+		s, err := internal.ToString(value)
+		if err != nil {
+			return nil, fmt.Errorf("measurement field %q: %w", p.MeasurementField, err)
+		}
+		name = s
+		delete(flat, p.MeasurementField)
+	}
+
+	timestamp := p.TimeFunc()
+	if p.Timestamp != "" {
+		value, found := flat[p.Timestamp]
+		if !found {
+			return nil, fmt.Errorf("timestamp field %q not found", p.Timestamp)
+		}
+		if t, ok := value.(time.Time); ok {
+			timestamp = t
+		} else {
+			t, err := internal.ParseTimestamp(p.TimestampFormat, value, "")
+			if err != nil {
+				return nil, fmt.Errorf("parsing timestamp %v failed: %w", value, err)
+			}
+			timestamp = t
+		}
+		delete(flat, p.Timestamp)
+	}
+
+	tags := make(map[string]string, len(p.DefaultTags)+len(p.Tags))
+	for k, v := range p.DefaultTags {
+		tags[k] = v
+	}
+	for _, path := range p.Tags {
+		if value, found := flat[path]; found {
+			s, err := internal.ToString(value)
+			if err != nil {
+				return nil, fmt.Errorf("tag %q: %w", path, err)
+			}
+			tags[p.key(path)] = s
+		}
+		delete(flat, path)
+	}
+
+	fields := make(map[string]interface{}, len(flat))
+	if len(p.Fields) > 0 {
+		for _, path := range p.Fields {
+			if value, found := flat[path]; found {
+				fields[p.key(path)] = fieldValue(value)
+			}
+		}
+	} else {
+		for path, value := range flat {
+			fields[p.key(path)] = fieldValue(value)
+		}
+	}
+
+	return metric.New(name, tags, fields, timestamp), nil
+}
+
+// key converts the dotted field path into a tag or field key
+func (p *Parser) key(path string) string {
+	return strings.ReplaceAll(path, ".", p.FieldSeparator)
+}
+
+// flatten collects all scalar values of the message with their dotted
+// field path. Repeated fields and maps use the index and the key as path
+// element respectively. The given field is skipped.
+func flatten(flat map[string]interface{}, prefix string, msg protoreflect.Message, skip protoreflect.FieldDescriptor) {
+	fields := msg.Descriptor().Fields()
+	for i := 0; i < fields.Len(); i++ {
+		fd := fields.Get(i)
+		if fd == skip || (fd.HasPresence() && !msg.Has(fd)) {
+			continue
+		}
+		path := join(prefix, string(fd.Name()))
+		value := msg.Get(fd)
+
+		switch {
+		case fd.IsList():
+			list := value.List()
+			for j := 0; j < list.Len(); j++ {
+				flattenValue(flat, join(path, strconv.Itoa(j)), fd, list.Get(j), skip)
+			}
+		case fd.IsMap():
+			value.Map().Range(func(k protoreflect.MapKey, v protoreflect.Value) bool {
+				flattenValue(flat, join(path, k.String()), fd.MapValue(), v, skip)
+				return true
+			})
+		default:
+			flattenValue(flat, path, fd, value, skip)
+		}
+	}
+}
+
+func flattenValue(flat map[string]interface{}, path string, fd protoreflect.FieldDescriptor, value protoreflect.Value, skip protoreflect.FieldDescriptor) {
+	switch fd.Kind() {
+	case protoreflect.BoolKind:
+		flat[path] = value.Bool()
+	case protoreflect.EnumKind:
+		if ev := fd.Enum().Values().ByNumber(value.Enum()); ev != nil {
+			flat[path] = string(ev.Name())
+		} else {
+			flat[path] = int64(value.Enum())
+		}
+	case protoreflect.Int32Kind, protoreflect.Sint32Kind, protoreflect.Sfixed32Kind,
+		protoreflect.Int64Kind, protoreflect.Sint64Kind, protoreflect.Sfixed64Kind:
+		flat[path] = value.Int()
+	case protoreflect.Uint32Kind, protoreflect.Fixed32Kind, protoreflect.Uint64Kind, protoreflect.Fixed64Kind:
+		flat[path] = value.Uint()
+	case protoreflect.FloatKind, protoreflect.DoubleKind:
+		flat[path] = value.Float()
+	case protoreflect.StringKind:
+		flat[path] = value.String()
+	case protoreflect.BytesKind:
+		flat[path] = string(value.Bytes())
+	case protoreflect.MessageKind, protoreflect.GroupKind:
+		msg := value.Message()
+		if t, ok := protobuf.TimeFromMessage(msg); ok {
+			flat[path] = t
+			return
+		}
+		flatten(flat, path, msg, skip)
+	}
+}
+
+func join(prefix, name string) string {
+	if prefix == "" {
+		return name
+	}
+	return prefix + "." + name
+}
+
+func fieldValue(value interface{}) interface{} {
+	if t, ok := value.(time.Time); ok {
+		return t.UnixNano()
+	}
+	return value
+}
+
+func (p *Parser) InitFromConfig(config *parsers.Config) error {
+	p.Files = config.ProtobufFiles
+	p.ImportPaths = config.ProtobufImportPaths
+	p.DescriptorSet = config.ProtobufDescriptorSet
+	p.MessageType = config.ProtobufMessageType
+	p.Measurement = config.ProtobufMeasurement
+	p.MeasurementField = config.ProtobufMeasurementField
+	p.MetricPath = config.ProtobufMetricPath
+	p.Tags = config.ProtobufTags
+	p.Fields = config.ProtobufFields
+	p.FieldSeparator = config.ProtobufFieldSeparator
+	p.Timestamp = config.ProtobufTimestamp
+	p.TimestampFormat = config.ProtobufTimestampFormat
+	p.LengthDelimited = config.ProtobufLengthDelimited
+	p.DefaultTags = config.DefaultTags
+
+	return p.Init()
+}
+
+func init() {
+	parsers.Add("protobuf",
+		func(defaultMetricName string) telegraf.Parser {
+			return &Parser{MetricName: defaultMetricName}
+		})
+}
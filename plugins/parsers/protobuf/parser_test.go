@@ -0,0 +1,198 @@
+package protobuf
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/encoding/protowire"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/dynamicpb"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/metric"
+	"github.com/influxdata/telegraf/plugins/common/protobuf"
+	"github.com/influxdata/telegraf/testutil"
+)
+
+var descriptor = protobuf.Descriptor{
+	Files:       []string{"metrics.proto"},
+	ImportPaths: []string{"testdata"},
+	MessageType: "telegraf.test.Batch",
+}
+
+const batch = `{
+  "host": "server01",
+  "location": {"rack": "r1", "slot": 4},
+  "samples": [
+    {"name": "cpu", "value": 42.5, "count": "3", "time": "2022-04-15T05:20:00Z"},
+    {"name": "mem", "value": 0, "count": "7", "time": "2022-04-15T05:20:01Z"}
+  ],
+  "labels": {"dc": "eu"},
+  "status": "OK",
+  "buckets": [1, 2],
+  "timestamp": "1650000000"
+}`
+
+func encode(t *testing.T, text string) []byte {
+	msgDesc, err := descriptor.Load()
+	require.NoError(t, err)
+
+	msg := dynamicpb.NewMessage(msgDesc)
+	require.NoError(t, protojson.Unmarshal([]byte(text), msg))
+	buf, err := proto.Marshal(msg)
+	require.NoError(t, err)
+	return buf
+}
+
+func TestParseMessage(t *testing.T) {
+	parser := &Parser{
+		Descriptor:  descriptor,
+		MetricName:  "protobuf",
+		Measurement: "batch",
+		Tags:        []string{"host", "location.rack", "labels.dc"},
+		Fields:      []string{"location.slot", "status", "buckets.0", "buckets.1", "samples.1.value"},
+		Timestamp:   "timestamp",
+	}
+	require.NoError(t, parser.Init())
+
+	metrics, err := parser.Parse(encode(t, batch))
+	require.NoError(t, err)
+
+	expected := []telegraf.Metric{
+		metric.New(
+			"batch",
+			map[string]string{"host": "server01", "location_rack": "r1", "labels_dc": "eu"},
+			map[string]interface{}{
+				"location_slot":   int64(4),
+				"status":          "OK",
+				"buckets_0":       int64(1),
+				"buckets_1":       int64(2),
+				"samples_1_value": float64(0),
+			},
+			time.Unix(1650000000, 0),
+		),
+	}
+	testutil.RequireMetricsEqual(t, expected, metrics)
+}
+
+func TestParseMetricPath(t *testing.T) {
+	parser := &Parser{
+		Descriptor:       descriptor,
+		MetricName:       "protobuf",
+		MeasurementField: "name",
+		MetricPath:       "samples",
+		Tags:             []string{"host", "status"},
+		Fields:           []string{"value", "count", "location.slot"},
+		FieldSeparator:   ".",
+		Timestamp:        "time",
+	}
+	require.NoError(t, parser.Init())
+
+	metrics, err := parser.Parse(encode(t, batch))
+	require.NoError(t, err)
+
+	expected := []telegraf.Metric{
+		metric.New(
+			"cpu",
+			map[string]string{"host": "server01", "status": "OK"},
+			map[string]interface{}{"value": 42.5, "count": uint64(3), "location.slot": int64(4)},
+			time.Unix(1650000000, 0),
+		),
+		metric.New(
+			"mem",
+			map[string]string{"host": "server01", "status": "OK"},
+			map[string]interface{}{"value": float64(0), "count": uint64(7), "location.slot": int64(4)},
+			time.Unix(1650000001, 0),
+		),
+	}
+	testutil.RequireMetricsEqual(t, expected, metrics)
+}
+
+func TestParseLengthDelimited(t *testing.T) {
+	now := time.Unix(1650000000, 0)
+	parser := &Parser{
+		Descriptor:      descriptor,
+		MetricName:      "protobuf",
+		Fields:          []string{"location.rack"},
+		LengthDelimited: true,
+		TimeFunc:        func() time.Time { return now },
+	}
+	require.NoError(t, parser.Init())
+	parser.SetDefaultTags(map[string]string{"source": "socket"})
+
+	var buf []byte
+	for _, rack := range []string{"r1", "r2"} {
+		msg := encode(t, `{"location": {"rack": "`+rack+`"}}`)
+		buf = protowire.AppendVarint(buf, uint64(len(msg)))
+		buf = append(buf, msg...)
+	}
+
+	metrics, err := parser.Parse(buf)
+	require.NoError(t, err)
+
+	expected := []telegraf.Metric{
+		metric.New("protobuf", map[string]string{"source": "socket"}, map[string]interface{}{"location_rack": "r1"}, now),
+		metric.New("protobuf", map[string]string{"source": "socket"}, map[string]interface{}{"location_rack": "r2"}, now),
+	}
+	testutil.RequireMetricsEqual(t, expected, metrics)
+
+	_, err = parser.Parse(buf[:len(buf)-1])
+	require.Error(t, err)
+}
+
+func TestDescriptorSet(t *testing.T) {
+	msgDesc, err := descriptor.Load()
+	require.NoError(t, err)
+
+	set := &descriptorpb.FileDescriptorSet{
+		File: []*descriptorpb.FileDescriptorProto{
+			protodesc.ToFileDescriptorProto(msgDesc.ParentFile().Imports().Get(0).FileDescriptor),
+			protodesc.ToFileDescriptorProto(msgDesc.ParentFile()),
+		},
+	}
+	buf, err := proto.Marshal(set)
+	require.NoError(t, err)
+
+	filename := filepath.Join(t.TempDir(), "metrics.pb")
+	require.NoError(t, os.WriteFile(filename, buf, 0600))
+
+	parser := &Parser{
+		Descriptor: protobuf.Descriptor{
+			DescriptorSet: filename,
+			MessageType:   "telegraf.test.Batch",
+		},
+		MetricName: "protobuf",
+		Fields:     []string{"host"},
+	}
+	require.NoError(t, parser.Init())
+
+	m, err := parser.ParseLine(string(encode(t, batch)))
+	require.NoError(t, err)
+	require.Equal(t, map[string]interface{}{"host": "server01"}, m.Fields())
+}
+
+func TestInitErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		parser *Parser
+	}{
+		{"no definition", &Parser{Descriptor: protobuf.Descriptor{MessageType: "telegraf.test.Batch"}}},
+		{"no message type", &Parser{Descriptor: protobuf.Descriptor{Files: descriptor.Files, ImportPaths: descriptor.ImportPaths}}},
+		{"unknown message type", &Parser{Descriptor: protobuf.Descriptor{Files: descriptor.Files, ImportPaths: descriptor.ImportPaths, MessageType: "Batch"}}},
+		{"unknown metric path", &Parser{Descriptor: descriptor, MetricPath: "points"}},
+		{"metric path not repeated", &Parser{Descriptor: descriptor, MetricPath: "location"}},
+		{"metric path through repeated", &Parser{Descriptor: descriptor, MetricPath: "samples.time"}},
+		{"invalid timestamp format", &Parser{Descriptor: descriptor, TimestampFormat: "RFC3339"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.Error(t, tt.parser.Init())
+		})
+	}
+}
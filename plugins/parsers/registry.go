@@ -169,9 +169,36 @@ type Config struct {
 	CSVTrimSpace         bool     `toml:"csv_trim_space"`
 	CSVSkipValues        []string `toml:"csv_skip_values"`
 
+	// Avro configuration
+	AvroSchema           string   `toml:"avro_schema"`
+	AvroSchemaRegistry   string   `toml:"avro_schema_registry"`
+	AvroSchemaID         int      `toml:"avro_schema_id"`
+	AvroMeasurement      string   `toml:"avro_measurement"`
+	AvroMeasurementField string   `toml:"avro_measurement_field"`
+	AvroTags             []string `toml:"avro_tags"`
+	AvroFields           []string `toml:"avro_fields"`
+	AvroFieldSeparator   string   `toml:"avro_field_separator"`
+	AvroTimestamp        string   `toml:"avro_timestamp"`
+	AvroTimestampFormat  string   `toml:"avro_timestamp_format"`
+
 	// FormData configuration
 	FormUrlencodedTagKeys []string `toml:"form_urlencoded_tag_keys"`
 
+	// Protocol-buffer configuration
+	ProtobufFiles            []string `toml:"protobuf_files"`
+	ProtobufImportPaths      []string `toml:"protobuf_import_paths"`
+	ProtobufDescriptorSet    string   `toml:"protobuf_descriptor_set"`
+	ProtobufMessageType      string   `toml:"protobuf_message_type"`
+	ProtobufMeasurement      string   `toml:"protobuf_measurement"`
+	ProtobufMeasurementField string   `toml:"protobuf_measurement_field"`
+	ProtobufMetricPath       string   `toml:"protobuf_metric_path"`
+	ProtobufTags             []string `toml:"protobuf_tags"`
+	ProtobufFields           []string `toml:"protobuf_fields"`
+	ProtobufFieldSeparator   string   `toml:"protobuf_field_separator"`
+	ProtobufTimestamp        string   `toml:"protobuf_timestamp"`
+	ProtobufTimestampFormat  string   `toml:"protobuf_timestamp_format"`
+	ProtobufLengthDelimited  bool     `toml:"protobuf_length_delimited"`
+
 	// Prometheus configuration
 	PrometheusIgnoreTimestamp bool `toml:"prometheus_ignore_timestamp"`
 
@@ -3,18 +3,23 @@ package parsers_test
 import (
 	"reflect"
 	"testing"
+	"unsafe"
 
 	"github.com/stretchr/testify/require"
 
 	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/plugins/common/protobuf"
 	"github.com/influxdata/telegraf/plugins/parsers"
 	_ "github.com/influxdata/telegraf/plugins/parsers/all"
 )
 
 func TestRegistry_BackwardCompatibility(t *testing.T) {
 	cfg := &parsers.Config{
-		MetricName:        "parser_compatibility_test",
-		CSVHeaderRowCount: 42,
+		MetricName:          "parser_compatibility_test",
+		CSVHeaderRowCount:   42,
+		AvroSchemaRegistry:  "http://localhost:8081",
+		ProtobufFiles:       []string{"protobuf/testdata/metrics.proto"},
+		ProtobufMessageType: "telegraf.test.Batch",
 	}
 
 	// Some parsers need certain settings to not error. Furthermore, we
@@ -23,6 +28,21 @@ func TestRegistry_BackwardCompatibility(t *testing.T) {
 		param map[string]interface{}
 		mask  []string
 	}{
+		"avro": {
+			param: map[string]interface{}{
+				"SchemaRegistry": cfg.AvroSchemaRegistry,
+			},
+			mask: []string{"TimeFunc"},
+		},
+		"protobuf": {
+			param: map[string]interface{}{
+				"Descriptor": protobuf.Descriptor{
+					Files:       cfg.ProtobufFiles,
+					MessageType: cfg.ProtobufMessageType,
+				},
+			},
+			mask: []string{"TimeFunc", "msgDesc"},
+		},
 		"csv": {
 			param: map[string]interface{}{
 				"HeaderRowCount": cfg.CSVHeaderRowCount,
@@ -57,8 +77,11 @@ func TestRegistry_BackwardCompatibility(t *testing.T) {
 			a := reflect.Indirect(reflect.ValueOf(actual))
 			e := reflect.Indirect(reflect.ValueOf(expected))
 			for _, key := range settings.mask {
+				// Unexported fields need to be made settable first
 				af := a.FieldByName(key)
+				af = reflect.NewAt(af.Type(), unsafe.Pointer(af.UnsafeAddr())).Elem()
 				ef := e.FieldByName(key)
+				ef = reflect.NewAt(ef.Type(), unsafe.Pointer(ef.UnsafeAddr())).Elem()
 
 				v := reflect.Zero(ef.Type())
 				af.Set(v)
@@ -0,0 +1,334 @@
+package cardinality
+
+import (
+	"fmt"
+	"hash/fnv"
+	"sort"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/config"
+	"github.com/influxdata/telegraf/plugins/processors"
+	"github.com/influxdata/telegraf/selfstat"
+)
+
+const sampleConfig = `
+  ## Maximum number of unique series (measurement name and tags) per
+  ## measurement.
+  # limit = 10000
+
+  ## Action for metrics of new series once the limit of the measurement is
+  ## reached:
+  ##   drop     -- drop the metric
+  ##   overflow -- replace the value of the tag with the highest cardinality
+  ##               by the overflow_value
+  ##   strip    -- remove the tag with the highest cardinality
+  # action = "overflow"
+
+  ## Tag value used by the "overflow" action.
+  # overflow_value = "__overflow__"
+
+  ## Maximum number of unique series per measurement created by the
+  ## "overflow" and "strip" actions. Metrics are dropped if rewriting all tags
+  ## not kept does not result in a known series or one within this limit.
+  # rewrite_limit = 1000
+
+  ## Tags never replaced or removed. Metrics of new series with only these
+  ## tags are dropped when over the limit.
+  # keep = ["host"]
+
+  ## Number of tags with the highest cardinality per measurement to report
+  ## in the internal metrics.
+  # top_k = 3
+
+  ## Maximum number of tag keys per measurement to estimate the cardinality
+  ## for; further tag keys are never replaced or removed.
+  # max_tag_keys = 100
+
+  ## Interval to forget all series and start counting anew; never if zero.
+  # reset_interval = "0s"
+`
+
+const (
+	// seriesPrecision and tagPrecision are the precisions of the estimators
+	// with a standard error of about 0.8% and 1.6% respectively
+	seriesPrecision = 14
+	tagPrecision    = 12
+
+	// refreshInterval limits how often the estimates are computed
+	refreshInterval = time.Second
+)
+
+type Cardinality struct {
+	Limit         int             `toml:"limit"`
+	Action        string          `toml:"action"`
+	OverflowValue string          `toml:"overflow_value"`
+	RewriteLimit  int             `toml:"rewrite_limit"`
+	Keep          []string        `toml:"keep"`
+	TopK          int             `toml:"top_k"`
+	MaxTagKeys    int             `toml:"max_tag_keys"`
+	ResetInterval config.Duration `toml:"reset_interval"`
+	Log           telegraf.Logger `toml:"-"`
+
+	keep         map[string]bool
+	measurements map[string]*measurement
+	lastReset    time.Time
+
+	// now is used to throttle the estimation and for resets
+	now func() time.Time
+}
+
+// measurement holds the series and estimators of a measurement
+type measurement struct {
+	name string
+
+	// series are the admitted series and rewritten the series admitted
+	// after rewriting the tags
+	series    map[uint64]bool
+	rewritten map[uint64]bool
+
+	// seen and tagValues estimate the number of all series and of the
+	// values per tag key, including those over the limit
+	seen      *hyperLogLog
+	tagValues map[string]*hyperLogLog
+
+	// ranking holds the tag keys by descending cardinality
+	ranking   []string
+	refreshed time.Time
+	limited   bool
+
+	seriesStat  selfstat.Stat
+	limitedStat selfstat.Stat
+	tagStats    map[string]selfstat.Stat
+}
+
+func (*Cardinality) SampleConfig() string {
+	return sampleConfig
+}
+
+func (*Cardinality) Description() string {
+	return "Limit the number of series per measurement by dropping or rewriting metrics of new series"
+}
+
+func (c *Cardinality) Init() error {
+	if c.Limit <= 0 {
+		return fmt.Errorf("limit must be positive")
+	}
+
+	switch c.Action {
+	case "":
+		c.Action = "overflow"
+	case "drop", "overflow", "strip":
+	default:
+		return fmt.Errorf("invalid action %q", c.Action)
+	}
+	if c.Action == "overflow" && c.OverflowValue == "" {
+		return fmt.Errorf("overflow_value must not be empty")
+	}
+	if c.Action != "drop" && c.RewriteLimit <= 0 {
+		return fmt.Errorf("rewrite_limit must be positive")
+	}
+	if c.TopK < 0 {
+		return fmt.Errorf("top_k must not be negative")
+	}
+	if c.MaxTagKeys <= 0 {
+		return fmt.Errorf("max_tag_keys must be positive")
+	}
+
+	c.keep = make(map[string]bool, len(c.Keep))
+	for _, key := range c.Keep {
+		c.keep[key] = true
+	}
+
+	if c.now == nil {
+		c.now = time.Now
+	}
+	c.measurements = make(map[string]*measurement)
+	c.lastReset = c.now()
+
+	return nil
+}
+
+func (c *Cardinality) Apply(in ...telegraf.Metric) []telegraf.Metric {
+	now := c.now()
+	if c.ResetInterval > 0 && now.Sub(c.lastReset) >= time.Duration(c.ResetInterval) {
+		// Forget the measurements entirely to not report stale ones forever
+		for _, m := range c.measurements {
+			m.unregister()
+		}
+		c.measurements = make(map[string]*measurement)
+		c.lastReset = now
+	}
+
+	out := in[:0]
+	for _, metric := range in {
+		m, found := c.measurements[metric.Name()]
+		if !found {
+			m = c.newMeasurement(metric.Name())
+			c.measurements[metric.Name()] = m
+		}
+
+		for _, tag := range metric.TagList() {
+			values, found := m.tagValues[tag.Key]
+			if !found {
+				if len(m.tagValues) >= c.MaxTagKeys {
+					continue
+				}
+				values = newHyperLogLog(tagPrecision)
+				m.tagValues[tag.Key] = values
+			}
+			values.add(hashString(tag.Value))
+		}
+
+		id := metric.HashID()
+		m.seen.add(id)
+		if m.series[id] || len(m.series) < c.Limit {
+			m.series[id] = true
+			m.refresh(now, c.TopK, false)
+			out = append(out, metric)
+			continue
+		}
+
+		// The metric would exceed the limit
+		m.limitedStat.Incr(1)
+		m.refresh(now, c.TopK, true)
+
+		if c.Action == "drop" || !c.rewrite(m, metric) {
+			metric.Drop()
+			continue
+		}
+		out = append(out, metric)
+	}
+
+	return out
+}
+
+// rewrite replaces or removes the tags of the metric by descending
+// cardinality until the resulting series is known or within the rewrite
+// limit. It returns false if there is no such series.
+func (c *Cardinality) rewrite(m *measurement, metric telegraf.Metric) bool {
+	for _, key := range m.ranking {
+		if c.keep[key] || !metric.HasTag(key) {
+			continue
+		}
+		if c.Action == "overflow" {
+			metric.AddTag(key, c.OverflowValue)
+		} else {
+			metric.RemoveTag(key)
+		}
+
+		id := metric.HashID()
+		if m.rewritten[id] || m.series[id] {
+			return true
+		}
+		if len(m.rewritten) < c.RewriteLimit {
+			m.rewritten[id] = true
+			return true
+		}
+	}
+	return false
+}
+
+func (c *Cardinality) newMeasurement(name string) *measurement {
+	tags := map[string]string{"measurement": name}
+	m := &measurement{
+		name:        name,
+		seriesStat:  selfstat.Register("cardinality", "series", tags),
+		limitedStat: selfstat.Register("cardinality", "limited", tags),
+		tagStats:    make(map[string]selfstat.Stat),
+	}
+	m.reset()
+	return m
+}
+
+// reset forgets all series of the measurement
+func (m *measurement) reset() {
+	m.series = make(map[uint64]bool)
+	m.rewritten = make(map[uint64]bool)
+	m.seen = newHyperLogLog(seriesPrecision)
+	m.tagValues = make(map[string]*hyperLogLog)
+	m.ranking = nil
+	m.refreshed = time.Time{}
+	m.limited = false
+}
+
+// unregister removes all internal metrics of the measurement
+func (m *measurement) unregister() {
+	selfstat.Unregister(m.seriesStat)
+	selfstat.Unregister(m.limitedStat)
+	for key, stat := range m.tagStats {
+		selfstat.Unregister(stat)
+		delete(m.tagStats, key)
+	}
+}
+
+// refresh updates the estimates and the ranking of the tag keys at most
+// once per refresh interval, and immediately when the limit is first reached
+func (m *measurement) refresh(now time.Time, topK int, limited bool) {
+	first := limited && !m.limited
+	if now.Sub(m.refreshed) < refreshInterval && !first {
+		return
+	}
+	m.refreshed = now
+	m.limited = m.limited || limited
+
+	m.seriesStat.Set(int64(m.seen.estimate()))
+
+	estimates := make(map[string]uint64, len(m.tagValues))
+	m.ranking = make([]string, 0, len(m.tagValues))
+	for key, values := range m.tagValues {
+		estimates[key] = values.estimate()
+		m.ranking = append(m.ranking, key)
+	}
+	sort.Slice(m.ranking, func(i, j int) bool {
+		ki, kj := m.ranking[i], m.ranking[j]
+		if estimates[ki] != estimates[kj] {
+			return estimates[ki] > estimates[kj]
+		}
+		return ki < kj
+	})
+
+	// Report the top offenders only once the limit is reached; keys falling
+	// out of the top are removed.
+	if !m.limited {
+		return
+	}
+	top := make(map[string]bool, topK)
+	for i, key := range m.ranking {
+		if i >= topK {
+			break
+		}
+		top[key] = true
+		if _, found := m.tagStats[key]; !found {
+			tags := map[string]string{"measurement": m.name, "tag": key}
+			m.tagStats[key] = selfstat.Register("cardinality", "tag_values", tags)
+		}
+	}
+	for key, stat := range m.tagStats {
+		if !top[key] {
+			selfstat.Unregister(stat)
+			delete(m.tagStats, key)
+			continue
+		}
+		stat.Set(int64(estimates[key]))
+	}
+}
+
+func hashString(s string) uint64 {
+	h := fnv.New64a()
+	h.Write([]byte(s)) //nolint:revive // from hash.go: "It never returns an error"
+	return h.Sum64()
+}
+
+func init() {
+	processors.Add("cardinality", func() telegraf.Processor {
+		return &Cardinality{
+			Limit:         10000,
+			Action:        "overflow",
+			OverflowValue: "__overflow__",
+			RewriteLimit:  1000,
+			TopK:          3,
+			MaxTagKeys:    100,
+		}
+	})
+}
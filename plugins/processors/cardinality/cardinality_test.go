@@ -0,0 +1,291 @@
+package cardinality
+
+import (
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/config"
+	"github.com/influxdata/telegraf/metric"
+	"github.com/influxdata/telegraf/selfstat"
+	"github.com/influxdata/telegraf/testutil"
+)
+
+var ts = time.Unix(1650000000, 0)
+
+// requests returns metrics of one series per user for two hosts
+func requests(name string, users int) []telegraf.Metric {
+	metrics := make([]telegraf.Metric, 0, 2*users)
+	for i := 0; i < users; i++ {
+		for _, host := range []string{"a", "b"} {
+			tags := map[string]string{"host": host, "user_id": strconv.Itoa(i)}
+			metrics = append(metrics, metric.New(name, tags, map[string]interface{}{"value": 1}, ts))
+		}
+	}
+	return metrics
+}
+
+func newCardinality(t *testing.T, c *Cardinality) *Cardinality {
+	c.OverflowValue = "__overflow__"
+	if c.RewriteLimit == 0 {
+		c.RewriteLimit = 10
+	}
+	if c.TopK == 0 {
+		c.TopK = 3
+	}
+	if c.MaxTagKeys == 0 {
+		c.MaxTagKeys = 100
+	}
+	c.Log = testutil.Logger{}
+	require.NoError(t, c.Init())
+	return c
+}
+
+func TestInitErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		plugin   *Cardinality
+		expected string
+	}{
+		{
+			name:     "no limit",
+			plugin:   &Cardinality{},
+			expected: "limit must be positive",
+		},
+		{
+			name:     "invalid action",
+			plugin:   &Cardinality{Limit: 10, Action: "truncate"},
+			expected: "invalid action \"truncate\"",
+		},
+		{
+			name:     "empty overflow value",
+			plugin:   &Cardinality{Limit: 10, Action: "overflow"},
+			expected: "overflow_value must not be empty",
+		},
+		{
+			name:     "negative top_k",
+			plugin:   &Cardinality{Limit: 10, Action: "drop", TopK: -1},
+			expected: "top_k must not be negative",
+		},
+		{
+			name:     "no rewrite limit",
+			plugin:   &Cardinality{Limit: 10, Action: "strip"},
+			expected: "rewrite_limit must be positive",
+		},
+		{
+			name:     "no max tag keys",
+			plugin:   &Cardinality{Limit: 10, Action: "drop"},
+			expected: "max_tag_keys must be positive",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.EqualError(t, tt.plugin.Init(), tt.expected)
+		})
+	}
+}
+
+func TestDrop(t *testing.T) {
+	plugin := newCardinality(t, &Cardinality{Limit: 4, Action: "drop"})
+
+	input := requests("drop", 5)
+	var tracked []telegraf.Metric
+	var delivered int
+	for _, m := range input {
+		m, _ = metric.WithTracking(m, func(telegraf.DeliveryInfo) { delivered++ })
+		tracked = append(tracked, m)
+	}
+	actual := plugin.Apply(tracked...)
+
+	testutil.RequireMetricsEqual(t, requests("drop", 2), actual)
+	require.Equal(t, 6, delivered)
+
+	// Known series still pass
+	actual = plugin.Apply(requests("drop", 5)...)
+	testutil.RequireMetricsEqual(t, requests("drop", 2), actual)
+	require.Equal(t, int64(12), selfstat.Values("cardinality", map[string]string{"measurement": "drop"})["limited"])
+}
+
+func TestOverflow(t *testing.T) {
+	plugin := newCardinality(t, &Cardinality{Limit: 4, Action: "overflow"})
+
+	expected := requests("overflow", 2)
+	for i := 0; i < 3; i++ {
+		for _, host := range []string{"a", "b"} {
+			tags := map[string]string{"host": host, "user_id": "__overflow__"}
+			expected = append(expected, metric.New("overflow", tags, map[string]interface{}{"value": 1}, ts))
+		}
+	}
+
+	actual := plugin.Apply(requests("overflow", 5)...)
+	testutil.RequireMetricsEqual(t, expected, actual)
+}
+
+func TestStrip(t *testing.T) {
+	plugin := newCardinality(t, &Cardinality{Limit: 4, Action: "strip"})
+
+	expected := requests("strip", 2)
+	for i := 0; i < 3; i++ {
+		for _, host := range []string{"a", "b"} {
+			tags := map[string]string{"host": host}
+			expected = append(expected, metric.New("strip", tags, map[string]interface{}{"value": 1}, ts))
+		}
+	}
+
+	actual := plugin.Apply(requests("strip", 5)...)
+	testutil.RequireMetricsEqual(t, expected, actual)
+}
+
+func TestRewriteLimit(t *testing.T) {
+	tests := []struct {
+		action string
+		tags   map[string]string
+	}{
+		{"overflow", map[string]string{"host": "a", "user_id": "__overflow__"}},
+		{"strip", map[string]string{"host": "a"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.action, func(t *testing.T) {
+			name := "rewrite_" + tt.action
+			plugin := newCardinality(t, &Cardinality{Limit: 4, Action: tt.action, RewriteLimit: 1})
+
+			// Rewriting the user_id of host b and then the host itself
+			// results in new series over the rewrite limit
+			expected := requests(name, 2)
+			for i := 0; i < 3; i++ {
+				expected = append(expected, metric.New(name, tt.tags, map[string]interface{}{"value": 1}, ts))
+			}
+
+			actual := plugin.Apply(requests(name, 5)...)
+			testutil.RequireMetricsEqual(t, expected, actual)
+		})
+	}
+}
+
+func TestMaxTagKeys(t *testing.T) {
+	plugin := newCardinality(t, &Cardinality{Limit: 10, Action: "drop", MaxTagKeys: 2})
+
+	tags := map[string]string{"a": "1", "b": "2", "c": "3", "d": "4"}
+	plugin.Apply(metric.New("tagkeys", tags, map[string]interface{}{"value": 1}, ts))
+	require.Len(t, plugin.measurements["tagkeys"].tagValues, 2)
+}
+
+func TestKeep(t *testing.T) {
+	plugin := newCardinality(t, &Cardinality{Limit: 2, Action: "strip", Keep: []string{"user_id"}})
+
+	// The host tag has the highest cardinality not kept
+	input := []telegraf.Metric{
+		metric.New("keep", map[string]string{"host": "a", "user_id": "1"}, map[string]interface{}{"value": 1}, ts),
+		metric.New("keep", map[string]string{"host": "b", "user_id": "2"}, map[string]interface{}{"value": 1}, ts),
+		metric.New("keep", map[string]string{"host": "c", "user_id": "3"}, map[string]interface{}{"value": 1}, ts),
+		metric.New("keep", map[string]string{"user_id": "4"}, map[string]interface{}{"value": 1}, ts),
+	}
+	expected := []telegraf.Metric{
+		metric.New("keep", map[string]string{"host": "a", "user_id": "1"}, map[string]interface{}{"value": 1}, ts),
+		metric.New("keep", map[string]string{"host": "b", "user_id": "2"}, map[string]interface{}{"value": 1}, ts),
+		metric.New("keep", map[string]string{"user_id": "3"}, map[string]interface{}{"value": 1}, ts),
+	}
+
+	actual := plugin.Apply(input...)
+	testutil.RequireMetricsEqual(t, expected, actual)
+}
+
+func TestLimitPerMeasurement(t *testing.T) {
+	plugin := newCardinality(t, &Cardinality{Limit: 4, Action: "drop"})
+
+	input := append(requests("perm_a", 2), requests("perm_b", 2)...)
+	actual := plugin.Apply(input...)
+	testutil.RequireMetricsEqual(t, input, actual)
+}
+
+func TestReset(t *testing.T) {
+	now := ts
+	plugin := &Cardinality{
+		Limit:         2,
+		Action:        "drop",
+		ResetInterval: config.Duration(time.Hour),
+		now:           func() time.Time { return now },
+	}
+	plugin = newCardinality(t, plugin)
+
+	input := requests("reset", 2)
+	actual := plugin.Apply(input...)
+	testutil.RequireMetricsEqual(t, input[:2], actual)
+
+	now = now.Add(time.Hour)
+	actual = plugin.Apply(input[2:]...)
+	testutil.RequireMetricsEqual(t, input[2:], actual)
+
+	// Measurements without metrics since the reset are forgotten
+	require.NotEmpty(t, selfstat.Values("cardinality", map[string]string{"measurement": "reset"}))
+	now = now.Add(time.Hour)
+	plugin.Apply(requests("reset_other", 1)...)
+	require.Empty(t, selfstat.Values("cardinality", map[string]string{"measurement": "reset"}))
+	require.NotContains(t, plugin.measurements, "reset")
+}
+
+func TestStats(t *testing.T) {
+	now := ts
+	plugin := &Cardinality{
+		Limit:  10,
+		Action: "drop",
+		now:    func() time.Time { return now },
+	}
+	plugin = newCardinality(t, plugin)
+
+	plugin.Apply(requests("stats", 100)...)
+
+	// The estimates are computed when the limit is first reached
+	values := selfstat.Values("cardinality", map[string]string{"measurement": "stats"})
+	require.Equal(t, int64(190), values["limited"])
+	require.Equal(t, int64(11), values["series"])
+	userValues := selfstat.Values("cardinality", map[string]string{"measurement": "stats", "tag": "user_id"})
+	require.Equal(t, int64(6), userValues["tag_values"])
+	hostValues := selfstat.Values("cardinality", map[string]string{"measurement": "stats", "tag": "host"})
+	require.Equal(t, int64(2), hostValues["tag_values"])
+
+	// and afterwards at most once per second
+	plugin.Apply(requests("stats", 200)...)
+	values = selfstat.Values("cardinality", map[string]string{"measurement": "stats"})
+	require.Equal(t, int64(11), values["series"])
+
+	now = now.Add(time.Second)
+	plugin.Apply(requests("stats", 1)...)
+	values = selfstat.Values("cardinality", map[string]string{"measurement": "stats"})
+	require.InDelta(t, 400, values["series"], 20)
+	userValues = selfstat.Values("cardinality", map[string]string{"measurement": "stats", "tag": "user_id"})
+	require.InDelta(t, 200, userValues["tag_values"], 10)
+}
+
+func TestTopKStats(t *testing.T) {
+	now := ts
+	plugin := &Cardinality{
+		Limit:  2,
+		Action: "drop",
+		TopK:   1,
+		now:    func() time.Time { return now },
+	}
+	plugin = newCardinality(t, plugin)
+
+	// The host has the most values when the limit is reached
+	var input []telegraf.Metric
+	for i := 0; i < 3; i++ {
+		tags := map[string]string{"host": strconv.Itoa(i), "user_id": "1"}
+		input = append(input, metric.New("topk", tags, map[string]interface{}{"value": 1}, ts))
+	}
+	plugin.Apply(input...)
+	hostTags := map[string]string{"measurement": "topk", "tag": "host"}
+	userTags := map[string]string{"measurement": "topk", "tag": "user_id"}
+	require.Equal(t, int64(3), selfstat.Values("cardinality", hostTags)["tag_values"])
+	require.Empty(t, selfstat.Values("cardinality", userTags))
+
+	// and is replaced by the user_id later on
+	plugin.Apply(requests("topk", 10)...)
+	now = now.Add(time.Second)
+	plugin.Apply(requests("topk", 1)...)
+	require.Empty(t, selfstat.Values("cardinality", hostTags))
+	require.Equal(t, int64(10), selfstat.Values("cardinality", userTags)["tag_values"])
+}
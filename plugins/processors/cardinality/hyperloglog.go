@@ -0,0 +1,65 @@
+package cardinality
+
+import (
+	"math"
+	"math/bits"
+)
+
+// hyperLogLog estimates the number of distinct hashes added using the
+// HyperLogLog algorithm of Flajolet et al. with the linear counting
+// correction for small cardinalities.
+type hyperLogLog struct {
+	precision uint8
+	registers []uint8
+}
+
+func newHyperLogLog(precision uint8) *hyperLogLog {
+	return &hyperLogLog{
+		precision: precision,
+		registers: make([]uint8, 1<<precision),
+	}
+}
+
+// add adds the hash of an element
+func (h *hyperLogLog) add(hash uint64) {
+	// The FNV hashes of metrics are not well distributed in the upper bits
+	hash = mix(hash)
+
+	idx := hash >> (64 - h.precision)
+	w := hash<<h.precision | 1<<(h.precision-1)
+	rho := uint8(bits.LeadingZeros64(w)) + 1
+	if rho > h.registers[idx] {
+		h.registers[idx] = rho
+	}
+}
+
+// estimate returns the estimated number of distinct elements added
+func (h *hyperLogLog) estimate() uint64 {
+	m := float64(len(h.registers))
+
+	var sum float64
+	var zeros int
+	for _, r := range h.registers {
+		sum += math.Ldexp(1, -int(r))
+		if r == 0 {
+			zeros++
+		}
+	}
+
+	alpha := 0.7213 / (1 + 1.079/m)
+	e := alpha * m * m / sum
+	if e <= 2.5*m && zeros > 0 {
+		e = m * math.Log(m/float64(zeros))
+	}
+	return uint64(e + 0.5)
+}
+
+// mix is the finalizer of MurmurHash3 distributing the bits of the hash
+func mix(h uint64) uint64 {
+	h ^= h >> 33
+	h *= 0xff51afd7ed558ccd
+	h ^= h >> 33
+	h *= 0xc4ceb3fe1a85ec53
+	h ^= h >> 33
+	return h
+}
@@ -0,0 +1,39 @@
+package cardinality
+
+import (
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestHyperLogLogEstimate(t *testing.T) {
+	tests := []struct {
+		n         int
+		precision uint8
+	}{
+		{n: 0, precision: 12},
+		{n: 10, precision: 12},
+		{n: 1000, precision: 12},
+		{n: 100000, precision: 12},
+		{n: 100000, precision: 14},
+	}
+	for _, tt := range tests {
+		h := newHyperLogLog(tt.precision)
+		for i := 0; i < tt.n; i++ {
+			// Adding the elements twice must not change the estimate
+			h.add(hashString("value" + strconv.Itoa(i)))
+			h.add(hashString("value" + strconv.Itoa(i)))
+		}
+		require.InEpsilon(t, float64(tt.n)+1, float64(h.estimate())+1, 0.05, "%d elements", tt.n)
+	}
+}
+
+func TestHyperLogLogSequentialHashes(t *testing.T) {
+	// Hashes only differing in the lower bits are counted correctly
+	h := newHyperLogLog(12)
+	for i := uint64(0); i < 10000; i++ {
+		h.add(i)
+	}
+	require.InEpsilon(t, 10000.0, float64(h.estimate()), 0.05)
+}
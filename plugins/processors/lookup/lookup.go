@@ -0,0 +1,286 @@
+package lookup
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/config"
+	"github.com/influxdata/telegraf/plugins/processors"
+	"github.com/influxdata/telegraf/selfstat"
+)
+
+const sampleConfig = `
+  ## Files containing the lookup table. Entries of later files take
+  ## precedence over earlier ones.
+  files = ["/etc/telegraf/inventory.csv"]
+
+  ## Format of the files, one of "csv", "json" or "sqlite".
+  ##   csv    -- first row contains the column names
+  ##   json   -- array of objects with the column names as keys
+  ##   sqlite -- database file; the table is the result of the query below
+  # format = "csv"
+
+  ## Query producing the table from a SQLite database.
+  # query = "SELECT * FROM inventory"
+
+  ## Tags to match against the key columns of the table. All tags must match
+  ## for an entry to be applied.
+  key_tags = ["host"]
+
+  ## Columns of the table holding the key in the order of key_tags; defaults
+  ## to the names of the key_tags.
+  # key_columns = []
+
+  ## Columns to add as tags and as fields to matching metrics. Field values
+  ## are converted to integers, floats or booleans where possible.
+  tags = ["rack", "owner"]
+  # fields = []
+
+  ## Interval to check the files for modifications and reload the table.
+  # reload_interval = "1m"
+`
+
+type Lookup struct {
+	Files          []string        `toml:"files"`
+	Format         string          `toml:"format"`
+	Query          string          `toml:"query"`
+	KeyTags        []string        `toml:"key_tags"`
+	KeyColumns     []string        `toml:"key_columns"`
+	Tags           []string        `toml:"tags"`
+	Fields         []string        `toml:"fields"`
+	ReloadInterval config.Duration `toml:"reload_interval"`
+	Log            telegraf.Logger `toml:"-"`
+
+	table      map[string]row
+	modified   map[string]time.Time
+	hits       selfstat.Stat
+	misses     selfstat.Stat
+	entries    selfstat.Stat
+	reloadErrs selfstat.Stat
+	cancel     chan struct{}
+	wg         sync.WaitGroup
+	sync.RWMutex
+}
+
+// row is an entry of the table with the values by column name as read from
+// the file
+type row map[string]interface{}
+
+func (*Lookup) SampleConfig() string {
+	return sampleConfig
+}
+
+func (*Lookup) Description() string {
+	return "Enrich metrics with tags and fields from a lookup table keyed by tag values"
+}
+
+func (l *Lookup) Init() error {
+	if len(l.Files) == 0 {
+		return fmt.Errorf("no files specified")
+	}
+
+	switch l.Format {
+	case "":
+		l.Format = "csv"
+	case "csv", "json":
+	case "sqlite":
+		if l.Query == "" {
+			return fmt.Errorf("query must be specified for format %q", l.Format)
+		}
+	default:
+		return fmt.Errorf("invalid format %q", l.Format)
+	}
+
+	if len(l.KeyTags) == 0 {
+		return fmt.Errorf("no key tags specified")
+	}
+	if len(l.KeyColumns) == 0 {
+		l.KeyColumns = l.KeyTags
+	}
+	if len(l.KeyColumns) != len(l.KeyTags) {
+		return fmt.Errorf("number of key columns (%d) does not match the number of key tags (%d)", len(l.KeyColumns), len(l.KeyTags))
+	}
+
+	if l.ReloadInterval <= 0 {
+		return fmt.Errorf("reload_interval must be positive")
+	}
+
+	tags := map[string]string{"files": strings.Join(l.Files, ",")}
+	l.hits = selfstat.Register("lookup", "hits", tags)
+	l.misses = selfstat.Register("lookup", "misses", tags)
+	l.entries = selfstat.Register("lookup", "entries", tags)
+	l.reloadErrs = selfstat.Register("lookup", "reload_errors", tags)
+
+	// Fail early on unreadable tables, later errors keep the previous table
+	l.modified = make(map[string]time.Time, len(l.Files))
+	return l.load()
+}
+
+func (l *Lookup) Start(_ telegraf.Accumulator) error {
+	l.cancel = make(chan struct{})
+
+	// Check the files in the background to not delay metrics while
+	// reading large tables
+	l.wg.Add(1)
+	go func() {
+		defer l.wg.Done()
+		ticker := time.NewTicker(time.Duration(l.ReloadInterval))
+		defer ticker.Stop()
+		for {
+			select {
+			case <-l.cancel:
+				return
+			case <-ticker.C:
+				l.reloadIfModified()
+			}
+		}
+	}()
+
+	return nil
+}
+
+func (l *Lookup) Add(m telegraf.Metric, acc telegraf.Accumulator) error {
+	l.enrich(m)
+	acc.AddMetric(m)
+	return nil
+}
+
+func (l *Lookup) Stop() error {
+	close(l.cancel)
+	l.wg.Wait()
+	return nil
+}
+
+// enrich adds the tags and fields of the matching table entry to the metric
+func (l *Lookup) enrich(m telegraf.Metric) {
+	key, ok := l.key(m)
+	if !ok {
+		l.misses.Incr(1)
+		return
+	}
+
+	l.RLock()
+	entry, found := l.table[key]
+	l.RUnlock()
+	if !found {
+		l.misses.Incr(1)
+		return
+	}
+	l.hits.Incr(1)
+
+	for _, column := range l.Tags {
+		if v, found := entry[column]; found {
+			m.AddTag(column, toString(v))
+		}
+	}
+	for _, column := range l.Fields {
+		if v, found := entry[column]; found {
+			m.AddField(column, fieldValue(v))
+		}
+	}
+}
+
+// key returns the lookup key of the metric and false if a key tag is missing
+func (l *Lookup) key(m telegraf.Metric) (string, bool) {
+	values := make([]string, 0, len(l.KeyTags))
+	for _, tag := range l.KeyTags {
+		v, found := m.GetTag(tag)
+		if !found {
+			return "", false
+		}
+		values = append(values, v)
+	}
+	return strings.Join(values, "\x00"), true
+}
+
+// reloadIfModified reloads the table if any file changed since the last load
+func (l *Lookup) reloadIfModified() {
+	changed := false
+	for _, filename := range l.Files {
+		info, err := os.Stat(filename)
+		if err != nil {
+			l.Log.Errorf("Checking %q failed: %v", filename, err)
+			continue
+		}
+		if !info.ModTime().Equal(l.modified[filename]) {
+			changed = true
+		}
+	}
+	if !changed {
+		return
+	}
+
+	if err := l.load(); err != nil {
+		l.reloadErrs.Incr(1)
+		l.Log.Errorf("Reloading table failed, keeping previous entries: %v", err)
+		return
+	}
+	l.RLock()
+	l.Log.Debugf("Reloaded table with %d entries", len(l.table))
+	l.RUnlock()
+}
+
+// load reads all files and replaces the table on success
+func (l *Lookup) load() error {
+	table := make(map[string]row)
+	modified := make(map[string]time.Time, len(l.Files))
+	for _, filename := range l.Files {
+		info, err := os.Stat(filename)
+		if err != nil {
+			return err
+		}
+		modified[filename] = info.ModTime()
+
+		var rows []row
+		switch l.Format {
+		case "csv":
+			rows, err = loadCSV(filename)
+		case "json":
+			rows, err = loadJSON(filename)
+		case "sqlite":
+			rows, err = loadSQL("sqlite", filename, l.Query)
+		}
+		if err != nil {
+			return fmt.Errorf("loading %q failed: %w", filename, err)
+		}
+
+		for i, r := range rows {
+			key, err := l.rowKey(r)
+			if err != nil {
+				return fmt.Errorf("entry %d of %q: %w", i+1, filename, err)
+			}
+			table[key] = r
+		}
+	}
+
+	l.Lock()
+	l.table = table
+	l.Unlock()
+	l.modified = modified
+	l.entries.Set(int64(len(table)))
+	return nil
+}
+
+func (l *Lookup) rowKey(r row) (string, error) {
+	values := make([]string, 0, len(l.KeyColumns))
+	for _, column := range l.KeyColumns {
+		v, found := r[column]
+		if !found {
+			return "", fmt.Errorf("key column %q missing", column)
+		}
+		values = append(values, toString(v))
+	}
+	return strings.Join(values, "\x00"), nil
+}
+
+func init() {
+	processors.AddStreaming("lookup", func() telegraf.StreamingProcessor {
+		return &Lookup{
+			ReloadInterval: config.Duration(time.Minute),
+		}
+	})
+}
@@ -0,0 +1,184 @@
+package lookup
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/config"
+	"github.com/influxdata/telegraf/metric"
+	"github.com/influxdata/telegraf/testutil"
+)
+
+var ts = time.Unix(1650000000, 0)
+
+func TestLookupCSV(t *testing.T) {
+	plugin := &Lookup{
+		Files:   []string{"testdata/inventory.csv"},
+		KeyTags: []string{"host", "datacenter"},
+		// The table calls the datacenter "dc"
+		KeyColumns:     []string{"host", "dc"},
+		Tags:           []string{"rack", "owner"},
+		Fields:         []string{"cost", "active"},
+		ReloadInterval: config.Duration(time.Minute),
+		Log:            testutil.Logger{},
+	}
+	require.NoError(t, plugin.Init())
+
+	input := []telegraf.Metric{
+		metric.New("cpu", map[string]string{"host": "server01", "datacenter": "us"}, map[string]interface{}{"value": 1.0}, ts),
+		metric.New("cpu", map[string]string{"host": "server02", "datacenter": "eu"}, map[string]interface{}{"value": 2.0}, ts),
+		metric.New("cpu", map[string]string{"host": "server03", "datacenter": "eu"}, map[string]interface{}{"value": 3.0}, ts),
+		metric.New("cpu", map[string]string{"host": "server01"}, map[string]interface{}{"value": 4.0}, ts),
+	}
+
+	expected := []telegraf.Metric{
+		metric.New(
+			"cpu",
+			map[string]string{"host": "server01", "datacenter": "us", "rack": "r9", "owner": "team-c"},
+			map[string]interface{}{"value": 1.0, "cost": int64(3), "active": true},
+			ts,
+		),
+		metric.New(
+			"cpu",
+			map[string]string{"host": "server02", "datacenter": "eu", "rack": "r2", "owner": "team-b"},
+			map[string]interface{}{"value": 2.0, "cost": int64(7), "active": false},
+			ts,
+		),
+		metric.New("cpu", map[string]string{"host": "server03", "datacenter": "eu"}, map[string]interface{}{"value": 3.0}, ts),
+		metric.New("cpu", map[string]string{"host": "server01"}, map[string]interface{}{"value": 4.0}, ts),
+	}
+
+	testutil.RequireMetricsEqual(t, expected, process(t, plugin, input))
+	require.Equal(t, int64(2), plugin.hits.Get())
+	require.Equal(t, int64(2), plugin.misses.Get())
+	require.Equal(t, int64(3), plugin.entries.Get())
+}
+
+func TestLookupJSON(t *testing.T) {
+	plugin := &Lookup{
+		Files:          []string{"testdata/inventory.json"},
+		Format:         "json",
+		KeyTags:        []string{"host"},
+		Tags:           []string{"owner"},
+		Fields:         []string{"cost", "rack"},
+		ReloadInterval: config.Duration(time.Minute),
+		Log:            testutil.Logger{},
+	}
+	require.NoError(t, plugin.Init())
+
+	input := []telegraf.Metric{
+		metric.New("cpu", map[string]string{"host": "server01"}, map[string]interface{}{}, ts),
+		metric.New("cpu", map[string]string{"host": "server02"}, map[string]interface{}{}, ts),
+	}
+
+	expected := []telegraf.Metric{
+		metric.New("cpu", map[string]string{"host": "server01", "owner": "team-a"}, map[string]interface{}{"cost": 12.5, "rack": "r1"}, ts),
+		metric.New("cpu", map[string]string{"host": "server02"}, map[string]interface{}{"cost": int64(7), "rack": "r2"}, ts),
+	}
+
+	testutil.RequireMetricsEqual(t, expected, process(t, plugin, input))
+}
+
+func TestReload(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "table.csv")
+	require.NoError(t, os.WriteFile(filename, []byte("host,rack\nserver01,r1\n"), 0600))
+
+	plugin := &Lookup{
+		Files:          []string{filename},
+		KeyTags:        []string{"host"},
+		Tags:           []string{"rack"},
+		ReloadInterval: config.Duration(time.Minute),
+		Log:            testutil.Logger{},
+	}
+	require.NoError(t, plugin.Init())
+
+	apply := func() string {
+		m := metric.New("cpu", map[string]string{"host": "server01"}, map[string]interface{}{"value": 1.0}, ts)
+		out := process(t, plugin, []telegraf.Metric{m})
+		rack, _ := out[0].GetTag("rack")
+		return rack
+	}
+	require.Equal(t, "r1", apply())
+
+	// Changes are picked up when checking the files
+	require.NoError(t, os.WriteFile(filename, []byte("host,rack\nserver01,r2\n"), 0600))
+	require.NoError(t, os.Chtimes(filename, ts, ts.Add(time.Hour)))
+	require.Equal(t, "r1", apply())
+	plugin.reloadIfModified()
+	require.Equal(t, "r2", apply())
+
+	// Broken files keep the previous table
+	require.NoError(t, os.WriteFile(filename, []byte("host,rack\nserver01,r3,extra\n"), 0600))
+	require.NoError(t, os.Chtimes(filename, ts, ts.Add(2*time.Hour)))
+	plugin.reloadIfModified()
+	require.Equal(t, "r2", apply())
+	require.Equal(t, int64(1), plugin.reloadErrs.Get())
+}
+
+func TestReloadBackground(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "table.csv")
+	require.NoError(t, os.WriteFile(filename, []byte("host,rack\nserver01,r1\n"), 0600))
+
+	plugin := &Lookup{
+		Files:          []string{filename},
+		KeyTags:        []string{"host"},
+		Tags:           []string{"rack"},
+		ReloadInterval: config.Duration(10 * time.Millisecond),
+		Log:            testutil.Logger{},
+	}
+	require.NoError(t, plugin.Init())
+
+	var acc testutil.Accumulator
+	require.NoError(t, plugin.Start(&acc))
+	defer plugin.Stop()
+
+	require.NoError(t, os.WriteFile(filename, []byte("host,rack\nserver01,r2\n"), 0600))
+	require.NoError(t, os.Chtimes(filename, ts, ts.Add(time.Hour)))
+	require.Eventually(t, func() bool {
+		m := metric.New("cpu", map[string]string{"host": "server01"}, map[string]interface{}{"value": 1.0}, ts)
+		require.NoError(t, plugin.Add(m, &acc))
+		rack, _ := m.GetTag("rack")
+		return rack == "r2"
+	}, time.Second, 10*time.Millisecond)
+}
+
+func TestInitErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		plugin *Lookup
+	}{
+		{"no files", &Lookup{KeyTags: []string{"host"}}},
+		{"invalid format", &Lookup{Files: []string{"testdata/inventory.csv"}, Format: "xml", KeyTags: []string{"host"}}},
+		{"sqlite without query", &Lookup{Files: []string{"inventory.db"}, Format: "sqlite", KeyTags: []string{"host"}}},
+		{"no key tags", &Lookup{Files: []string{"testdata/inventory.csv"}}},
+		{"key column count", &Lookup{Files: []string{"testdata/inventory.csv"}, KeyTags: []string{"host"}, KeyColumns: []string{"host", "dc"}}},
+		{"missing key column", &Lookup{Files: []string{"testdata/inventory.csv"}, KeyTags: []string{"serial"}}},
+		{"missing file", &Lookup{Files: []string{"testdata/missing.csv"}, KeyTags: []string{"host"}}},
+		{"reload interval", &Lookup{Files: []string{"testdata/inventory.csv"}, KeyTags: []string{"host"}, ReloadInterval: config.Duration(-time.Second)}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.plugin.Log = testutil.Logger{}
+			if tt.plugin.ReloadInterval == 0 {
+				tt.plugin.ReloadInterval = config.Duration(time.Minute)
+			}
+			require.Error(t, tt.plugin.Init())
+		})
+	}
+}
+
+// process runs the metrics through the started plugin
+func process(t *testing.T, plugin *Lookup, input []telegraf.Metric) []telegraf.Metric {
+	var acc testutil.Accumulator
+	require.NoError(t, plugin.Start(&acc))
+	for _, m := range input {
+		require.NoError(t, plugin.Add(m, &acc))
+	}
+	require.NoError(t, plugin.Stop())
+	return acc.GetTelegrafMetrics()
+}
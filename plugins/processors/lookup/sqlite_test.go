@@ -0,0 +1,54 @@
+//go:build !mips && !mips64
+// +build !mips,!mips64
+
+package lookup
+
+import (
+	gosql "database/sql"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/config"
+	"github.com/influxdata/telegraf/metric"
+	"github.com/influxdata/telegraf/testutil"
+)
+
+func TestLookupSQLite(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "inventory.db")
+
+	db, err := gosql.Open("sqlite", filename)
+	require.NoError(t, err)
+	_, err = db.Exec("CREATE TABLE inventory (host TEXT, rack TEXT, cost REAL, cores INTEGER)")
+	require.NoError(t, err)
+	_, err = db.Exec("INSERT INTO inventory VALUES ('server01', 'r1', 12.5, 8), ('server02', NULL, 7.5, 4)")
+	require.NoError(t, err)
+	require.NoError(t, db.Close())
+
+	plugin := &Lookup{
+		Files:          []string{filename},
+		Format:         "sqlite",
+		Query:          "SELECT host, rack, cost, cores FROM inventory",
+		KeyTags:        []string{"host"},
+		Tags:           []string{"rack"},
+		Fields:         []string{"cost", "cores"},
+		ReloadInterval: config.Duration(time.Minute),
+		Log:            testutil.Logger{},
+	}
+	require.NoError(t, plugin.Init())
+
+	input := []telegraf.Metric{
+		metric.New("cpu", map[string]string{"host": "server01"}, map[string]interface{}{}, ts),
+		metric.New("cpu", map[string]string{"host": "server02"}, map[string]interface{}{}, ts),
+	}
+
+	expected := []telegraf.Metric{
+		metric.New("cpu", map[string]string{"host": "server01", "rack": "r1"}, map[string]interface{}{"cost": 12.5, "cores": int64(8)}, ts),
+		metric.New("cpu", map[string]string{"host": "server02"}, map[string]interface{}{"cost": 7.5, "cores": int64(4)}, ts),
+	}
+
+	testutil.RequireMetricsEqual(t, expected, process(t, plugin, input))
+}
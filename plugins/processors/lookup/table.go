@@ -0,0 +1,153 @@
+package lookup
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+)
+
+// loadCSV reads a table with the column names in the first row
+func loadCSV(filename string) ([]row, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	records, err := csv.NewReader(f).ReadAll()
+	if err != nil {
+		return nil, err
+	}
+	if len(records) == 0 {
+		return nil, nil
+	}
+
+	header := records[0]
+	rows := make([]row, 0, len(records)-1)
+	for _, record := range records[1:] {
+		r := make(row, len(header))
+		for i, column := range header {
+			r[column] = record[i]
+		}
+		rows = append(rows, r)
+	}
+	return rows, nil
+}
+
+// loadJSON reads a table given as array of objects
+func loadJSON(filename string) ([]row, error) {
+	buf, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	var objects []map[string]interface{}
+	decoder := json.NewDecoder(bytes.NewReader(buf))
+	decoder.UseNumber()
+	if err := decoder.Decode(&objects); err != nil {
+		return nil, err
+	}
+
+	rows := make([]row, 0, len(objects))
+	for _, obj := range objects {
+		r := make(row, len(obj))
+		for column, v := range obj {
+			switch v.(type) {
+			case nil, []interface{}, map[string]interface{}:
+				// Only scalar values can be added to metrics
+			default:
+				r[column] = v
+			}
+		}
+		rows = append(rows, r)
+	}
+	return rows, nil
+}
+
+// loadSQL reads the table resulting from the query. The driver has to be
+// registered for the platform.
+func loadSQL(driver, dsn, query string) ([]row, error) {
+	db, err := sql.Open(driver, dsn)
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+
+	result, err := db.Query(query)
+	if err != nil {
+		return nil, err
+	}
+	defer result.Close()
+
+	columns, err := result.Columns()
+	if err != nil {
+		return nil, err
+	}
+
+	var rows []row
+	for result.Next() {
+		values := make([]interface{}, len(columns))
+		pointers := make([]interface{}, len(columns))
+		for i := range values {
+			pointers[i] = &values[i]
+		}
+		if err := result.Scan(pointers...); err != nil {
+			return nil, err
+		}
+
+		r := make(row, len(columns))
+		for i, column := range columns {
+			switch v := values[i].(type) {
+			case nil:
+			case []byte:
+				r[column] = string(v)
+			default:
+				r[column] = v
+			}
+		}
+		rows = append(rows, r)
+	}
+	return rows, result.Err()
+}
+
+// toString returns the value as written in the table
+func toString(value interface{}) string {
+	switch v := value.(type) {
+	case string:
+		return v
+	case json.Number:
+		return v.String()
+	}
+	return fmt.Sprintf("%v", value)
+}
+
+// fieldValue converts the value to a field type. Text is converted to an
+// integer, float or boolean if possible.
+func fieldValue(value interface{}) interface{} {
+	var text string
+	switch v := value.(type) {
+	case string:
+		text = v
+	case json.Number:
+		text = v.String()
+	case int64, float64, bool:
+		return v
+	default:
+		return toString(v)
+	}
+
+	if v, err := strconv.ParseInt(text, 10, 64); err == nil {
+		return v
+	}
+	if v, err := strconv.ParseFloat(text, 64); err == nil {
+		return v
+	}
+	if v, err := strconv.ParseBool(text); err == nil {
+		return v
+	}
+	return text
+}
@@ -0,0 +1,279 @@
+package rate
+
+import (
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/config"
+	"github.com/influxdata/telegraf/filter"
+	"github.com/influxdata/telegraf/plugins/processors"
+)
+
+const sampleConfig = `
+  ## Fields to compute the rate and delta for, globs accepted. All numeric
+  ## fields if empty.
+  # fields = []
+
+  ## Values to add to the metrics, "rate" and/or "delta". The new fields are
+  ## named after the original field with the corresponding suffix.
+  # emit = ["rate"]
+  # rate_suffix = "_rate"
+  # delta_suffix = "_delta"
+
+  ## Unit of time the rate is given in.
+  # rate_period = "1s"
+
+  ## Treat the fields as monotonic counters. A decrease is then considered a
+  ## counter wrap or reset and no negative values are emitted. Set to false
+  ## to compute the derivative of gauges.
+  # non_negative = true
+
+  ## Width of integer counters to detect wraps for, one of "auto", "32", "64"
+  ## or "none". With "auto", 32 bit wraps are assumed for values fitting into
+  ## 32 bit. A decrease from the upper to the lower half of the counter range
+  ## is a wrap, any other decrease is a reset and only updates the previous
+  ## value.
+  # counter_wrap = "auto"
+
+  ## Remove the original fields from the metrics.
+  # drop_original = false
+
+  ## Series without metrics for this duration are forgotten.
+  # series_timeout = "1h"
+`
+
+type Rate struct {
+	Fields        []string        `toml:"fields"`
+	Emit          []string        `toml:"emit"`
+	RateSuffix    string          `toml:"rate_suffix"`
+	DeltaSuffix   string          `toml:"delta_suffix"`
+	RatePeriod    config.Duration `toml:"rate_period"`
+	NonNegative   bool            `toml:"non_negative"`
+	CounterWrap   string          `toml:"counter_wrap"`
+	DropOriginal  bool            `toml:"drop_original"`
+	SeriesTimeout config.Duration `toml:"series_timeout"`
+	Log           telegraf.Logger `toml:"-"`
+
+	fieldFilter filter.Filter
+	emitRate    bool
+	emitDelta   bool
+	series      map[uint64]*series
+	lastCleanup time.Time
+
+	// now is used to expire series
+	now func() time.Time
+}
+
+// series holds the previous values of a series by field
+type series struct {
+	fields   map[string]sample
+	lastSeen time.Time
+}
+
+type sample struct {
+	value interface{}
+	time  time.Time
+}
+
+func (*Rate) SampleConfig() string {
+	return sampleConfig
+}
+
+func (*Rate) Description() string {
+	return "Add the rate and delta of counters to each metric using the previous value of the series"
+}
+
+func (r *Rate) Init() error {
+	var err error
+	if r.fieldFilter, err = filter.Compile(r.Fields); err != nil {
+		return fmt.Errorf("compiling field filter failed: %w", err)
+	}
+
+	if len(r.Emit) == 0 {
+		r.Emit = []string{"rate"}
+	}
+	for _, e := range r.Emit {
+		switch e {
+		case "rate":
+			r.emitRate = true
+		case "delta":
+			r.emitDelta = true
+		default:
+			return fmt.Errorf("invalid emit value %q", e)
+		}
+	}
+
+	if r.RatePeriod <= 0 {
+		return fmt.Errorf("rate_period must be positive")
+	}
+
+	switch r.CounterWrap {
+	case "":
+		r.CounterWrap = "auto"
+	case "auto", "32", "64", "none":
+	default:
+		return fmt.Errorf("invalid counter_wrap %q", r.CounterWrap)
+	}
+
+	if r.now == nil {
+		r.now = time.Now
+	}
+	r.series = make(map[uint64]*series)
+	r.lastCleanup = r.now()
+
+	return nil
+}
+
+func (r *Rate) Apply(in ...telegraf.Metric) []telegraf.Metric {
+	now := r.now()
+	r.cleanup(now)
+
+	for _, m := range in {
+		id := m.HashID()
+		s, found := r.series[id]
+		if !found {
+			s = &series{fields: make(map[string]sample)}
+			r.series[id] = s
+		}
+		s.lastSeen = now
+
+		// Iterate over a copy as fields are added and removed
+		fields := append([]*telegraf.Field(nil), m.FieldList()...)
+		for _, field := range fields {
+			if r.fieldFilter != nil && !r.fieldFilter.Match(field.Key) {
+				continue
+			}
+			if _, ok := toFloat(field.Value); !ok {
+				continue
+			}
+
+			current := sample{value: field.Value, time: m.Time()}
+			previous, hasPrevious := s.fields[field.Key]
+			s.fields[field.Key] = current
+			if r.DropOriginal {
+				m.RemoveField(field.Key)
+			}
+			if !hasPrevious {
+				continue
+			}
+
+			elapsed := current.time.Sub(previous.time)
+			if elapsed <= 0 {
+				continue
+			}
+			delta, ok := r.delta(previous.value, current.value)
+			if !ok {
+				r.Log.Debugf("Counter %q of %q decreased, assuming a reset", field.Key, m.Name())
+				continue
+			}
+
+			if r.emitDelta {
+				m.AddField(field.Key+r.DeltaSuffix, delta)
+			}
+			if r.emitRate {
+				m.AddField(field.Key+r.RateSuffix, delta*float64(r.RatePeriod)/float64(elapsed))
+			}
+		}
+	}
+
+	return in
+}
+
+// delta returns the difference between the values and false for counter
+// resets. Differences of integers are computed exactly before conversion.
+func (r *Rate) delta(previous, current interface{}) (float64, bool) {
+	if !r.NonNegative {
+		p, _ := toFloat(previous)
+		c, _ := toFloat(current)
+		return c - p, true
+	}
+
+	p, pok := toUint(previous)
+	c, cok := toUint(current)
+	if !pok || !cok {
+		pf, _ := toFloat(previous)
+		cf, _ := toFloat(current)
+		if cf < pf {
+			return 0, false
+		}
+		return cf - pf, true
+	}
+
+	if c >= p {
+		return float64(c - p), true
+	}
+
+	var bits uint
+	switch r.CounterWrap {
+	case "none":
+		return 0, false
+	case "32":
+		bits = 32
+	case "64":
+		bits = 64
+	case "auto":
+		bits = 64
+		if p <= math.MaxUint32 {
+			bits = 32
+		}
+	}
+	max := uint64(math.MaxUint64) >> (64 - bits)
+	half := max / 2
+	if p > max || p <= half || c > half {
+		return 0, false
+	}
+	return float64((c - p) & max), true
+}
+
+// cleanup forgets series not seen for the series timeout. The check is only
+// performed once per timeout to save cycles.
+func (r *Rate) cleanup(now time.Time) {
+	if r.SeriesTimeout <= 0 || now.Sub(r.lastCleanup) < time.Duration(r.SeriesTimeout) {
+		return
+	}
+	r.lastCleanup = now
+	for id, s := range r.series {
+		if now.Sub(s.lastSeen) >= time.Duration(r.SeriesTimeout) {
+			delete(r.series, id)
+		}
+	}
+}
+
+func toFloat(value interface{}) (float64, bool) {
+	switch v := value.(type) {
+	case int64:
+		return float64(v), true
+	case uint64:
+		return float64(v), true
+	case float64:
+		return v, true
+	}
+	return 0, false
+}
+
+func toUint(value interface{}) (uint64, bool) {
+	switch v := value.(type) {
+	case int64:
+		if v < 0 {
+			return 0, false
+		}
+		return uint64(v), true
+	case uint64:
+		return v, true
+	}
+	return 0, false
+}
+
+func init() {
+	processors.Add("rate", func() telegraf.Processor {
+		return &Rate{
+			RateSuffix:    "_rate",
+			DeltaSuffix:   "_delta",
+			RatePeriod:    config.Duration(time.Second),
+			NonNegative:   true,
+			SeriesTimeout: config.Duration(time.Hour),
+		}
+	})
+}
@@ -0,0 +1,147 @@
+package rate
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/config"
+	"github.com/influxdata/telegraf/metric"
+	"github.com/influxdata/telegraf/testutil"
+)
+
+var ts = time.Unix(1650000000, 0)
+
+func newRate(t *testing.T, r *Rate) *Rate {
+	r.RateSuffix = "_rate"
+	r.DeltaSuffix = "_delta"
+	if r.RatePeriod == 0 {
+		r.RatePeriod = config.Duration(time.Second)
+	}
+	r.SeriesTimeout = config.Duration(time.Hour)
+	r.Log = testutil.Logger{}
+	require.NoError(t, r.Init())
+	return r
+}
+
+func TestRate(t *testing.T) {
+	plugin := newRate(t, &Rate{
+		Fields:      []string{"bytes_*"},
+		Emit:        []string{"rate", "delta"},
+		NonNegative: true,
+	})
+
+	input := []telegraf.Metric{
+		metric.New("net", map[string]string{"interface": "eth0"}, map[string]interface{}{"bytes_recv": uint64(1000), "drop_in": int64(1)}, ts),
+		metric.New("net", map[string]string{"interface": "eth1"}, map[string]interface{}{"bytes_recv": uint64(50)}, ts),
+		metric.New("net", map[string]string{"interface": "eth0"}, map[string]interface{}{"bytes_recv": uint64(3000), "drop_in": int64(2)}, ts.Add(10*time.Second)),
+		metric.New("net", map[string]string{"interface": "eth1"}, map[string]interface{}{"bytes_recv": uint64(50)}, ts.Add(5*time.Second)),
+	}
+
+	expected := []telegraf.Metric{
+		metric.New("net", map[string]string{"interface": "eth0"}, map[string]interface{}{"bytes_recv": uint64(1000), "drop_in": int64(1)}, ts),
+		metric.New("net", map[string]string{"interface": "eth1"}, map[string]interface{}{"bytes_recv": uint64(50)}, ts),
+		metric.New(
+			"net",
+			map[string]string{"interface": "eth0"},
+			map[string]interface{}{"bytes_recv": uint64(3000), "drop_in": int64(2), "bytes_recv_rate": 200.0, "bytes_recv_delta": 2000.0},
+			ts.Add(10*time.Second),
+		),
+		metric.New(
+			"net",
+			map[string]string{"interface": "eth1"},
+			map[string]interface{}{"bytes_recv": uint64(50), "bytes_recv_rate": 0.0, "bytes_recv_delta": 0.0},
+			ts.Add(5*time.Second),
+		),
+	}
+
+	// Metrics of separate batches are related
+	var actual []telegraf.Metric
+	for _, m := range input {
+		actual = append(actual, plugin.Apply(m)...)
+	}
+	testutil.RequireMetricsEqual(t, expected, actual)
+}
+
+func TestCounterWrap(t *testing.T) {
+	tests := []struct {
+		name     string
+		wrap     string
+		previous interface{}
+		current  interface{}
+		expected interface{}
+	}{
+		{"32 bit auto", "auto", uint64(math.MaxUint32 - 9), uint64(10), 20.0},
+		{"32 bit", "32", int64(math.MaxUint32), int64(0), 1.0},
+		{"64 bit auto", "auto", uint64(math.MaxUint64 - 4), uint64(5), 10.0},
+		{"64 bit", "64", uint64(math.MaxUint32 - 9), uint64(10), nil},
+		{"reset", "auto", uint64(1000), uint64(10), nil},
+		{"reset above 32 bit", "32", uint64(math.MaxUint32 + 10), uint64(10), nil},
+		{"no wrap", "none", uint64(math.MaxUint32 - 9), uint64(10), nil},
+		{"float reset", "auto", 100.5, 10.5, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			plugin := newRate(t, &Rate{Emit: []string{"delta"}, NonNegative: true, CounterWrap: tt.wrap})
+
+			plugin.Apply(metric.New("counter", map[string]string{}, map[string]interface{}{"value": tt.previous}, ts))
+			out := plugin.Apply(metric.New("counter", map[string]string{}, map[string]interface{}{"value": tt.current}, ts.Add(time.Second)))
+
+			delta, found := out[0].GetField("value_delta")
+			if tt.expected == nil {
+				require.False(t, found)
+				return
+			}
+			require.Equal(t, tt.expected, delta)
+		})
+	}
+}
+
+func TestGaugeDerivative(t *testing.T) {
+	plugin := newRate(t, &Rate{DropOriginal: true, RatePeriod: config.Duration(time.Minute)})
+
+	plugin.Apply(metric.New("mem", map[string]string{}, map[string]interface{}{"used": 100.0, "state": "ok"}, ts))
+	out := plugin.Apply(metric.New("mem", map[string]string{}, map[string]interface{}{"used": 70.0, "state": "ok"}, ts.Add(30*time.Second)))
+
+	expected := []telegraf.Metric{
+		metric.New("mem", map[string]string{}, map[string]interface{}{"used_rate": -60.0, "state": "ok"}, ts.Add(30*time.Second)),
+	}
+	testutil.RequireMetricsEqual(t, expected, out)
+}
+
+func TestSeriesTimeout(t *testing.T) {
+	now := ts
+	plugin := newRate(t, &Rate{NonNegative: true, now: func() time.Time { return now }})
+
+	plugin.Apply(metric.New("cpu", map[string]string{"host": "a"}, map[string]interface{}{"value": 1.0}, ts))
+	plugin.Apply(metric.New("cpu", map[string]string{"host": "b"}, map[string]interface{}{"value": 1.0}, ts))
+	require.Len(t, plugin.series, 2)
+
+	now = now.Add(30 * time.Minute)
+	plugin.Apply(metric.New("cpu", map[string]string{"host": "a"}, map[string]interface{}{"value": 2.0}, ts.Add(time.Minute)))
+	require.Len(t, plugin.series, 2)
+
+	now = now.Add(time.Hour)
+	plugin.Apply(metric.New("cpu", map[string]string{"host": "c"}, map[string]interface{}{"value": 1.0}, ts))
+	require.Len(t, plugin.series, 1)
+}
+
+func TestInitErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		plugin *Rate
+	}{
+		{"invalid emit", &Rate{Emit: []string{"average"}, RatePeriod: config.Duration(time.Second)}},
+		{"invalid rate period", &Rate{}},
+		{"invalid counter wrap", &Rate{CounterWrap: "16", RatePeriod: config.Duration(time.Second)}},
+		{"invalid field filter", &Rate{Fields: []string{"["}, RatePeriod: config.Duration(time.Second)}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.Error(t, tt.plugin.Init())
+		})
+	}
+}
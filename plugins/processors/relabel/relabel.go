@@ -0,0 +1,87 @@
+package relabel
+
+import (
+	"fmt"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/plugins/common/relabel"
+	"github.com/influxdata/telegraf/plugins/processors"
+)
+
+const sampleConfig = `
+  ## Relabelling rules applied in order to the tags of the metrics and the
+  ## __name__ label holding the measurement name. The options and actions
+  ## are the same as of the Prometheus relabel_configs.
+  [[processors.relabel.rule]]
+    ## Tags joined by the separator to the value matched by the regex.
+    source_labels = ["__name__", "code"]
+    # separator = ";"
+
+    ## Regular expression anchored at both ends.
+    regex = "(.+)_total;5.."
+
+    ## Action to perform:
+    ##   replace   -- set target_label to the replacement if the regex
+    ##                matches, removing the tag if the replacement is empty
+    ##   keep      -- drop metrics not matching the regex
+    ##   drop      -- drop metrics matching the regex
+    ##   hashmod   -- set target_label to the modulus of the hash of the value
+    ##   labelmap  -- copy tags with names matching the regex to the
+    ##                replacement
+    ##   labeldrop -- remove tags with names matching the regex
+    ##   labelkeep -- remove tags with names not matching the regex
+    # action = "replace"
+
+    ## Tag to set by the replace and hashmod actions; may refer to the
+    ## capture groups of the regex for replace.
+    target_label = "__name__"
+
+    ## Replacement for the replace and labelmap actions; may refer to the
+    ## capture groups of the regex.
+    replacement = "${1}_errors"
+
+    ## Modulus for the hashmod action.
+    # modulus = 0
+`
+
+type Relabel struct {
+	Rules []*relabel.Config `toml:"rule"`
+}
+
+func (*Relabel) SampleConfig() string {
+	return sampleConfig
+}
+
+func (*Relabel) Description() string {
+	return "Relabel tags and measurement names using Prometheus relabelling rules"
+}
+
+func (r *Relabel) Init() error {
+	if len(r.Rules) == 0 {
+		return fmt.Errorf("no rules configured")
+	}
+	for i, rule := range r.Rules {
+		if err := rule.Init(); err != nil {
+			return fmt.Errorf("rule %d: %w", i+1, err)
+		}
+	}
+	return nil
+}
+
+func (r *Relabel) Apply(in ...telegraf.Metric) []telegraf.Metric {
+	out := in[:0]
+	for _, m := range in {
+		if !relabel.ApplyMetric(r.Rules, m) {
+			m.Drop()
+			continue
+		}
+		out = append(out, m)
+	}
+	return out
+}
+
+func init() {
+	processors.Add("relabel", func() telegraf.Processor {
+		return &Relabel{}
+	})
+}
@@ -0,0 +1,80 @@
+package relabel
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/plugins/common/relabel"
+	"github.com/influxdata/telegraf/testutil"
+)
+
+func str(s string) *string {
+	return &s
+}
+
+func TestApply(t *testing.T) {
+	r := &Relabel{
+		Rules: []*relabel.Config{
+			{SourceLabels: []string{"__name__"}, Regex: str("go_.*"), Action: "drop"},
+			{SourceLabels: []string{"__name__", "code"}, Regex: str("(.+)_total;5.."), TargetLabel: "__name__", Replacement: str("${1}_errors")},
+			{Regex: str("__meta_(.+)"), Action: "labelmap"},
+			{Regex: str("__meta_.+"), Action: "labeldrop"},
+			{SourceLabels: []string{"instance"}, TargetLabel: "shard", Modulus: 4, Action: "hashmod"},
+		},
+	}
+	require.NoError(t, r.Init())
+
+	input := []telegraf.Metric{
+		testutil.MustMetric(
+			"http_requests_total",
+			map[string]string{"code": "503", "instance": "10.0.0.1:9100", "__meta_dc": "fra"},
+			map[string]interface{}{"counter": 3.0},
+			time.Unix(0, 0),
+		),
+		testutil.MustMetric(
+			"http_requests_total",
+			map[string]string{"code": "200", "instance": "10.0.0.2:9100"},
+			map[string]interface{}{"counter": 42.0},
+			time.Unix(0, 0),
+		),
+		testutil.MustMetric(
+			"go_goroutines",
+			map[string]string{"instance": "10.0.0.1:9100"},
+			map[string]interface{}{"gauge": 15.0},
+			time.Unix(0, 0),
+		),
+	}
+	expected := []telegraf.Metric{
+		testutil.MustMetric(
+			"http_requests_errors",
+			map[string]string{"code": "503", "instance": "10.0.0.1:9100", "dc": "fra", "shard": "1"},
+			map[string]interface{}{"counter": 3.0},
+			time.Unix(0, 0),
+		),
+		testutil.MustMetric(
+			"http_requests_total",
+			map[string]string{"code": "200", "instance": "10.0.0.2:9100", "shard": "1"},
+			map[string]interface{}{"counter": 42.0},
+			time.Unix(0, 0),
+		),
+	}
+
+	actual := r.Apply(input...)
+	testutil.RequireMetricsEqual(t, expected, actual)
+}
+
+func TestInitErrors(t *testing.T) {
+	r := &Relabel{}
+	require.EqualError(t, r.Init(), "no rules configured")
+
+	r = &Relabel{
+		Rules: []*relabel.Config{
+			{Regex: str("a"), Action: "labeldrop"},
+			{Action: "hashmod", TargetLabel: "shard"},
+		},
+	}
+	require.EqualError(t, r.Init(), `rule 2: action "hashmod" requires a non-zero modulus`)
+}
@@ -0,0 +1,182 @@
+package threshold
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/config"
+	"github.com/influxdata/telegraf/filter"
+)
+
+// State of a series
+type State int
+
+const (
+	StateOK State = iota
+	StateWarn
+	StateCrit
+)
+
+func (s State) String() string {
+	switch s {
+	case StateWarn:
+		return "warn"
+	case StateCrit:
+		return "crit"
+	}
+	return "ok"
+}
+
+func parseState(s string) (State, error) {
+	switch s {
+	case "ok":
+		return StateOK, nil
+	case "warn":
+		return StateWarn, nil
+	case "crit":
+		return StateCrit, nil
+	}
+	return StateOK, fmt.Errorf("invalid state %q", s)
+}
+
+type Rule struct {
+	Name         string          `toml:"name"`
+	Measurement  []string        `toml:"measurement"`
+	Field        string          `toml:"field"`
+	GroupBy      []string        `toml:"group_by"`
+	Type         string          `toml:"type"`
+	Direction    string          `toml:"direction"`
+	Warn         *float64        `toml:"warn"`
+	Crit         *float64        `toml:"crit"`
+	Hysteresis   float64         `toml:"hysteresis"`
+	Window       config.Duration `toml:"window"`
+	DeadmanState string          `toml:"deadman_state"`
+
+	measurement  filter.Filter
+	deadmanState State
+}
+
+func (r *Rule) init() error {
+	if r.Name == "" {
+		return fmt.Errorf("rule name must be specified")
+	}
+
+	var err error
+	if r.measurement, err = filter.Compile(r.Measurement); err != nil {
+		return fmt.Errorf("rule %q: compiling measurement filter failed: %w", r.Name, err)
+	}
+
+	switch r.Type {
+	case "":
+		r.Type = "threshold"
+		fallthrough
+	case "threshold", "rate":
+		if r.Field == "" {
+			return fmt.Errorf("rule %q: field must be specified", r.Name)
+		}
+		if r.Warn == nil && r.Crit == nil {
+			return fmt.Errorf("rule %q: either warn or crit threshold must be specified", r.Name)
+		}
+		if r.Hysteresis < 0 {
+			return fmt.Errorf("rule %q: hysteresis must not be negative", r.Name)
+		}
+	case "deadman":
+		if r.Window <= 0 {
+			return fmt.Errorf("rule %q: window must be positive", r.Name)
+		}
+		if r.DeadmanState == "" {
+			r.DeadmanState = "crit"
+		}
+		if r.deadmanState, err = parseState(r.DeadmanState); err != nil {
+			return fmt.Errorf("rule %q: %w", r.Name, err)
+		}
+		if r.deadmanState == StateOK {
+			return fmt.Errorf("rule %q: deadman state must be warn or crit", r.Name)
+		}
+	default:
+		return fmt.Errorf("rule %q: invalid type %q", r.Name, r.Type)
+	}
+
+	switch r.Direction {
+	case "":
+		r.Direction = "above"
+	case "above", "below":
+	default:
+		return fmt.Errorf("rule %q: invalid direction %q", r.Name, r.Direction)
+	}
+
+	if r.Warn != nil && r.Crit != nil {
+		if (r.Direction == "above" && *r.Warn > *r.Crit) || (r.Direction == "below" && *r.Warn < *r.Crit) {
+			return fmt.Errorf("rule %q: warn threshold is beyond the crit threshold", r.Name)
+		}
+	}
+
+	return nil
+}
+
+// matches returns if the metric is evaluated by the rule
+func (r *Rule) matches(m telegraf.Metric) bool {
+	if r.measurement != nil && !r.measurement.Match(m.Name()) {
+		return false
+	}
+	if r.Type == "deadman" && r.Field == "" {
+		return true
+	}
+	return m.HasField(r.Field)
+}
+
+// seriesTags returns the tags identifying the series of the metric
+func (r *Rule) seriesTags(m telegraf.Metric) map[string]string {
+	if len(r.GroupBy) == 0 {
+		return m.Tags()
+	}
+	tags := make(map[string]string, len(r.GroupBy))
+	for _, key := range r.GroupBy {
+		if v, found := m.GetTag(key); found {
+			tags[key] = v
+		}
+	}
+	return tags
+}
+
+// evaluate returns the state for the value given the current state. A state
+// is left only when the value crosses its threshold by the hysteresis.
+func (r *Rule) evaluate(value float64, current State) State {
+	if r.exceeds(value, r.Crit, current >= StateCrit) {
+		return StateCrit
+	}
+	if r.exceeds(value, r.Warn, current >= StateWarn) {
+		return StateWarn
+	}
+	return StateOK
+}
+
+func (r *Rule) exceeds(value float64, threshold *float64, active bool) bool {
+	if threshold == nil {
+		return false
+	}
+	limit := *threshold
+	if r.Direction == "below" {
+		if active {
+			limit += r.Hysteresis
+		}
+		return value <= limit
+	}
+	if active {
+		limit -= r.Hysteresis
+	}
+	return value >= limit
+}
+
+// series holds the state of a series of a rule
+type series struct {
+	rule        *Rule
+	measurement string
+	tags        map[string]string
+	state       State
+	lastSeen    time.Time
+	lastValue   float64
+	lastTime    time.Time
+	hasPrevious bool
+}
@@ -0,0 +1,289 @@
+package threshold
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/config"
+	"github.com/influxdata/telegraf/metric"
+	"github.com/influxdata/telegraf/plugins/processors"
+)
+
+const sampleConfig = `
+  ## Name of the state-change event metrics.
+  # event_measurement = "alert"
+
+  ## Interval to check deadman rules and to expire series.
+  # check_interval = "10s"
+
+  ## Series without data for this duration are forgotten. Series of deadman
+  ## rules are never forgotten.
+  # series_timeout = "1h"
+
+  [[processors.threshold.rule]]
+    ## Name of the rule, added to the events as "rule" tag.
+    name = "cpu_usage"
+
+    ## Measurements to evaluate, globs accepted. All measurements if empty.
+    measurement = ["cpu"]
+
+    ## Field to evaluate. Optional for deadman rules.
+    field = "usage_user"
+
+    ## Tags identifying a series; all tags if empty.
+    # group_by = ["host"]
+
+    ## Type of the rule, one of
+    ##   threshold -- compare the field value against the thresholds
+    ##   rate      -- compare the change of the field value per second
+    ##   deadman   -- enter the deadman_state if a series has no data for the
+    ##                window duration
+    # type = "threshold"
+
+    ## Thresholds for the warn and crit states and whether values "above" or
+    ## "below" the thresholds are in the state.
+    warn = 80.0
+    crit = 90.0
+    # direction = "above"
+
+    ## Amount the value has to cross back over a threshold to leave the state.
+    # hysteresis = 0.0
+
+    ## Window and state of deadman rules.
+    # window = "5m"
+    # deadman_state = "crit"
+`
+
+type Threshold struct {
+	EventMeasurement string          `toml:"event_measurement"`
+	CheckInterval    config.Duration `toml:"check_interval"`
+	SeriesTimeout    config.Duration `toml:"series_timeout"`
+	Rules            []*Rule         `toml:"rule"`
+	Log              telegraf.Logger `toml:"-"`
+
+	series map[string]*series
+	acc    telegraf.Accumulator
+	cancel chan struct{}
+	wg     sync.WaitGroup
+	sync.Mutex
+
+	// now is used for deadman and expiry checks
+	now func() time.Time
+}
+
+func (*Threshold) SampleConfig() string {
+	return sampleConfig
+}
+
+func (*Threshold) Description() string {
+	return "Evaluate threshold, rate and deadman rules and emit events on state changes"
+}
+
+func (t *Threshold) Init() error {
+	if len(t.Rules) == 0 {
+		return fmt.Errorf("no rules specified")
+	}
+
+	names := make(map[string]bool, len(t.Rules))
+	for _, r := range t.Rules {
+		if err := r.init(); err != nil {
+			return err
+		}
+		if names[r.Name] {
+			return fmt.Errorf("duplicate rule name %q", r.Name)
+		}
+		names[r.Name] = true
+	}
+
+	if t.CheckInterval <= 0 {
+		return fmt.Errorf("check_interval must be positive")
+	}
+
+	if t.now == nil {
+		t.now = time.Now
+	}
+	t.series = make(map[string]*series)
+
+	return nil
+}
+
+func (t *Threshold) Start(acc telegraf.Accumulator) error {
+	t.acc = acc
+	t.cancel = make(chan struct{})
+
+	t.wg.Add(1)
+	go func() {
+		defer t.wg.Done()
+		ticker := time.NewTicker(time.Duration(t.CheckInterval))
+		defer ticker.Stop()
+		for {
+			select {
+			case <-t.cancel:
+				return
+			case <-ticker.C:
+				t.check()
+			}
+		}
+	}()
+
+	return nil
+}
+
+func (t *Threshold) Add(m telegraf.Metric, acc telegraf.Accumulator) error {
+	t.Lock()
+	defer t.Unlock()
+
+	acc.AddMetric(m)
+
+	now := t.now()
+	for _, r := range t.Rules {
+		if !r.matches(m) {
+			continue
+		}
+
+		tags := r.seriesTags(m)
+		key := seriesKey(r.Name, m.Name(), tags)
+		s, found := t.series[key]
+		if !found {
+			s = &series{rule: r, measurement: m.Name(), tags: tags}
+			t.series[key] = s
+		}
+		s.lastSeen = now
+
+		if r.Type == "deadman" {
+			// Any data recovers the series
+			t.transition(acc, s, StateOK, nil, m.Time())
+			continue
+		}
+
+		fv, _ := m.GetField(r.Field)
+		value, ok := toFloat(fv)
+		if !ok {
+			t.Log.Debugf("Rule %q: field %q of %q has unsupported type %T", r.Name, r.Field, m.Name(), fv)
+			continue
+		}
+
+		if r.Type == "rate" {
+			previous, previousTime, hasPrevious := s.lastValue, s.lastTime, s.hasPrevious
+			s.lastValue, s.lastTime, s.hasPrevious = value, m.Time(), true
+
+			elapsed := m.Time().Sub(previousTime).Seconds()
+			if !hasPrevious || elapsed <= 0 {
+				continue
+			}
+			value = (value - previous) / elapsed
+		}
+
+		t.transition(acc, s, r.evaluate(value, s.state), &value, m.Time())
+	}
+
+	return nil
+}
+
+func (t *Threshold) Stop() error {
+	close(t.cancel)
+	t.wg.Wait()
+	return nil
+}
+
+// check moves series of deadman rules without data within the window into
+// the deadman state and forgets series without data for the series timeout
+func (t *Threshold) check() {
+	t.Lock()
+	defer t.Unlock()
+
+	now := t.now()
+	for key, s := range t.series {
+		idle := now.Sub(s.lastSeen)
+		if s.rule.Type == "deadman" {
+			if idle >= time.Duration(s.rule.Window) {
+				t.transition(t.acc, s, s.rule.deadmanState, nil, now)
+			}
+			continue
+		}
+		if t.SeriesTimeout > 0 && idle >= time.Duration(t.SeriesTimeout) {
+			delete(t.series, key)
+		}
+	}
+}
+
+// transition sets the state of the series and emits an event if it changed
+func (t *Threshold) transition(acc telegraf.Accumulator, s *series, state State, value *float64, ts time.Time) {
+	if state == s.state {
+		return
+	}
+	previous := s.state
+	s.state = state
+
+	tags := make(map[string]string, len(s.tags)+4)
+	for k, v := range s.tags {
+		tags[k] = v
+	}
+	tags["rule"] = s.rule.Name
+	tags["measurement"] = s.measurement
+	tags["state"] = state.String()
+	if s.rule.Field != "" {
+		tags["field"] = s.rule.Field
+	}
+
+	fields := map[string]interface{}{
+		"state_code":     int64(state),
+		"previous_state": previous.String(),
+	}
+	if value != nil {
+		fields["value"] = *value
+	}
+
+	acc.AddMetric(metric.New(t.EventMeasurement, tags, fields, ts))
+}
+
+func seriesKey(rule, measurement string, tags map[string]string) string {
+	keys := make([]string, 0, len(tags))
+	for k := range tags {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var b strings.Builder
+	b.WriteString(rule)
+	b.WriteByte(0)
+	b.WriteString(measurement)
+	for _, k := range keys {
+		b.WriteByte(0)
+		b.WriteString(k)
+		b.WriteByte('=')
+		b.WriteString(tags[k])
+	}
+	return b.String()
+}
+
+func toFloat(value interface{}) (float64, bool) {
+	switch v := value.(type) {
+	case int64:
+		return float64(v), true
+	case uint64:
+		return float64(v), true
+	case float64:
+		return v, true
+	case bool:
+		if v {
+			return 1, true
+		}
+		return 0, true
+	}
+	return 0, false
+}
+
+func init() {
+	processors.AddStreaming("threshold", func() telegraf.StreamingProcessor {
+		return &Threshold{
+			EventMeasurement: "alert",
+			CheckInterval:    config.Duration(10 * time.Second),
+			SeriesTimeout:    config.Duration(time.Hour),
+		}
+	})
+}
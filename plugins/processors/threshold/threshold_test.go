@@ -0,0 +1,232 @@
+package threshold
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/config"
+	"github.com/influxdata/telegraf/metric"
+	"github.com/influxdata/telegraf/testutil"
+)
+
+var ts = time.Unix(1650000000, 0)
+
+func float(v float64) *float64 {
+	return &v
+}
+
+func newThreshold(t *testing.T, rules ...*Rule) *Threshold {
+	plugin := &Threshold{
+		EventMeasurement: "alert",
+		CheckInterval:    config.Duration(10 * time.Second),
+		SeriesTimeout:    config.Duration(time.Hour),
+		Rules:            rules,
+		Log:              testutil.Logger{},
+	}
+	require.NoError(t, plugin.Init())
+	return plugin
+}
+
+// events returns the state-change metrics emitted so far
+func events(acc *testutil.Accumulator) []telegraf.Metric {
+	var result []telegraf.Metric
+	for _, m := range acc.GetTelegrafMetrics() {
+		if m.Name() == "alert" {
+			result = append(result, m)
+		}
+	}
+	return result
+}
+
+func event(host, state, previous string, value float64, tm time.Time) telegraf.Metric {
+	return metric.New(
+		"alert",
+		map[string]string{"host": host, "rule": "cpu_usage", "measurement": "cpu", "field": "usage", "state": state},
+		map[string]interface{}{"state_code": int64(map[string]int{"ok": 0, "warn": 1, "crit": 2}[state]), "previous_state": previous, "value": value},
+		tm,
+	)
+}
+
+func TestThresholdHysteresis(t *testing.T) {
+	plugin := newThreshold(t, &Rule{
+		Name:        "cpu_usage",
+		Measurement: []string{"cpu"},
+		Field:       "usage",
+		Warn:        float(80),
+		Crit:        float(90),
+		Hysteresis:  5,
+	})
+
+	acc := &testutil.Accumulator{}
+	values := []float64{50, 85, 88, 95, 87, 84, 79, 76, 74}
+	for i, v := range values {
+		m := metric.New("cpu", map[string]string{"host": "a"}, map[string]interface{}{"usage": v}, ts.Add(time.Duration(i)*time.Second))
+		require.NoError(t, plugin.Add(m, acc))
+	}
+
+	// All metrics pass through
+	require.Len(t, acc.GetTelegrafMetrics(), len(values)+4)
+
+	expected := []telegraf.Metric{
+		event("a", "warn", "ok", 85, ts.Add(1*time.Second)),
+		event("a", "crit", "warn", 95, ts.Add(3*time.Second)),
+		event("a", "warn", "crit", 84, ts.Add(5*time.Second)),
+		event("a", "ok", "warn", 74, ts.Add(8*time.Second)),
+	}
+	testutil.RequireMetricsEqual(t, expected, events(acc))
+}
+
+func TestThresholdSeries(t *testing.T) {
+	plugin := newThreshold(t, &Rule{
+		Name:      "cpu_usage",
+		Field:     "usage",
+		GroupBy:   []string{"host"},
+		Direction: "below",
+		Crit:      float(10),
+	})
+
+	acc := &testutil.Accumulator{}
+	input := []telegraf.Metric{
+		metric.New("cpu", map[string]string{"host": "a", "cpu": "cpu0"}, map[string]interface{}{"usage": 5.0}, ts),
+		metric.New("cpu", map[string]string{"host": "b", "cpu": "cpu0"}, map[string]interface{}{"usage": 50.0}, ts),
+		metric.New("cpu", map[string]string{"host": "a", "cpu": "cpu1"}, map[string]interface{}{"usage": int64(7)}, ts),
+		metric.New("cpu", map[string]string{"host": "b", "cpu": "cpu1"}, map[string]interface{}{"usage": "text"}, ts),
+		metric.New("mem", map[string]string{"host": "b"}, map[string]interface{}{"free": 1.0}, ts),
+	}
+	for _, m := range input {
+		require.NoError(t, plugin.Add(m, acc))
+	}
+
+	expected := []telegraf.Metric{
+		metric.New(
+			"alert",
+			map[string]string{"host": "a", "rule": "cpu_usage", "measurement": "cpu", "field": "usage", "state": "crit"},
+			map[string]interface{}{"state_code": int64(2), "previous_state": "ok", "value": 5.0},
+			ts,
+		),
+	}
+	testutil.RequireMetricsEqual(t, expected, events(acc))
+}
+
+func TestRate(t *testing.T) {
+	plugin := newThreshold(t, &Rule{
+		Name:  "cpu_usage",
+		Field: "usage",
+		Type:  "rate",
+		Warn:  float(10),
+	})
+
+	acc := &testutil.Accumulator{}
+	// Rates of 5/s, 20/s and 0/s
+	for i, v := range []float64{0, 10, 50, 50} {
+		m := metric.New("cpu", map[string]string{"host": "a"}, map[string]interface{}{"usage": v}, ts.Add(time.Duration(i*2)*time.Second))
+		require.NoError(t, plugin.Add(m, acc))
+	}
+
+	expected := []telegraf.Metric{
+		event("a", "warn", "ok", 20, ts.Add(4*time.Second)),
+		event("a", "ok", "warn", 0, ts.Add(6*time.Second)),
+	}
+	testutil.RequireMetricsEqual(t, expected, events(acc))
+}
+
+func TestDeadman(t *testing.T) {
+	plugin := newThreshold(t, &Rule{
+		Name:         "heartbeat",
+		Measurement:  []string{"system"},
+		GroupBy:      []string{"host"},
+		Type:         "deadman",
+		Window:       config.Duration(time.Minute),
+		DeadmanState: "warn",
+	})
+	now := ts
+	plugin.now = func() time.Time { return now }
+
+	acc := &testutil.Accumulator{}
+	plugin.acc = acc
+
+	m := metric.New("system", map[string]string{"host": "a"}, map[string]interface{}{"uptime": int64(1)}, ts)
+	require.NoError(t, plugin.Add(m, acc))
+
+	now = now.Add(30 * time.Second)
+	plugin.check()
+	require.Empty(t, events(acc))
+
+	now = now.Add(30 * time.Second)
+	plugin.check()
+	plugin.check()
+
+	m = metric.New("system", map[string]string{"host": "a"}, map[string]interface{}{"uptime": int64(61)}, now)
+	require.NoError(t, plugin.Add(m, acc))
+
+	expected := []telegraf.Metric{
+		metric.New(
+			"alert",
+			map[string]string{"host": "a", "rule": "heartbeat", "measurement": "system", "state": "warn"},
+			map[string]interface{}{"state_code": int64(1), "previous_state": "ok"},
+			now,
+		),
+		metric.New(
+			"alert",
+			map[string]string{"host": "a", "rule": "heartbeat", "measurement": "system", "state": "ok"},
+			map[string]interface{}{"state_code": int64(0), "previous_state": "warn"},
+			now,
+		),
+	}
+	testutil.RequireMetricsEqual(t, expected, events(acc))
+}
+
+func TestSeriesTimeout(t *testing.T) {
+	plugin := newThreshold(t, &Rule{Name: "cpu_usage", Field: "usage", Warn: float(80)})
+	now := ts
+	plugin.now = func() time.Time { return now }
+
+	acc := &testutil.Accumulator{}
+	plugin.acc = acc
+	m := metric.New("cpu", map[string]string{"host": "a"}, map[string]interface{}{"usage": 1.0}, ts)
+	require.NoError(t, plugin.Add(m, acc))
+	require.Len(t, plugin.series, 1)
+
+	now = now.Add(time.Hour)
+	plugin.check()
+	require.Empty(t, plugin.series)
+}
+
+func TestStartStop(t *testing.T) {
+	plugin := newThreshold(t, &Rule{Name: "cpu_usage", Field: "usage", Warn: float(80)})
+	acc := &testutil.Accumulator{}
+	require.NoError(t, plugin.Start(acc))
+
+	m := metric.New("cpu", map[string]string{"host": "a"}, map[string]interface{}{"usage": 90.0}, ts)
+	require.NoError(t, plugin.Add(m, acc))
+	require.NoError(t, plugin.Stop())
+	require.Len(t, events(acc), 1)
+}
+
+func TestInitErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		rules []*Rule
+	}{
+		{"no rules", nil},
+		{"no name", []*Rule{{Field: "usage", Warn: float(1)}}},
+		{"no field", []*Rule{{Name: "a", Warn: float(1)}}},
+		{"no thresholds", []*Rule{{Name: "a", Field: "usage"}}},
+		{"invalid type", []*Rule{{Name: "a", Field: "usage", Warn: float(1), Type: "average"}}},
+		{"invalid direction", []*Rule{{Name: "a", Field: "usage", Warn: float(1), Direction: "up"}}},
+		{"warn beyond crit", []*Rule{{Name: "a", Field: "usage", Warn: float(2), Crit: float(1)}}},
+		{"negative hysteresis", []*Rule{{Name: "a", Field: "usage", Warn: float(1), Hysteresis: -1}}},
+		{"deadman without window", []*Rule{{Name: "a", Type: "deadman"}}},
+		{"deadman ok state", []*Rule{{Name: "a", Type: "deadman", Window: config.Duration(time.Minute), DeadmanState: "ok"}}},
+		{"duplicate name", []*Rule{{Name: "a", Field: "usage", Warn: float(1)}, {Name: "a", Field: "usage", Warn: float(1)}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			plugin := &Threshold{CheckInterval: config.Duration(time.Second), Rules: tt.rules}
+			require.Error(t, plugin.Init())
+		})
+	}
+}
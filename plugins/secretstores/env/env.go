@@ -0,0 +1,69 @@
+package env
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/plugins/secretstores"
+)
+
+const sampleConfig = `
+  ## Unique identifier for the secret-store.
+  ## This ID is used when referencing secrets, e.g. "@{env_secrets:password}".
+  id = "env_secrets"
+
+  ## Prefix prepended to the key to form the name of the environment variable.
+  ## Keys are case-sensitive; with prefix "TELEGRAF_" the secret "PASSWORD"
+  ## is read from the variable "TELEGRAF_PASSWORD".
+  # prefix = ""
+`
+
+// Environment provides secrets from environment variables of the process.
+type Environment struct {
+	Prefix string `toml:"prefix"`
+}
+
+func (e *Environment) SampleConfig() string {
+	return sampleConfig
+}
+
+func (e *Environment) Description() string {
+	return "Read secrets from environment variables"
+}
+
+// Get returns the value of the environment variable for the key.
+func (e *Environment) Get(key string) ([]byte, error) {
+	value, found := os.LookupEnv(e.Prefix + key)
+	if !found {
+		return nil, fmt.Errorf("environment variable %q not set", e.Prefix+key)
+	}
+	return []byte(value), nil
+}
+
+// Set is not supported as the environment of the process is read-only.
+func (e *Environment) Set(_, _ string) error {
+	return errors.New("setting secrets is not supported by this store")
+}
+
+// List returns the keys of all environment variables matching the prefix.
+func (e *Environment) List() ([]string, error) {
+	var keys []string
+	for _, kv := range os.Environ() {
+		name := strings.SplitN(kv, "=", 2)[0]
+		if strings.HasPrefix(name, e.Prefix) {
+			keys = append(keys, strings.TrimPrefix(name, e.Prefix))
+		}
+	}
+	sort.Strings(keys)
+	return keys, nil
+}
+
+func init() {
+	secretstores.Add("env", func() telegraf.SecretStore {
+		return &Environment{}
+	})
+}
@@ -0,0 +1,41 @@
+package env
+
+import (
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestGet(t *testing.T) {
+	t.Setenv("TELEGRAF_TEST_PASSWORD", "secret")
+
+	store := &Environment{Prefix: "TELEGRAF_TEST_"}
+	secret, err := store.Get("PASSWORD")
+	require.NoError(t, err)
+	require.Equal(t, "secret", string(secret))
+
+	_, err = store.Get("UNKNOWN")
+	require.EqualError(t, err, `environment variable "TELEGRAF_TEST_UNKNOWN" not set`)
+}
+
+func TestList(t *testing.T) {
+	t.Setenv("TELEGRAF_TEST_B", "b")
+	t.Setenv("TELEGRAF_TEST_A", "a")
+
+	store := &Environment{Prefix: "TELEGRAF_TEST_"}
+	keys, err := store.List()
+	require.NoError(t, err)
+	require.Equal(t, []string{"A", "B"}, keys)
+}
+
+func TestGetCaseSensitive(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("Environment variables are case-insensitive on Windows")
+	}
+	t.Setenv("TELEGRAF_TEST_PASSWORD", "secret")
+
+	store := &Environment{Prefix: "TELEGRAF_TEST_"}
+	_, err := store.Get("password")
+	require.EqualError(t, err, `environment variable "TELEGRAF_TEST_password" not set`)
+}
@@ -0,0 +1,111 @@
+package exec
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	osExec "os/exec"
+	"strings"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/config"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/plugins/secretstores"
+)
+
+const sampleConfig = `
+  ## Unique identifier for the secret-store.
+  ## This ID is used when referencing secrets, e.g. "@{helper:password}".
+  id = "helper"
+
+  ## Command of the helper program including its arguments.  The operation
+  ## and key are appended to the arguments:
+  ##   <command> get <key>   secret is returned on stdout
+  ##   <command> set <key>   secret is passed on stdin
+  ##   <command> list        keys are returned on stdout, one per line
+  command = ["/usr/local/bin/telegraf-secrets"]
+
+  ## Timeout for a single call of the helper.
+  # timeout = "5s"
+`
+
+// Exec requests secrets from an external helper program.
+type Exec struct {
+	Command []string        `toml:"command"`
+	Timeout config.Duration `toml:"timeout"`
+}
+
+func (e *Exec) SampleConfig() string {
+	return sampleConfig
+}
+
+func (e *Exec) Description() string {
+	return "Request secrets from an external helper program"
+}
+
+func (e *Exec) Init() error {
+	if len(e.Command) == 0 {
+		return errors.New("no command specified")
+	}
+	if e.Timeout <= 0 {
+		e.Timeout = config.Duration(5 * time.Second)
+	}
+	return nil
+}
+
+// Get returns the output of the helper for the key.  A single trailing
+// newline is removed.
+func (e *Exec) Get(key string) ([]byte, error) {
+	out, err := e.run(nil, "get", key)
+	if err != nil {
+		return nil, err
+	}
+
+	secret := bytes.TrimSuffix(out, []byte("\n"))
+	secret = bytes.TrimSuffix(secret, []byte("\r"))
+	return secret, nil
+}
+
+// Set passes the secret to the helper on stdin.
+func (e *Exec) Set(key, value string) error {
+	_, err := e.run(strings.NewReader(value), "set", key)
+	return err
+}
+
+// List returns the keys reported by the helper.
+func (e *Exec) List() ([]string, error) {
+	out, err := e.run(nil, "list")
+	if err != nil {
+		return nil, err
+	}
+
+	var keys []string
+	for _, line := range strings.Split(string(out), "\n") {
+		if key := strings.TrimSpace(line); key != "" {
+			keys = append(keys, key)
+		}
+	}
+	return keys, nil
+}
+
+func (e *Exec) run(stdin *strings.Reader, args ...string) ([]byte, error) {
+	args = append(append([]string{}, e.Command[1:]...), args...)
+	cmd := osExec.Command(e.Command[0], args...)
+	if stdin != nil {
+		cmd.Stdin = stdin
+	}
+
+	out, err := internal.StdOutputTimeout(cmd, time.Duration(e.Timeout))
+	if err != nil {
+		// Do not include the output, it might contain parts of the secret
+		return nil, fmt.Errorf("running helper %q failed: %w", e.Command[0], err)
+	}
+	return out, nil
+}
+
+func init() {
+	secretstores.Add("exec", func() telegraf.SecretStore {
+		return &Exec{}
+	})
+}
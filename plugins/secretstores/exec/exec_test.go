@@ -0,0 +1,63 @@
+//go:build !windows
+// +build !windows
+
+package exec
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+const helper = `#!/bin/sh
+case "$1" in
+  get)  [ "$2" = "password" ] && echo "secret" && exit 0; exit 1 ;;
+  list) printf "password\ntoken\n" ;;
+  set)  cat > "$(dirname "$0")/$2" ;;
+esac
+`
+
+func newHelper(t *testing.T) string {
+	fn := filepath.Join(t.TempDir(), "helper.sh")
+	require.NoError(t, os.WriteFile(fn, []byte(helper), 0700))
+	return fn
+}
+
+func TestGet(t *testing.T) {
+	store := &Exec{Command: []string{newHelper(t)}}
+	require.NoError(t, store.Init())
+
+	secret, err := store.Get("password")
+	require.NoError(t, err)
+	require.Equal(t, "secret", string(secret))
+
+	_, err = store.Get("unknown")
+	require.Error(t, err)
+}
+
+func TestList(t *testing.T) {
+	store := &Exec{Command: []string{newHelper(t)}}
+	require.NoError(t, store.Init())
+
+	keys, err := store.List()
+	require.NoError(t, err)
+	require.Equal(t, []string{"password", "token"}, keys)
+}
+
+func TestSet(t *testing.T) {
+	fn := newHelper(t)
+	store := &Exec{Command: []string{fn}}
+	require.NoError(t, store.Init())
+
+	require.NoError(t, store.Set("token", "value"))
+	buf, err := os.ReadFile(filepath.Join(filepath.Dir(fn), "token"))
+	require.NoError(t, err)
+	require.Equal(t, "value", string(buf))
+}
+
+func TestInitNoCommand(t *testing.T) {
+	store := &Exec{}
+	require.EqualError(t, store.Init(), "no command specified")
+}
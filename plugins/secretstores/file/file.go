@@ -0,0 +1,258 @@
+package file
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+
+	"golang.org/x/crypto/scrypt"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/plugins/secretstores"
+)
+
+const sampleConfig = `
+  ## Unique identifier for the secret-store.
+  ## This ID is used when referencing secrets, e.g. "@{vault:password}".
+  id = "vault"
+
+  ## File holding the encrypted secrets, the file is created when the first
+  ## secret is set.
+  path = "/etc/telegraf/secrets.json"
+
+  ## Password used to encrypt the file.  Use an environment variable to avoid
+  ## storing the password in the configuration.
+  password = "$TELEGRAF_SECRETS_PASSWORD"
+`
+
+// Parameters for the key derivation, see the scrypt documentation.
+const (
+	scryptN       = 32768
+	scryptR       = 8
+	scryptP       = 1
+	keyLength     = 32
+	saltLength    = 16
+	formatVersion = 1
+)
+
+// container is the on-disk format of the store.
+type container struct {
+	Version int    `json:"version"`
+	Salt    []byte `json:"salt"`
+	Nonce   []byte `json:"nonce"`
+	Data    []byte `json:"data"`
+}
+
+// File keeps secrets in an AES-GCM encrypted file.  The file is decrypted
+// each time a secret is requested, only the derived key is kept in memory.
+type File struct {
+	Path     string `toml:"path"`
+	Password string `toml:"password"`
+
+	sync.Mutex
+	salt []byte
+	key  []byte
+}
+
+func (f *File) SampleConfig() string {
+	return sampleConfig
+}
+
+func (f *File) Description() string {
+	return "Read secrets from a password-encrypted file"
+}
+
+func (f *File) Init() error {
+	if f.Path == "" {
+		return errors.New("path not specified")
+	}
+	if f.Password == "" {
+		return errors.New("password not specified")
+	}
+
+	// Check the password early if the file exists already
+	secrets, err := f.read()
+	if err != nil {
+		return err
+	}
+	release(secrets)
+
+	return nil
+}
+
+// Get returns the secret for the key.
+func (f *File) Get(key string) ([]byte, error) {
+	f.Lock()
+	defer f.Unlock()
+
+	secrets, err := f.read()
+	if err != nil {
+		return nil, err
+	}
+	defer release(secrets)
+
+	value, found := secrets[key]
+	if !found {
+		return nil, fmt.Errorf("secret %q not found", key)
+	}
+	return append([]byte{}, value...), nil
+}
+
+// Set stores the secret for the key and rewrites the file.
+func (f *File) Set(key, value string) error {
+	f.Lock()
+	defer f.Unlock()
+
+	secrets, err := f.read()
+	if err != nil {
+		return err
+	}
+	defer release(secrets)
+
+	secrets[key] = []byte(value)
+	return f.write(secrets)
+}
+
+// List returns the keys of all secrets in the file.
+func (f *File) List() ([]string, error) {
+	f.Lock()
+	defer f.Unlock()
+
+	secrets, err := f.read()
+	if err != nil {
+		return nil, err
+	}
+	defer release(secrets)
+
+	keys := make([]string, 0, len(secrets))
+	for k := range secrets {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys, nil
+}
+
+// read decrypts the file, a missing file is treated as an empty store.
+func (f *File) read() (map[string][]byte, error) {
+	buf, err := os.ReadFile(f.Path)
+	if errors.Is(err, os.ErrNotExist) {
+		return make(map[string][]byte), nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var c container
+	if err := json.Unmarshal(buf, &c); err != nil {
+		return nil, fmt.Errorf("decoding %q failed: %w", f.Path, err)
+	}
+	if c.Version != formatVersion {
+		return nil, fmt.Errorf("unsupported format version %d", c.Version)
+	}
+
+	aead, err := f.cipher(c.Salt)
+	if err != nil {
+		return nil, err
+	}
+	plain, err := aead.Open(nil, c.Nonce, c.Data, nil)
+	if err != nil {
+		return nil, errors.New("decrypting secrets failed, wrong password?")
+	}
+	defer release(map[string][]byte{"": plain})
+
+	secrets := make(map[string][]byte)
+	if err := json.Unmarshal(plain, &secrets); err != nil {
+		return nil, fmt.Errorf("decoding secrets failed: %w", err)
+	}
+	return secrets, nil
+}
+
+// write encrypts the secrets with a new salt and nonce and atomically
+// replaces the file.
+func (f *File) write(secrets map[string][]byte) error {
+	plain, err := json.Marshal(secrets)
+	if err != nil {
+		return err
+	}
+	defer release(map[string][]byte{"": plain})
+
+	c := container{
+		Version: formatVersion,
+		Salt:    make([]byte, saltLength),
+	}
+	if _, err := rand.Read(c.Salt); err != nil {
+		return err
+	}
+
+	aead, err := f.cipher(c.Salt)
+	if err != nil {
+		return err
+	}
+	c.Nonce = make([]byte, aead.NonceSize())
+	if _, err := rand.Read(c.Nonce); err != nil {
+		return err
+	}
+	c.Data = aead.Seal(nil, c.Nonce, plain, nil)
+
+	buf, err := json.Marshal(c)
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(f.Path), filepath.Base(f.Path)+".tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(buf); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), f.Path)
+}
+
+// cipher returns the AEAD for the given salt, the derived key is cached as
+// the derivation is intentionally expensive.
+func (f *File) cipher(salt []byte) (cipher.AEAD, error) {
+	if f.key == nil || !bytes.Equal(f.salt, salt) {
+		key, err := scrypt.Key([]byte(f.Password), salt, scryptN, scryptR, scryptP, keyLength)
+		if err != nil {
+			return nil, err
+		}
+		f.salt = salt
+		f.key = key
+	}
+
+	block, err := aes.NewCipher(f.key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// release overwrites all secrets in memory.
+func release(secrets map[string][]byte) {
+	for _, v := range secrets {
+		for i := range v {
+			v[i] = 0
+		}
+	}
+}
+
+func init() {
+	secretstores.Add("file", func() telegraf.SecretStore {
+		return &File{}
+	})
+}
@@ -0,0 +1,53 @@
+package file
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSetGet(t *testing.T) {
+	fn := filepath.Join(t.TempDir(), "secrets.json")
+
+	store := &File{Path: fn, Password: "password"}
+	require.NoError(t, store.Init())
+	require.NoError(t, store.Set("db", "secret"))
+	require.NoError(t, store.Set("token", "value"))
+
+	// The plain secret must not end up in the file
+	buf, err := os.ReadFile(fn)
+	require.NoError(t, err)
+	require.NotContains(t, string(buf), "secret")
+
+	store = &File{Path: fn, Password: "password"}
+	require.NoError(t, store.Init())
+
+	secret, err := store.Get("db")
+	require.NoError(t, err)
+	require.Equal(t, "secret", string(secret))
+
+	keys, err := store.List()
+	require.NoError(t, err)
+	require.Equal(t, []string{"db", "token"}, keys)
+
+	_, err = store.Get("unknown")
+	require.EqualError(t, err, `secret "unknown" not found`)
+}
+
+func TestWrongPassword(t *testing.T) {
+	fn := filepath.Join(t.TempDir(), "secrets.json")
+
+	store := &File{Path: fn, Password: "password"}
+	require.NoError(t, store.Init())
+	require.NoError(t, store.Set("db", "secret"))
+
+	store = &File{Path: fn, Password: "wrong"}
+	require.EqualError(t, store.Init(), "decrypting secrets failed, wrong password?")
+}
+
+func TestInitMissingOptions(t *testing.T) {
+	require.EqualError(t, (&File{Password: "password"}).Init(), "path not specified")
+	require.EqualError(t, (&File{Path: "secrets.json"}).Init(), "password not specified")
+}
@@ -0,0 +1,151 @@
+package keyring
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"sort"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/plugins/secretstores"
+)
+
+const sampleConfig = `
+  ## Unique identifier for the secret-store.
+  ## This ID is used when referencing secrets, e.g. "@{keyring:password}".
+  id = "keyring"
+
+  ## Directory of the keyring, defaults to "$HOME/.telegraf/keyring".
+  # directory = ""
+
+  ## Service name used to separate the secrets of multiple applications or
+  ## Telegraf instances sharing the keyring.
+  # service = "telegraf"
+`
+
+// keyRe restricts keys to names usable as file names on all platforms.
+var keyRe = regexp.MustCompile(`^[\w.\-]+$`)
+
+// Keyring is a stand-in for the keyring of the operating system.  Each
+// secret is kept in a separate file only accessible by the owner.
+type Keyring struct {
+	Directory string `toml:"directory"`
+	Service   string `toml:"service"`
+
+	path string
+}
+
+func (k *Keyring) SampleConfig() string {
+	return sampleConfig
+}
+
+func (k *Keyring) Description() string {
+	return "Read secrets from a file-based keyring of the current user"
+}
+
+func (k *Keyring) Init() error {
+	if k.Directory == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return fmt.Errorf("determining default directory: %w", err)
+		}
+		k.Directory = filepath.Join(home, ".telegraf", "keyring")
+	}
+	if k.Service == "" {
+		k.Service = "telegraf"
+	}
+	if !keyRe.MatchString(k.Service) {
+		return fmt.Errorf("invalid service name %q", k.Service)
+	}
+	k.path = filepath.Join(k.Directory, k.Service)
+
+	return nil
+}
+
+// Get returns the secret for the key.  Secrets readable by other users are
+// refused.
+func (k *Keyring) Get(key string) ([]byte, error) {
+	fn, err := k.filename(key)
+	if err != nil {
+		return nil, err
+	}
+
+	info, err := os.Stat(fn)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("secret %q not found", key)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := checkPermissions(info); err != nil {
+		return nil, fmt.Errorf("secret %q: %w", key, err)
+	}
+
+	return os.ReadFile(fn)
+}
+
+// Set stores the secret for the key.
+func (k *Keyring) Set(key, value string) error {
+	fn, err := k.filename(key)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(k.path, 0700); err != nil {
+		return err
+	}
+
+	tmp := fn + ".tmp"
+	if err := os.WriteFile(tmp, []byte(value), 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, fn)
+}
+
+// List returns the keys of all secrets of the service.
+func (k *Keyring) List() ([]string, error) {
+	entries, err := os.ReadDir(k.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var keys []string
+	for _, entry := range entries {
+		if entry.Type().IsRegular() && filepath.Ext(entry.Name()) != ".tmp" {
+			keys = append(keys, entry.Name())
+		}
+	}
+	sort.Strings(keys)
+	return keys, nil
+}
+
+func (k *Keyring) filename(key string) (string, error) {
+	if !keyRe.MatchString(key) || key == "." || key == ".." {
+		return "", fmt.Errorf("invalid key %q", key)
+	}
+	return filepath.Join(k.path, key), nil
+}
+
+// checkPermissions refuses files accessible by group or others, similar to
+// ssh private keys.  File modes are not meaningful on Windows.
+func checkPermissions(info os.FileInfo) error {
+	if runtime.GOOS == "windows" {
+		return nil
+	}
+	if perm := info.Mode().Perm(); perm&0077 != 0 {
+		return fmt.Errorf("permissions %#o are too open", perm)
+	}
+	return nil
+}
+
+func init() {
+	secretstores.Add("keyring", func() telegraf.SecretStore {
+		return &Keyring{}
+	})
+}
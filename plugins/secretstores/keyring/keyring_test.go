@@ -0,0 +1,56 @@
+package keyring
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSetGetList(t *testing.T) {
+	store := &Keyring{Directory: t.TempDir()}
+	require.NoError(t, store.Init())
+
+	keys, err := store.List()
+	require.NoError(t, err)
+	require.Empty(t, keys)
+
+	require.NoError(t, store.Set("password", "secret"))
+	require.NoError(t, store.Set("token", "value"))
+
+	secret, err := store.Get("password")
+	require.NoError(t, err)
+	require.Equal(t, "secret", string(secret))
+
+	keys, err = store.List()
+	require.NoError(t, err)
+	require.Equal(t, []string{"password", "token"}, keys)
+
+	_, err = store.Get("unknown")
+	require.EqualError(t, err, `secret "unknown" not found`)
+}
+
+func TestInvalidKey(t *testing.T) {
+	store := &Keyring{Directory: t.TempDir()}
+	require.NoError(t, store.Init())
+
+	require.EqualError(t, store.Set("../passwd", "secret"), `invalid key "../passwd"`)
+	_, err := store.Get("..")
+	require.EqualError(t, err, `invalid key ".."`)
+}
+
+func TestPermissionsTooOpen(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("Skipping test on windows")
+	}
+
+	store := &Keyring{Directory: t.TempDir()}
+	require.NoError(t, store.Init())
+	require.NoError(t, store.Set("password", "secret"))
+	require.NoError(t, os.Chmod(filepath.Join(store.path, "password"), 0644))
+
+	_, err := store.Get("password")
+	require.EqualError(t, err, `secret "password": permissions 0644 are too open`)
+}
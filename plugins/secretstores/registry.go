@@ -0,0 +1,13 @@
+package secretstores
+
+import (
+	"github.com/influxdata/telegraf"
+)
+
+type Creator func() telegraf.SecretStore
+
+var SecretStores = map[string]Creator{}
+
+func Add(name string, creator Creator) {
+	SecretStores[name] = creator
+}
@@ -0,0 +1,273 @@
+package avro
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/linkedin/goavro/v2"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/plugins/common/avro"
+)
+
+// Serializer encodes metrics as Avro records, optionally framed in the
+// Confluent wire format
+type Serializer struct {
+	Schema           string
+	SchemaRegistry   string
+	SchemaID         int
+	MeasurementField string
+	Timestamp        string
+	TimestampFormat  string
+
+	registry *avro.SchemaRegistry
+	codec    *goavro.Codec
+	fields   []recordField
+	sync.Mutex
+}
+
+// recordField describes a top-level field of the record schema with the
+// candidate types a value can be encoded as
+type recordField struct {
+	name  string
+	types []string
+}
+
+// NewSerializer creates an avro.Serializer
+func NewSerializer(s *Serializer) (*Serializer, error) {
+	switch {
+	case s.Schema == "" && s.SchemaRegistry == "":
+		return nil, errors.New("either `avro_schema` or `avro_schema_registry` must be specified")
+	case s.Schema != "" && s.SchemaRegistry != "":
+		return nil, errors.New("`avro_schema` and `avro_schema_registry` are mutually exclusive")
+	case s.SchemaRegistry != "" && s.SchemaID <= 0:
+		return nil, errors.New("`avro_schema_id` must be specified when using a schema registry")
+	case s.SchemaID < 0:
+		return nil, errors.New("`avro_schema_id` must not be negative")
+	}
+
+	switch s.TimestampFormat {
+	case "":
+		s.TimestampFormat = "unix"
+	case "unix", "unix_ms", "unix_us", "unix_ns":
+	default:
+		return nil, fmt.Errorf("invalid timestamp format %q", s.TimestampFormat)
+	}
+
+	if s.Schema != "" {
+		codec, err := goavro.NewCodec(s.Schema)
+		if err != nil {
+			return nil, fmt.Errorf("invalid schema: %w", err)
+		}
+		if err := s.setCodec(codec); err != nil {
+			return nil, err
+		}
+		return s, nil
+	}
+
+	registry, err := avro.NewSchemaRegistry(s.SchemaRegistry, avro.DefaultTimeout)
+	if err != nil {
+		return nil, err
+	}
+	s.registry = registry
+
+	return s, nil
+}
+
+// Serialize implements serializers.Serializer.Serialize
+func (s *Serializer) Serialize(metric telegraf.Metric) ([]byte, error) {
+	return s.serialize(nil, metric)
+}
+
+// SerializeBatch implements serializers.Serializer.SerializeBatch
+func (s *Serializer) SerializeBatch(metrics []telegraf.Metric) ([]byte, error) {
+	var buf []byte
+	for _, m := range metrics {
+		var err error
+		if buf, err = s.serialize(buf, m); err != nil {
+			return nil, err
+		}
+	}
+	return buf, nil
+}
+
+func (s *Serializer) serialize(buf []byte, metric telegraf.Metric) ([]byte, error) {
+	codec, fields, err := s.schema()
+	if err != nil {
+		return nil, err
+	}
+
+	record := make(map[string]interface{}, len(fields))
+	for _, field := range fields {
+		value, found := s.lookup(metric, field.name)
+		if !found {
+			if field.nullable() {
+				record[field.name] = nil
+			}
+			// Leave the field to the schema's default
+			continue
+		}
+
+		encoded, err := s.encode(field, value)
+		if err != nil {
+			return nil, fmt.Errorf("field %q: %w", field.name, err)
+		}
+		record[field.name] = encoded
+	}
+
+	if s.SchemaID > 0 {
+		buf = avro.AppendWireHeader(buf, s.SchemaID)
+	}
+	return codec.BinaryFromNative(buf, record)
+}
+
+// schema returns the codec and record layout, querying the schema registry
+// on first use
+func (s *Serializer) schema() (*goavro.Codec, []recordField, error) {
+	s.Lock()
+	defer s.Unlock()
+
+	if s.codec == nil {
+		codec, err := s.registry.Codec(s.SchemaID)
+		if err != nil {
+			return nil, nil, err
+		}
+		if err := s.setCodec(codec); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	return s.codec, s.fields, nil
+}
+
+func (s *Serializer) setCodec(codec *goavro.Codec) error {
+	var schema struct {
+		Type   string `json:"type"`
+		Fields []struct {
+			Name string      `json:"name"`
+			Type interface{} `json:"type"`
+		} `json:"fields"`
+	}
+	if err := json.Unmarshal([]byte(codec.Schema()), &schema); err != nil || schema.Type != "record" {
+		return errors.New("schema must describe a record")
+	}
+
+	fields := make([]recordField, 0, len(schema.Fields))
+	for _, f := range schema.Fields {
+		fields = append(fields, recordField{name: f.Name, types: typeNames(f.Type)})
+	}
+	s.codec = codec
+	s.fields = fields
+
+	return nil
+}
+
+func (s *Serializer) lookup(metric telegraf.Metric, name string) (interface{}, bool) {
+	switch name {
+	case s.MeasurementField:
+		return metric.Name(), true
+	case s.Timestamp:
+		return metric.Time(), true
+	}
+	if value, found := metric.GetTag(name); found {
+		return value, true
+	}
+	return metric.GetField(name)
+}
+
+// encode converts the value to the first matching type of the field,
+// wrapping it for union fields
+func (s *Serializer) encode(field recordField, value interface{}) (interface{}, error) {
+	union := len(field.types) > 1
+	for _, typ := range field.types {
+		if typ == "null" {
+			continue
+		}
+		converted, err := s.convert(typ, value)
+		if err != nil {
+			continue
+		}
+		if union {
+			return goavro.Union(typ, converted), nil
+		}
+		return converted, nil
+	}
+	return nil, fmt.Errorf("cannot convert %T to any of %v", value, field.types)
+}
+
+func (s *Serializer) convert(typ string, value interface{}) (interface{}, error) {
+	if t, ok := value.(time.Time); ok {
+		switch typ {
+		case "long.timestamp-millis", "long.timestamp-micros":
+			return t, nil
+		}
+		value = s.unixTime(t)
+	}
+
+	switch typ {
+	case "boolean":
+		return internal.ToBool(value)
+	case "int":
+		v, err := internal.ToInt64(value)
+		return int32(v), err
+	case "long", "long.timestamp-millis", "long.timestamp-micros":
+		return internal.ToInt64(value)
+	case "float":
+		v, err := internal.ToFloat64(value)
+		return float32(v), err
+	case "double":
+		return internal.ToFloat64(value)
+	case "string":
+		return internal.ToString(value)
+	case "bytes":
+		v, err := internal.ToString(value)
+		return []byte(v), err
+	}
+	return nil, fmt.Errorf("unsupported type %q", typ)
+}
+
+func (s *Serializer) unixTime(t time.Time) int64 {
+	switch s.TimestampFormat {
+	case "unix_ms":
+		return t.UnixNano() / int64(time.Millisecond)
+	case "unix_us":
+		return t.UnixNano() / int64(time.Microsecond)
+	case "unix_ns":
+		return t.UnixNano()
+	}
+	return t.Unix()
+}
+
+func (f recordField) nullable() bool {
+	for _, typ := range f.types {
+		if typ == "null" {
+			return true
+		}
+	}
+	return false
+}
+
+// typeNames returns the names used by goavro for the given field type
+func typeNames(schema interface{}) []string {
+	switch t := schema.(type) {
+	case string:
+		return []string{t}
+	case []interface{}:
+		var names []string
+		for _, member := range t {
+			names = append(names, typeNames(member)...)
+		}
+		return names
+	case map[string]interface{}:
+		typ, _ := t["type"].(string)
+		if logical, ok := t["logicalType"].(string); ok {
+			return []string{typ + "." + logical}
+		}
+		return []string{typ}
+	}
+	return nil
+}
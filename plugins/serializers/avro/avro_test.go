@@ -0,0 +1,166 @@
+package avro
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/linkedin/goavro/v2"
+	"github.com/stretchr/testify/require"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/metric"
+	"github.com/influxdata/telegraf/plugins/common/avro"
+)
+
+const schema = `{
+  "type": "record",
+  "name": "Measurement",
+  "fields": [
+    {"name": "name", "type": "string"},
+    {"name": "host", "type": "string"},
+    {"name": "value", "type": "double"},
+    {"name": "count", "type": ["null", "long"]},
+    {"name": "status", "type": "string", "default": "ok"},
+    {"name": "time", "type": {"type": "long", "logicalType": "timestamp-millis"}}
+  ]
+}`
+
+func decode(t *testing.T, buf []byte) map[string]interface{} {
+	codec, err := goavro.NewCodec(schema)
+	require.NoError(t, err)
+	native, rest, err := codec.NativeFromBinary(buf)
+	require.NoError(t, err)
+	require.Empty(t, rest)
+	return native.(map[string]interface{})
+}
+
+func TestSerialize(t *testing.T) {
+	s, err := NewSerializer(&Serializer{
+		Schema:           schema,
+		MeasurementField: "name",
+		Timestamp:        "time",
+	})
+	require.NoError(t, err)
+
+	now := time.Unix(1650000000, 123000000).UTC()
+	m := metric.New(
+		"cpu",
+		map[string]string{"host": "server01"},
+		map[string]interface{}{"value": int64(42), "count": uint64(3)},
+		now,
+	)
+
+	buf, err := s.Serialize(m)
+	require.NoError(t, err)
+
+	expected := map[string]interface{}{
+		"name":   "cpu",
+		"host":   "server01",
+		"value":  float64(42),
+		"count":  goavro.Union("long", int64(3)),
+		"status": "ok",
+		"time":   now,
+	}
+	require.Equal(t, expected, decode(t, buf))
+
+	// Missing nullable fields are written as null
+	m.RemoveField("count")
+	buf, err = s.Serialize(m)
+	require.NoError(t, err)
+	require.Nil(t, decode(t, buf)["count"])
+
+	// Missing fields without default cannot be encoded
+	m.RemoveTag("host")
+	_, err = s.Serialize(m)
+	require.Error(t, err)
+}
+
+func TestSerializeWireFormat(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		require.Equal(t, "/schemas/ids/3", r.URL.Path)
+		require.NoError(t, json.NewEncoder(w).Encode(map[string]string{"schema": schema}))
+	}))
+	defer server.Close()
+
+	s, err := NewSerializer(&Serializer{
+		SchemaRegistry:   server.URL,
+		SchemaID:         3,
+		MeasurementField: "name",
+		Timestamp:        "time",
+	})
+	require.NoError(t, err)
+
+	m := metric.New(
+		"cpu",
+		map[string]string{"host": "server01"},
+		map[string]interface{}{"value": 42.5},
+		time.Unix(1650000000, 0),
+	)
+
+	buf, err := s.SerializeBatch([]telegraf.Metric{m, m})
+	require.NoError(t, err)
+
+	single, err := s.Serialize(m)
+	require.NoError(t, err)
+	require.Equal(t, append(append([]byte{}, single...), single...), buf)
+	require.Equal(t, 1, requests)
+
+	id, payload, err := avro.SplitWireFormat(single)
+	require.NoError(t, err)
+	require.Equal(t, 3, id)
+	record := decode(t, payload)
+	require.Equal(t, "cpu", record["name"])
+	require.Equal(t, 42.5, record["value"])
+}
+
+func TestSerializeTimestampFormat(t *testing.T) {
+	s, err := NewSerializer(&Serializer{
+		Schema: `{
+			"type": "record",
+			"name": "Measurement",
+			"fields": [
+				{"name": "ts", "type": "long"},
+				{"name": "value", "type": ["null", "long", "string"]}
+			]
+		}`,
+		Timestamp:       "ts",
+		TimestampFormat: "unix_ms",
+	})
+	require.NoError(t, err)
+
+	m := metric.New("cpu", nil, map[string]interface{}{"value": "high"}, time.Unix(1650000000, 0))
+	buf, err := s.Serialize(m)
+	require.NoError(t, err)
+
+	native, _, err := s.codec.NativeFromBinary(buf)
+	require.NoError(t, err)
+	require.Equal(t, map[string]interface{}{
+		"ts":    int64(1650000000000),
+		"value": goavro.Union("string", "high"),
+	}, native)
+}
+
+func TestNewSerializerErrors(t *testing.T) {
+	tests := []struct {
+		name       string
+		serializer *Serializer
+	}{
+		{"no schema", &Serializer{}},
+		{"both schema sources", &Serializer{Schema: schema, SchemaRegistry: "http://localhost:8081", SchemaID: 1}},
+		{"registry without id", &Serializer{SchemaRegistry: "http://localhost:8081"}},
+		{"negative id", &Serializer{Schema: schema, SchemaID: -1}},
+		{"not a record", &Serializer{Schema: `"string"`}},
+		{"invalid timestamp format", &Serializer{Schema: schema, TimestampFormat: "RFC3339"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewSerializer(tt.serializer)
+			require.Error(t, err)
+		})
+	}
+}
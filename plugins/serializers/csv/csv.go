@@ -0,0 +1,300 @@
+package csv
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/influxdata/telegraf"
+)
+
+// Special column names referring to the metric name and timestamp. All other
+// columns have to be prefixed with "tag." or "field.".
+const (
+	columnTimestamp   = "timestamp"
+	columnMeasurement = "measurement"
+	prefixTag         = "tag."
+	prefixField       = "field."
+)
+
+// Serializer writes metrics as rows of comma separated values
+type Serializer struct {
+	// Columns to write in the given order; derived from the metrics if empty
+	Columns []string
+	// Header emission, one of "none", "once" or "batch"
+	Header string
+	// Single character separating the columns
+	Separator string
+	// Format of the timestamp column, either "unix", "unix_ms", "unix_us",
+	// "unix_ns" or a Go time layout
+	TimestampFormat string
+	// Derive the columns from all metrics of a batch instead of the first one
+	Wide bool
+
+	comma rune
+
+	// With the header written once, the output is a stream of metrics
+	// started by StartStream.  Without streams each batch is a stream.  The
+	// columns derived for the first metrics of a stream are kept for the
+	// stream.
+	sync.Mutex
+	streams       bool
+	headerPending bool
+	streamColumns []string
+}
+
+// NewSerializer creates a csv.Serializer
+func NewSerializer(s *Serializer) (*Serializer, error) {
+	switch s.Header {
+	case "":
+		s.Header = "none"
+	case "none", "once", "batch":
+	default:
+		return nil, fmt.Errorf("invalid header mode %q", s.Header)
+	}
+
+	if s.Separator == "" {
+		s.Separator = ","
+	}
+	if utf8.RuneCountInString(s.Separator) != 1 {
+		return nil, fmt.Errorf("separator must be a single character, got %q", s.Separator)
+	}
+	s.comma, _ = utf8.DecodeRuneInString(s.Separator)
+	if s.comma == '"' || s.comma == '\r' || s.comma == '\n' || s.comma == utf8.RuneError {
+		return nil, fmt.Errorf("invalid separator %q", s.Separator)
+	}
+
+	if s.TimestampFormat == "" {
+		s.TimestampFormat = "unix"
+	}
+
+	for _, c := range s.Columns {
+		switch {
+		case c == columnTimestamp, c == columnMeasurement:
+		case strings.HasPrefix(c, prefixTag) && len(c) > len(prefixTag):
+		case strings.HasPrefix(c, prefixField) && len(c) > len(prefixField):
+		default:
+			return nil, fmt.Errorf("invalid column %q", c)
+		}
+	}
+
+	return s, nil
+}
+
+// Serialize implements serializers.Serializer.Serialize
+func (s *Serializer) Serialize(metric telegraf.Metric) ([]byte, error) {
+	return s.SerializeBatch([]telegraf.Metric{metric})
+}
+
+// StartStream implements serializers.HeaderSerializer.StartStream, in "once"
+// mode the header is written with the next metrics if requested and the
+// columns are derived again.
+func (s *Serializer) StartStream(writeHeader bool) {
+	s.Lock()
+	defer s.Unlock()
+
+	s.streams = true
+	s.headerPending = writeHeader
+	s.streamColumns = nil
+}
+
+// ResumeStream implements serializers.HeaderSerializer.ResumeStream, in
+// "once" mode the columns are taken from the header of the stream unless
+// configured.
+func (s *Serializer) ResumeStream(r io.Reader, writeHeader bool) error {
+	var columns []string
+	if s.Header == "once" && len(s.Columns) == 0 {
+		reader := csv.NewReader(r)
+		reader.Comma = s.comma
+		names, err := reader.Read()
+		if err != nil {
+			return fmt.Errorf("reading header failed: %w", err)
+		}
+		columns = names
+	}
+
+	s.Lock()
+	defer s.Unlock()
+
+	s.streams = true
+	s.headerPending = writeHeader
+	s.streamColumns = columns
+	return nil
+}
+
+// SerializeBatch implements serializers.Serializer.SerializeBatch
+func (s *Serializer) SerializeBatch(metrics []telegraf.Metric) ([]byte, error) {
+	if len(metrics) == 0 {
+		return nil, nil
+	}
+
+	writeHeader := s.Header == "batch"
+	var columns []string
+	if s.Header == "once" {
+		s.Lock()
+		switch {
+		case !s.streams:
+			writeHeader = true
+			columns = s.columns(metrics)
+		default:
+			if s.streamColumns == nil {
+				s.streamColumns = s.columns(metrics)
+			}
+			writeHeader = s.headerPending
+			s.headerPending = false
+			columns = s.streamColumns
+		}
+		s.Unlock()
+	} else {
+		columns = s.columns(metrics)
+	}
+
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+	w.Comma = s.comma
+
+	if writeHeader {
+		if err := w.Write(header(columns)); err != nil {
+			return nil, err
+		}
+	}
+
+	row := make([]string, len(columns))
+	for _, m := range metrics {
+		for i, c := range columns {
+			row[i] = s.value(m, c)
+		}
+		if err := w.Write(row); err != nil {
+			return nil, err
+		}
+	}
+
+	w.Flush()
+	if err := w.Error(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// header returns the column names without their prefix. Names shared by
+// several columns, e.g. a tag and a field of the same key, keep their prefix.
+func header(columns []string) []string {
+	names := make([]string, 0, len(columns))
+	count := make(map[string]int, len(columns))
+	for _, c := range columns {
+		name := strings.TrimPrefix(strings.TrimPrefix(c, prefixTag), prefixField)
+		names = append(names, name)
+		count[name]++
+	}
+	for i, c := range columns {
+		if count[names[i]] > 1 {
+			names[i] = c
+		}
+	}
+	return names
+}
+
+// columns returns the columns to write for the batch. Without configured
+// columns they are derived from the first metric, or all metrics in wide
+// mode, with the timestamp and measurement first followed by the sorted tags
+// and fields.
+func (s *Serializer) columns(metrics []telegraf.Metric) []string {
+	if len(s.Columns) > 0 {
+		return s.Columns
+	}
+
+	if !s.Wide {
+		metrics = metrics[:1]
+	}
+
+	tags := make(map[string]bool)
+	fields := make(map[string]bool)
+	for _, m := range metrics {
+		for _, tag := range m.TagList() {
+			tags[tag.Key] = true
+		}
+		for _, field := range m.FieldList() {
+			fields[field.Key] = true
+		}
+	}
+
+	columns := make([]string, 0, len(tags)+len(fields)+2)
+	columns = append(columns, columnTimestamp, columnMeasurement)
+	columns = append(columns, sortedKeys(prefixTag, tags)...)
+	columns = append(columns, sortedKeys(prefixField, fields)...)
+
+	return columns
+}
+
+func sortedKeys(prefix string, set map[string]bool) []string {
+	keys := make([]string, 0, len(set))
+	for k := range set {
+		keys = append(keys, prefix+k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// value returns the formatted value of the column, or an empty string if the
+// metric does not contain the column
+func (s *Serializer) value(m telegraf.Metric, column string) string {
+	switch {
+	case column == columnTimestamp:
+		return s.formatTime(m.Time())
+	case column == columnMeasurement:
+		return m.Name()
+	case strings.HasPrefix(column, prefixTag):
+		v, _ := m.GetTag(column[len(prefixTag):])
+		return v
+	case strings.HasPrefix(column, prefixField):
+		if v, found := m.GetField(column[len(prefixField):]); found {
+			return formatField(v)
+		}
+	default:
+		// Columns read from a header lack the prefix if the name is unique
+		if v, found := m.GetTag(column); found {
+			return v
+		}
+		if v, found := m.GetField(column); found {
+			return formatField(v)
+		}
+	}
+	return ""
+}
+
+func (s *Serializer) formatTime(t time.Time) string {
+	switch s.TimestampFormat {
+	case "unix":
+		return strconv.FormatInt(t.Unix(), 10)
+	case "unix_ms":
+		return strconv.FormatInt(t.UnixNano()/int64(time.Millisecond), 10)
+	case "unix_us":
+		return strconv.FormatInt(t.UnixNano()/int64(time.Microsecond), 10)
+	case "unix_ns":
+		return strconv.FormatInt(t.UnixNano(), 10)
+	}
+	return t.UTC().Format(s.TimestampFormat)
+}
+
+func formatField(value interface{}) string {
+	switch v := value.(type) {
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case int64:
+		return strconv.FormatInt(v, 10)
+	case uint64:
+		return strconv.FormatUint(v, 10)
+	case bool:
+		return strconv.FormatBool(v)
+	case string:
+		return v
+	}
+	return fmt.Sprintf("%v", value)
+}
@@ -0,0 +1,216 @@
+package csv
+
+import (
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/metric"
+	"github.com/influxdata/telegraf/plugins/parsers/csv"
+)
+
+var ts = time.Unix(1650000000, 123456789)
+
+var metrics = []telegraf.Metric{
+	metric.New(
+		"cpu",
+		map[string]string{"host": "server01", "cpu": "cpu0"},
+		map[string]interface{}{"usage": 42.5, "count": int64(3)},
+		ts,
+	),
+	metric.New(
+		"cpu",
+		map[string]string{"host": "server02"},
+		map[string]interface{}{"usage": 1.5, "state": "idle, waiting", "ok": true},
+		ts.Add(time.Second),
+	),
+}
+
+func TestSerializeBatch(t *testing.T) {
+	tests := []struct {
+		name       string
+		serializer *Serializer
+		expected   string
+	}{
+		{
+			name:       "defaults",
+			serializer: &Serializer{},
+			expected: "1650000000,cpu,cpu0,server01,3,42.5\n" +
+				"1650000001,cpu,,server02,,1.5\n",
+		},
+		{
+			name:       "wide with header",
+			serializer: &Serializer{Wide: true, Header: "batch"},
+			expected: "timestamp,measurement,cpu,host,count,ok,state,usage\n" +
+				"1650000000,cpu,cpu0,server01,3,,,42.5\n" +
+				"1650000001,cpu,,server02,,true,\"idle, waiting\",1.5\n",
+		},
+		{
+			name: "columns",
+			serializer: &Serializer{
+				Columns:         []string{"field.usage", "tag.host", "timestamp", "field.missing"},
+				Header:          "batch",
+				Separator:       ";",
+				TimestampFormat: "unix_ms",
+			},
+			expected: "usage;host;timestamp;missing\n" +
+				"42.5;server01;1650000000123;\n" +
+				"1.5;server02;1650000001123;\n",
+		},
+		{
+			name:       "time layout",
+			serializer: &Serializer{Columns: []string{"timestamp", "measurement"}, TimestampFormat: time.RFC3339Nano},
+			expected: "2022-04-15T05:20:00.123456789Z,cpu\n" +
+				"2022-04-15T05:20:01.123456789Z,cpu\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, err := NewSerializer(tt.serializer)
+			require.NoError(t, err)
+
+			buf, err := s.SerializeBatch(metrics)
+			require.NoError(t, err)
+			require.Equal(t, tt.expected, string(buf))
+		})
+	}
+}
+
+func TestHeaderOnce(t *testing.T) {
+	s, err := NewSerializer(&Serializer{Header: "once"})
+	require.NoError(t, err)
+
+	// Without streams each batch gets a header
+	buf, err := s.Serialize(metrics[1])
+	require.NoError(t, err)
+	require.Equal(t, "timestamp,measurement,host,ok,state,usage\n1650000001,cpu,server02,true,\"idle, waiting\",1.5\n", string(buf))
+
+	s.StartStream(true)
+	buf, err = s.Serialize(metrics[0])
+	require.NoError(t, err)
+	require.Equal(t, "timestamp,measurement,cpu,host,count,usage\n1650000000,cpu,cpu0,server01,3,42.5\n", string(buf))
+
+	// The columns of the first batch of the stream are kept to match the header
+	buf, err = s.Serialize(metrics[1])
+	require.NoError(t, err)
+	require.Equal(t, "1650000001,cpu,,server02,,1.5\n", string(buf))
+
+	// A new stream derives the columns again
+	s.StartStream(false)
+	buf, err = s.Serialize(metrics[1])
+	require.NoError(t, err)
+	require.Equal(t, "1650000001,cpu,server02,true,\"idle, waiting\",1.5\n", string(buf))
+	require.Empty(t, s.Columns)
+}
+
+func TestHeaderOnceResume(t *testing.T) {
+	s, err := NewSerializer(&Serializer{Header: "once", Separator: ";"})
+	require.NoError(t, err)
+
+	// The columns are taken from the header of the stream
+	existing := "usage;tag.state;field.state;measurement;timestamp\n1;a;b;cpu;1649999999\n"
+	require.NoError(t, s.ResumeStream(strings.NewReader(existing), false))
+	buf, err := s.SerializeBatch(metrics)
+	require.NoError(t, err)
+	require.Equal(t, "42.5;;;cpu;1650000000\n1.5;;idle, waiting;cpu;1650000001\n", string(buf))
+
+	// and written if requested
+	require.NoError(t, s.ResumeStream(strings.NewReader(existing), true))
+	buf, err = s.Serialize(metrics[1])
+	require.NoError(t, err)
+	require.Equal(t, "usage;tag.state;field.state;measurement;timestamp\n1.5;;idle, waiting;cpu;1650000001\n", string(buf))
+
+	require.Error(t, s.ResumeStream(strings.NewReader(""), false))
+}
+
+func TestHeaderOnceConcurrent(t *testing.T) {
+	s, err := NewSerializer(&Serializer{Header: "once"})
+	require.NoError(t, err)
+	s.StartStream(true)
+
+	var wg sync.WaitGroup
+	headers := make(chan int, 10)
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			buf, err := s.SerializeBatch(metrics)
+			require.NoError(t, err)
+			headers <- strings.Count(string(buf), "timestamp")
+		}()
+	}
+	wg.Wait()
+	close(headers)
+
+	var count int
+	for n := range headers {
+		count += n
+	}
+	require.Equal(t, 1, count)
+}
+
+func TestHeaderCollision(t *testing.T) {
+	s, err := NewSerializer(&Serializer{Header: "batch"})
+	require.NoError(t, err)
+
+	m := metric.New(
+		"cpu",
+		map[string]string{"host": "a", "timestamp": "b"},
+		map[string]interface{}{"host": "c", "value": 1.0},
+		ts,
+	)
+	buf, err := s.Serialize(m)
+	require.NoError(t, err)
+	require.Equal(t, "timestamp,measurement,tag.host,tag.timestamp,field.host,value\n1650000000,cpu,a,b,c,1\n", string(buf))
+}
+
+func TestRoundTrip(t *testing.T) {
+	s, err := NewSerializer(&Serializer{Wide: true, Header: "batch", TimestampFormat: "unix_ns"})
+	require.NoError(t, err)
+
+	buf, err := s.SerializeBatch(metrics)
+	require.NoError(t, err)
+
+	parser := &csv.Parser{
+		HeaderRowCount:    1,
+		MeasurementColumn: "measurement",
+		TimestampColumn:   "timestamp",
+		TimestampFormat:   "unix_ns",
+		TagColumns:        []string{"cpu", "host"},
+		SkipValues:        []string{""},
+	}
+	require.NoError(t, parser.Init())
+
+	parsed, err := parser.Parse(buf)
+	require.NoError(t, err)
+	require.Len(t, parsed, 2)
+	for i, m := range parsed {
+		require.Equal(t, metrics[i].Name(), m.Name())
+		require.Equal(t, metrics[i].Time().UnixNano(), m.Time().UnixNano())
+		require.Equal(t, metrics[i].Tags(), m.Tags())
+		require.Equal(t, metrics[i].Fields(), m.Fields())
+	}
+}
+
+func TestInvalidConfig(t *testing.T) {
+	tests := []struct {
+		name       string
+		serializer *Serializer
+	}{
+		{"header", &Serializer{Header: "always"}},
+		{"separator", &Serializer{Separator: ";;"}},
+		{"quote separator", &Serializer{Separator: "\""}},
+		{"column", &Serializer{Columns: []string{"host"}}},
+		{"empty tag column", &Serializer{Columns: []string{"tag."}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewSerializer(tt.serializer)
+			require.Error(t, err)
+		})
+	}
+}
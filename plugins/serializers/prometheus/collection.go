@@ -11,6 +11,7 @@ import (
 	"google.golang.org/protobuf/proto"
 
 	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/plugins/common/exphistogram"
 )
 
 const helpString = "Telegraf collected metric"
@@ -54,6 +55,9 @@ type Histogram struct {
 	Buckets []Bucket
 	Count   uint64
 	Sum     float64
+
+	// Exponential is set for native histograms
+	Exponential *exphistogram.Histogram
 }
 
 func (h *Histogram) merge(b Bucket) {
@@ -182,7 +186,17 @@ func (c *Collection) createLabels(metric telegraf.Metric) []LabelPair {
 
 func (c *Collection) Add(metric telegraf.Metric, now time.Time) {
 	labels := c.createLabels(metric)
+
+	histograms, used := exphistogram.FromMetric(metric)
+	for field, h := range histograms {
+		c.addNativeHistogram(metric, field, h, labels, now)
+	}
+
 	for _, field := range metric.FieldList() {
+		if used[field.Key] {
+			continue
+		}
+
 		metricName := MetricName(metric.Name(), field.Key, metric.Type())
 		metricName, ok := SanitizeMetricName(metricName)
 		if !ok {
@@ -338,6 +352,49 @@ func (c *Collection) Add(metric telegraf.Metric, now time.Time) {
 	}
 }
 
+// addNativeHistogram adds the exponential histogram of the field as a native
+// histogram. Histograms with a finer resolution than supported by Prometheus
+// are downscaled, histograms with a coarser resolution are dropped.
+func (c *Collection) addNativeHistogram(metric telegraf.Metric, field string, h *exphistogram.Histogram, labels []LabelPair, now time.Time) {
+	if h.Scale < exphistogram.PrometheusMinScale {
+		return
+	}
+	h.Downscale(exphistogram.PrometheusMaxScale)
+
+	metricName, ok := SanitizeMetricName(MetricName(metric.Name(), field, metric.Type()))
+	if !ok {
+		return
+	}
+
+	family := MetricFamily{
+		Name: metricName,
+		Type: telegraf.Histogram,
+	}
+	entry, ok := c.Entries[family]
+	if !ok {
+		entry = Entry{
+			Family:  family,
+			Metrics: make(map[MetricKey]*Metric),
+		}
+		c.Entries[family] = entry
+	}
+
+	metricKey := MakeMetricKey(labels)
+	if m, ok := entry.Metrics[metricKey]; ok && metric.Time().Before(m.Time) {
+		return
+	}
+	entry.Metrics[metricKey] = &Metric{
+		Labels:  labels,
+		Time:    metric.Time(),
+		AddTime: now,
+		Histogram: &Histogram{
+			Count:       h.Count,
+			Sum:         h.Sum,
+			Exponential: h,
+		},
+	}
+}
+
 func (c *Collection) Expire(now time.Time, age time.Duration) {
 	expireTime := now.Add(-age)
 	for _, entry := range c.Entries {
@@ -454,6 +511,9 @@ func (c *Collection) GetProto() []*dto.MetricFamily {
 					SampleCount: proto.Uint64(metric.Histogram.Count),
 					SampleSum:   proto.Float64(metric.Histogram.Sum),
 				}
+				if metric.Histogram.Exponential != nil {
+					setNativeHistogram(m.Histogram, metric.Histogram.Exponential)
+				}
 			case telegraf.Summary:
 				quantiles := make([]*dto.Quantile, 0, len(metric.Summary.Quantiles))
 				for _, quantile := range metric.Summary.Quantiles {
@@ -482,3 +542,29 @@ func (c *Collection) GetProto() []*dto.MetricFamily {
 
 	return result
 }
+
+// setNativeHistogram sets the native histogram fields of the histogram
+func setNativeHistogram(m *dto.Histogram, h *exphistogram.Histogram) {
+	m.Schema = proto.Int32(h.Scale)
+	m.ZeroThreshold = proto.Float64(h.ZeroThreshold)
+	m.ZeroCount = proto.Uint64(h.ZeroCount)
+	m.PositiveSpan, m.PositiveDelta = bucketSpans(&h.Positive)
+	m.NegativeSpan, m.NegativeDelta = bucketSpans(&h.Negative)
+
+	// An empty span marks histograms without buckets as native histograms
+	if len(m.PositiveSpan) == 0 && len(m.NegativeSpan) == 0 && h.ZeroThreshold == 0 && h.ZeroCount == 0 {
+		m.PositiveSpan = []*dto.BucketSpan{{Offset: proto.Int32(0), Length: proto.Uint32(0)}}
+	}
+}
+
+func bucketSpans(b *exphistogram.Buckets) ([]*dto.BucketSpan, []int64) {
+	spans, deltas := b.Spans()
+	result := make([]*dto.BucketSpan, 0, len(spans))
+	for _, span := range spans {
+		result = append(result, &dto.BucketSpan{
+			Offset: proto.Int32(span.Offset),
+			Length: proto.Uint32(span.Length),
+		})
+	}
+	return result, deltas
+}
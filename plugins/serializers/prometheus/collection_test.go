@@ -842,3 +842,54 @@ func TestExportTimestamps(t *testing.T) {
 		})
 	}
 }
+
+func TestNativeHistogram(t *testing.T) {
+	c := NewCollection(FormatConfig{})
+	m := testutil.MustMetric(
+		"http",
+		map[string]string{"host": "example.org"},
+		map[string]interface{}{
+			"latency_count":          uint64(7),
+			"latency_sum":            42.0,
+			"latency_scale":          int64(9),
+			"latency_zero_threshold": 0.001,
+			"latency_zero_count":     uint64(1),
+			"latency_positive_0":     uint64(2),
+			"latency_positive_1":     uint64(1),
+			"latency_positive_5":     uint64(2),
+			"latency_negative_-3":    uint64(1),
+		},
+		time.Unix(0, 0),
+		telegraf.Histogram,
+	)
+	c.Add(m, time.Unix(0, 0))
+
+	expected := []*dto.MetricFamily{
+		{
+			Name: proto.String("http_latency"),
+			Help: proto.String(helpString),
+			Type: dto.MetricType_HISTOGRAM.Enum(),
+			Metric: []*dto.Metric{
+				{
+					Label: []*dto.LabelPair{{Name: proto.String("host"), Value: proto.String("example.org")}},
+					Histogram: &dto.Histogram{
+						Bucket:        []*dto.Bucket{},
+						SampleCount:   proto.Uint64(7),
+						SampleSum:     proto.Float64(42.0),
+						Schema:        proto.Int32(8),
+						ZeroThreshold: proto.Float64(0.001),
+						ZeroCount:     proto.Uint64(1),
+						PositiveSpan: []*dto.BucketSpan{
+							{Offset: proto.Int32(1), Length: proto.Uint32(1)},
+							{Offset: proto.Int32(1), Length: proto.Uint32(1)},
+						},
+						PositiveDelta: []int64{3, -1},
+						NegativeSpan:  []*dto.BucketSpan{{Offset: proto.Int32(-1), Length: proto.Uint32(1)}},
+						NegativeDelta: []int64{1},
+					},
+				},
+			},
+		},
+	}
+	require.Equal(t, expected, c.GetProto())
+}
@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"fmt"
 	"hash/fnv"
+	"math"
 	"sort"
 	"strconv"
 	"strings"
@@ -11,8 +12,10 @@ import (
 
 	"github.com/golang/snappy"
 	"github.com/prometheus/prometheus/prompb"
+	"google.golang.org/protobuf/encoding/protowire"
 
 	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/plugins/common/exphistogram"
 	"github.com/influxdata/telegraf/plugins/serializers/prometheus"
 )
 
@@ -55,11 +58,37 @@ func (s *Serializer) Serialize(metric telegraf.Metric) ([]byte, error) {
 func (s *Serializer) SerializeBatch(metrics []telegraf.Metric) ([]byte, error) {
 	var buf bytes.Buffer
 	var entries = make(map[MetricKey]prompb.TimeSeries)
+	var nativeEntries = make(map[MetricKey]nativeHistogram)
 	for _, metric := range metrics {
 		commonLabels := s.createLabels(metric)
+
+		histograms, used := exphistogram.FromMetric(metric)
+		for field, h := range histograms {
+			// Prometheus does not support the resolution
+			if h.Scale < exphistogram.PrometheusMinScale {
+				continue
+			}
+			h.Downscale(exphistogram.PrometheusMaxScale)
+
+			metricName := prometheus.MetricName(metric.Name(), field, metric.Type())
+			metricName, ok := prometheus.SanitizeMetricName(metricName)
+			if !ok {
+				continue
+			}
+			metrickey, promts := getPromNativeTS(metricName, commonLabels, h, metric.Time())
+			if m, ok := nativeEntries[metrickey]; ok && metric.Time().Before(m.time) {
+				continue
+			}
+			nativeEntries[metrickey] = nativeHistogram{ts: promts, time: metric.Time()}
+		}
+
 		var metrickey MetricKey
 		var promts prompb.TimeSeries
 		for _, field := range metric.FieldList() {
+			if used[field.Key] {
+				continue
+			}
+
 			metricName := prometheus.MetricName(metric.Name(), field.Key, metric.Type())
 			metricName, ok := prometheus.SanitizeMetricName(metricName)
 			if !ok {
@@ -203,12 +232,15 @@ func (s *Serializer) SerializeBatch(metrics []telegraf.Metric) ([]byte, error) {
 		}
 	}
 
-	var promTS = make([]prompb.TimeSeries, len(entries))
+	var promTS = make([]prompb.TimeSeries, len(entries), len(entries)+len(nativeEntries))
 	var i int
 	for _, promts := range entries {
 		promTS[i] = promts
 		i++
 	}
+	for _, native := range nativeEntries {
+		promTS = append(promTS, native.ts)
+	}
 
 	if s.config.MetricSortOrder == SortMetrics {
 		sort.Slice(promTS, func(i, j int) bool {
@@ -341,3 +373,67 @@ func getPromTS(name string, labels []prompb.Label, value float64, ts time.Time)
 	})
 	return MakeMetricKey(labels), prompb.TimeSeries{Labels: labels, Samples: sample}
 }
+
+// nativeHistogram is a time series of a native histogram
+type nativeHistogram struct {
+	ts   prompb.TimeSeries
+	time time.Time
+}
+
+// getPromNativeTS returns a time series with the exponential histogram as
+// native histogram. The histograms field of the time series is missing in
+// the prompb package, so the field is encoded as unrecognized field.
+func getPromNativeTS(name string, labels []prompb.Label, h *exphistogram.Histogram, ts time.Time) (MetricKey, prompb.TimeSeries) {
+	labelscopy := make([]prompb.Label, len(labels), len(labels)+1)
+	copy(labelscopy, labels)
+	labels = append(labelscopy, prompb.Label{
+		Name:  "__name__",
+		Value: name,
+	})
+
+	var msg []byte
+	msg = protowire.AppendTag(msg, 1, protowire.VarintType) // count_int
+	msg = protowire.AppendVarint(msg, h.Count)
+	msg = protowire.AppendTag(msg, 3, protowire.Fixed64Type) // sum
+	msg = protowire.AppendFixed64(msg, math.Float64bits(h.Sum))
+	msg = protowire.AppendTag(msg, 4, protowire.VarintType) // schema
+	msg = protowire.AppendVarint(msg, protowire.EncodeZigZag(int64(h.Scale)))
+	msg = protowire.AppendTag(msg, 5, protowire.Fixed64Type) // zero_threshold
+	msg = protowire.AppendFixed64(msg, math.Float64bits(h.ZeroThreshold))
+	msg = protowire.AppendTag(msg, 6, protowire.VarintType) // zero_count_int
+	msg = protowire.AppendVarint(msg, h.ZeroCount)
+	msg = appendBuckets(msg, 8, 9, &h.Negative)              // negative_spans, negative_deltas
+	msg = appendBuckets(msg, 11, 12, &h.Positive)            // positive_spans, positive_deltas
+	msg = protowire.AppendTag(msg, 15, protowire.VarintType) // timestamp
+	msg = protowire.AppendVarint(msg, uint64(ts.UnixNano()/int64(time.Millisecond)))
+
+	var histograms []byte
+	histograms = protowire.AppendTag(histograms, 4, protowire.BytesType)
+	histograms = protowire.AppendBytes(histograms, msg)
+
+	return MakeMetricKey(labels), prompb.TimeSeries{Labels: labels, XXX_unrecognized: histograms}
+}
+
+// appendBuckets appends the buckets as spans and packed deltas
+func appendBuckets(b []byte, spansField, deltasField protowire.Number, buckets *exphistogram.Buckets) []byte {
+	spans, deltas := buckets.Spans()
+	for _, span := range spans {
+		var msg []byte
+		msg = protowire.AppendTag(msg, 1, protowire.VarintType)
+		msg = protowire.AppendVarint(msg, protowire.EncodeZigZag(int64(span.Offset)))
+		msg = protowire.AppendTag(msg, 2, protowire.VarintType)
+		msg = protowire.AppendVarint(msg, uint64(span.Length))
+		b = protowire.AppendTag(b, spansField, protowire.BytesType)
+		b = protowire.AppendBytes(b, msg)
+	}
+	if len(deltas) == 0 {
+		return b
+	}
+
+	var packed []byte
+	for _, delta := range deltas {
+		packed = protowire.AppendVarint(packed, protowire.EncodeZigZag(delta))
+	}
+	b = protowire.AppendTag(b, deltasField, protowire.BytesType)
+	return protowire.AppendBytes(b, packed)
+}
@@ -3,6 +3,7 @@ package prometheusremotewrite
 import (
 	"bytes"
 	"fmt"
+	"math"
 	"strings"
 	"testing"
 	"time"
@@ -10,6 +11,7 @@ import (
 	"github.com/golang/snappy"
 	"github.com/prometheus/common/model"
 	"github.com/prometheus/prometheus/prompb"
+	"google.golang.org/protobuf/encoding/protowire"
 
 	"github.com/influxdata/telegraf"
 	"github.com/influxdata/telegraf/testutil"
@@ -698,3 +700,70 @@ func protoToSamples(req *prompb.WriteRequest) model.Samples {
 	}
 	return samples
 }
+
+func TestRemoteWriteSerializeNativeHistogram(t *testing.T) {
+	metrics := []telegraf.Metric{
+		testutil.MustMetric(
+			"http",
+			map[string]string{"host": "example.org"},
+			map[string]interface{}{
+				"latency_count":          uint64(4),
+				"latency_sum":            7.5,
+				"latency_scale":          int64(0),
+				"latency_zero_threshold": 0.0,
+				"latency_zero_count":     uint64(1),
+				"latency_positive_0":     uint64(1),
+				"latency_positive_3":     uint64(1),
+				"latency_negative_1":     uint64(1),
+			},
+			time.Unix(1, 0),
+			telegraf.Histogram,
+		),
+		testutil.MustMetric(
+			"http",
+			map[string]string{"host": "example.org"},
+			map[string]interface{}{"requests": 4.0},
+			time.Unix(1, 0),
+		),
+	}
+
+	s, err := NewSerializer(FormatConfig{MetricSortOrder: SortMetrics})
+	require.NoError(t, err)
+	data, err := s.SerializeBatch(metrics)
+	require.NoError(t, err)
+
+	protobuff, err := snappy.Decode(nil, data)
+	require.NoError(t, err)
+	var req prompb.WriteRequest
+	require.NoError(t, req.Unmarshal(protobuff))
+	require.Len(t, req.Timeseries, 2)
+
+	var native *prompb.TimeSeries
+	for i, ts := range req.Timeseries {
+		for _, l := range ts.Labels {
+			if l.Name == "__name__" && l.Value == "http_latency" {
+				native = &req.Timeseries[i]
+			}
+		}
+	}
+	require.NotNil(t, native)
+	require.Empty(t, native.Samples)
+	require.Contains(t, native.Labels, prompb.Label{Name: "host", Value: "example.org"})
+
+	series := testutil.DecodeProto(t, native.XXX_unrecognized)
+	require.Len(t, series[4], 1)
+	histogram := testutil.DecodeProto(t, series[4][0].([]byte))
+	require.Equal(t, []interface{}{uint64(4)}, histogram[1])
+	require.Equal(t, []interface{}{math.Float64bits(7.5)}, histogram[3])
+	require.Equal(t, []interface{}{protowire.EncodeZigZag(0)}, histogram[4])
+	require.Equal(t, []interface{}{uint64(1)}, histogram[6])
+	require.Equal(t, []interface{}{uint64(1000)}, histogram[15])
+
+	// Positive buckets 1 and 4 in Prometheus indices
+	require.Len(t, histogram[11], 2)
+	require.Equal(t, map[protowire.Number][]interface{}{1: {protowire.EncodeZigZag(1)}, 2: {uint64(1)}}, testutil.DecodeProto(t, histogram[11][0].([]byte)))
+	require.Equal(t, map[protowire.Number][]interface{}{1: {protowire.EncodeZigZag(2)}, 2: {uint64(1)}}, testutil.DecodeProto(t, histogram[11][1].([]byte)))
+	require.Equal(t, []interface{}{[]byte{0x02, 0x00}}, histogram[12])
+	require.Len(t, histogram[8], 1)
+	require.Equal(t, []interface{}{[]byte{0x02}}, histogram[9])
+}
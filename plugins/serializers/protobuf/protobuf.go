@@ -0,0 +1,190 @@
+package protobuf
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"google.golang.org/protobuf/encoding/protowire"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/dynamicpb"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/plugins/common/protobuf"
+)
+
+// Serializer encodes metrics as protocol-buffer messages of the configured
+// type
+type Serializer struct {
+	protobuf.Descriptor
+	MeasurementField string
+	Timestamp        string
+	TimestampFormat  string
+	LengthDelimited  bool
+
+	msgDesc protoreflect.MessageDescriptor
+}
+
+// NewSerializer creates a protobuf.Serializer
+func NewSerializer(s *Serializer) (*Serializer, error) {
+	msgDesc, err := s.Descriptor.Load()
+	if err != nil {
+		return nil, err
+	}
+	s.msgDesc = msgDesc
+
+	switch s.TimestampFormat {
+	case "":
+		s.TimestampFormat = "unix"
+	case "unix", "unix_ms", "unix_us", "unix_ns":
+	default:
+		return nil, fmt.Errorf("invalid timestamp format %q", s.TimestampFormat)
+	}
+
+	return s, nil
+}
+
+// Serialize implements serializers.Serializer.Serialize
+func (s *Serializer) Serialize(metric telegraf.Metric) ([]byte, error) {
+	return s.serialize(nil, metric)
+}
+
+// SerializeBatch implements serializers.Serializer.SerializeBatch
+func (s *Serializer) SerializeBatch(metrics []telegraf.Metric) ([]byte, error) {
+	if len(metrics) > 1 && !s.LengthDelimited {
+		return nil, errors.New("serializing multiple metrics requires `protobuf_length_delimited`")
+	}
+
+	var buf []byte
+	for _, m := range metrics {
+		var err error
+		if buf, err = s.serialize(buf, m); err != nil {
+			return nil, err
+		}
+	}
+	return buf, nil
+}
+
+func (s *Serializer) serialize(buf []byte, metric telegraf.Metric) ([]byte, error) {
+	msg := dynamicpb.NewMessage(s.msgDesc)
+
+	fields := s.msgDesc.Fields()
+	for i := 0; i < fields.Len(); i++ {
+		fd := fields.Get(i)
+		value, found := s.lookup(metric, string(fd.Name()))
+		if !found {
+			continue
+		}
+		if err := s.set(msg, fd, value); err != nil {
+			return nil, fmt.Errorf("field %q: %w", fd.Name(), err)
+		}
+	}
+
+	data, err := proto.Marshal(msg)
+	if err != nil {
+		return nil, err
+	}
+
+	if s.LengthDelimited {
+		buf = protowire.AppendVarint(buf, uint64(len(data)))
+	}
+	return append(buf, data...), nil
+}
+
+func (s *Serializer) lookup(metric telegraf.Metric, name string) (interface{}, bool) {
+	switch name {
+	case s.MeasurementField:
+		return metric.Name(), true
+	case s.Timestamp:
+		return metric.Time(), true
+	}
+	if value, found := metric.GetTag(name); found {
+		return value, true
+	}
+	return metric.GetField(name)
+}
+
+func (s *Serializer) set(msg protoreflect.Message, fd protoreflect.FieldDescriptor, value interface{}) error {
+	if fd.IsList() || fd.IsMap() {
+		return errors.New("repeated and map fields are not supported")
+	}
+
+	if fd.Kind() == protoreflect.MessageKind {
+		t, ok := value.(time.Time)
+		if !ok || !protobuf.IsTimestamp(fd.Message()) {
+			return fmt.Errorf("cannot convert %T to message %q", value, fd.Message().FullName())
+		}
+		v := msg.NewField(fd)
+		protobuf.SetTime(v.Message(), t)
+		msg.Set(fd, v)
+		return nil
+	}
+
+	if t, ok := value.(time.Time); ok {
+		value = s.unixTime(t)
+	}
+
+	v, err := convert(fd, value)
+	if err != nil {
+		return err
+	}
+	msg.Set(fd, v)
+	return nil
+}
+
+func convert(fd protoreflect.FieldDescriptor, value interface{}) (protoreflect.Value, error) {
+	switch fd.Kind() {
+	case protoreflect.BoolKind:
+		v, err := internal.ToBool(value)
+		return protoreflect.ValueOfBool(v), err
+	case protoreflect.EnumKind:
+		if name, ok := value.(string); ok {
+			ev := fd.Enum().Values().ByName(protoreflect.Name(name))
+			if ev == nil {
+				return protoreflect.Value{}, fmt.Errorf("unknown value %q for enum %q", name, fd.Enum().FullName())
+			}
+			return protoreflect.ValueOfEnum(ev.Number()), nil
+		}
+		v, err := internal.ToInt64(value)
+		return protoreflect.ValueOfEnum(protoreflect.EnumNumber(v)), err
+	case protoreflect.Int32Kind, protoreflect.Sint32Kind, protoreflect.Sfixed32Kind:
+		v, err := internal.ToInt64(value)
+		return protoreflect.ValueOfInt32(int32(v)), err
+	case protoreflect.Int64Kind, protoreflect.Sint64Kind, protoreflect.Sfixed64Kind:
+		v, err := internal.ToInt64(value)
+		return protoreflect.ValueOfInt64(v), err
+	case protoreflect.Uint32Kind, protoreflect.Fixed32Kind:
+		v, err := internal.ToUint64(value)
+		return protoreflect.ValueOfUint32(uint32(v)), err
+	case protoreflect.Uint64Kind, protoreflect.Fixed64Kind:
+		v, err := internal.ToUint64(value)
+		return protoreflect.ValueOfUint64(v), err
+	case protoreflect.FloatKind:
+		v, err := internal.ToFloat64(value)
+		return protoreflect.ValueOfFloat32(float32(v)), err
+	case protoreflect.DoubleKind:
+		v, err := internal.ToFloat64(value)
+		return protoreflect.ValueOfFloat64(v), err
+	case protoreflect.StringKind:
+		v, err := internal.ToString(value)
+		return protoreflect.ValueOfString(v), err
+	case protoreflect.BytesKind:
+		v, err := internal.ToString(value)
+		return protoreflect.ValueOfBytes([]byte(v)), err
+	}
+	return protoreflect.Value{}, fmt.Errorf("unsupported kind %q", fd.Kind())
+}
+
+func (s *Serializer) unixTime(t time.Time) int64 {
+	switch s.TimestampFormat {
+	case "unix_ms":
+		return t.UnixNano() / int64(time.Millisecond)
+	case "unix_us":
+		return t.UnixNano() / int64(time.Microsecond)
+	case "unix_ns":
+		return t.UnixNano()
+	}
+	return t.Unix()
+}
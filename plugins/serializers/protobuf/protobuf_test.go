@@ -0,0 +1,97 @@
+package protobuf
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/encoding/protowire"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/dynamicpb"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/metric"
+	"github.com/influxdata/telegraf/plugins/common/protobuf"
+)
+
+var descriptor = protobuf.Descriptor{
+	Files:       []string{"metric.proto"},
+	ImportPaths: []string{"testdata"},
+	MessageType: "telegraf.test.Metric",
+}
+
+func decode(t *testing.T, s *Serializer, buf []byte) string {
+	msg := dynamicpb.NewMessage(s.msgDesc)
+	require.NoError(t, proto.Unmarshal(buf, msg))
+	text, err := protojson.MarshalOptions{UseProtoNames: true}.Marshal(msg)
+	require.NoError(t, err)
+	return string(text)
+}
+
+func TestSerialize(t *testing.T) {
+	s, err := NewSerializer(&Serializer{
+		Descriptor:       descriptor,
+		MeasurementField: "name",
+		Timestamp:        "time",
+	})
+	require.NoError(t, err)
+
+	m := metric.New(
+		"cpu",
+		map[string]string{"host": "server01", "status": "FAILED"},
+		map[string]interface{}{"value": int64(42), "count": uint64(3), "other": "ignored"},
+		time.Unix(1650000000, 5000),
+	)
+
+	buf, err := s.Serialize(m)
+	require.NoError(t, err)
+	require.JSONEq(t,
+		`{"name": "cpu", "host": "server01", "value": 42, "count": 3, "status": "FAILED", "time": "2022-04-15T05:20:00.000005Z"}`,
+		decode(t, s, buf),
+	)
+
+	m.AddField("count", "many")
+	_, err = s.Serialize(m)
+	require.Error(t, err)
+}
+
+func TestSerializeBatchLengthDelimited(t *testing.T) {
+	s, err := NewSerializer(&Serializer{
+		Descriptor:      descriptor,
+		Timestamp:       "unix_ms",
+		TimestampFormat: "unix_ms",
+		LengthDelimited: true,
+	})
+	require.NoError(t, err)
+
+	metrics := []telegraf.Metric{
+		metric.New("cpu", map[string]string{"host": "a"}, map[string]interface{}{"value": 1.5}, time.Unix(1650000000, 0)),
+		metric.New("cpu", map[string]string{"host": "b"}, map[string]interface{}{"value": 2.5}, time.Unix(1650000001, 0)),
+	}
+	buf, err := s.SerializeBatch(metrics)
+	require.NoError(t, err)
+
+	var messages []string
+	for len(buf) > 0 {
+		size, n := protowire.ConsumeVarint(buf)
+		require.Greater(t, n, 0)
+		messages = append(messages, decode(t, s, buf[n:n+int(size)]))
+		buf = buf[n+int(size):]
+	}
+	require.Len(t, messages, 2)
+	require.JSONEq(t, `{"host": "a", "value": 1.5, "unix_ms": "1650000000000"}`, messages[0])
+	require.JSONEq(t, `{"host": "b", "value": 2.5, "unix_ms": "1650000001000"}`, messages[1])
+
+	s.LengthDelimited = false
+	_, err = s.SerializeBatch(metrics)
+	require.Error(t, err)
+}
+
+func TestNewSerializerErrors(t *testing.T) {
+	_, err := NewSerializer(&Serializer{Descriptor: protobuf.Descriptor{MessageType: "telegraf.test.Metric"}})
+	require.Error(t, err)
+
+	_, err = NewSerializer(&Serializer{Descriptor: descriptor, TimestampFormat: "RFC3339"})
+	require.Error(t, err)
+}
@@ -2,10 +2,14 @@ package serializers
 
 import (
 	"fmt"
+	"io"
 	"time"
 
 	"github.com/influxdata/telegraf"
+	common "github.com/influxdata/telegraf/plugins/common/protobuf"
+	"github.com/influxdata/telegraf/plugins/serializers/avro"
 	"github.com/influxdata/telegraf/plugins/serializers/carbon2"
+	"github.com/influxdata/telegraf/plugins/serializers/csv"
 	"github.com/influxdata/telegraf/plugins/serializers/graphite"
 	"github.com/influxdata/telegraf/plugins/serializers/influx"
 	"github.com/influxdata/telegraf/plugins/serializers/json"
@@ -13,6 +17,7 @@ import (
 	"github.com/influxdata/telegraf/plugins/serializers/nowmetric"
 	"github.com/influxdata/telegraf/plugins/serializers/prometheus"
 	"github.com/influxdata/telegraf/plugins/serializers/prometheusremotewrite"
+	"github.com/influxdata/telegraf/plugins/serializers/protobuf"
 	"github.com/influxdata/telegraf/plugins/serializers/splunkmetric"
 	"github.com/influxdata/telegraf/plugins/serializers/wavefront"
 )
@@ -44,6 +49,25 @@ type Serializer interface {
 	SerializeBatch(metrics []telegraf.Metric) ([]byte, error)
 }
 
+// HeaderSerializer is implemented by serializers writing a header once per
+// stream of metrics, e.g. the column names of the CSV format.  Outputs
+// writing to files call StartStream or ResumeStream when they start writing
+// to a file.  For outputs never calling them each batch is a stream.
+type HeaderSerializer interface {
+	Serializer
+
+	// StartStream starts a new stream of metrics.  The next metrics are
+	// preceded by the header if writeHeader is set, it is not set when
+	// continuing a stream such as a non-empty file.
+	StartStream(writeHeader bool)
+
+	// ResumeStream continues the stream read by r, e.g. a non-empty file,
+	// keeping the layout given by its header.  The next metrics are
+	// preceded by the header if writeHeader is set, e.g. for other files
+	// written at the same time.
+	ResumeStream(r io.Reader, writeHeader bool) error
+}
+
 // Config is a struct that covers the data types needed for all serializer types,
 // and can be used to instantiate _any_ of the serializers.
 type Config struct {
@@ -117,6 +141,62 @@ type Config struct {
 	// Output string fields as metric labels; when false string fields are
 	// discarded.
 	PrometheusStringAsLabel bool `toml:"prometheus_string_as_label"`
+
+	// Inline Avro schema of the records to write
+	AvroSchema string `toml:"avro_schema"`
+
+	// Address of the schema registry to retrieve the schema from
+	AvroSchemaRegistry string `toml:"avro_schema_registry"`
+
+	// Schema ID to reference in the Confluent wire format; plain Avro
+	// records are written if unset
+	AvroSchemaID int `toml:"avro_schema_id"`
+
+	// Record field receiving the metric name
+	AvroMeasurementField string `toml:"avro_measurement_field"`
+
+	// Record field receiving the metric timestamp
+	AvroTimestamp string `toml:"avro_timestamp"`
+
+	// Unit of the timestamp for non-logical record field types
+	AvroTimestampFormat string `toml:"avro_timestamp_format"`
+
+	// Protocol-buffer definition files and their import paths
+	ProtobufFiles       []string `toml:"protobuf_files"`
+	ProtobufImportPaths []string `toml:"protobuf_import_paths"`
+
+	// Compiled FileDescriptorSet as alternative to the definition files
+	ProtobufDescriptorSet string `toml:"protobuf_descriptor_set"`
+
+	// Fully qualified name of the message type to write
+	ProtobufMessageType string `toml:"protobuf_message_type"`
+
+	// Message field receiving the metric name
+	ProtobufMeasurementField string `toml:"protobuf_measurement_field"`
+
+	// Message field receiving the metric timestamp
+	ProtobufTimestamp string `toml:"protobuf_timestamp"`
+
+	// Unit of the timestamp for integer message fields
+	ProtobufTimestampFormat string `toml:"protobuf_timestamp_format"`
+
+	// Prefix each message with its varint encoded length
+	ProtobufLengthDelimited bool `toml:"protobuf_length_delimited"`
+
+	// Ordered columns to write for CSV output
+	CSVColumns []string `toml:"csv_columns"`
+
+	// Emission of the CSV header row, either "none", "once" or "batch"
+	CSVHeader string `toml:"csv_header"`
+
+	// Character separating the CSV columns
+	CSVSeparator string `toml:"csv_separator"`
+
+	// Format of the CSV timestamp column
+	CSVTimestampFormat string `toml:"csv_timestamp_format"`
+
+	// Derive the CSV columns from all metrics of a batch
+	CSVWide bool `toml:"csv_wide"`
 }
 
 // NewSerializer a Serializer interface based on the given config.
@@ -144,6 +224,12 @@ func NewSerializer(config *Config) (Serializer, error) {
 		serializer, err = NewPrometheusRemoteWriteSerializer(config)
 	case "msgpack":
 		serializer, err = NewMsgpackSerializer()
+	case "avro":
+		serializer, err = NewAvroSerializer(config)
+	case "protobuf":
+		serializer, err = NewProtobufSerializer(config)
+	case "csv":
+		serializer, err = NewCSVSerializer(config)
 	default:
 		err = fmt.Errorf("invalid data format: %s", config.DataFormat)
 	}
@@ -261,6 +347,42 @@ func NewGraphiteSerializer(prefix, template string, tagSupport bool, tagSanitize
 	}, nil
 }
 
+func NewAvroSerializer(config *Config) (Serializer, error) {
+	return avro.NewSerializer(&avro.Serializer{
+		Schema:           config.AvroSchema,
+		SchemaRegistry:   config.AvroSchemaRegistry,
+		SchemaID:         config.AvroSchemaID,
+		MeasurementField: config.AvroMeasurementField,
+		Timestamp:        config.AvroTimestamp,
+		TimestampFormat:  config.AvroTimestampFormat,
+	})
+}
+
+func NewProtobufSerializer(config *Config) (Serializer, error) {
+	return protobuf.NewSerializer(&protobuf.Serializer{
+		Descriptor: common.Descriptor{
+			Files:         config.ProtobufFiles,
+			ImportPaths:   config.ProtobufImportPaths,
+			DescriptorSet: config.ProtobufDescriptorSet,
+			MessageType:   config.ProtobufMessageType,
+		},
+		MeasurementField: config.ProtobufMeasurementField,
+		Timestamp:        config.ProtobufTimestamp,
+		TimestampFormat:  config.ProtobufTimestampFormat,
+		LengthDelimited:  config.ProtobufLengthDelimited,
+	})
+}
+
+func NewCSVSerializer(config *Config) (Serializer, error) {
+	return csv.NewSerializer(&csv.Serializer{
+		Columns:         config.CSVColumns,
+		Header:          config.CSVHeader,
+		Separator:       config.CSVSeparator,
+		TimestampFormat: config.CSVTimestampFormat,
+		Wide:            config.CSVWide,
+	})
+}
+
 func NewMsgpackSerializer() (Serializer, error) {
 	return msgpack.NewSerializer(), nil
 }
@@ -0,0 +1,15 @@
+package telegraf
+
+// SecretStore is a plugin providing secrets referenced in the configuration
+// of other plugins.  Secrets are requested each time they are used, so
+// implementations should avoid keeping the plain secrets in memory.
+type SecretStore interface {
+	PluginDescriber
+
+	// Get returns the secret stored under the given key.
+	Get(key string) ([]byte, error)
+	// Set stores the secret under the given key.
+	Set(key, value string) error
+	// List returns the keys of all secrets in the store.
+	List() ([]string, error)
+}
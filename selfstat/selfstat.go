@@ -75,6 +75,13 @@ func RegisterTiming(measurement, field string, tags map[string]string) Stat {
 	return registry.registerTiming("internal_"+measurement, field, tags)
 }
 
+// Unregister removes the given stat from the selfstat registry. Stats of
+// dynamic entities, e.g. discovered targets, should be unregistered once the
+// entity is gone to not report stale values forever.
+func Unregister(s Stat) {
+	registry.unregister(s)
+}
+
 // Metrics returns all registered stats as telegraf metrics.
 func Metrics() []telegraf.Metric {
 	registry.mu.Lock()
@@ -104,6 +111,25 @@ func Metrics() []telegraf.Metric {
 	return metrics
 }
 
+// Values returns the current values of the stats registered for the given
+// measurement and tags, keyed by field.  Unlike Metrics, reading the values
+// does not reset the average of timing stats.
+func Values(measurement string, tags map[string]string) map[string]int64 {
+	registry.mu.Lock()
+	defer registry.mu.Unlock()
+
+	stats := registry.stats[key("internal_"+measurement, tags)]
+	values := make(map[string]int64, len(stats))
+	for field, stat := range stats {
+		if p, ok := stat.(interface{ peek() int64 }); ok {
+			values[field] = p.peek()
+		} else {
+			values[field] = stat.Get()
+		}
+	}
+	return values
+}
+
 type Registry struct {
 	stats map[uint64]map[string]Stat
 	mu    sync.Mutex
@@ -155,6 +181,21 @@ func (r *Registry) registerTiming(measurement, field string, tags map[string]str
 	return s
 }
 
+func (r *Registry) unregister(s Stat) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	key := key(s.Name(), s.Tags())
+	stats, ok := r.stats[key]
+	if !ok || stats[s.FieldName()] != s {
+		return
+	}
+	delete(stats, s.FieldName())
+	if len(stats) == 0 {
+		delete(r.stats, key)
+	}
+}
+
 func (r *Registry) get(key uint64, field string) (Stat, bool) {
 	if _, ok := r.stats[key]; !ok {
 		return nil, false
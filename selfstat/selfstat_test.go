@@ -108,6 +108,48 @@ func TestRegisterTimingAndIncrAndSet(t *testing.T) {
 	assert.Equal(t, "internal_test", foo.Name())
 }
 
+func TestValues(t *testing.T) {
+	testLock.Lock()
+	defer testCleanup()
+	s1 := Register("test", "test_field1", map[string]string{"test": "foo"})
+	s2 := RegisterTiming("test", "test_field2_ns", map[string]string{"test": "foo"})
+	Register("test", "test_field3", map[string]string{"test": "bar"})
+
+	s1.Incr(3)
+	s2.Incr(10)
+	s2.Incr(20)
+
+	expected := map[string]int64{"test_field1": 3, "test_field2_ns": 15}
+	require.Equal(t, expected, Values("test", map[string]string{"test": "foo"}))
+
+	// reading the values must not reset timings
+	require.Equal(t, expected, Values("test", map[string]string{"test": "foo"}))
+	require.Equal(t, int64(15), s2.Get())
+
+	require.Empty(t, Values("test", map[string]string{"test": "unknown"}))
+}
+
+func TestUnregister(t *testing.T) {
+	testLock.Lock()
+	defer testCleanup()
+	s1 := Register("test", "test_field1", map[string]string{"test": "foo"})
+	s2 := Register("test", "test_field2", map[string]string{"test": "foo"})
+	s1.Incr(1)
+	s2.Incr(2)
+
+	Unregister(s1)
+	require.Equal(t, map[string]int64{"test_field2": 2}, Values("test", map[string]string{"test": "foo"}))
+	require.Len(t, Metrics(), 1)
+
+	Unregister(s2)
+	require.Empty(t, Values("test", map[string]string{"test": "foo"}))
+	require.Empty(t, Metrics())
+
+	// registering again starts anew
+	s1 = Register("test", "test_field1", map[string]string{"test": "foo"})
+	require.Equal(t, int64(0), s1.Get())
+}
+
 func TestStatKeyConsistency(t *testing.T) {
 	lhs := key("internal_stats", map[string]string{
 		"foo":   "bar",
@@ -0,0 +1,36 @@
+package testutil
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+func TestDecodeProto(t *testing.T) {
+	var nested []byte
+	nested = protowire.AppendTag(nested, 1, protowire.VarintType)
+	nested = protowire.AppendVarint(nested, protowire.EncodeZigZag(-1))
+
+	var b []byte
+	b = protowire.AppendTag(b, 1, protowire.BytesType)
+	b = protowire.AppendBytes(b, []byte("name"))
+	b = protowire.AppendTag(b, 2, protowire.Fixed64Type)
+	b = protowire.AppendFixed64(b, math.Float64bits(1.5))
+	b = protowire.AppendTag(b, 3, protowire.Fixed32Type)
+	b = protowire.AppendFixed32(b, 7)
+	b = protowire.AppendTag(b, 4, protowire.BytesType)
+	b = protowire.AppendBytes(b, nested)
+	b = protowire.AppendTag(b, 4, protowire.BytesType)
+	b = protowire.AppendBytes(b, nested)
+
+	fields := DecodeProto(t, b)
+	require.Equal(t, map[protowire.Number][]interface{}{
+		1: {[]byte("name")},
+		2: {math.Float64bits(1.5)},
+		3: {uint64(7)},
+		4: {nested, nested},
+	}, fields)
+	require.Equal(t, map[protowire.Number][]interface{}{1: {uint64(1)}}, DecodeProto(t, fields[4][0].([]byte)))
+}